@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -14,12 +16,23 @@ import (
 	"github.com/ashep/go-app/httpserver"
 	"github.com/ashep/go-app/prommetrics"
 	"github.com/ashep/go-app/runner"
+	"github.com/ashep/ujds/internal/idempotencyrepo"
 	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/jobrepo"
+	"github.com/ashep/ujds/internal/jobrunner"
+	"github.com/ashep/ujds/internal/recordarchiver"
+	"github.com/ashep/ujds/internal/recordcompactor"
+	"github.com/ashep/ujds/internal/recordreaper"
 	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/recordsweeper"
+	"github.com/ashep/ujds/internal/rpc/idempotency"
 	"github.com/ashep/ujds/internal/rpc/indexhandler"
 	"github.com/ashep/ujds/internal/rpc/recordhandler"
+	"github.com/ashep/ujds/internal/schemareloader"
 	"github.com/ashep/ujds/internal/validation"
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 	indexconnect "github.com/ashep/ujds/sdk/proto/ujds/index/v1/v1connect"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
 	recordconnect "github.com/ashep/ujds/sdk/proto/ujds/record/v1/v1connect"
 	"github.com/ashep/ujds/sql"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -34,6 +47,46 @@ func Run(rt *runner.Runtime[Config]) error { //nolint:cyclop // to do
 		cfg.Server.Addr = ":9000"
 	}
 
+	if cfg.Reaper.Interval <= 0 {
+		cfg.Reaper.Interval = time.Minute
+	}
+
+	if cfg.Reaper.BatchSize == 0 {
+		cfg.Reaper.BatchSize = 1000
+	}
+
+	if cfg.Sweeper.Interval <= 0 {
+		cfg.Sweeper.Interval = time.Minute * 10
+	}
+
+	if cfg.Sweeper.BatchSize == 0 {
+		cfg.Sweeper.BatchSize = 1000
+	}
+
+	if cfg.Compactor.Interval <= 0 {
+		cfg.Compactor.Interval = time.Hour
+	}
+
+	if cfg.Compactor.BatchSize == 0 {
+		cfg.Compactor.BatchSize = 1000
+	}
+
+	if cfg.Archiver.Interval <= 0 {
+		cfg.Archiver.Interval = time.Hour
+	}
+
+	if cfg.Archiver.BatchSize == 0 {
+		cfg.Archiver.BatchSize = 10000
+	}
+
+	if cfg.Idempotency.Window <= 0 {
+		cfg.Idempotency.Window = time.Hour * 24
+	}
+
+	if cfg.Idempotency.Lease <= 0 {
+		cfg.Idempotency.Lease = time.Minute * 5
+	}
+
 	migRes, err := dbmigrator.RunPostgres(cfg.DB.DSN, l, dbmigrator.Source{FS: sql.FS, Path: "migrations"})
 	if err != nil {
 		return fmt.Errorf("migrate db: %w", err)
@@ -58,25 +111,83 @@ func Run(rt *runner.Runtime[Config]) error { //nolint:cyclop // to do
 	prommetrics.RegisterServer(rt.AppName, rt.AppVersion, srv)
 
 	idxNameValidator := validation.NewIndexNameValidator()
-	recIDValidator := validation.NewRecordIDValidator()
-	recDataValidator := validation.NewJSONValidator(cfg.Validation.IndexStruct)
+
+	recIDValidator, err := validation.NewRecordIDValidator(cfg.Validation.RecordIDStruct)
+	if err != nil {
+		return fmt.Errorf("init record id validator: %w", err)
+	}
+
+	var schemasDir fs.FS
+	if cfg.Validation.SchemasDir != "" {
+		schemasDir = os.DirFS(cfg.Validation.SchemasDir)
+	}
+
+	recDataValidator, err := validation.NewJSONValidator(cfg.Validation.IndexStruct, schemasDir)
+	if err != nil {
+		return fmt.Errorf("init record data validator: %w", err)
+	}
+
+	loadSchemasConfig := func() (schemareloader.Config, error) {
+		c, err := loadConfig(rt.AppName2)
+		if err != nil {
+			return schemareloader.Config{}, err
+		}
+
+		return schemareloader.Config{Schemas: c.Validation.IndexStruct, Dir: c.Validation.SchemasDir}, nil
+	}
+
+	schemasCfg := schemareloader.Config{Schemas: cfg.Validation.IndexStruct, Dir: cfg.Validation.SchemasDir}
+	schemaReloader := schemareloader.New(recDataValidator, schemasCfg, loadSchemasConfig, rt.Log)
+	go schemaReloader.Run(rt.Ctx)
 
 	ir := indexrepo.New(db, idxNameValidator, rt.Log)
 	rr := recordrepo.New(db, idxNameValidator, recIDValidator, rt.Log)
 
-	icps := connect.WithInterceptors(auth(rt.Cfg.Server.AuthToken))
+	go recordreaper.New(rr, cfg.Reaper.Interval, cfg.Reaper.BatchSize, rt.Log).Run(rt.Ctx)
 
-	indexPath, indexHandler := indexconnect.NewIndexServiceHandler(
-		indexhandler.New(ir, recDataValidator, idxNameValidator, time.Now, rt.Log),
-		icps,
-	)
+	sweeper, err := recordsweeper.New(ir, rr, cfg.Sweeper.IndexStruct, cfg.Sweeper.Interval, cfg.Sweeper.BatchSize,
+		time.Now, rt.Log)
+	if err != nil {
+		return fmt.Errorf("init stale records sweeper: %w", err)
+	}
+	go sweeper.Run(rt.Ctx)
+
+	archiver, err := recordarchiver.New(ir, rr, segmentStore(cfg), cfg.Archiver.IndexStruct, cfg.Archiver.Interval,
+		cfg.Archiver.BatchSize, time.Now, rt.Log)
+	if err != nil {
+		return fmt.Errorf("init history archiver: %w", err)
+	}
+	go archiver.Run(rt.Ctx)
+
+	compactor, err := recordcompactor.New(ir, rr, cfg.Compactor.IndexStruct, archiver.Archives, cfg.Compactor.Interval,
+		cfg.Compactor.BatchSize, time.Now, rt.Log)
+	if err != nil {
+		return fmt.Errorf("init history compactor: %w", err)
+	}
+	go compactor.Run(rt.Ctx)
+
+	jr := jobrunner.New(rt.Ctx, jobrepo.New(db, rt.Log), rt.Log)
+	defer jr.Wait()
+
+	idem := idempotency.New(idempotencyrepo.New(db, rt.Log), cfg.Idempotency.Window, cfg.Idempotency.Lease,
+		idempotentProcedures(), time.Now, rt.Log)
+	go idem.Run(rt.Ctx)
+
+	icps := connect.WithInterceptors(auth(rt.Cfg.Server.AuthToken), idem)
+
+	idxHandler := indexhandler.New(ir, rr, jr, recDataValidator, idxNameValidator, compactor, archiver, time.Now,
+		rt.Log)
+	indexPath, indexHandler := indexconnect.NewIndexServiceHandler(idxHandler, icps)
 	srv.Handle(indexPath, cors(indexHandler))
+	srv.Handle("/export/{index}", cors(auth(rt.Cfg.Server.AuthToken).wrapHTTP(http.HandlerFunc(idxHandler.ExportNDJSON))))
 
-	recordPath, recordHandler := recordconnect.NewRecordServiceHandler(
-		recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, time.Now, rt.Log),
-		icps,
+	recHandler := recordhandler.New(
+		ir, rr, idxNameValidator, recIDValidator, recDataValidator, sweeper, archiver,
+		time.Now, rt.Log,
 	)
+	recordPath, recordHandler := recordconnect.NewRecordServiceHandler(recHandler, icps)
 	srv.Handle(recordPath, cors(recordHandler))
+	srv.Handle("/import/{index}", cors(auth(rt.Cfg.Server.AuthToken).wrapHTTP(http.HandlerFunc(recHandler.ImportNDJSON))))
 
 	var resErr error
 	rt.Log.Info().Str("addr", srv.Listener().Addr().String()).Msg("starting")
@@ -89,20 +200,75 @@ func Run(rt *runner.Runtime[Config]) error { //nolint:cyclop // to do
 	return resErr
 }
 
-func auth(token string) connect.UnaryInterceptorFunc {
-	return func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			if token == "" {
-				return next(ctx, req)
-			}
+// idempotentProcedures returns the mutating procedures which honour idempotency keys. The streaming RecordService/Import
+// is excluded: its request can't be hashed before it is processed, and importing the same records again is a no-op.
+func idempotentProcedures() map[string]idempotency.ReplayFunc {
+	return map[string]idempotency.ReplayFunc{
+		indexconnect.IndexServicePushProcedure:      idempotency.Replay[indexproto.PushResponse](),
+		indexconnect.IndexServiceClearProcedure:     idempotency.Replay[indexproto.ClearResponse](),
+		indexconnect.IndexServiceCopyProcedure:      idempotency.Replay[indexproto.CopyResponse](),
+		indexconnect.IndexServiceSetSchemaProcedure: idempotency.Replay[indexproto.SetSchemaResponse](),
+		indexconnect.IndexServiceCompactProcedure:   idempotency.Replay[indexproto.CompactResponse](),
+		recordconnect.RecordServicePushProcedure:    idempotency.Replay[recordproto.PushResponse](),
+		recordconnect.RecordServiceBatchProcedure:   idempotency.Replay[recordproto.BatchResponse](),
+	}
+}
+
+// authInterceptor checks the authorization token of both unary and streaming requests.
+type authInterceptor struct {
+	token string
+}
+
+func auth(token string) *authInterceptor {
+	return &authInterceptor{token: token}
+}
+
+func (a *authInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if err := a.check(req.Header()); err != nil {
+			return nil, err
+		}
+
+		return next(ctx, req)
+	}
+}
+
+func (a *authInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
 
-			if token == strings.ReplaceAll(req.Header().Get("Authorization"), "Bearer ", "") {
-				return next(ctx, req)
-			}
+func (a *authInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := a.check(conn.RequestHeader()); err != nil {
+			return err
+		}
 
-			return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("not authorized"))
+		return next(ctx, conn)
+	}
+}
+
+// wrapHTTP checks the authorization token of plain HTTP requests.
+func (a *authInterceptor) wrapHTTP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.check(r.Header); err != nil {
+			_ = connect.NewErrorWriter().Write(w, r, err)
+			return
 		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *authInterceptor) check(h http.Header) error {
+	if a.token == "" {
+		return nil
+	}
+
+	if a.token == strings.ReplaceAll(h.Get("Authorization"), "Bearer ", "") {
+		return nil
 	}
+
+	return connect.NewError(connect.CodeUnauthenticated, errors.New("not authorized"))
 }
 
 func cors(next http.Handler) http.Handler {
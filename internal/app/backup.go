@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ashep/go-app/dbmigrator"
+	"github.com/ashep/go-app/runner"
+	"github.com/ashep/ujds/internal/backup"
+	"github.com/ashep/ujds/internal/recordarchiver"
+	"github.com/ashep/ujds/sql"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Backup returns the run func of the backup command, which writes all the service's data to the file at path.
+func Backup(path string) func(rt *runner.Runtime[Config]) error {
+	return func(rt *runner.Runtime[Config]) error {
+		pool, err := pgxpool.New(rt.Ctx, rt.Cfg.DB.DSN)
+		if err != nil {
+			return fmt.Errorf("connect to db: %w", err)
+		}
+		defer pool.Close()
+
+		// The backup is written next to the target file first, so a failed backup never replaces a good one
+		f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return fmt.Errorf("create file: %w", err)
+		}
+
+		defer func() {
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}()
+
+		stats, err := backup.New(stdlib.OpenDBFromPool(pool), segmentStore(rt.Cfg), time.Now, rt.Log).Backup(rt.Ctx, f)
+		if err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("close file: %w", err)
+		}
+
+		if err := os.Rename(f.Name(), path); err != nil {
+			return fmt.Errorf("rename file: %w", err)
+		}
+
+		rt.Log.Info().
+			Str("path", path).
+			Uint64("indices", stats.Indices).
+			Uint64("revisions", stats.Revisions).
+			Uint64("records", stats.Records).
+			Msg("backup done")
+
+		return nil
+	}
+}
+
+// Restore returns the run func of the restore command, which restores a backup from the file at path into an empty
+// database.
+func Restore(path string) func(rt *runner.Runtime[Config]) error {
+	return func(rt *runner.Runtime[Config]) error {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open file: %w", err)
+		}
+
+		defer func() {
+			_ = f.Close()
+		}()
+
+		if _, err := dbmigrator.RunPostgres(rt.Cfg.DB.DSN, rt.Log, dbmigrator.Source{FS: sql.FS, Path: "migrations"}); err != nil {
+			return fmt.Errorf("migrate db: %w", err)
+		}
+
+		pool, err := pgxpool.New(rt.Ctx, rt.Cfg.DB.DSN)
+		if err != nil {
+			return fmt.Errorf("connect to db: %w", err)
+		}
+		defer pool.Close()
+
+		stats, err := backup.New(stdlib.OpenDBFromPool(pool), segmentStore(rt.Cfg), time.Now, rt.Log).Restore(rt.Ctx, f)
+		if err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+
+		rt.Log.Info().
+			Str("path", path).
+			Uint64("indices", stats.Indices).
+			Uint64("revisions", stats.Revisions).
+			Uint64("records", stats.Records).
+			Msg("restore done")
+
+		return nil
+	}
+}
+
+// segmentStore returns the store of history archive segment files, or nil if the archive dir is not configured.
+func segmentStore(cfg *Config) recordarchiver.Store {
+	if cfg.Archiver.Dir == "" {
+		return nil
+	}
+
+	return recordarchiver.NewDirStore(cfg.Archiver.Dir)
+}
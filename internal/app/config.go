@@ -2,7 +2,16 @@ package app
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"time"
+
+	"github.com/ashep/go-app/cfgloader"
+	"github.com/ashep/ujds/internal/recordarchiver"
+	"github.com/ashep/ujds/internal/recordcompactor"
+	"github.com/ashep/ujds/internal/recordsweeper"
+	"github.com/ashep/ujds/internal/validation"
 )
 
 type Server struct {
@@ -15,14 +24,54 @@ type Database struct {
 }
 
 type Validation struct {
-	Index       string                     // to load from env var
-	IndexStruct map[string]json.RawMessage `json:"index" yaml:"index" env:"ignore"`
+	Index          string                             // to load from env var
+	IndexStruct    map[string]json.RawMessage         `json:"index" yaml:"index" env:"ignore"`
+	SchemasDir     string                             `json:"schemas_dir" yaml:"schemas_dir"`
+	RecordID       string                             // to load from env var
+	RecordIDStruct map[string]validation.RecordIDRule `json:"record_id" yaml:"record_id" env:"ignore"`
+}
+
+type Reaper struct {
+	Interval  time.Duration `json:"interval" yaml:"interval"`     // how often expired records are deleted
+	BatchSize uint32        `json:"batch_size" yaml:"batch_size"` // number of records deleted per query
+}
+
+type Sweeper struct {
+	Interval    time.Duration                   `json:"interval" yaml:"interval"`     // how often stale records are swept
+	BatchSize   uint32                          `json:"batch_size" yaml:"batch_size"` // number of records swept per query
+	Index       string                          // to load from env var
+	IndexStruct map[string]recordsweeper.Policy `json:"index" yaml:"index" env:"ignore"`
+}
+
+type Compactor struct {
+	Interval    time.Duration                     `json:"interval" yaml:"interval"`     // how often history is compacted
+	BatchSize   uint32                            `json:"batch_size" yaml:"batch_size"` // number of revisions checked per query
+	Index       string                            // to load from env var
+	IndexStruct map[string]recordcompactor.Policy `json:"index" yaml:"index" env:"ignore"`
+}
+
+type Archiver struct {
+	Interval    time.Duration                    `json:"interval" yaml:"interval"`     // how often history is archived
+	BatchSize   uint32                           `json:"batch_size" yaml:"batch_size"` // number of revisions per segment
+	Dir         string                           `json:"dir" yaml:"dir"`               // segment files directory
+	Index       string                           // to load from env var
+	IndexStruct map[string]recordarchiver.Policy `json:"index" yaml:"index" env:"ignore"`
+}
+
+type Idempotency struct {
+	Window time.Duration `json:"window" yaml:"window"` // how long responses are stored for idempotency keys
+	Lease  time.Duration `json:"lease" yaml:"lease"`   // how long a request with an idempotency key may be processed
 }
 
 type Config struct {
-	DB         Database   `json:"db" yaml:"db"`
-	Server     Server     `json:"server" yaml:"server"`
-	Validation Validation `json:"validation" yaml:"validation"`
+	DB          Database    `json:"db" yaml:"db"`
+	Server      Server      `json:"server" yaml:"server"`
+	Validation  Validation  `json:"validation" yaml:"validation"`
+	Reaper      Reaper      `json:"reaper" yaml:"reaper"`
+	Sweeper     Sweeper     `json:"sweeper" yaml:"sweeper"`
+	Compactor   Compactor   `json:"compactor" yaml:"compactor"`
+	Archiver    Archiver    `json:"archiver" yaml:"archiver"`
+	Idempotency Idempotency `json:"idempotency" yaml:"idempotency"`
 }
 
 func (c *Config) Validate() error {
@@ -32,6 +81,30 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.Validation.RecordID != "" {
+		if err := json.Unmarshal([]byte(c.Validation.RecordID), &c.Validation.RecordIDStruct); err != nil {
+			return fmt.Errorf("VALIDATION_RECORDID: parse JSON: %w", err)
+		}
+	}
+
+	if c.Sweeper.Index != "" {
+		if err := json.Unmarshal([]byte(c.Sweeper.Index), &c.Sweeper.IndexStruct); err != nil {
+			return fmt.Errorf("SWEEPER_INDEX: parse JSON: %w", err)
+		}
+	}
+
+	if c.Compactor.Index != "" {
+		if err := json.Unmarshal([]byte(c.Compactor.Index), &c.Compactor.IndexStruct); err != nil {
+			return fmt.Errorf("COMPACTOR_INDEX: parse JSON: %w", err)
+		}
+	}
+
+	if c.Archiver.Index != "" {
+		if err := json.Unmarshal([]byte(c.Archiver.Index), &c.Archiver.IndexStruct); err != nil {
+			return fmt.Errorf("ARCHIVER_INDEX: parse JSON: %w", err)
+		}
+	}
+
 	if c.Validation.IndexStruct == nil {
 		c.Validation.IndexStruct = make(map[string]json.RawMessage)
 	}
@@ -40,3 +113,34 @@ func (c *Config) Validate() error {
 
 	return nil
 }
+
+// loadConfig loads the configuration anew the same way it's loaded at startup: from the env variables, then from
+// config.yml and the files set by the *_CONFIG_PATH env variables.
+func loadConfig(appName string) (Config, error) {
+	cfg := Config{}
+	prefixes := []string{"APP", appName}
+
+	for _, prefix := range prefixes {
+		if err := cfgloader.LoadFromEnv(prefix, &cfg); err != nil {
+			return Config{}, fmt.Errorf("load %s_ env vars: %w", prefix, err)
+		}
+	}
+
+	if err := cfgloader.LoadFromPath("config.yml", &cfg, nil); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return Config{}, fmt.Errorf("load config.yml: %w", err)
+	}
+
+	for _, prefix := range prefixes {
+		if pth := os.Getenv(prefix + "_CONFIG_PATH"); pth != "" {
+			if err := cfgloader.LoadFromPath(pth, &cfg, nil); err != nil {
+				return Config{}, fmt.Errorf("load %s: %w", pth, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
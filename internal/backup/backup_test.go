@@ -0,0 +1,160 @@
+package backup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/backup"
+)
+
+// storeMock keeps segment files in memory.
+type storeMock struct {
+	files map[string][]byte
+}
+
+func (m *storeMock) Put(_ context.Context, name string, data []byte) error {
+	if m.files == nil {
+		m.files = make(map[string][]byte)
+	}
+
+	m.files[name] = data
+
+	return nil
+}
+
+func (m *storeMock) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	data, ok := m.files[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestManager_Backup(tt *testing.T) {
+	now := func() time.Time { return time.Unix(123456789, 0) }
+	ts := time.Unix(123, 0).UTC()
+
+	tt.Run("DbQueryError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT id, name, title, schema_version, ttl, read_only, insert_only, no_history, labels, metadata,
+created_at, updated_at FROM index`).
+			WillReturnError(errors.New("theDbError"))
+		dbm.ExpectRollback()
+
+		m := backup.New(db, nil, now, zerolog.Nop())
+		_, err = m.Backup(context.Background(), io.Discard)
+
+		assert.EqualError(t, err, "write indices: db query: theDbError")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("SegmentStoreNotConfigured", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`FROM index ORDER BY id`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM index_schema ORDER BY index_id, version`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM record_log ORDER BY id`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM record ORDER BY index_id, id`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM record_log_segment ORDER BY id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "index_id", "path", "min_rev", "max_rev", "revisions",
+				"record_ids", "created_at"}).
+				AddRow(1, 1, "1/a.ndjson.gz", 1, 2, 2, `["theRecordID"]`, ts))
+		dbm.ExpectRollback()
+
+		m := backup.New(db, nil, now, zerolog.Nop())
+		_, err = m.Backup(context.Background(), io.Discard)
+
+		assert.EqualError(t, err, "write segments: segment 1/a.ndjson.gz: archive dir is not configured")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("SegmentFileNotFound", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`FROM index ORDER BY id`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM index_schema ORDER BY index_id, version`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM record_log ORDER BY id`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM record ORDER BY index_id, id`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectQuery(`FROM record_log_segment ORDER BY id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "index_id", "path", "min_rev", "max_rev", "revisions",
+				"record_ids", "created_at"}).
+				AddRow(1, 1, "1/a.ndjson.gz", 1, 2, 2, `["theRecordID"]`, ts))
+		dbm.ExpectRollback()
+
+		m := backup.New(db, &storeMock{}, now, zerolog.Nop())
+		_, err = m.Backup(context.Background(), io.Discard)
+
+		assert.EqualError(t, err, "write segments: segment 1/a.ndjson.gz: file does not exist")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`FROM index ORDER BY id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "title", "schema_version", "ttl", "read_only",
+				"insert_only", "no_history", "labels", "metadata", "created_at", "updated_at"}).
+				AddRow(1, "theIndex", "theTitle", 1, 60, true, false, false, `{"team": "search"}`, `{}`, ts, ts))
+		dbm.ExpectQuery(`FROM index_schema ORDER BY index_id, version`).
+			WillReturnRows(sqlmock.NewRows([]string{"index_id", "version", "schema", "created_at"}).
+				AddRow(1, 1, `{"type": "object"}`, ts))
+		dbm.ExpectQuery(`FROM record_log ORDER BY id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "index_id", "record_id", "data", "created_at"}).
+				AddRow(3, 1, "theRecordID", `{"foo": "bar"}`, ts))
+		dbm.ExpectQuery(`FROM record ORDER BY index_id, id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "index_id", "log_id", "created_at", "updated_at",
+				"touched_at", "expires_at", "stale_at"}).
+				AddRow("theRecordID", 1, 3, ts, ts, ts, ts, nil))
+		dbm.ExpectQuery(`FROM record_log_segment ORDER BY id`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "index_id", "path", "min_rev", "max_rev", "revisions",
+				"record_ids", "created_at"}).
+				AddRow(1, 1, "1/a.ndjson.gz", 1, 2, 2, `["theRecordID"]`, ts))
+		dbm.ExpectCommit()
+
+		buf := &bytes.Buffer{}
+		store := &storeMock{files: map[string][]byte{"1/a.ndjson.gz": []byte("theSegment")}}
+
+		m := backup.New(db, store, now, zerolog.Nop())
+		stats, err := m.Backup(context.Background(), buf)
+
+		require.NoError(t, err)
+		assert.NoError(t, dbm.ExpectationsWereMet())
+		assert.Equal(t, backup.Stats{Indices: 1, Schemas: 1, Revisions: 1, Records: 1, Segments: 1}, stats)
+
+		zr, err := gzip.NewReader(buf)
+		require.NoError(t, err)
+		b, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		assert.Equal(t, strings.Join([]string{
+			`{"format":"ujds-backup","version":1,"created_at":"1973-11-29T21:33:09Z"}`,
+			`{"index":{"id":1,"name":"theIndex","title":"theTitle","schema_version":1,"ttl":60,"read_only":true,"labels":{"team":"search"},"metadata":{},"created_at":"1970-01-01T00:02:03Z","updated_at":"1970-01-01T00:02:03Z"}}`,
+			`{"schema":{"index_id":1,"version":1,"schema":{"type":"object"},"created_at":"1970-01-01T00:02:03Z"}}`,
+			`{"log":{"id":3,"index_id":1,"record_id":"theRecordID","data":{"foo":"bar"},"created_at":"1970-01-01T00:02:03Z"}}`,
+			`{"record":{"id":"theRecordID","index_id":1,"log_id":3,"created_at":"1970-01-01T00:02:03Z","updated_at":"1970-01-01T00:02:03Z","touched_at":"1970-01-01T00:02:03Z","expires_at":"1970-01-01T00:02:03Z"}}`,
+			`{"segment":{"id":1,"index_id":1,"path":"1/a.ndjson.gz","min_rev":1,"max_rev":2,"revisions":2,"record_ids":["theRecordID"],"created_at":"1970-01-01T00:02:03Z","content":"dGhlU2VnbWVudA=="}}`,
+		}, "\n")+"\n", string(b))
+	})
+}
@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// FormatName identifies backup files.
+	FormatName = "ujds-backup"
+
+	// FormatVersion is the version of backups being written. Restore accepts backups of this version only.
+	FormatVersion = 1
+)
+
+// header is the first line of a backup.
+type header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// entry is a line of a backup following the header. Exactly one of its fields is set. Entries are written in the
+// order of the fields, so everything an entry refers to is restored before it.
+type entry struct {
+	Index   *indexEntry   `json:"index,omitempty"`
+	Schema  *schemaEntry  `json:"schema,omitempty"`
+	Log     *logEntry     `json:"log,omitempty"`
+	Record  *recordEntry  `json:"record,omitempty"`
+	Segment *segmentEntry `json:"segment,omitempty"`
+}
+
+type indexEntry struct {
+	ID            uint64          `json:"id"`
+	Name          string          `json:"name"`
+	Title         *string         `json:"title,omitempty"`
+	SchemaVersion uint32          `json:"schema_version"`
+	TTL           uint32          `json:"ttl"`
+	ReadOnly      bool            `json:"read_only,omitempty"`
+	InsertOnly    bool            `json:"insert_only,omitempty"`
+	NoHistory     bool            `json:"no_history,omitempty"`
+	Labels        json.RawMessage `json:"labels"`
+	Metadata      json.RawMessage `json:"metadata"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+type schemaEntry struct {
+	IndexID   uint64          `json:"index_id"`
+	Version   uint32          `json:"version"`
+	Schema    json.RawMessage `json:"schema"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// logEntry is a history revision. Revision IDs are kept, so clients' cursors remain meaningful after restore.
+type logEntry struct {
+	ID        uint64          `json:"id"`
+	IndexID   uint64          `json:"index_id"`
+	RecordID  string          `json:"record_id"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// recordEntry is a current record. Its data is restored from the revision it refers to and its checksum is computed
+// from the data.
+type recordEntry struct {
+	ID        string     `json:"id"`
+	IndexID   uint64     `json:"index_id"`
+	LogID     uint64     `json:"log_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	TouchedAt time.Time  `json:"touched_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	StaleAt   *time.Time `json:"stale_at,omitempty"`
+}
+
+// segmentEntry is a history archive segment along with its file.
+type segmentEntry struct {
+	ID        uint64          `json:"id"`
+	IndexID   uint64          `json:"index_id"`
+	Path      string          `json:"path"`
+	MinRev    uint64          `json:"min_rev"`
+	MaxRev    uint64          `json:"max_rev"`
+	Revisions uint32          `json:"revisions"`
+	RecordIDs json.RawMessage `json:"record_ids"`
+	CreatedAt time.Time       `json:"created_at"`
+	Content   []byte          `json:"content"`
+}
+
+// Stats is the number of items backed up or restored.
+type Stats struct {
+	Indices   uint64
+	Schemas   uint64
+	Revisions uint64
+	Records   uint64
+	Segments  uint64
+}
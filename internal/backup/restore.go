@@ -0,0 +1,210 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// restoreStatements are the statements used to restore entries, in the order of the entry fields.
+var restoreStatements = []string{
+	`INSERT INTO index (id, name, title, schema_version, ttl, read_only, insert_only, no_history, labels, metadata,
+created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+	`INSERT INTO index_schema (index_id, version, schema, created_at) VALUES ($1, $2, $3, $4)`,
+	`INSERT INTO record_log (id, index_id, record_id, data, created_at) VALUES ($1, $2, $3, $4, $5)`,
+	`INSERT INTO record (id, index_id, log_id, checksum, data, created_at, updated_at, touched_at, expires_at, stale_at)
+SELECT $1::VARCHAR, $2::INT, $3::BIGINT, sha256(convert_to(l.data::TEXT, 'UTF8')), l.data, $4::TIMESTAMP,
+$5::TIMESTAMP, $6::TIMESTAMP, $7::TIMESTAMP, $8::TIMESTAMP FROM record_log l WHERE l.id=$3`,
+	`INSERT INTO record_log_segment (id, index_id, path, min_rev, max_rev, revisions, record_ids, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+}
+
+// sequences are the tables whose ID sequences are moved past the restored IDs.
+var sequences = []string{"index", "record_log", "record_log_segment"}
+
+// Restore reads a backup written by Backup and restores it within a single transaction. The database must be empty.
+// IDs of indices and history revisions are kept, so revision ordering and clients' cursors remain the same. Segment
+// files are written to the store as they are read, so they are left in place if the restore fails.
+func (m *Manager) Restore(ctx context.Context, r io.Reader) (Stats, error) {
+	stats := Stats{}
+
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return stats, fmt.Errorf("gzip open: %w", err)
+	}
+
+	br := bufio.NewReader(zr)
+
+	if err := readHeader(br); err != nil {
+		return stats, err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("db begin: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	notEmpty := false
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM index)`).Scan(&notEmpty); err != nil {
+		return stats, fmt.Errorf("db check empty: %w", err)
+	} else if notEmpty {
+		return stats, errors.New("database is not empty")
+	}
+
+	stmts := make([]*sql.Stmt, 0, len(restoreStatements))
+
+	defer func() {
+		for _, s := range stmts {
+			if err := s.Close(); err != nil {
+				m.l.Error().Err(err).Msg("prepared statement close failed")
+			}
+		}
+	}()
+
+	for _, q := range restoreStatements {
+		s, err := tx.PrepareContext(ctx, q)
+		if err != nil {
+			return stats, fmt.Errorf("prepare statement: %w", err)
+		}
+
+		stmts = append(stmts, s)
+	}
+
+	for line := 2; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return stats, fmt.Errorf("read line %d: %w", line, err)
+		} else if len(bytes.TrimSpace(b)) == 0 {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			continue
+		}
+
+		e := entry{}
+		if err := json.Unmarshal(b, &e); err != nil {
+			return stats, fmt.Errorf("line %d: decode: %w", line, err)
+		}
+
+		if err := m.restoreEntry(ctx, stmts, e, &stats); err != nil {
+			return stats, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	for _, t := range sequences {
+		q := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), (SELECT COALESCE(max(id), 0) + 1 FROM %[1]s), false)`, t)
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return stats, fmt.Errorf("db set %s sequence: %w", t, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("db commit: %w", err)
+	}
+
+	return stats, nil
+}
+
+func readHeader(br *bufio.Reader) error {
+	b, err := br.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read header: %w", err)
+	}
+
+	h := header{}
+	if err := json.Unmarshal(b, &h); err != nil || h.Format != FormatName {
+		return errors.New("not a backup")
+	}
+
+	if h.Version != FormatVersion {
+		return fmt.Errorf("unsupported backup version %d", h.Version)
+	}
+
+	return nil
+}
+
+func (m *Manager) restoreEntry(ctx context.Context, stmts []*sql.Stmt, e entry, stats *Stats) error {
+	var (
+		err error
+		res sql.Result
+	)
+
+	switch {
+	case e.Index != nil:
+		i := e.Index
+		_, err = stmts[0].ExecContext(ctx, i.ID, i.Name, i.Title, i.SchemaVersion, i.TTL, i.ReadOnly, i.InsertOnly,
+			i.NoHistory, string(i.Labels), string(i.Metadata), i.CreatedAt, i.UpdatedAt)
+		stats.Indices++
+	case e.Schema != nil:
+		s := e.Schema
+		_, err = stmts[1].ExecContext(ctx, s.IndexID, s.Version, string(s.Schema), s.CreatedAt)
+		stats.Schemas++
+	case e.Log != nil:
+		l := e.Log
+		_, err = stmts[2].ExecContext(ctx, l.ID, l.IndexID, l.RecordID, string(l.Data), l.CreatedAt)
+		stats.Revisions++
+	case e.Record != nil:
+		r := e.Record
+		res, err = stmts[3].ExecContext(ctx, r.ID, r.IndexID, r.LogID, r.CreatedAt, r.UpdatedAt, r.TouchedAt,
+			r.ExpiresAt, r.StaleAt)
+		stats.Records++
+	case e.Segment != nil:
+		s := e.Segment
+		if err := m.restoreSegment(ctx, s); err != nil {
+			return err
+		}
+
+		_, err = stmts[4].ExecContext(ctx, s.ID, s.IndexID, s.Path, s.MinRev, s.MaxRev, s.Revisions, string(s.RecordIDs),
+			s.CreatedAt)
+		stats.Segments++
+	default:
+		return errors.New("unknown entry")
+	}
+
+	if err != nil {
+		return fmt.Errorf("db exec: %w", err)
+	}
+
+	if res != nil {
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("get db rows affected: %w", err)
+		} else if n == 0 {
+			return fmt.Errorf("revision %d of record %s is not found", e.Record.LogID, e.Record.ID)
+		}
+	}
+
+	return nil
+}
+
+// restoreSegment writes the segment's file to the store.
+func (m *Manager) restoreSegment(ctx context.Context, s *segmentEntry) error {
+	if !filepath.IsLocal(filepath.FromSlash(s.Path)) {
+		return fmt.Errorf("segment %s: invalid path", s.Path)
+	}
+
+	if s.Content == nil {
+		return fmt.Errorf("segment %s: file content is missing", s.Path)
+	}
+
+	if m.segments == nil {
+		return fmt.Errorf("segment %s: archive dir is not configured", s.Path)
+	}
+
+	if err := m.segments.Put(ctx, s.Path, s.Content); err != nil {
+		return fmt.Errorf("segment %s: %w", s.Path, err)
+	}
+
+	return nil
+}
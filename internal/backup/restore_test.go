@@ -0,0 +1,206 @@
+package backup_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/backup"
+)
+
+// gzipLines returns the lines joined with new lines and gzip-compressed.
+func gzipLines(t *testing.T, lines ...string) *bytes.Buffer {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+
+	_, err := zw.Write([]byte(strings.Join(lines, "\n") + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf
+}
+
+func TestManager_Restore(tt *testing.T) {
+	now := func() time.Time { return time.Unix(123456789, 0) }
+	ts := time.Unix(123, 0).UTC()
+	hdr := `{"format":"ujds-backup","version":1,"created_at":"1973-11-29T21:33:09Z"}`
+
+	tt.Run("NotABackup", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		m := backup.New(db, nil, now, zerolog.Nop())
+		_, err = m.Restore(context.Background(), gzipLines(t, `{"foo":"bar"}`))
+
+		assert.EqualError(t, err, "not a backup")
+	})
+
+	tt.Run("UnsupportedVersion", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		m := backup.New(db, nil, now, zerolog.Nop())
+		_, err = m.Restore(context.Background(), gzipLines(t, `{"format":"ujds-backup","version":2}`))
+
+		assert.EqualError(t, err, "unsupported backup version 2")
+	})
+
+	tt.Run("DatabaseNotEmpty", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		dbm.ExpectRollback()
+
+		m := backup.New(db, nil, now, zerolog.Nop())
+		_, err = m.Restore(context.Background(), gzipLines(t, hdr))
+
+		assert.EqualError(t, err, "database is not empty")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("RecordRevisionNotFound", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		for range 5 {
+			dbm.ExpectPrepare(`INSERT INTO`)
+		}
+		dbm.ExpectExec(`INSERT INTO record \(`).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbm.ExpectRollback()
+
+		m := backup.New(db, nil, now, zerolog.Nop())
+		_, err = m.Restore(context.Background(), gzipLines(t, hdr,
+			`{"record":{"id":"theRecordID","index_id":1,"log_id":3,"created_at":"1970-01-01T00:02:03Z","updated_at":"1970-01-01T00:02:03Z","touched_at":"1970-01-01T00:02:03Z"}}`,
+		))
+
+		assert.EqualError(t, err, "line 2: revision 3 of record theRecordID is not found")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		for range 5 {
+			dbm.ExpectPrepare(`INSERT INTO`)
+		}
+		dbm.ExpectExec(`INSERT INTO index \(`).
+			WithArgs(1, "theIndex", "theTitle", 1, 60, true, false, false, `{"team":"search"}`, `{}`, ts, ts).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`INSERT INTO index_schema`).
+			WithArgs(1, 1, `{"type":"object"}`, ts).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`INSERT INTO record_log \(`).
+			WithArgs(3, 1, "theRecordID", `{"foo":"bar"}`, ts).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`INSERT INTO record \(`).
+			WithArgs("theRecordID", 1, 3, ts, ts, ts, ts, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`INSERT INTO record_log_segment`).
+			WithArgs(1, 1, "1/a.ndjson.gz", 1, 2, 2, `["theRecordID"]`, ts).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`SELECT setval\(pg_get_serial_sequence\('index'`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`SELECT setval\(pg_get_serial_sequence\('record_log'`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`SELECT setval\(pg_get_serial_sequence\('record_log_segment'`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit()
+
+		store := &storeMock{}
+
+		m := backup.New(db, store, now, zerolog.Nop())
+		stats, err := m.Restore(context.Background(), gzipLines(t, hdr,
+			`{"index":{"id":1,"name":"theIndex","title":"theTitle","schema_version":1,"ttl":60,"read_only":true,"labels":{"team":"search"},"metadata":{},"created_at":"1970-01-01T00:02:03Z","updated_at":"1970-01-01T00:02:03Z"}}`,
+			`{"schema":{"index_id":1,"version":1,"schema":{"type":"object"},"created_at":"1970-01-01T00:02:03Z"}}`,
+			`{"log":{"id":3,"index_id":1,"record_id":"theRecordID","data":{"foo":"bar"},"created_at":"1970-01-01T00:02:03Z"}}`,
+			``,
+			`{"record":{"id":"theRecordID","index_id":1,"log_id":3,"created_at":"1970-01-01T00:02:03Z","updated_at":"1970-01-01T00:02:03Z","touched_at":"1970-01-01T00:02:03Z","expires_at":"1970-01-01T00:02:03Z"}}`,
+			`{"segment":{"id":1,"index_id":1,"path":"1/a.ndjson.gz","min_rev":1,"max_rev":2,"revisions":2,"record_ids":["theRecordID"],"created_at":"1970-01-01T00:02:03Z","content":"dGhlU2VnbWVudA=="}}`,
+		))
+
+		require.NoError(t, err)
+		assert.NoError(t, dbm.ExpectationsWereMet())
+		assert.Equal(t, backup.Stats{Indices: 1, Schemas: 1, Revisions: 1, Records: 1, Segments: 1}, stats)
+		assert.Equal(t, map[string][]byte{"1/a.ndjson.gz": []byte("theSegment")}, store.files)
+	})
+
+	tt.Run("SegmentInvalidPath", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		for range 5 {
+			dbm.ExpectPrepare(`INSERT INTO`)
+		}
+		dbm.ExpectRollback()
+
+		store := &storeMock{}
+
+		m := backup.New(db, store, now, zerolog.Nop())
+		_, err = m.Restore(context.Background(), gzipLines(t, hdr,
+			`{"segment":{"id":1,"index_id":1,"path":"../a.ndjson.gz","min_rev":1,"max_rev":2,"revisions":2,"record_ids":["theRecordID"],"created_at":"1970-01-01T00:02:03Z","content":"dGhlU2VnbWVudA=="}}`,
+		))
+
+		assert.EqualError(t, err, "line 2: segment ../a.ndjson.gz: invalid path")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+		assert.Empty(t, store.files)
+	})
+
+	tt.Run("SegmentStoreNotConfigured", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		for range 5 {
+			dbm.ExpectPrepare(`INSERT INTO`)
+		}
+		dbm.ExpectRollback()
+
+		m := backup.New(db, nil, now, zerolog.Nop())
+		_, err = m.Restore(context.Background(), gzipLines(t, hdr,
+			`{"segment":{"id":1,"index_id":1,"path":"1/a.ndjson.gz","min_rev":1,"max_rev":2,"revisions":2,"record_ids":["theRecordID"],"created_at":"1970-01-01T00:02:03Z","content":"dGhlU2VnbWVudA=="}}`,
+		))
+
+		assert.EqualError(t, err, "line 2: segment 1/a.ndjson.gz: archive dir is not configured")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("SegmentContentMissing", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		for range 5 {
+			dbm.ExpectPrepare(`INSERT INTO`)
+		}
+		dbm.ExpectRollback()
+
+		store := &storeMock{}
+
+		m := backup.New(db, store, now, zerolog.Nop())
+		_, err = m.Restore(context.Background(), gzipLines(t, hdr,
+			`{"segment":{"id":1,"index_id":1,"path":"1/a.ndjson.gz","min_rev":1,"max_rev":2,"revisions":2,"record_ids":["theRecordID"],"created_at":"1970-01-01T00:02:03Z"}}`,
+		))
+
+		assert.EqualError(t, err, "line 2: segment 1/a.ndjson.gz: file content is missing")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+		assert.Empty(t, store.files)
+	})
+}
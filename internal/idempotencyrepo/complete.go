@@ -0,0 +1,21 @@
+package idempotencyrepo
+
+import (
+	"context"
+	"fmt"
+)
+
+// Complete stores the response to the request the key was reserved for. Nothing is stored if the reservation has
+// been taken over by another request since.
+func (r *Repository) Complete(ctx context.Context, key Key, response []byte) error {
+	if response == nil {
+		response = []byte{} // nil response means the request is being processed
+	}
+
+	q := `UPDATE idempotency_key SET response=$4 WHERE key=$1 AND procedure=$2 AND lease_token=$3`
+	if _, err := r.db.ExecContext(ctx, q, key.Key, key.Procedure, key.LeaseToken, response); err != nil {
+		return fmt.Errorf("db exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package idempotencyrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/idempotencyrepo"
+)
+
+func TestRepository_Complete(tt *testing.T) {
+	key := idempotencyrepo.Key{Key: "theKey", Procedure: "/theProc", LeaseToken: "theLeaseToken"}
+
+	tt.Run("DbExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE idempotency_key SET response=\$4 WHERE key=\$1 AND procedure=\$2 AND lease_token=\$3`).
+			WithArgs("theKey", "/theProc", "theLeaseToken", []byte{1}).
+			WillReturnError(errors.New("theDBError"))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		err = repo.Complete(context.Background(), key, []byte{1})
+
+		assert.EqualError(t, err, "db exec: theDBError")
+	})
+
+	tt.Run("OkEmptyResponse", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE idempotency_key`).
+			WithArgs("theKey", "/theProc", "theLeaseToken", []byte{}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		err = repo.Complete(context.Background(), key, nil)
+
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,24 @@
+package idempotencyrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeleteExpired deletes the keys stored earlier than the window and returns their number.
+func (r *Repository) DeleteExpired(ctx context.Context, window time.Duration) (uint64, error) {
+	q := `DELETE FROM idempotency_key WHERE created_at <= now() - make_interval(secs => $1)`
+
+	res, err := r.db.ExecContext(ctx, q, window.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("db exec: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get db rows affected: %w", err)
+	}
+
+	return uint64(n), nil //nolint:gosec // ok
+}
@@ -0,0 +1,46 @@
+package idempotencyrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/idempotencyrepo"
+)
+
+func TestRepository_DeleteExpired(tt *testing.T) {
+	tt.Run("DbExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM idempotency_key WHERE created_at <= now\(\) - make_interval\(secs => \$1\)`).
+			WithArgs(float64(60)).
+			WillReturnError(errors.New("theDBError"))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		_, err = repo.DeleteExpired(context.Background(), time.Minute)
+
+		assert.EqualError(t, err, "db exec: theDBError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM idempotency_key`).
+			WithArgs(float64(60)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		n, err := repo.DeleteExpired(context.Background(), time.Minute)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), n)
+	})
+}
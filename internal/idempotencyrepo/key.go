@@ -0,0 +1,15 @@
+package idempotencyrepo
+
+import "errors"
+
+// ErrReleased is returned if the key was released while being reserved, so the request can be retried.
+var ErrReleased = errors.New("key has been released concurrently")
+
+// Key is an idempotency key used with a procedure.
+type Key struct {
+	Key         string
+	Procedure   string
+	RequestHash []byte
+	Response    []byte // nil while the request is being processed
+	LeaseToken  string // tells the reservation apart from the later ones of the same key
+}
@@ -0,0 +1,17 @@
+package idempotencyrepo
+
+import (
+	"context"
+	"fmt"
+)
+
+// Release deletes the key, so the request can be retried with it. Nothing is deleted if the reservation has been
+// taken over by another request since.
+func (r *Repository) Release(ctx context.Context, key Key) error {
+	q := `DELETE FROM idempotency_key WHERE key=$1 AND procedure=$2 AND lease_token=$3`
+	if _, err := r.db.ExecContext(ctx, q, key.Key, key.Procedure, key.LeaseToken); err != nil {
+		return fmt.Errorf("db exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package idempotencyrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/idempotencyrepo"
+)
+
+func TestRepository_Release(tt *testing.T) {
+	key := idempotencyrepo.Key{Key: "theKey", Procedure: "/theProc", LeaseToken: "theLeaseToken"}
+
+	tt.Run("DbExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM idempotency_key WHERE key=\$1 AND procedure=\$2 AND lease_token=\$3`).
+			WithArgs("theKey", "/theProc", "theLeaseToken").
+			WillReturnError(errors.New("theDBError"))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		err = repo.Release(context.Background(), key)
+
+		assert.EqualError(t, err, "db exec: theDBError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM idempotency_key`).
+			WithArgs("theKey", "/theProc", "theLeaseToken").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		err = repo.Release(context.Background(), key)
+
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
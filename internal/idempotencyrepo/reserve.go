@@ -0,0 +1,53 @@
+package idempotencyrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// Reserve stores the key used with the procedure for a request with the given hash, unless the key was used with the
+// procedure within the window. It returns true if the key is reserved; otherwise it returns the key stored before.
+// Keys stored earlier than the window are replaced, as well as keys of requests which are still not completed after
+// the lease, e.g. because the server processing them has crashed.
+func (r *Repository) Reserve(
+	ctx context.Context,
+	key, procedure string,
+	requestHash []byte,
+	window, lease time.Duration,
+) (Key, bool, error) {
+	if key == "" {
+		return Key{}, false, apperrors.InvalidArgError{Subj: "key", Reason: "must not be empty"}
+	}
+
+	res := Key{Key: key, Procedure: procedure, RequestHash: requestHash}
+
+	q := `INSERT INTO idempotency_key (key, procedure, request_hash) VALUES ($1, $2, $3)
+ON CONFLICT (key, procedure) DO UPDATE SET request_hash=EXCLUDED.request_hash, response=NULL,
+lease_token=gen_random_uuid(), created_at=now()
+WHERE idempotency_key.created_at <= now() - make_interval(secs => $4)
+   OR (idempotency_key.response IS NULL AND idempotency_key.created_at <= now() - make_interval(secs => $5))
+RETURNING lease_token`
+
+	err := r.db.QueryRowContext(ctx, q, key, procedure, requestHash, window.Seconds(), lease.Seconds()).
+		Scan(&res.LeaseToken)
+	if err == nil {
+		return res, true, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return Key{}, false, fmt.Errorf("db insert: %w", err)
+	}
+
+	q = `SELECT request_hash, response, lease_token FROM idempotency_key WHERE key=$1 AND procedure=$2`
+	err = r.db.QueryRowContext(ctx, q, key, procedure).Scan(&res.RequestHash, &res.Response, &res.LeaseToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Key{}, false, ErrReleased
+	} else if err != nil {
+		return Key{}, false, fmt.Errorf("db select: %w", err)
+	}
+
+	return res, false, nil
+}
@@ -0,0 +1,120 @@
+package idempotencyrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/idempotencyrepo"
+)
+
+func TestRepository_Reserve(tt *testing.T) {
+	tt.Run("EmptyKey", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		_, _, err = repo.Reserve(context.Background(), "", "/theProc", []byte{1}, time.Hour, time.Minute)
+
+		assert.EqualError(t, err, "invalid key: must not be empty")
+	})
+
+	tt.Run("DbInsertError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`INSERT INTO idempotency_key`).
+			WithArgs("theKey", "/theProc", []byte{1}, float64(3600), float64(60)).
+			WillReturnError(errors.New("theDBError"))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		_, _, err = repo.Reserve(context.Background(), "theKey", "/theProc", []byte{1}, time.Hour, time.Minute)
+
+		assert.EqualError(t, err, "db insert: theDBError")
+	})
+
+	tt.Run("DbSelectError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`INSERT INTO idempotency_key`).WillReturnError(sql.ErrNoRows)
+		dbm.ExpectQuery(`SELECT request_hash, response, lease_token FROM idempotency_key WHERE key=\$1 AND procedure=\$2`).
+			WithArgs("theKey", "/theProc").
+			WillReturnError(errors.New("theDBError"))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		_, _, err = repo.Reserve(context.Background(), "theKey", "/theProc", []byte{1}, time.Hour, time.Minute)
+
+		assert.EqualError(t, err, "db select: theDBError")
+	})
+
+	tt.Run("Released", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`INSERT INTO idempotency_key`).WillReturnError(sql.ErrNoRows)
+		dbm.ExpectQuery(`SELECT request_hash, response, lease_token FROM idempotency_key`).
+			WithArgs("theKey", "/theProc").
+			WillReturnError(sql.ErrNoRows)
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		_, _, err = repo.Reserve(context.Background(), "theKey", "/theProc", []byte{1}, time.Hour, time.Minute)
+
+		assert.ErrorIs(t, err, idempotencyrepo.ErrReleased)
+	})
+
+	tt.Run("OkReserved", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`INSERT INTO idempotency_key .+ `+
+			`WHERE idempotency_key.created_at <= now\(\) - make_interval\(secs => \$4\)\s+`+
+			`OR \(idempotency_key.response IS NULL AND idempotency_key.created_at <= now\(\) - `+
+			`make_interval\(secs => \$5\)\)\s+RETURNING lease_token`).
+			WithArgs("theKey", "/theProc", []byte{1}, float64(3600), float64(60)).
+			WillReturnRows(sqlmock.NewRows([]string{"lease_token"}).AddRow("theLeaseToken"))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		key, ok, err := repo.Reserve(context.Background(), "theKey", "/theProc", []byte{1}, time.Hour, time.Minute)
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, idempotencyrepo.Key{
+			Key:         "theKey",
+			Procedure:   "/theProc",
+			RequestHash: []byte{1},
+			LeaseToken:  "theLeaseToken",
+		}, key)
+	})
+
+	tt.Run("OkUsed", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`INSERT INTO idempotency_key`).WillReturnError(sql.ErrNoRows)
+		dbm.ExpectQuery(`SELECT request_hash, response, lease_token FROM idempotency_key`).
+			WithArgs("theKey", "/theProc").
+			WillReturnRows(sqlmock.NewRows([]string{"request_hash", "response", "lease_token"}).
+				AddRow([]byte{2}, []byte{3}, "theOtherLeaseToken"))
+
+		repo := idempotencyrepo.New(db, zerolog.Nop())
+		key, ok, err := repo.Reserve(context.Background(), "theKey", "/theProc", []byte{1}, time.Hour, time.Minute)
+
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, idempotencyrepo.Key{
+			Key:         "theKey",
+			Procedure:   "/theProc",
+			RequestHash: []byte{2},
+			Response:    []byte{3},
+			LeaseToken:  "theOtherLeaseToken",
+		}, key)
+	})
+}
@@ -2,9 +2,13 @@ package indexrepo
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 )
 
+// Clear deletes all the index's records and their history. Records of read-only and insert-only indices can't be
+// deleted, so clearing such indices fails with ModeError.
 func (r *Repository) Clear(ctx context.Context, name string) error {
 	if err := r.nameValidator.Validate(name); err != nil {
 		return err //nolint:wrapcheck // ok
@@ -15,20 +19,39 @@ func (r *Repository) Clear(ctx context.Context, name string) error {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx,
-		`DELETE FROM record WHERE index_id=(SELECT id FROM index WHERE name=$1 LIMIT 1)`, name)
-	if err != nil {
+	defer func() {
 		_ = tx.Rollback()
+	}()
+
+	// The index row is locked, so its mode doesn't change until the records are deleted
+	id, mode := uint64(0), Mode{}
+	err = tx.QueryRowContext(ctx, `SELECT id, read_only, insert_only FROM index WHERE name=$1 FOR SHARE`, name).
+		Scan(&id, &mode.ReadOnly, &mode.InsertOnly)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("get index mode: %w", err)
+	case mode.ReadOnly:
+		return ModeError{Reason: "index is read-only"}
+	case mode.InsertOnly:
+		return ModeError{Reason: "records of an insert-only index can't be deleted"}
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM record WHERE index_id=$1`, id); err != nil {
 		return fmt.Errorf("delete records: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx,
-		`DELETE FROM record_log WHERE index_id=(SELECT id FROM index WHERE name=$1 LIMIT 1)`, name)
-	if err != nil {
-		_ = tx.Rollback()
+	if _, err = tx.ExecContext(ctx, `DELETE FROM record_log WHERE index_id=$1`, id); err != nil {
 		return fmt.Errorf("delete record log: %w", err)
 	}
 
+	// Archived segment files are left in the archive store, only the manifest is cleared
+	if _, err = tx.ExecContext(ctx, `DELETE FROM record_log_segment WHERE index_id=$1`, id); err != nil {
+		return fmt.Errorf("delete record log segments: %w", err)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("db commit: %w", err)
 	}
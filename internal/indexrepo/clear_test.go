@@ -46,6 +46,92 @@ func TestIndexRepository_Clear(tt *testing.T) {
 		assert.EqualError(t, err, "begin transaction: theBeginTxError")
 	})
 
+	tt.Run("GetModeError", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index`).
+			WillReturnError(errors.New("theQueryError"))
+		dbm.ExpectRollback()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		err = repo.Clear(context.Background(), "theIndex")
+
+		assert.EqualError(t, err, "get index mode: theQueryError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}))
+		dbm.ExpectRollback()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		err = repo.Clear(context.Background(), "theIndex")
+
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("ReadOnly", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}).AddRow(123, true, false))
+		dbm.ExpectRollback()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		err = repo.Clear(context.Background(), "theIndex")
+
+		assert.ErrorIs(t, err, indexrepo.ModeError{Reason: "index is read-only"})
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("InsertOnly", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}).AddRow(123, false, true))
+		dbm.ExpectRollback()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		err = repo.Clear(context.Background(), "theIndex")
+
+		assert.ErrorIs(t, err, indexrepo.ModeError{Reason: "records of an insert-only index can't be deleted"})
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
 	tt.Run("ExecDeleteRecordsError", func(t *testing.T) {
 		nameValidator := &stringValidatorMock{}
 		nameValidator.ValidateFunc = func(s string) error {
@@ -56,13 +142,19 @@ func TestIndexRepository_Clear(tt *testing.T) {
 		require.NoError(t, err)
 
 		dbm.ExpectBegin()
-		dbm.ExpectExec(`DELETE FROM record`).
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index WHERE name=\$1 FOR SHARE`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}).AddRow(123, false, false))
+		dbm.ExpectExec(`DELETE FROM record WHERE index_id=\$1`).
+			WithArgs(123).
 			WillReturnError(errors.New("theDeleteRecordsError"))
+		dbm.ExpectRollback()
 
 		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
 		err = repo.Clear(context.Background(), "theIndex")
 
 		assert.EqualError(t, err, "delete records: theDeleteRecordsError")
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
 	tt.Run("ExecDeleteRecordLogsError", func(t *testing.T) {
@@ -75,15 +167,53 @@ func TestIndexRepository_Clear(tt *testing.T) {
 		require.NoError(t, err)
 
 		dbm.ExpectBegin()
-		dbm.ExpectExec(`DELETE FROM record`).
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index WHERE name=\$1 FOR SHARE`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}).AddRow(123, false, false))
+		dbm.ExpectExec(`DELETE FROM record WHERE index_id=\$1`).
+			WithArgs(123).
 			WillReturnResult(sqlmock.NewResult(0, 0))
-		dbm.ExpectExec(`DELETE FROM record_log`).
+		dbm.ExpectExec(`DELETE FROM record_log WHERE index_id=\$1`).
+			WithArgs(123).
 			WillReturnError(errors.New("theDeleteRecordLogsError"))
+		dbm.ExpectRollback()
 
 		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
 		err = repo.Clear(context.Background(), "theIndex")
 
 		assert.EqualError(t, err, "delete record log: theDeleteRecordLogsError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("ExecDeleteRecordLogSegmentsError", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index WHERE name=\$1 FOR SHARE`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}).AddRow(123, false, false))
+		dbm.ExpectExec(`DELETE FROM record WHERE index_id=\$1`).
+			WithArgs(123).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbm.ExpectExec(`DELETE FROM record_log WHERE index_id=\$1`).
+			WithArgs(123).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbm.ExpectExec(`DELETE FROM record_log_segment WHERE index_id=\$1`).
+			WithArgs(123).
+			WillReturnError(errors.New("theDeleteSegmentsError"))
+		dbm.ExpectRollback()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		err = repo.Clear(context.Background(), "theIndex")
+
+		assert.EqualError(t, err, "delete record log segments: theDeleteSegmentsError")
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
 	tt.Run("CommitError", func(t *testing.T) {
@@ -96,9 +226,17 @@ func TestIndexRepository_Clear(tt *testing.T) {
 		require.NoError(t, err)
 
 		dbm.ExpectBegin()
-		dbm.ExpectExec(`DELETE FROM record`).
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index WHERE name=\$1 FOR SHARE`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}).AddRow(123, false, false))
+		dbm.ExpectExec(`DELETE FROM record WHERE index_id=\$1`).
+			WithArgs(123).
 			WillReturnResult(sqlmock.NewResult(0, 0))
-		dbm.ExpectExec(`DELETE FROM record_log`).
+		dbm.ExpectExec(`DELETE FROM record_log WHERE index_id=\$1`).
+			WithArgs(123).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbm.ExpectExec(`DELETE FROM record_log_segment WHERE index_id=\$1`).
+			WithArgs(123).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 		dbm.ExpectCommit().WillReturnError(errors.New("theCommitError"))
 
@@ -106,6 +244,7 @@ func TestIndexRepository_Clear(tt *testing.T) {
 		err = repo.Clear(context.Background(), "theIndex")
 
 		assert.EqualError(t, err, "db commit: theCommitError")
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
 	tt.Run("Ok", func(t *testing.T) {
@@ -118,9 +257,17 @@ func TestIndexRepository_Clear(tt *testing.T) {
 		require.NoError(t, err)
 
 		dbm.ExpectBegin()
-		dbm.ExpectExec(`DELETE FROM record`).
+		dbm.ExpectQuery(`SELECT id, read_only, insert_only FROM index WHERE name=\$1 FOR SHARE`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_only", "insert_only"}).AddRow(123, false, false))
+		dbm.ExpectExec(`DELETE FROM record WHERE index_id=\$1`).
+			WithArgs(123).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbm.ExpectExec(`DELETE FROM record_log WHERE index_id=\$1`).
+			WithArgs(123).
 			WillReturnResult(sqlmock.NewResult(0, 0))
-		dbm.ExpectExec(`DELETE FROM record_log`).
+		dbm.ExpectExec(`DELETE FROM record_log_segment WHERE index_id=\$1`).
+			WithArgs(123).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 		dbm.ExpectCommit()
 
@@ -128,5 +275,6 @@ func TestIndexRepository_Clear(tt *testing.T) {
 		err = repo.Clear(context.Background(), "theIndex")
 
 		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 }
@@ -3,8 +3,10 @@ package indexrepo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ashep/go-apperrors"
 )
@@ -15,14 +17,26 @@ func (r *Repository) Get(ctx context.Context, name string) (Index, error) {
 	}
 
 	idx := Index{Name: name}
-	q := `SELECT id, title, created_at, updated_at FROM index WHERE name=$1`
+	q := `SELECT id, title, schema_version, ttl, read_only, insert_only, no_history, labels, metadata, created_at,
+updated_at FROM index WHERE name=$1`
 
+	ttl, labels, metadata := int64(0), []byte(nil), []byte(nil)
 	row := r.db.QueryRowContext(ctx, q, name)
-	if err := row.Scan(&idx.ID, &idx.Title, &idx.CreatedAt, &idx.UpdatedAt); errors.Is(err, sql.ErrNoRows) {
+	err := row.Scan(&idx.ID, &idx.Title, &idx.SchemaVersion, &ttl, &idx.Mode.ReadOnly, &idx.Mode.InsertOnly,
+		&idx.Mode.NoHistory, &labels, &metadata, &idx.CreatedAt, &idx.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
 		return Index{}, apperrors.NotFoundError{Subj: "index"}
 	} else if err != nil {
 		return Index{}, fmt.Errorf("db scan: %w", err)
 	}
 
+	if err := json.Unmarshal(labels, &idx.Labels); err != nil {
+		return Index{}, fmt.Errorf("labels unmarshal: %w", err)
+	}
+
+	idx.Metadata = metadata
+
+	idx.TTL = time.Duration(ttl) * time.Second
+
 	return idx, nil
 }
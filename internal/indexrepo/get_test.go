@@ -3,8 +3,11 @@ package indexrepo_test
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ashep/go-apperrors"
@@ -68,4 +71,42 @@ func TestIndexRepository_Get(tt *testing.T) {
 
 		require.EqualError(t, err, "db scan: theDBExecError")
 	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "title", "schema_version", "ttl", "read_only", "insert_only", "no_history",
+			"labels", "metadata", "created_at", "updated_at"}).
+			AddRow(123, "theTitle", 3, 3600, false, true, true, `{"team": "search"}`, `{"owner": "John"}`,
+				time.Unix(234, 0), time.Unix(345, 0))
+
+		dbm.
+			ExpectQuery(regexp.QuoteMeta(`SELECT id, title, schema_version, ttl, read_only, insert_only, no_history, labels, metadata, created_at,
+updated_at FROM index WHERE name=$1`)).
+			WithArgs("theIndex").
+			WillReturnRows(rows)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		idx, err := repo.Get(context.Background(), "theIndex")
+
+		require.NoError(t, err)
+		assert.Equal(t, indexrepo.Index{
+			ID:            123,
+			Name:          "theIndex",
+			Title:         sql.NullString{String: "theTitle", Valid: true},
+			SchemaVersion: 3,
+			TTL:           time.Hour,
+			Mode:          indexrepo.Mode{InsertOnly: true, NoHistory: true},
+			Labels:        map[string]string{"team": "search"},
+			Metadata:      json.RawMessage(`{"owner": "John"}`),
+			CreatedAt:     time.Unix(234, 0),
+			UpdatedAt:     time.Unix(345, 0),
+		}, idx)
+	})
 }
@@ -2,17 +2,82 @@ package indexrepo
 
 import (
 	"database/sql"
+	"encoding/json"
+	"math"
 	"time"
+
+	"github.com/ashep/ujds/internal/labelselector"
 )
 
+// MaxTTL is the maximum default TTL of records the database can store.
+const MaxTTL = math.MaxInt32 * time.Second
+
 type IndexFilter struct {
-	Names []string
+	Names  []string               // name patterns; allowed wildcard symbols: *
+	Title  string                 // case-insensitive title substring
+	Labels labelselector.Selector // empty one matches all indices
+}
+
+type ListSort int
+
+const (
+	SortByName ListSort = iota
+	SortByCreatedAt
+	SortByUpdatedAt
+)
+
+type ListRequest struct {
+	Filter IndexFilter
+	Sort   ListSort
+	Desc   bool
+	Cursor string // opaque cursor returned by a previous List call
+	Limit  uint32
 }
 
 type Index struct {
-	ID        uint64
-	Name      string
-	Title     sql.NullString
+	ID            uint64
+	Name          string
+	Title         sql.NullString
+	SchemaVersion uint32        // active stored schema version; zero if none
+	TTL           time.Duration // default TTL of the index's records; zero if records don't expire
+	Mode          Mode
+	Labels        map[string]string
+	Metadata      json.RawMessage // JSON object
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Mode is the write restrictions of an index's records.
+type Mode struct {
+	ReadOnly   bool // records can't be written
+	InsertOnly bool // existing records' data can't be changed and records can't be deleted
+	NoHistory  bool // only the current revisions of records are kept
+}
+
+// ModeError is returned when an index mode doesn't allow an operation. Both index and record writes fail with it.
+type ModeError struct {
+	Reason string
+}
+
+func (e ModeError) Error() string {
+	return e.Reason
+}
+
+type Schema struct {
+	Version   uint32
+	Schema    json.RawMessage
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+type Stats struct {
+	Records        uint64
+	HistoryRecords uint64
+	DataSize       uint64
+	LastPushAt     sql.NullTime
+	NotTouched     uint64
+}
+
+type StatsRequest struct {
+	NotTouchedSince *time.Time
+	Approximate     bool // estimate counts and sizes instead of counting them
 }
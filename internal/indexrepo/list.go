@@ -2,15 +2,94 @@ package indexrepo
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ashep/go-apperrors"
 )
 
-func (r *Repository) List(ctx context.Context) ([]Index, error) {
-	q := "SELECT id, name, title, created_at, updated_at FROM index"
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`) //nolint:gochecknoglobals // ok
+
+// listCursor is a position in an indices list: the sort key and the ID of the last index of a page. The position
+// doesn't depend on the index itself, so it stays valid if the index is changed or removed between calls.
+type listCursor struct {
+	Sort ListSort `json:"s"`
+	Key  string   `json:"k"`
+	ID   uint64   `json:"i"`
+}
+
+// List returns indices matching the request filter. Results are ordered by the requested field and the index ID.
+// The returned cursor is empty if there are no more indices. Zero limit means no limit.
+func (r *Repository) List(ctx context.Context, req ListRequest) ([]Index, string, error) {
+	sortCol := "name"
+
+	switch req.Sort {
+	case SortByName:
+	case SortByCreatedAt:
+		sortCol = "created_at"
+	case SortByUpdatedAt:
+		sortCol = "updated_at"
+	default:
+		return nil, "", fmt.Errorf("invalid sort: %d", req.Sort)
+	}
+
+	sortDir, cmp := "ASC", ">"
+	if req.Desc {
+		sortDir, cmp = "DESC", "<"
+	}
+
+	conds := make([]string, 0)
+	qArgs := make([]any, 0)
+
+	if len(req.Filter.Names) != 0 {
+		nameConds := make([]string, len(req.Filter.Names))
+
+		for i, pat := range req.Filter.Names {
+			qArgs = append(qArgs, strings.ReplaceAll(likeEscaper.Replace(pat), "*", "%"))
+			nameConds[i] = fmt.Sprintf("name LIKE $%d", len(qArgs))
+		}
+
+		conds = append(conds, "("+strings.Join(nameConds, " OR ")+")")
+	}
+
+	if req.Filter.Title != "" {
+		qArgs = append(qArgs, "%"+likeEscaper.Replace(req.Filter.Title)+"%")
+		conds = append(conds, fmt.Sprintf("title ILIKE $%d", len(qArgs)))
+	}
+
+	if !req.Filter.Labels.Empty() {
+		conds = append(conds, "("+req.Filter.Labels.String("labels", len(qArgs)+1)+")")
+		qArgs = append(qArgs, req.Filter.Labels.Args()...)
+	}
+
+	if req.Cursor != "" {
+		key, id, err := decodeListCursor(req.Cursor, req.Sort)
+		if err != nil {
+			return nil, "", err
+		}
+
+		qArgs = append(qArgs, key, id)
+		conds = append(conds, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortCol, cmp, len(qArgs)-1, len(qArgs)))
+	}
+
+	q := "SELECT id, name, title, labels, metadata, created_at, updated_at FROM index"
+	if len(conds) != 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	q += fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s", sortCol, sortDir)
 
-	rows, err := r.db.QueryContext(ctx, q)
+	if req.Limit != 0 {
+		qArgs = append(qArgs, req.Limit+1)
+		q += fmt.Sprintf(" LIMIT $%d", len(qArgs))
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, qArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("db query: %w", err)
+		return nil, "", fmt.Errorf("db query: %w", err)
 	}
 
 	defer func() {
@@ -20,17 +99,80 @@ func (r *Repository) List(ctx context.Context) ([]Index, error) {
 	res := make([]Index, 0)
 
 	for rows.Next() {
-		idx := Index{}
-		if err := rows.Scan(&idx.ID, &idx.Name, &idx.Title, &idx.CreatedAt, &idx.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("db scan: %w", err)
+		idx, labels, metadata := Index{}, []byte(nil), []byte(nil)
+		if err := rows.Scan(&idx.ID, &idx.Name, &idx.Title, &labels, &metadata, &idx.CreatedAt, &idx.UpdatedAt); err != nil {
+			return nil, "", fmt.Errorf("db scan: %w", err)
+		}
+
+		if err := json.Unmarshal(labels, &idx.Labels); err != nil {
+			return nil, "", fmt.Errorf("labels unmarshal: %w", err)
 		}
 
+		idx.Metadata = metadata
+
 		res = append(res, idx)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("db rows iteration: %w", err)
+		return nil, "", fmt.Errorf("db rows iteration: %w", err)
+	}
+
+	newCursor := ""
+	if req.Limit != 0 && len(res) > int(req.Limit) {
+		res = res[:req.Limit]
+
+		if newCursor, err = encodeListCursor(res[len(res)-1], req.Sort); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return res, newCursor, nil
+}
+
+func encodeListCursor(idx Index, sort ListSort) (string, error) {
+	cur := listCursor{Sort: sort, ID: idx.ID}
+
+	switch sort {
+	case SortByName:
+		cur.Key = idx.Name
+	case SortByCreatedAt:
+		cur.Key = idx.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case SortByUpdatedAt:
+		cur.Key = idx.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	b, err := json.Marshal(cur)
+	if err != nil {
+		return "", fmt.Errorf("cursor marshal: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeListCursor returns the sort key and the index ID of a cursor made for the same sort.
+func decodeListCursor(s string, sort ListSort) (any, uint64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, 0, apperrors.InvalidArgError{Subj: "cursor", Reason: "malformed"}
+	}
+
+	cur := listCursor{}
+	if err := json.Unmarshal(b, &cur); err != nil {
+		return nil, 0, apperrors.InvalidArgError{Subj: "cursor", Reason: "malformed"}
+	}
+
+	if cur.Sort != sort {
+		return nil, 0, apperrors.InvalidArgError{Subj: "cursor", Reason: "made for another sort"}
+	}
+
+	if sort == SortByName {
+		return cur.Key, cur.ID, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, cur.Key)
+	if err != nil {
+		return nil, 0, apperrors.InvalidArgError{Subj: "cursor", Reason: "malformed"}
 	}
 
-	return res, nil
+	return t, cur.ID, nil
 }
@@ -2,7 +2,9 @@ package indexrepo_test
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"regexp"
 	"testing"
 	"time"
 
@@ -12,9 +14,49 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/labelselector"
 )
 
 func TestIndexRepository_List(tt *testing.T) {
+	tt.Run("InvalidSort", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, _, err = repo.List(context.Background(), indexrepo.ListRequest{Sort: 123})
+
+		assert.EqualError(t, err, "invalid sort: 123")
+	})
+
+	tt.Run("MalformedCursor", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, _, err = repo.List(context.Background(), indexrepo.ListRequest{Cursor: "123"})
+
+		assert.EqualError(t, err, "invalid cursor: malformed")
+	})
+
+	tt.Run("CursorOfAnotherSort", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, _, err = repo.List(context.Background(), indexrepo.ListRequest{
+			Sort:   indexrepo.SortByCreatedAt,
+			Cursor: listCursor(`{"s":0,"k":"foo","i":6}`),
+		})
+
+		assert.EqualError(t, err, "invalid cursor: made for another sort")
+	})
+
 	tt.Run("DbQueryError", func(t *testing.T) {
 		nameValidator := &stringValidatorMock{}
 
@@ -26,7 +68,7 @@ func TestIndexRepository_List(tt *testing.T) {
 			WillReturnError(errors.New("theQueryError"))
 
 		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
-		_, err = repo.List(context.Background())
+		_, _, err = repo.List(context.Background(), indexrepo.ListRequest{})
 
 		assert.EqualError(t, err, "db query: theQueryError")
 	})
@@ -37,8 +79,8 @@ func TestIndexRepository_List(tt *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
-		rows := sqlmock.NewRows([]string{"id", "name", "schema", "created_at", "updated_at"}).
-			AddRow(123, "indexName", "{}", time.Unix(234, 0), time.Unix(345, 0)).
+		rows := sqlmock.NewRows([]string{"id", "name", "title", "labels", "metadata", "created_at", "updated_at"}).
+			AddRow(123, "indexName", "indexTitle", `{}`, `{}`, time.Unix(234, 0), time.Unix(345, 0)).
 			RowError(0, errors.New("theRowError"))
 
 		dbm.
@@ -46,8 +88,112 @@ func TestIndexRepository_List(tt *testing.T) {
 			WillReturnRows(rows)
 
 		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
-		_, err = repo.List(context.Background())
+		_, _, err = repo.List(context.Background(), indexrepo.ListRequest{})
 
 		assert.EqualError(t, err, "db rows iteration: theRowError")
 	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "title", "labels", "metadata", "created_at", "updated_at"}).
+			AddRow(1, "index1", "title1", `{"team":"search"}`, `{"owner":"John"}`, time.Unix(234, 0), time.Unix(345, 0)).
+			AddRow(2, "index2", nil, `{}`, `{}`, time.Unix(456, 0), time.Unix(567, 0))
+
+		dbm.
+			ExpectQuery(regexp.QuoteMeta("SELECT id, name, title, labels, metadata, created_at, updated_at FROM index " +
+				"ORDER BY name ASC, id ASC")).
+			WithoutArgs().
+			WillReturnRows(rows)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		res, cur, err := repo.List(context.Background(), indexrepo.ListRequest{})
+
+		require.NoError(t, err)
+		assert.Zero(t, cur)
+		require.Len(t, res, 2)
+		assert.Equal(t, uint64(1), res[0].ID)
+		assert.Equal(t, "index1", res[0].Name)
+		assert.Equal(t, "title1", res[0].Title.String)
+		assert.Equal(t, time.Unix(234, 0), res[0].CreatedAt)
+		assert.Equal(t, time.Unix(345, 0), res[0].UpdatedAt)
+		assert.Equal(t, map[string]string{"team": "search"}, res[0].Labels)
+		assert.JSONEq(t, `{"owner":"John"}`, string(res[0].Metadata))
+		assert.Equal(t, uint64(2), res[1].ID)
+		assert.False(t, res[1].Title.Valid)
+	})
+
+	tt.Run("OkByNameWithCursorOfRemovedIndex", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "title", "labels", "metadata", "created_at", "updated_at"}).
+			AddRow(7, "index7", nil, `{}`, `{}`, time.Unix(234, 0), time.Unix(345, 0))
+
+		dbm.
+			ExpectQuery(regexp.QuoteMeta("SELECT id, name, title, labels, metadata, created_at, updated_at FROM index "+
+				"WHERE (name, id) > ($1, $2) ORDER BY name ASC, id ASC LIMIT $3")).
+			WithArgs("index6", uint64(6), uint32(2)).
+			WillReturnRows(rows)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		res, cur, err := repo.List(context.Background(), indexrepo.ListRequest{
+			Cursor: listCursor(`{"s":0,"k":"index6","i":6}`),
+			Limit:  1,
+		})
+
+		require.NoError(t, err)
+		assert.Empty(t, cur)
+		require.Len(t, res, 1)
+		assert.Equal(t, "index7", res[0].Name)
+	})
+
+	tt.Run("OkWithFilterSortAndPagination", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"id", "name", "title", "labels", "metadata", "created_at", "updated_at"}).
+			AddRow(5, "foo.1", "title", `{}`, `{}`, time.Unix(234, 0), time.Unix(345, 0)).
+			AddRow(4, "foo.2", "title", `{}`, `{}`, time.Unix(234, 0), time.Unix(344, 0)).
+			AddRow(3, "bar_1", "title", `{}`, `{}`, time.Unix(234, 0), time.Unix(343, 0))
+
+		dbm.
+			ExpectQuery(regexp.QuoteMeta("SELECT id, name, title, labels, metadata, created_at, updated_at FROM index "+
+				"WHERE (name LIKE $1 OR name LIKE $2) AND title ILIKE $3 "+
+				"AND (labels->>$4 = $5 AND labels->>$6 IS DISTINCT FROM $7) "+
+				"AND (updated_at, id) < ($8, $9) "+
+				"ORDER BY updated_at DESC, id DESC LIMIT $10")).
+			WithArgs("foo.%", `bar\_%`, `%50\%%`, "team", "search", "env", "prod", time.Unix(346, 0).UTC(), uint64(6),
+				uint32(3)).
+			WillReturnRows(rows)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		sel, err := labelselector.Parse("team=search,env!=prod")
+		require.NoError(t, err)
+
+		res, cur, err := repo.List(context.Background(), indexrepo.ListRequest{
+			Filter: indexrepo.IndexFilter{Names: []string{"foo.*", "bar_*"}, Title: "50%", Labels: sel},
+			Sort:   indexrepo.SortByUpdatedAt,
+			Desc:   true,
+			Cursor: listCursor(`{"s":2,"k":"1970-01-01T00:05:46Z","i":6}`),
+			Limit:  2,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, listCursor(`{"s":2,"k":"1970-01-01T00:05:44Z","i":4}`), cur)
+		require.Len(t, res, 2)
+		assert.Equal(t, "foo.1", res[0].Name)
+		assert.Equal(t, "foo.2", res[1].Name)
+	})
+}
+
+func listCursor(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
 }
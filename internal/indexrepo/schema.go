@@ -0,0 +1,130 @@
+package indexrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// SetSchema stores a new version of the index's record validation schema and makes it active. An empty schema
+// deactivates the stored schema without creating a new version. The active version number is returned.
+func (r *Repository) SetSchema(ctx context.Context, name string, schema json.RawMessage) (uint32, error) {
+	if err := r.nameValidator.Validate(name); err != nil {
+		return 0, err //nolint:wrapcheck // ok
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("db begin: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var indexID uint64
+
+	row := tx.QueryRowContext(ctx, `SELECT id FROM index WHERE name=$1 FOR UPDATE`, name)
+	if err := row.Scan(&indexID); errors.Is(err, sql.ErrNoRows) {
+		return 0, apperrors.NotFoundError{Subj: "index"}
+	} else if err != nil {
+		return 0, fmt.Errorf("get index db scan: %w", err)
+	}
+
+	version := uint32(0)
+
+	if len(schema) != 0 {
+		q := `INSERT INTO index_schema (index_id, version, schema)
+SELECT $1, coalesce(max(version), 0) + 1, $2 FROM index_schema WHERE index_id=$1 RETURNING version`
+
+		if err := tx.QueryRowContext(ctx, q, indexID, string(schema)).Scan(&version); err != nil {
+			return 0, fmt.Errorf("insert schema db scan: %w", err)
+		}
+	}
+
+	q := `UPDATE index SET schema_version=$1, updated_at=now() WHERE id=$2`
+	if _, err := tx.ExecContext(ctx, q, version, indexID); err != nil {
+		return 0, fmt.Errorf("update index db exec: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("db commit: %w", err)
+	}
+
+	return version, nil
+}
+
+// GetSchema returns a specific version of the index's stored schema.
+func (r *Repository) GetSchema(ctx context.Context, name string, version uint32) (Schema, error) {
+	if err := r.nameValidator.Validate(name); err != nil {
+		return Schema{}, err //nolint:wrapcheck // ok
+	}
+
+	q := `SELECT s.version, s.schema, s.created_at FROM index_schema s
+LEFT JOIN index i ON s.index_id = i.id WHERE i.name=$1 AND s.version=$2`
+
+	sch, raw := Schema{}, ""
+
+	row := r.db.QueryRowContext(ctx, q, name, version)
+	if err := row.Scan(&sch.Version, &raw, &sch.CreatedAt); errors.Is(err, sql.ErrNoRows) {
+		return Schema{}, apperrors.NotFoundError{Subj: "schema"}
+	} else if err != nil {
+		return Schema{}, fmt.Errorf("db scan: %w", err)
+	}
+
+	sch.Schema = json.RawMessage(raw)
+
+	return sch, nil
+}
+
+// SchemaHistory returns the index's stored schema versions, newest first. The cursor is the last version of the
+// previous page; the returned cursor is zero if there are no more versions.
+func (r *Repository) SchemaHistory(ctx context.Context, name string, cursor uint64, limit uint32) ([]Schema, uint64, error) {
+	if _, err := r.Get(ctx, name); err != nil {
+		return nil, 0, err
+	}
+
+	if cursor == 0 {
+		cursor = uint64(^uint32(0))
+	}
+
+	q := `SELECT s.version, s.schema, s.created_at FROM index_schema s
+LEFT JOIN index i ON s.index_id = i.id WHERE i.name=$1 AND s.version < $2 ORDER BY s.version DESC LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, q, name, cursor, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("db query: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]Schema, 0)
+	version, raw, createdAt := uint32(0), "", time.Time{}
+
+	for rows.Next() {
+		if err := rows.Scan(&version, &raw, &createdAt); err != nil {
+			return nil, 0, fmt.Errorf("db scan: %w", err)
+		}
+
+		res = append(res, Schema{Version: version, Schema: json.RawMessage(raw), CreatedAt: createdAt})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("db rows iteration: %w", err)
+	}
+
+	newCursor := uint64(0)
+	if len(res) > int(limit) {
+		newCursor = uint64(res[limit-1].Version)
+		res = res[:limit]
+	}
+
+	return res, newCursor, nil
+}
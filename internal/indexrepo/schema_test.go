@@ -0,0 +1,235 @@
+package indexrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+)
+
+func TestIndexRepository_SetSchema(tt *testing.T) {
+	tt.Run("NameValidatorError", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return errors.New("theValidatorError")
+		}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, err = repo.SetSchema(context.Background(), "theIndex", json.RawMessage(`{}`))
+
+		assert.EqualError(t, err, "theValidatorError")
+	})
+
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM index WHERE name=$1 FOR UPDATE`)).
+			WithArgs("theIndex").
+			WillReturnError(sql.ErrNoRows)
+		dbm.ExpectRollback()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, err = repo.SetSchema(context.Background(), "theIndex", json.RawMessage(`{}`))
+
+		assert.ErrorIs(t, err, apperrors.NotFoundError{Subj: "index"})
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("InsertSchemaError", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM index WHERE name=$1 FOR UPDATE`)).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`INSERT INTO index_schema`).
+			WithArgs(uint64(123), `{"type":"object"}`).
+			WillReturnError(errors.New("theInsertError"))
+		dbm.ExpectRollback()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, err = repo.SetSchema(context.Background(), "theIndex", json.RawMessage(`{"type":"object"}`))
+
+		assert.EqualError(t, err, "insert schema db scan: theInsertError")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM index WHERE name=$1 FOR UPDATE`)).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`INSERT INTO index_schema`).
+			WithArgs(uint64(123), `{"type":"object"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+		dbm.ExpectExec(regexp.QuoteMeta(`UPDATE index SET schema_version=$1, updated_at=now() WHERE id=$2`)).
+			WithArgs(uint32(3), uint64(123)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		ver, err := repo.SetSchema(context.Background(), "theIndex", json.RawMessage(`{"type":"object"}`))
+
+		require.NoError(t, err)
+		assert.Equal(t, uint32(3), ver)
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("OkDeactivate", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM index WHERE name=$1 FOR UPDATE`)).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectExec(regexp.QuoteMeta(`UPDATE index SET schema_version=$1, updated_at=now() WHERE id=$2`)).
+			WithArgs(uint32(0), uint64(123)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit()
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		ver, err := repo.SetSchema(context.Background(), "theIndex", nil)
+
+		require.NoError(t, err)
+		assert.Zero(t, ver)
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
+
+func TestIndexRepository_GetSchema(tt *testing.T) {
+	tt.Run("NotFound", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT .+ FROM index_schema`).
+			WithArgs("theIndex", uint32(2)).
+			WillReturnError(sql.ErrNoRows)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, err = repo.GetSchema(context.Background(), "theIndex", 2)
+
+		assert.ErrorIs(t, err, apperrors.NotFoundError{Subj: "schema"})
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT .+ FROM index_schema`).
+			WithArgs("theIndex", uint32(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"version", "schema", "created_at"}).
+				AddRow(2, `{"type":"object"}`, time.Unix(123, 0)))
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		sch, err := repo.GetSchema(context.Background(), "theIndex", 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, indexrepo.Schema{
+			Version:   2,
+			Schema:    json.RawMessage(`{"type":"object"}`),
+			CreatedAt: time.Unix(123, 0),
+		}, sch)
+	})
+}
+
+func TestIndexRepository_SchemaHistory(tt *testing.T) {
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT .+ FROM index WHERE name=\$1`).
+			WithArgs("theIndex").
+			WillReturnError(sql.ErrNoRows)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, _, err = repo.SchemaHistory(context.Background(), "theIndex", 0, 10)
+
+		assert.ErrorIs(t, err, apperrors.NotFoundError{Subj: "index"})
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT .+ FROM index WHERE name=\$1`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "title", "schema_version", "ttl", "read_only", "insert_only",
+				"no_history", "labels", "metadata", "created_at", "updated_at"}).
+				AddRow(123, "theTitle", 3, 0, false, false, false, `{}`, `{}`, time.Unix(234, 0), time.Unix(345, 0)))
+		dbm.ExpectQuery(`SELECT .+ FROM index_schema .+ ORDER BY s.version DESC LIMIT \$3`).
+			WithArgs("theIndex", uint64(3), uint32(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"version", "schema", "created_at"}).
+				AddRow(2, `{"type":"array"}`, time.Unix(200, 0)).
+				AddRow(1, `{}`, time.Unix(100, 0)))
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		res, cur, err := repo.SchemaHistory(context.Background(), "theIndex", 3, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), cur)
+		assert.Equal(t, []indexrepo.Schema{
+			{Version: 2, Schema: json.RawMessage(`{"type":"array"}`), CreatedAt: time.Unix(200, 0)},
+		}, res)
+	})
+}
@@ -0,0 +1,192 @@
+package indexrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// Stats returns an index's statistics. If req.Approximate is set, record counts and the data size are estimated from
+// the planner statistics of the tables instead of being counted, which is cheap regardless of the index size.
+func (r *Repository) Stats(ctx context.Context, name string, req StatsRequest) (Stats, error) {
+	if err := r.nameValidator.Validate(name); err != nil {
+		return Stats{}, err //nolint:wrapcheck // ok
+	}
+
+	var indexID uint64
+
+	row := r.db.QueryRowContext(ctx, `SELECT id FROM index WHERE name=$1`, name)
+	if err := row.Scan(&indexID); errors.Is(err, sql.ErrNoRows) {
+		return Stats{}, apperrors.NotFoundError{Subj: "index"}
+	} else if err != nil {
+		return Stats{}, fmt.Errorf("get index db scan: %w", err)
+	}
+
+	res, err := r.IndicesStats(ctx, []uint64{indexID}, req)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return res[indexID], nil
+}
+
+// IndicesStats returns statistics of the indices keyed by index ID. The number of queries doesn't depend on the
+// number of indices.
+func (r *Repository) IndicesStats(ctx context.Context, indexIDs []uint64, req StatsRequest) (map[uint64]Stats, error) {
+	res := make(map[uint64]Stats, len(indexIDs))
+	if len(indexIDs) == 0 {
+		return res, nil
+	}
+
+	for _, id := range indexIDs {
+		res[id] = Stats{}
+	}
+
+	notTouchedSince := sql.NullTime{}
+	if req.NotTouchedSince != nil {
+		notTouchedSince = sql.NullTime{Time: *req.NotTouchedSince, Valid: true}
+	}
+
+	if req.Approximate {
+		return res, r.estimateStats(ctx, res, indexIDs, notTouchedSince)
+	}
+
+	return res, r.countStats(ctx, res, indexIDs, notTouchedSince)
+}
+
+// countStats counts the indices' records and sums their data sizes.
+func (r *Repository) countStats(
+	ctx context.Context,
+	res map[uint64]Stats,
+	indexIDs []uint64,
+	notTouchedSince sql.NullTime,
+) error {
+	ph, args := inList(indexIDs, 2) //nolint:mnd // after notTouchedSince
+	q := `SELECT index_id, count(*), coalesce(sum(pg_column_size(data)), 0), max(touched_at),
+count(*) FILTER (WHERE touched_at < $1) FROM record WHERE index_id IN (` + ph + `) GROUP BY index_id`
+
+	err := r.scanStats(ctx, q, append([]any{notTouchedSince}, args...), func(rows *sql.Rows) error {
+		id, st := uint64(0), Stats{}
+		if err := rows.Scan(&id, &st.Records, &st.DataSize, &st.LastPushAt, &st.NotTouched); err != nil {
+			return err //nolint:wrapcheck // ok
+		}
+
+		res[id] = st
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("record stats %w", err)
+	}
+
+	ph, args = inList(indexIDs, 1)
+	q = `SELECT index_id, count(*), coalesce(sum(pg_column_size(data)), 0) FROM record_log
+WHERE index_id IN (` + ph + `) GROUP BY index_id`
+
+	err = r.scanStats(ctx, q, args, func(rows *sql.Rows) error {
+		id, cnt, size := uint64(0), uint64(0), uint64(0)
+		if err := rows.Scan(&id, &cnt, &size); err != nil {
+			return err //nolint:wrapcheck // ok
+		}
+
+		st := res[id]
+		st.HistoryRecords = cnt
+		st.DataSize += size
+		res[id] = st
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("record log stats %w", err)
+	}
+
+	return nil
+}
+
+// estimateStats estimates the indices' record counts with the planner and their data sizes with the average row sizes
+// of the tables, so no records are read. The last push time is exact, it's a single index lookup. Indices without
+// records get zero record counts, since the planner never estimates less than a row.
+func (r *Repository) estimateStats(
+	ctx context.Context,
+	res map[uint64]Stats,
+	indexIDs []uint64,
+	notTouchedSince sql.NullTime,
+) error {
+	ph, args := inList(indexIDs, 2) //nolint:mnd // after notTouchedSince
+
+	q := `WITH e AS (
+	SELECT i.id, (SELECT max(touched_at) FROM record WHERE index_id = i.id) AS last_push_at,
+	row_estimate(format('SELECT 1 FROM record WHERE index_id = %s', i.id)) AS records,
+	row_estimate(format('SELECT 1 FROM record_log WHERE index_id = %s', i.id)) AS history_records,
+	CASE WHEN $1::TIMESTAMP IS NULL THEN 0 ELSE row_estimate(format(
+		'SELECT 1 FROM record WHERE index_id = %s AND touched_at < %L', i.id, $1::TIMESTAMP)) END AS not_touched
+	FROM index i WHERE i.id IN (` + ph + `)
+), w AS (
+	SELECT
+	(SELECT pg_table_size(oid) / greatest(reltuples, 1) FROM pg_class WHERE oid = 'record'::REGCLASS) AS record,
+	(SELECT pg_table_size(oid) / greatest(reltuples, 1) FROM pg_class WHERE oid = 'record_log'::REGCLASS) AS log
+)
+SELECT e.id, CASE WHEN e.last_push_at IS NULL THEN 0 ELSE e.records END, e.history_records,
+(CASE WHEN e.last_push_at IS NULL THEN 0 ELSE e.records * w.record END + e.history_records * w.log)::BIGINT,
+e.last_push_at, CASE WHEN e.last_push_at IS NULL THEN 0 ELSE e.not_touched END
+FROM e, w`
+
+	err := r.scanStats(ctx, q, append([]any{notTouchedSince}, args...), func(rows *sql.Rows) error {
+		id, st := uint64(0), Stats{}
+		if err := rows.Scan(&id, &st.Records, &st.HistoryRecords, &st.DataSize, &st.LastPushAt,
+			&st.NotTouched); err != nil {
+			return err //nolint:wrapcheck // ok
+		}
+
+		res[id] = st
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("estimate stats %w", err)
+	}
+
+	return nil
+}
+
+// scanStats runs the statistics query and calls scan for each row.
+func (r *Repository) scanStats(ctx context.Context, q string, args []any, scan func(rows *sql.Rows) error) error {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("db query: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("db scan: %w", err)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("db rows: %w", err)
+	}
+
+	return nil
+}
+
+// inList returns the placeholders of an IN list of the IDs, numbered starting from first, and the IDs as statement
+// arguments.
+func inList(ids []uint64, first int) (string, []any) {
+	ph := make([]string, 0, len(ids))
+	args := make([]any, 0, len(ids))
+
+	for i, id := range ids {
+		ph = append(ph, fmt.Sprintf("$%d", first+i))
+		args = append(args, id)
+	}
+
+	return strings.Join(ph, ", "), args
+}
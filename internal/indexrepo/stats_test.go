@@ -0,0 +1,180 @@
+package indexrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+)
+
+func TestIndexRepository_Stats(tt *testing.T) {
+	okValidator := func() *stringValidatorMock {
+		return &stringValidatorMock{ValidateFunc: func(s string) error { return nil }}
+	}
+
+	tt.Run("NameValidatorError", func(t *testing.T) {
+		nameValidator := &stringValidatorMock{}
+		nameValidator.ValidateFunc = func(s string) error {
+			return errors.New("theValidatorError")
+		}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
+		_, err = repo.Stats(context.Background(), "", indexrepo.StatsRequest{})
+
+		assert.EqualError(t, err, "theValidatorError")
+	})
+
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id FROM index`).WillReturnError(sql.ErrNoRows)
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		_, err = repo.Stats(context.Background(), "theIndex", indexrepo.StatsRequest{})
+
+		assert.ErrorIs(t, err, apperrors.NotFoundError{Subj: "index"})
+	})
+
+	tt.Run("RecordStatsError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id FROM index`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`SELECT index_id, count\(\*\), .+ FROM record WHERE`).WillReturnError(errors.New("theDBError"))
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		_, err = repo.Stats(context.Background(), "theIndex", indexrepo.StatsRequest{})
+
+		assert.EqualError(t, err, "record stats db query: theDBError")
+	})
+
+	tt.Run("RecordLogStatsError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id FROM index`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`SELECT index_id, count\(\*\), .+ FROM record WHERE`).
+			WillReturnRows(sqlmock.NewRows([]string{"index_id", "count", "size", "max", "count"}).AddRow(123, 1, 2, nil, 0))
+		dbm.ExpectQuery(`SELECT index_id, count\(\*\), .+ FROM record_log`).WillReturnError(errors.New("theDBError"))
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		_, err = repo.Stats(context.Background(), "theIndex", indexrepo.StatsRequest{})
+
+		assert.EqualError(t, err, "record log stats db query: theDBError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		ntSince := time.Unix(100, 0)
+
+		dbm.ExpectQuery(`SELECT id FROM index`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`SELECT index_id, count\(\*\), coalesce\(sum\(pg_column_size\(data\)\), 0\), max\(touched_at\), `+
+			`count\(\*\) FILTER \(WHERE touched_at < \$1\) FROM record WHERE index_id IN \(\$2\) GROUP BY index_id`).
+			WithArgs(sql.NullTime{Time: ntSince, Valid: true}, 123).
+			WillReturnRows(sqlmock.NewRows([]string{"index_id", "count", "size", "max", "count"}).
+				AddRow(123, 10, 200, time.Unix(300, 0), 4))
+		dbm.ExpectQuery(`SELECT index_id, count\(\*\), coalesce\(sum\(pg_column_size\(data\)\), 0\) FROM record_log ` +
+			`WHERE index_id IN \(\$1\) GROUP BY index_id`).
+			WithArgs(123).
+			WillReturnRows(sqlmock.NewRows([]string{"index_id", "count", "size"}).AddRow(123, 20, 400))
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		st, err := repo.Stats(context.Background(), "theIndex", indexrepo.StatsRequest{NotTouchedSince: &ntSince})
+		require.NoError(t, err)
+		assert.Equal(t, indexrepo.Stats{
+			Records:        10,
+			HistoryRecords: 20,
+			DataSize:       600,
+			LastPushAt:     sql.NullTime{Time: time.Unix(300, 0), Valid: true},
+			NotTouched:     4,
+		}, st)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("OkNoRecords", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id FROM index`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`SELECT index_id, count\(\*\), .+ FROM record WHERE`).
+			WithArgs(sql.NullTime{}, 123).
+			WillReturnRows(sqlmock.NewRows([]string{"index_id", "count", "size", "max", "count"}))
+		dbm.ExpectQuery(`SELECT index_id, count\(\*\), .+ FROM record_log`).
+			WillReturnRows(sqlmock.NewRows([]string{"index_id", "count", "size"}))
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		st, err := repo.Stats(context.Background(), "theIndex", indexrepo.StatsRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, indexrepo.Stats{}, st)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("EstimateError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id FROM index`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`WITH e AS`).WillReturnError(errors.New("theDBError"))
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		_, err = repo.Stats(context.Background(), "theIndex", indexrepo.StatsRequest{Approximate: true})
+
+		assert.EqualError(t, err, "estimate stats db query: theDBError")
+	})
+
+	tt.Run("OkApproximate", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		ntSince := time.Unix(100, 0)
+
+		// Nothing but the last push time is read from the tables
+		dbm.ExpectQuery(`SELECT id FROM index`).
+			WithArgs("theIndex").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+		dbm.ExpectQuery(`WITH e AS \(
+	SELECT i.id, \(SELECT max\(touched_at\) FROM record WHERE index_id = i.id\) AS last_push_at,
+	row_estimate\(format\('SELECT 1 FROM record WHERE index_id = %s', i.id\)\) AS records,
+	row_estimate\(format\('SELECT 1 FROM record_log WHERE index_id = %s', i.id\)\) AS history_records,
+	.+
+	FROM index i WHERE i.id IN \(\$2\)
+\), w AS \(.+pg_table_size.+\)
+SELECT .+ FROM e, w`).
+			WithArgs(sql.NullTime{Time: ntSince, Valid: true}, 123).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "records", "history_records", "size", "last_push_at", "not_touched"}).
+				AddRow(123, 1000, 3000, 40000, time.Unix(300, 0), 40))
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		st, err := repo.Stats(context.Background(), "theIndex", indexrepo.StatsRequest{
+			NotTouchedSince: &ntSince,
+			Approximate:     true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, indexrepo.Stats{
+			Records:        1000,
+			HistoryRecords: 3000,
+			DataSize:       40000,
+			LastPushAt:     sql.NullTime{Time: time.Unix(300, 0), Valid: true},
+			NotTouched:     40,
+		}, st)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
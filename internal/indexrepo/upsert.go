@@ -3,24 +3,93 @@ package indexrepo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/labelselector"
 )
 
-func (r *Repository) Upsert(ctx context.Context, name, title string) error {
-	if err := r.nameValidator.Validate(name); err != nil {
+// UpsertRequest describes an index to create or update. Nil fields of an existing index are left unchanged; a new
+// index gets zero values for them.
+type UpsertRequest struct {
+	Name       string
+	Title      string
+	TTL        *time.Duration // default TTL of the index's records; zero means the records don't expire by default
+	ReadOnly   *bool
+	InsertOnly *bool
+	NoHistory  *bool
+	Labels     map[string]string // nil map leaves labels unchanged, an empty one removes them
+	Metadata   *json.RawMessage  // empty metadata is stored as an empty object
+}
+
+// Upsert creates an index or updates an existing one.
+func (r *Repository) Upsert(ctx context.Context, req UpsertRequest) error {
+	if err := r.nameValidator.Validate(req.Name); err != nil {
 		return err //nolint:wrapcheck // ok
 	}
 
+	ttl := sql.NullInt64{}
+	if req.TTL != nil {
+		if *req.TTL < 0 || *req.TTL > MaxTTL {
+			return apperrors.InvalidArgError{
+				Subj:   "ttl",
+				Reason: fmt.Sprintf("must be between 0 and %d seconds", MaxTTL/time.Second),
+			}
+		}
+
+		ttl = sql.NullInt64{Int64: int64(*req.TTL / time.Second), Valid: true}
+	}
+
+	labels := sql.NullString{}
+	if req.Labels != nil {
+		if err := labelselector.Validate(req.Labels); err != nil {
+			return apperrors.InvalidArgError{Subj: "labels", Reason: err.Error()}
+		}
+
+		b, err := json.Marshal(req.Labels)
+		if err != nil {
+			return fmt.Errorf("labels marshal: %w", err)
+		}
+
+		labels = sql.NullString{String: string(b), Valid: true}
+	}
+
+	metadata := sql.NullString{}
+	if req.Metadata != nil {
+		metadata = sql.NullString{String: string(*req.Metadata), Valid: true}
+
+		if len(*req.Metadata) == 0 {
+			metadata.String = `{}`
+		} else if err := json.Unmarshal(*req.Metadata, &map[string]json.RawMessage{}); err != nil {
+			return apperrors.InvalidArgError{Subj: "metadata", Reason: "must be a JSON object"}
+		}
+	}
+
 	sqlTitle := sql.NullString{
-		String: title,
-		Valid:  title != "",
+		String: req.Title,
+		Valid:  req.Title != "",
 	}
 
-	q := `INSERT INTO index (name, title) VALUES ($1, $2) 
-ON CONFLICT (name) DO UPDATE SET title=$2, updated_at=now()`
-	if _, err := r.db.ExecContext(ctx, q, name, sqlTitle); err != nil {
+	q := `INSERT INTO index (name, title, ttl, read_only, insert_only, no_history, labels, metadata)
+VALUES ($1, $2, COALESCE($3, 0), COALESCE($4, false), COALESCE($5, false), COALESCE($6, false),
+COALESCE($7::JSONB, '{}'), COALESCE($8::JSONB, '{}'))
+ON CONFLICT (name) DO UPDATE SET title=$2, ttl=COALESCE($3, index.ttl), read_only=COALESCE($4, index.read_only),
+insert_only=COALESCE($5, index.insert_only), no_history=COALESCE($6, index.no_history),
+labels=COALESCE($7::JSONB, index.labels), metadata=COALESCE($8::JSONB, index.metadata), updated_at=now()`
+	if _, err := r.db.ExecContext(ctx, q, req.Name, sqlTitle, ttl, nullBool(req.ReadOnly), nullBool(req.InsertOnly),
+		nullBool(req.NoHistory), labels, metadata); err != nil {
 		return fmt.Errorf("db query failed: %w", err)
 	}
 
 	return nil
 }
+
+func nullBool(v *bool) sql.NullBool {
+	if v == nil {
+		return sql.NullBool{}
+	}
+
+	return sql.NullBool{Bool: *v, Valid: true}
+}
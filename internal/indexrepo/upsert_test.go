@@ -2,10 +2,14 @@ package indexrepo_test
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -14,6 +18,10 @@ import (
 )
 
 func TestIndexRepository_Upsert(tt *testing.T) {
+	okValidator := func() *stringValidatorMock {
+		return &stringValidatorMock{ValidateFunc: func(s string) error { return nil }}
+	}
+
 	tt.Run("NameValidatorError", func(t *testing.T) {
 		nameValidator := &stringValidatorMock{}
 		nameValidator.ValidateFunc = func(s string) error {
@@ -24,17 +32,51 @@ func TestIndexRepository_Upsert(tt *testing.T) {
 		require.NoError(t, err)
 
 		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
-		err = repo.Upsert(context.Background(), "", "")
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{})
 
 		assert.EqualError(t, err, "theValidatorError")
 	})
 
-	tt.Run("DBExecError", func(t *testing.T) {
-		nameValidator := &stringValidatorMock{}
-		nameValidator.ValidateFunc = func(s string) error {
-			return nil
-		}
+	tt.Run("NegativeTTL", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{Name: "theIndex", TTL: ptr(-time.Second)})
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "ttl", Reason: "must be between 0 and 2147483647 seconds"})
+	})
 
+	tt.Run("InvalidLabels", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{
+			Name:   "theIndex",
+			Labels: map[string]string{"team": "the team"},
+		})
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{
+			Subj:   "labels",
+			Reason: `label "team" value "the team" must match the regexp ^([a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-9])?)?$`,
+		})
+	})
+
+	tt.Run("InvalidMetadata", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{
+			Name:     "theIndex",
+			Metadata: ptr(json.RawMessage(`[1]`)),
+		})
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "metadata", Reason: "must be a JSON object"})
+	})
+
+	tt.Run("DBExecError", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
@@ -42,47 +84,89 @@ func TestIndexRepository_Upsert(tt *testing.T) {
 			ExpectExec(`INSERT INTO index`).
 			WillReturnError(errors.New("theDBExecError"))
 
-		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
-		err = repo.Upsert(context.Background(), "theIndex", "theTitle")
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{Name: "theIndex", Title: "theTitle"})
 
 		require.EqualError(t, err, "db query failed: theDBExecError")
 	})
 
 	tt.Run("Ok", func(t *testing.T) {
-		nameValidator := &stringValidatorMock{}
-		nameValidator.ValidateFunc = func(s string) error {
-			return nil
-		}
-
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
 		dbm.
 			ExpectExec(`INSERT INTO index`).
+			WithArgs("theIndex", sql.NullString{String: "theTitle", Valid: true},
+				sql.NullInt64{Int64: 3600, Valid: true}, sql.NullBool{Bool: true, Valid: true},
+				sql.NullBool{Valid: true}, sql.NullBool{Bool: true, Valid: true},
+				sql.NullString{String: `{"env":"prod","team":"search"}`, Valid: true},
+				sql.NullString{String: `{"owner": "John"}`, Valid: true}).
 			WillReturnResult(sqlmock.NewResult(123, 234))
 
-		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
-		err = repo.Upsert(context.Background(), "theIndex", "theTitle")
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{
+			Name:       "theIndex",
+			Title:      "theTitle",
+			TTL:        ptr(time.Hour),
+			ReadOnly:   ptr(true),
+			InsertOnly: ptr(false),
+			NoHistory:  ptr(true),
+			Labels:     map[string]string{"team": "search", "env": "prod"},
+			Metadata:   ptr(json.RawMessage(`{"owner": "John"}`)),
+		})
 
 		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
-	tt.Run("OkEmptyTitle", func(t *testing.T) {
-		nameValidator := &stringValidatorMock{}
-		nameValidator.ValidateFunc = func(s string) error {
-			return nil
-		}
-
+	tt.Run("OkEmpty", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
+		// Empty labels and metadata are stored as empty objects
 		dbm.
 			ExpectExec(`INSERT INTO index`).
+			WithArgs("theIndex", sql.NullString{}, sql.NullInt64{Valid: true}, sql.NullBool{}, sql.NullBool{},
+				sql.NullBool{}, sql.NullString{String: `{}`, Valid: true}, sql.NullString{String: `{}`, Valid: true}).
 			WillReturnResult(sqlmock.NewResult(123, 234))
 
-		repo := indexrepo.New(db, nameValidator, zerolog.Nop())
-		err = repo.Upsert(context.Background(), "theIndex", "")
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{
+			Name:     "theIndex",
+			TTL:      ptr(time.Duration(0)),
+			Labels:   map[string]string{},
+			Metadata: ptr(json.RawMessage{}),
+		})
 
 		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
+
+	tt.Run("OkFieldsNotSet", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		// Fields which are not set are left unchanged
+		dbm.
+			ExpectExec(`INSERT INTO index \(name, title, ttl, read_only, insert_only, no_history, labels, metadata\)\s+`+
+				`VALUES \(\$1, \$2, COALESCE\(\$3, 0\), COALESCE\(\$4, false\), COALESCE\(\$5, false\), `+
+				`COALESCE\(\$6, false\),\s+COALESCE\(\$7::JSONB, '\{\}'\), COALESCE\(\$8::JSONB, '\{\}'\)\)\s+`+
+				`ON CONFLICT \(name\) DO UPDATE SET title=\$2, ttl=COALESCE\(\$3, index.ttl\), `+
+				`read_only=COALESCE\(\$4, index.read_only\),\s+insert_only=COALESCE\(\$5, index.insert_only\), `+
+				`no_history=COALESCE\(\$6, index.no_history\),\s+labels=COALESCE\(\$7::JSONB, index.labels\), `+
+				`metadata=COALESCE\(\$8::JSONB, index.metadata\), updated_at=now\(\)`).
+			WithArgs("theIndex", sql.NullString{String: "theTitle", Valid: true}, sql.NullInt64{}, sql.NullBool{},
+				sql.NullBool{}, sql.NullBool{}, sql.NullString{}, sql.NullString{}).
+			WillReturnResult(sqlmock.NewResult(123, 234))
+
+		repo := indexrepo.New(db, okValidator(), zerolog.Nop())
+		err = repo.Upsert(context.Background(), indexrepo.UpsertRequest{Name: "theIndex", Title: "theTitle"})
+
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
 }
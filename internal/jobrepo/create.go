@@ -0,0 +1,17 @@
+package jobrepo
+
+import (
+	"context"
+	"fmt"
+)
+
+func (r *Repository) Create(ctx context.Context, kind string) (uint64, error) {
+	var id uint64
+
+	q := `INSERT INTO job (kind) VALUES ($1) RETURNING id`
+	if err := r.db.QueryRowContext(ctx, q, kind).Scan(&id); err != nil {
+		return 0, fmt.Errorf("db scan: %w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,45 @@
+package jobrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/jobrepo"
+)
+
+func TestJobRepository_Create(tt *testing.T) {
+	tt.Run("DBScanError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`INSERT INTO job`).
+			WithArgs("theKind").
+			WillReturnError(errors.New("theDBError"))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		_, err = repo.Create(context.Background(), "theKind")
+
+		assert.EqualError(t, err, "db scan: theDBError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`INSERT INTO job`).
+			WithArgs("theKind").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(123))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		id, err := repo.Create(context.Background(), "theKind")
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(123), id)
+	})
+}
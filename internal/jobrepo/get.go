@@ -0,0 +1,29 @@
+package jobrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ashep/go-apperrors"
+)
+
+func (r *Repository) Get(ctx context.Context, id uint64) (Job, error) {
+	if id == 0 {
+		return Job{}, apperrors.InvalidArgError{Subj: "job id", Reason: "must not be zero"}
+	}
+
+	job := Job{ID: id}
+	q := `SELECT kind, status, processed, total, error, created_at, updated_at FROM job WHERE id=$1`
+
+	row := r.db.QueryRowContext(ctx, q, id)
+	err := row.Scan(&job.Kind, &job.Status, &job.Processed, &job.Total, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Job{}, apperrors.NotFoundError{Subj: "job"}
+	} else if err != nil {
+		return Job{}, fmt.Errorf("db scan: %w", err)
+	}
+
+	return job, nil
+}
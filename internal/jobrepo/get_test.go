@@ -0,0 +1,81 @@
+package jobrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/jobrepo"
+)
+
+func TestJobRepository_Get(tt *testing.T) {
+	tt.Run("ZeroID", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		_, err = repo.Get(context.Background(), 0)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "job id", Reason: "must not be zero"})
+	})
+
+	tt.Run("NotFound", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT .+ FROM job`).
+			WillReturnError(sql.ErrNoRows)
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		_, err = repo.Get(context.Background(), 123)
+
+		assert.ErrorIs(t, err, apperrors.NotFoundError{Subj: "job"})
+	})
+
+	tt.Run("DBScanError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT .+ FROM job`).
+			WillReturnError(errors.New("theDBError"))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		_, err = repo.Get(context.Background(), 123)
+
+		assert.EqualError(t, err, "db scan: theDBError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		rows := sqlmock.NewRows([]string{"kind", "status", "processed", "total", "error", "created_at", "updated_at"}).
+			AddRow("theKind", "done", 12, 34, nil, time.Unix(123, 0), time.Unix(234, 0))
+
+		dbm.ExpectQuery(`SELECT .+ FROM job`).
+			WithArgs(123).
+			WillReturnRows(rows)
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		job, err := repo.Get(context.Background(), 123)
+
+		require.NoError(t, err)
+		assert.Equal(t, jobrepo.Job{
+			ID:        123,
+			Kind:      "theKind",
+			Status:    jobrepo.StatusDone,
+			Processed: 12,
+			Total:     34,
+			CreatedAt: time.Unix(123, 0),
+			UpdatedAt: time.Unix(234, 0),
+		}, job)
+	})
+}
@@ -0,0 +1,23 @@
+package jobrepo
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+type Job struct {
+	ID        uint64
+	Kind      string
+	Status    string
+	Processed uint64
+	Total     uint64
+	Error     sql.NullString
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
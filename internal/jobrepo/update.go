@@ -0,0 +1,35 @@
+package jobrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Progress stores the number of processed items and the total number of items of a running job.
+func (r *Repository) Progress(ctx context.Context, id, processed, total uint64) error {
+	q := `UPDATE job SET processed=$2, total=$3, updated_at=now() WHERE id=$1`
+	if _, err := r.db.ExecContext(ctx, q, id, processed, total); err != nil {
+		return fmt.Errorf("db exec: %w", err)
+	}
+
+	return nil
+}
+
+// Finish marks a job as done, or as failed if jobErr is not nil.
+func (r *Repository) Finish(ctx context.Context, id uint64, jobErr error) error {
+	status := StatusDone
+	errMsg := sql.NullString{}
+
+	if jobErr != nil {
+		status = StatusFailed
+		errMsg = sql.NullString{String: jobErr.Error(), Valid: true}
+	}
+
+	q := `UPDATE job SET status=$2, error=$3, updated_at=now() WHERE id=$1`
+	if _, err := r.db.ExecContext(ctx, q, id, status, errMsg); err != nil {
+		return fmt.Errorf("db exec: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package jobrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/jobrepo"
+)
+
+func TestJobRepository_Progress(tt *testing.T) {
+	tt.Run("DBExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE job SET processed`).
+			WithArgs(123, 12, 34).
+			WillReturnError(errors.New("theDBError"))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		err = repo.Progress(context.Background(), 123, 12, 34)
+
+		assert.EqualError(t, err, "db exec: theDBError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE job SET processed`).
+			WithArgs(123, 12, 34).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		err = repo.Progress(context.Background(), 123, 12, 34)
+
+		require.NoError(t, err)
+	})
+}
+
+func TestJobRepository_Finish(tt *testing.T) {
+	tt.Run("DBExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE job SET status`).
+			WillReturnError(errors.New("theDBError"))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		err = repo.Finish(context.Background(), 123, nil)
+
+		assert.EqualError(t, err, "db exec: theDBError")
+	})
+
+	tt.Run("OkDone", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE job SET status`).
+			WithArgs(123, "done", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		err = repo.Finish(context.Background(), 123, nil)
+
+		require.NoError(t, err)
+	})
+
+	tt.Run("OkFailed", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE job SET status`).
+			WithArgs(123, "failed", "theJobError").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		repo := jobrepo.New(db, zerolog.Nop())
+		err = repo.Finish(context.Background(), 123, errors.New("theJobError"))
+
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,85 @@
+package jobrunner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ashep/ujds/internal/jobrepo"
+	"github.com/rs/zerolog"
+)
+
+type jobRepo interface {
+	Create(ctx context.Context, kind string) (uint64, error)
+	Get(ctx context.Context, id uint64) (jobrepo.Job, error)
+	Progress(ctx context.Context, id, processed, total uint64) error
+	Finish(ctx context.Context, id uint64, jobErr error) error
+}
+
+// ProgressFunc reports the number of processed items and the total number of items of a job.
+type ProgressFunc func(processed, total uint64)
+
+// Task is a function executed in background by a job.
+type Task func(ctx context.Context, progress ProgressFunc) error
+
+// Runner executes long-running tasks in background, keeping track of their state in the job repository.
+type Runner struct {
+	ctx  context.Context //nolint:containedctx // tasks must outlive requests that started them
+	repo jobRepo
+	wg   sync.WaitGroup
+	l    zerolog.Logger
+}
+
+func New(ctx context.Context, repo jobRepo, l zerolog.Logger) *Runner {
+	return &Runner{
+		ctx:  ctx,
+		repo: repo,
+		l:    l,
+	}
+}
+
+// Start registers a new job and runs the task in background. Tasks are canceled when the runner's context is done.
+func (r *Runner) Start(ctx context.Context, kind string, task Task) (uint64, error) {
+	id, err := r.repo.Create(ctx, kind)
+	if err != nil {
+		return 0, fmt.Errorf("create job: %w", err)
+	}
+
+	l := r.l.With().Uint64("job_id", id).Str("job_kind", kind).Logger()
+
+	r.wg.Go(func() {
+		l.Info().Msg("job started")
+
+		progress := func(processed, total uint64) {
+			if err := r.repo.Progress(r.ctx, id, processed, total); err != nil {
+				l.Error().Err(err).Msg("job progress update failed")
+			}
+		}
+
+		taskErr := task(r.ctx, progress)
+
+		// The runner's context may be already canceled here, but the job's final state must be stored anyway
+		if err := r.repo.Finish(context.WithoutCancel(r.ctx), id, taskErr); err != nil {
+			l.Error().Err(err).Msg("job finish failed")
+		}
+
+		if taskErr != nil {
+			l.Error().Err(taskErr).Msg("job failed")
+			return
+		}
+
+		l.Info().Msg("job done")
+	})
+
+	return id, nil
+}
+
+// Get returns a job's state.
+func (r *Runner) Get(ctx context.Context, id uint64) (jobrepo.Job, error) {
+	return r.repo.Get(ctx, id) //nolint:wrapcheck // ok
+}
+
+// Wait blocks until all the running tasks are finished.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
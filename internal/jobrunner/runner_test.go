@@ -0,0 +1,97 @@
+package jobrunner_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ashep/ujds/internal/jobrepo"
+	"github.com/ashep/ujds/internal/jobrunner"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jobRepoMock struct {
+	mu        sync.Mutex
+	createErr error
+	progress  [][2]uint64
+	finished  []error
+}
+
+func (m *jobRepoMock) Create(_ context.Context, _ string) (uint64, error) {
+	if m.createErr != nil {
+		return 0, m.createErr
+	}
+
+	return 123, nil
+}
+
+func (m *jobRepoMock) Get(_ context.Context, id uint64) (jobrepo.Job, error) {
+	return jobrepo.Job{ID: id}, nil
+}
+
+func (m *jobRepoMock) Progress(_ context.Context, _, processed, total uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.progress = append(m.progress, [2]uint64{processed, total})
+
+	return nil
+}
+
+func (m *jobRepoMock) Finish(_ context.Context, _ uint64, jobErr error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, jobErr)
+
+	return nil
+}
+
+func TestRunner_Start(tt *testing.T) {
+	tt.Run("CreateError", func(t *testing.T) {
+		repo := &jobRepoMock{createErr: errors.New("theCreateError")}
+		r := jobrunner.New(context.Background(), repo, zerolog.Nop())
+
+		_, err := r.Start(context.Background(), "theKind", func(context.Context, jobrunner.ProgressFunc) error {
+			t.Fatal("must not be called")
+			return nil
+		})
+
+		assert.EqualError(t, err, "create job: theCreateError")
+	})
+
+	tt.Run("TaskError", func(t *testing.T) {
+		lb := &strings.Builder{}
+		repo := &jobRepoMock{}
+		r := jobrunner.New(context.Background(), repo, zerolog.New(lb))
+
+		id, err := r.Start(context.Background(), "theKind", func(context.Context, jobrunner.ProgressFunc) error {
+			return errors.New("theTaskError")
+		})
+		require.NoError(t, err)
+		r.Wait()
+
+		assert.Equal(t, uint64(123), id)
+		assert.Equal(t, []error{errors.New("theTaskError")}, repo.finished)
+		assert.Contains(t, lb.String(), `"message":"job failed"`)
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		repo := &jobRepoMock{}
+		r := jobrunner.New(context.Background(), repo, zerolog.Nop())
+
+		id, err := r.Start(context.Background(), "theKind", func(_ context.Context, progress jobrunner.ProgressFunc) error {
+			progress(1, 2)
+			progress(2, 2)
+			return nil
+		})
+		require.NoError(t, err)
+		r.Wait()
+
+		assert.Equal(t, uint64(123), id)
+		assert.Equal(t, [][2]uint64{{1, 2}, {2, 2}}, repo.progress)
+		assert.Equal(t, []error{nil}, repo.finished)
+	})
+}
@@ -0,0 +1,223 @@
+// Package labelselector parses Kubernetes-like label selectors, e.g. `team=search,env!=prod`, and turns them into SQL
+// conditions on a JSONB column of string labels.
+package labelselector
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+//nolint:gochecknoglobals // ok
+var (
+	keyRe   = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._/-]{0,61}[a-zA-Z0-9])?$`)
+	valueRe = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-9])?)?$`)
+	setRe   = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+type operator int
+
+const (
+	opEquals    operator = iota // key=value or key==value
+	opNotEquals                 // key!=value; matches labels without the key too
+	opIn                        // key in (value1, value2)
+	opNotIn                     // key notin (value1, value2); matches labels without the key too
+	opExists                    // key
+	opNotExists                 // !key
+)
+
+type requirement struct {
+	key    string
+	op     operator
+	values []string // one for equality operators, none for existence ones
+}
+
+// Selector is a set of requirements, all of them must be met.
+type Selector struct {
+	reqs []requirement
+}
+
+// Parse parses a comma-separated list of requirements. An empty string is an empty selector which matches everything.
+func Parse(s string) (Selector, error) {
+	res := Selector{}
+
+	if strings.TrimSpace(s) == "" {
+		return res, nil
+	}
+
+	for _, term := range splitTerms(s) {
+		req, err := parseRequirement(strings.TrimSpace(term))
+		if err != nil {
+			return Selector{}, err
+		}
+
+		res.reqs = append(res.reqs, req)
+	}
+
+	return res, nil
+}
+
+// Empty reports whether the selector has no requirements.
+func (s Selector) Empty() bool {
+	return len(s.reqs) == 0
+}
+
+// String returns the SQL condition on the column. Statement arguments returned by Args are numbered starting from
+// firstArgIndex.
+func (s Selector) String(column string, firstArgIndex int) string {
+	if len(s.reqs) == 0 {
+		return "TRUE"
+	}
+
+	n := firstArgIndex - 1
+	arg := func() string {
+		n++
+		return fmt.Sprintf("$%d", n)
+	}
+
+	conds := make([]string, 0, len(s.reqs))
+
+	for _, req := range s.reqs {
+		val := column + "->>" + arg()
+
+		switch req.op {
+		case opEquals:
+			conds = append(conds, fmt.Sprintf("%s = %s", val, arg()))
+		case opNotEquals:
+			conds = append(conds, fmt.Sprintf("%s IS DISTINCT FROM %s", val, arg()))
+		case opIn, opNotIn:
+			ph := make([]string, 0, len(req.values))
+			for range req.values {
+				ph = append(ph, arg())
+			}
+
+			if req.op == opIn {
+				conds = append(conds, fmt.Sprintf("%s IN (%s)", val, strings.Join(ph, ", ")))
+			} else {
+				conds = append(conds, fmt.Sprintf("(%[1]s IS NULL OR %[1]s NOT IN (%[2]s))", val, strings.Join(ph, ", ")))
+			}
+		case opExists:
+			conds = append(conds, val+" IS NOT NULL")
+		case opNotExists:
+			conds = append(conds, val+" IS NULL")
+		}
+	}
+
+	return strings.Join(conds, " AND ")
+}
+
+// Args returns the statement arguments of the condition returned by String: each requirement's key followed by its
+// values.
+func (s Selector) Args() []any {
+	res := make([]any, 0)
+
+	for _, req := range s.reqs {
+		res = append(res, req.key)
+		for _, v := range req.values {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// Validate checks label keys and values.
+func Validate(labels map[string]string) error {
+	for _, k := range slices.Sorted(maps.Keys(labels)) {
+		if err := checkKey(k); err != nil {
+			return err
+		}
+
+		if err := checkValue(k, labels[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitTerms splits the selector by commas which are not within value sets.
+func splitTerms(s string) []string {
+	res := make([]string, 0)
+	depth, start := 0, 0
+
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				res = append(res, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(res, s[start:])
+}
+
+func parseRequirement(term string) (requirement, error) {
+	if term == "" {
+		return requirement{}, errors.New("empty requirement")
+	}
+
+	var req requirement
+
+	if m := setRe.FindStringSubmatch(term); m != nil {
+		if strings.TrimSpace(m[3]) == "" {
+			return requirement{}, fmt.Errorf("requirement %q: empty value set", term)
+		}
+
+		req = requirement{key: m[1], op: opIn}
+		if m[2] == "notin" {
+			req.op = opNotIn
+		}
+
+		for _, v := range strings.Split(m[3], ",") {
+			req.values = append(req.values, strings.TrimSpace(v))
+		}
+	} else if k, v, ok := strings.Cut(term, "!="); ok {
+		req = requirement{key: strings.TrimSpace(k), op: opNotEquals, values: []string{strings.TrimSpace(v)}}
+	} else if k, v, ok := strings.Cut(term, "=="); ok {
+		req = requirement{key: strings.TrimSpace(k), op: opEquals, values: []string{strings.TrimSpace(v)}}
+	} else if k, v, ok := strings.Cut(term, "="); ok {
+		req = requirement{key: strings.TrimSpace(k), op: opEquals, values: []string{strings.TrimSpace(v)}}
+	} else if k, ok := strings.CutPrefix(term, "!"); ok {
+		req = requirement{key: strings.TrimSpace(k), op: opNotExists}
+	} else {
+		req = requirement{key: term, op: opExists}
+	}
+
+	if err := checkKey(req.key); err != nil {
+		return requirement{}, fmt.Errorf("requirement %q: %w", term, err)
+	}
+
+	for _, v := range req.values {
+		if err := checkValue(req.key, v); err != nil {
+			return requirement{}, fmt.Errorf("requirement %q: %w", term, err)
+		}
+	}
+
+	return req, nil
+}
+
+func checkKey(k string) error {
+	if !keyRe.MatchString(k) {
+		return fmt.Errorf("label key %q must match the regexp %s", k, keyRe)
+	}
+
+	return nil
+}
+
+func checkValue(k, v string) error {
+	if !valueRe.MatchString(v) {
+		return fmt.Errorf("label %q value %q must match the regexp %s", k, v, valueRe)
+	}
+
+	return nil
+}
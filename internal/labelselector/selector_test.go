@@ -0,0 +1,84 @@
+package labelselector_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/labelselector"
+)
+
+func TestParse(tt *testing.T) {
+	tt.Run("Empty", func(t *testing.T) {
+		s, err := labelselector.Parse(" ")
+		require.NoError(t, err)
+		assert.True(t, s.Empty())
+		assert.Equal(t, "TRUE", s.String("labels", 1))
+		assert.Empty(t, s.Args())
+	})
+
+	tt.Run("Equality", func(t *testing.T) {
+		s, err := labelselector.Parse("team=search, env != prod,tier==backend")
+		require.NoError(t, err)
+		assert.False(t, s.Empty())
+		assert.Equal(t, "labels->>$3 = $4 AND labels->>$5 IS DISTINCT FROM $6 AND labels->>$7 = $8",
+			s.String("labels", 3))
+		assert.Equal(t, []any{"team", "search", "env", "prod", "tier", "backend"}, s.Args())
+	})
+
+	tt.Run("Sets", func(t *testing.T) {
+		s, err := labelselector.Parse("env in (dev, stage),team notin (search)")
+		require.NoError(t, err)
+		assert.Equal(t, "labels->>$1 IN ($2, $3) AND (labels->>$4 IS NULL OR labels->>$4 NOT IN ($5))",
+			s.String("labels", 1))
+		assert.Equal(t, []any{"env", "dev", "stage", "team", "search"}, s.Args())
+	})
+
+	tt.Run("Existence", func(t *testing.T) {
+		s, err := labelselector.Parse("owner,!example.com/legacy")
+		require.NoError(t, err)
+		assert.Equal(t, "labels->>$1 IS NOT NULL AND labels->>$2 IS NULL", s.String("labels", 1))
+		assert.Equal(t, []any{"owner", "example.com/legacy"}, s.Args())
+	})
+
+	tt.Run("EmptyRequirement", func(t *testing.T) {
+		_, err := labelselector.Parse("team=search,")
+		assert.EqualError(t, err, "empty requirement")
+	})
+
+	tt.Run("EmptyValueSet", func(t *testing.T) {
+		_, err := labelselector.Parse("env in ()")
+		assert.EqualError(t, err, `requirement "env in ()": empty value set`)
+	})
+
+	tt.Run("InvalidKey", func(t *testing.T) {
+		_, err := labelselector.Parse("te am=search")
+		assert.EqualError(t, err, `requirement "te am=search": label key "te am" must match the regexp `+
+			`^[a-zA-Z0-9]([a-zA-Z0-9._/-]{0,61}[a-zA-Z0-9])?$`)
+	})
+
+	tt.Run("InvalidValue", func(t *testing.T) {
+		_, err := labelselector.Parse("team=!search")
+		assert.EqualError(t, err, `requirement "team=!search": label "team" value "!search" must match the regexp `+
+			`^([a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-9])?)?$`)
+	})
+}
+
+func TestValidate(tt *testing.T) {
+	tt.Run("Ok", func(t *testing.T) {
+		require.NoError(t, labelselector.Validate(map[string]string{"team": "search", "example.com/env": ""}))
+	})
+
+	tt.Run("InvalidKey", func(t *testing.T) {
+		err := labelselector.Validate(map[string]string{"team": "search", "-env": "prod"})
+		assert.EqualError(t, err, `label key "-env" must match the regexp `+
+			`^[a-zA-Z0-9]([a-zA-Z0-9._/-]{0,61}[a-zA-Z0-9])?$`)
+	})
+
+	tt.Run("InvalidValue", func(t *testing.T) {
+		err := labelselector.Validate(map[string]string{"team": "search team"})
+		assert.EqualError(t, err, `label "team" value "search team" must match the regexp `+
+			`^([a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-9])?)?$`)
+	})
+}
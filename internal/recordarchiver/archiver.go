@@ -0,0 +1,347 @@
+package recordarchiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+// Policy defines which history revisions of records of indices whose names match a pattern are archived.
+type Policy struct {
+	OlderThan string `json:"older_than" yaml:"older_than"` // duration, e.g. "2160h"
+}
+
+type policy struct {
+	indexRe   *regexp.Regexp
+	olderThan time.Duration
+}
+
+type indexRepo interface {
+	List(ctx context.Context, req indexrepo.ListRequest) ([]indexrepo.Index, string, error)
+}
+
+type recordRepo interface {
+	ArchivableRevisions(ctx context.Context, indexID uint64, before time.Time, limit uint32) ([]recordrepo.Record, error)
+	ArchiveRevisions(ctx context.Context, seg recordrepo.Segment, revs []uint64) error
+	Segments(ctx context.Context, index, id string, cursor uint64) ([]recordrepo.Segment, error)
+	IndexSegments(ctx context.Context, indexID uint64, ids []string) ([]recordrepo.Segment, error)
+}
+
+// Archiver periodically moves old history revisions from the database into compressed segment files and reads them
+// back for history requests.
+type Archiver struct {
+	ir        indexRepo
+	rr        recordRepo
+	store     Store
+	policies  []policy
+	interval  time.Duration
+	batchSize uint32
+	now       func() time.Time
+	l         zerolog.Logger
+}
+
+// New creates an archiver. The policies map binds archive policies to index name regexp patterns. The archiver runs
+// every interval and puts up to batchSize revisions into a segment. The store may be nil if there are no policies; in
+// that case archived history is not read.
+func New(
+	ir indexRepo,
+	rr recordRepo,
+	store Store,
+	policies map[string]Policy,
+	interval time.Duration,
+	batchSize uint32,
+	now func() time.Time,
+	l zerolog.Logger,
+) (*Archiver, error) {
+	if store == nil && len(policies) != 0 {
+		return nil, errors.New("archive store is not configured")
+	}
+
+	res := make([]policy, 0, len(policies))
+
+	for pattern, pol := range policies {
+		p := policy{}
+
+		var err error
+		if p.indexRe, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("archive policy %q: %w", pattern, err)
+		}
+
+		if p.olderThan, err = time.ParseDuration(pol.OlderThan); err != nil {
+			return nil, fmt.Errorf("archive policy %q: older than: %w", pattern, err)
+		}
+
+		if p.olderThan <= 0 {
+			return nil, fmt.Errorf("archive policy %q: older than: must be positive", pattern)
+		}
+
+		res = append(res, p)
+	}
+
+	return &Archiver{
+		ir:        ir,
+		rr:        rr,
+		store:     store,
+		policies:  res,
+		interval:  interval,
+		batchSize: batchSize,
+		now:       now,
+		l:         l,
+	}, nil
+}
+
+// olderThanFor returns the archive threshold of an index. If several policies match the index name, the longest
+// threshold applies.
+func (a *Archiver) olderThanFor(index string) (time.Duration, bool) {
+	res, ok := time.Duration(0), false
+
+	for _, p := range a.policies {
+		if p.indexRe.MatchString(index) {
+			res, ok = max(res, p.olderThan), true
+		}
+	}
+
+	return res, ok
+}
+
+// Archives reports whether the history of an index is archived, i.e. an archive policy applies to it.
+func (a *Archiver) Archives(index string) bool {
+	_, ok := a.olderThanFor(index)
+
+	return ok
+}
+
+// Archive applies archive policies to the history of all the indices and returns the number of archived revisions.
+func (a *Archiver) Archive(ctx context.Context) (uint64, error) {
+	if len(a.policies) == 0 {
+		return 0, nil
+	}
+
+	indices, _, err := a.ir.List(ctx, indexrepo.ListRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("list indices: %w", err)
+	}
+
+	total := uint64(0)
+
+	for _, idx := range indices {
+		d, ok := a.olderThanFor(idx.Name)
+		if !ok {
+			continue
+		}
+
+		n, err := a.archiveIndex(ctx, idx.ID, a.now().Add(-d))
+		total += n
+
+		if n > 0 {
+			a.l.Info().Str("index", idx.Name).Uint64("count", n).Msg("history revisions archived")
+		}
+
+		if err != nil {
+			return total, fmt.Errorf("archive index %s: %w", idx.Name, err)
+		}
+	}
+
+	return total, nil
+}
+
+// archiveIndex moves index's revisions created before the given time into segments, one segment per batch.
+func (a *Archiver) archiveIndex(ctx context.Context, indexID uint64, before time.Time) (uint64, error) {
+	total := uint64(0)
+
+	for {
+		revs, err := a.rr.ArchivableRevisions(ctx, indexID, before, a.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("get archivable revisions: %w", err)
+		}
+
+		if len(revs) == 0 {
+			return total, nil
+		}
+
+		if err := a.archiveSegment(ctx, indexID, revs); err != nil {
+			return total, err
+		}
+
+		total += uint64(len(revs))
+
+		if len(revs) < int(a.batchSize) {
+			return total, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return total, err //nolint:wrapcheck // ok
+		}
+	}
+}
+
+// archiveSegment writes revisions into a segment file and then deletes them from the database. If the deletion fails,
+// the file is overwritten by the next attempt, because it is named after the range of revisions it contains.
+func (a *Archiver) archiveSegment(ctx context.Context, indexID uint64, revs []recordrepo.Record) error {
+	data, err := encodeSegment(revs)
+	if err != nil {
+		return fmt.Errorf("encode segment: %w", err)
+	}
+
+	seg := recordrepo.Segment{
+		IndexID:   indexID,
+		MinRev:    revs[0].Rev,
+		MaxRev:    revs[len(revs)-1].Rev,
+		Revisions: uint32(len(revs)), //nolint:gosec // limited by the batch size
+	}
+	seg.Path = fmt.Sprintf("%d/%020d-%020d.ndjson.gz", indexID, seg.MinRev, seg.MaxRev)
+
+	revIDs := make([]uint64, len(revs))
+	recIDs := make(map[string]struct{})
+
+	for i, rev := range revs {
+		revIDs[i] = rev.Rev
+
+		if _, ok := recIDs[rev.ID]; !ok {
+			recIDs[rev.ID] = struct{}{}
+			seg.RecordIDs = append(seg.RecordIDs, rev.ID)
+		}
+	}
+
+	if err := a.store.Put(ctx, seg.Path, data); err != nil {
+		return fmt.Errorf("put segment %s: %w", seg.Path, err)
+	}
+
+	if err := a.rr.ArchiveRevisions(ctx, seg, revIDs); err != nil {
+		return fmt.Errorf("archive revisions: %w", err)
+	}
+
+	return nil
+}
+
+// History returns up to limit archived revisions of a record, ordered by revision descending; zero limit means no
+// limit. Only revisions lower than the cursor, if it is not zero, and created at or after since, if it is not zero
+// UNIX time, are returned.
+func (a *Archiver) History(
+	ctx context.Context,
+	index, id string,
+	since time.Time,
+	cursor uint64,
+	limit uint32,
+) ([]recordrepo.Record, error) {
+	if a.store == nil {
+		return nil, nil
+	}
+
+	segs, err := a.rr.Segments(ctx, index, id, cursor)
+	if err != nil {
+		return nil, fmt.Errorf("get segments: %w", err)
+	}
+
+	res := make([]recordrepo.Record, 0)
+
+	for _, seg := range segs {
+		// Segments are ordered by their max revision, so the rest of them contain lower revisions only
+		if limit != 0 && len(res) == int(limit) && seg.MaxRev < res[len(res)-1].Rev {
+			break
+		}
+
+		revs, err := a.readSegment(ctx, seg)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rev := range revs {
+			if rev.ID != id || (cursor != 0 && rev.Rev >= cursor) || (since.Unix() != 0 && rev.CreatedAt.Before(since)) {
+				continue
+			}
+
+			res = append(res, rev)
+		}
+
+		sort.Slice(res, func(i, j int) bool { return res[i].Rev > res[j].Rev })
+
+		if limit != 0 && len(res) > int(limit) {
+			res = res[:limit]
+		}
+	}
+
+	return res, nil
+}
+
+// Revisions returns archived revisions of the index's records with the given IDs, keyed by record ID, oldest first.
+func (a *Archiver) Revisions(ctx context.Context, indexID uint64, ids []string) (map[string][]recordrepo.Record, error) {
+	if a.store == nil || len(ids) == 0 {
+		return nil, nil
+	}
+
+	segs, err := a.rr.IndexSegments(ctx, indexID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("get segments: %w", err)
+	}
+
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	res := make(map[string][]recordrepo.Record)
+
+	for _, seg := range segs {
+		revs, err := a.readSegment(ctx, seg)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rev := range revs {
+			if _, ok := wanted[rev.ID]; ok {
+				res[rev.ID] = append(res[rev.ID], rev)
+			}
+		}
+	}
+
+	// Segments may overlap if archive policies have changed
+	for _, revs := range res {
+		sort.Slice(revs, func(i, j int) bool { return revs[i].Rev < revs[j].Rev })
+	}
+
+	return res, nil
+}
+
+func (a *Archiver) readSegment(ctx context.Context, seg recordrepo.Segment) ([]recordrepo.Record, error) {
+	f, err := a.store.Open(ctx, seg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("open segment %s: %w", seg.Path, err)
+	}
+
+	defer func() {
+		_ = f.Close()
+	}()
+
+	revs, err := decodeSegment(f, seg.IndexID)
+	if err != nil {
+		return nil, fmt.Errorf("read segment %s: %w", seg.Path, err)
+	}
+
+	return revs, nil
+}
+
+// Run archives history every interval. It blocks until the context is done.
+func (a *Archiver) Run(ctx context.Context) {
+	t := time.NewTicker(a.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := a.Archive(ctx); err != nil && ctx.Err() == nil {
+				a.l.Error().Err(err).Msg("history archiving failed")
+			}
+		}
+	}
+}
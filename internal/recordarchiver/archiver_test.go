@@ -0,0 +1,461 @@
+package recordarchiver_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordarchiver"
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+type indexRepoMock struct {
+	indices []indexrepo.Index
+	err     error
+}
+
+func (m *indexRepoMock) List(_ context.Context, _ indexrepo.ListRequest) ([]indexrepo.Index, string, error) {
+	return m.indices, "", m.err
+}
+
+type archivableCall struct {
+	indexID uint64
+	before  time.Time
+	limit   uint32
+}
+
+type recordRepoMock struct {
+	mu            sync.Mutex
+	batches       [][]recordrepo.Record // revisions returned by consecutive ArchivableRevisions calls
+	archivableErr error
+	archiveErr    error
+	segmentsErr   error
+	archivable    []archivableCall
+	segments      []recordrepo.Segment
+	archived      [][]uint64
+}
+
+func (m *recordRepoMock) ArchivableRevisions(
+	_ context.Context,
+	indexID uint64,
+	before time.Time,
+	limit uint32,
+) ([]recordrepo.Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.archivable = append(m.archivable, archivableCall{indexID: indexID, before: before, limit: limit})
+
+	if len(m.batches) == 0 {
+		return nil, m.archivableErr
+	}
+
+	res := m.batches[0]
+	m.batches = m.batches[1:]
+
+	return res, nil
+}
+
+func (m *recordRepoMock) ArchiveRevisions(_ context.Context, seg recordrepo.Segment, revs []uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.archiveErr != nil {
+		return m.archiveErr
+	}
+
+	m.segments = append([]recordrepo.Segment{seg}, m.segments...) // ordered by max revision descending
+	m.archived = append(m.archived, revs)
+
+	return nil
+}
+
+func (m *recordRepoMock) Segments(_ context.Context, _, _ string, cursor uint64) ([]recordrepo.Segment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]recordrepo.Segment, 0)
+
+	for _, seg := range m.segments {
+		if cursor == 0 || seg.MinRev < cursor {
+			res = append(res, seg)
+		}
+	}
+
+	return res, m.segmentsErr
+}
+
+func (m *recordRepoMock) IndexSegments(_ context.Context, _ uint64, ids []string) ([]recordrepo.Segment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := make([]recordrepo.Segment, 0)
+
+	for _, seg := range slices.Backward(m.segments) {
+		for _, id := range ids {
+			if slices.Contains(seg.RecordIDs, id) {
+				res = append(res, seg)
+				break
+			}
+		}
+	}
+
+	return res, m.segmentsErr
+}
+
+func (m *recordRepoMock) archivableNum() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.archivable)
+}
+
+type storeMock struct {
+	putErr error
+}
+
+func (m *storeMock) Put(_ context.Context, _ string, _ []byte) error {
+	return m.putErr
+}
+
+func (m *storeMock) Open(_ context.Context, _ string) (io.ReadCloser, error) {
+	return nil, errors.New("theOpenError")
+}
+
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.String()
+}
+
+func now() time.Time {
+	return time.Unix(100000, 0)
+}
+
+func rev(rev uint64, id, data string) recordrepo.Record {
+	return recordrepo.Record{ID: id, IndexID: 1, Rev: rev, Data: data, CreatedAt: time.Unix(int64(rev), 0).UTC()}
+}
+
+func TestNew(tt *testing.T) {
+	tt.Run("NoStore", func(t *testing.T) {
+		_, err := recordarchiver.New(&indexRepoMock{}, &recordRepoMock{}, nil, map[string]recordarchiver.Policy{
+			"foo": {OlderThan: "1h"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, "archive store is not configured")
+	})
+
+	tt.Run("InvalidPattern", func(t *testing.T) {
+		_, err := recordarchiver.New(&indexRepoMock{}, &recordRepoMock{}, &storeMock{}, map[string]recordarchiver.Policy{
+			"(": {OlderThan: "1h"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.ErrorContains(t, err, `archive policy "(": error parsing regexp`)
+	})
+
+	tt.Run("InvalidDuration", func(t *testing.T) {
+		_, err := recordarchiver.New(&indexRepoMock{}, &recordRepoMock{}, &storeMock{}, map[string]recordarchiver.Policy{
+			"foo": {OlderThan: "abc"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `archive policy "foo": older than: time: invalid duration "abc"`)
+	})
+
+	tt.Run("NonPositiveDuration", func(t *testing.T) {
+		_, err := recordarchiver.New(&indexRepoMock{}, &recordRepoMock{}, &storeMock{}, map[string]recordarchiver.Policy{
+			"foo": {OlderThan: "0s"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `archive policy "foo": older than: must be positive`)
+	})
+}
+
+func TestArchiver_Archive(tt *testing.T) {
+	policies := map[string]recordarchiver.Policy{
+		"^foo":    {OlderThan: "1h"},
+		"^foo.ba": {OlderThan: "2h"},
+	}
+
+	tt.Run("IndexRepoError", func(t *testing.T) {
+		ir := &indexRepoMock{err: errors.New("theIndexRepoError")}
+
+		a, err := recordarchiver.New(ir, &recordRepoMock{}, &storeMock{}, policies, time.Minute, 2, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Archive(context.Background())
+		assert.EqualError(t, err, "list indices: theIndexRepoError")
+	})
+
+	tt.Run("ArchivableRevisionsError", func(t *testing.T) {
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{archivableErr: errors.New("theRecordRepoError")}
+
+		a, err := recordarchiver.New(ir, rr, &storeMock{}, policies, time.Minute, 2, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Archive(context.Background())
+		assert.EqualError(t, err, "archive index foo: get archivable revisions: theRecordRepoError")
+	})
+
+	tt.Run("StorePutError", func(t *testing.T) {
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{batches: [][]recordrepo.Record{{rev(1, "a", `{}`)}}}
+
+		a, err := recordarchiver.New(ir, rr, &storeMock{putErr: errors.New("thePutError")}, policies, time.Minute, 2,
+			now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Archive(context.Background())
+		assert.EqualError(t, err, "archive index foo: put segment 1/00000000000000000001-00000000000000000001.ndjson.gz: "+
+			"thePutError")
+		assert.Empty(t, rr.archived)
+	})
+
+	tt.Run("ArchiveRevisionsError", func(t *testing.T) {
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{
+			batches:    [][]recordrepo.Record{{rev(1, "a", `{}`)}},
+			archiveErr: errors.New("theRecordRepoError"),
+		}
+
+		a, err := recordarchiver.New(ir, rr, &storeMock{}, policies, time.Minute, 2, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Archive(context.Background())
+		assert.EqualError(t, err, "archive index foo: archive revisions: theRecordRepoError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo.bar"}, {ID: 2, Name: "baz"}}}
+		rr := &recordRepoMock{batches: [][]recordrepo.Record{
+			{rev(1, "a", `{"v": 1}`), rev(2, "b", `{"v": 2}`)},
+			{rev(3, "a", `{"v": 3}`)},
+		}}
+
+		a, err := recordarchiver.New(ir, rr, recordarchiver.NewDirStore(t.TempDir()), policies, time.Minute, 2, now,
+			zerolog.New(lb))
+		require.NoError(t, err)
+
+		n, err := a.Archive(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3), n)
+
+		c := archivableCall{indexID: 1, before: now().Add(-2 * time.Hour), limit: 2}
+		assert.Equal(t, []archivableCall{c, c}, rr.archivable)
+
+		assert.Equal(t, [][]uint64{{1, 2}, {3}}, rr.archived)
+		assert.Equal(t, []recordrepo.Segment{
+			{
+				IndexID:   1,
+				Path:      "1/00000000000000000003-00000000000000000003.ndjson.gz",
+				MinRev:    3,
+				MaxRev:    3,
+				Revisions: 1,
+				RecordIDs: []string{"a"},
+			},
+			{
+				IndexID:   1,
+				Path:      "1/00000000000000000001-00000000000000000002.ndjson.gz",
+				MinRev:    1,
+				MaxRev:    2,
+				Revisions: 2,
+				RecordIDs: []string{"a", "b"},
+			},
+		}, rr.segments)
+
+		assert.Equal(t, `{"level":"info","index":"foo.bar","count":3,"message":"history revisions archived"}`+"\n",
+			lb.String())
+	})
+}
+
+func TestArchiver_History(tt *testing.T) {
+	archive := func(t *testing.T) (*recordarchiver.Archiver, *recordRepoMock) {
+		t.Helper()
+
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{batches: [][]recordrepo.Record{
+			{rev(1, "a", `{"v": 1}`), rev(2, "b", `{"v": 2}`), rev(3, "a", `{"v": 3}`)},
+			{rev(4, "a", `{"v": 4}`), rev(5, "b", `{"v": 5}`), rev(6, "a", `{"v": 6}`)},
+		}}
+
+		a, err := recordarchiver.New(ir, rr, recordarchiver.NewDirStore(t.TempDir()), map[string]recordarchiver.Policy{
+			"foo": {OlderThan: "1h"},
+		}, time.Minute, 3, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Archive(context.Background())
+		require.NoError(t, err)
+
+		return a, rr
+	}
+
+	tt.Run("NoStore", func(t *testing.T) {
+		a, err := recordarchiver.New(&indexRepoMock{}, &recordRepoMock{}, nil, nil, time.Minute, 3, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		res, err := a.History(context.Background(), "foo", "a", time.Unix(0, 0), 0, 10)
+		require.NoError(t, err)
+		assert.Empty(t, res)
+	})
+
+	tt.Run("SegmentsError", func(t *testing.T) {
+		a, rr := archive(t)
+		rr.segmentsErr = errors.New("theRecordRepoError")
+
+		_, err := a.History(context.Background(), "foo", "a", time.Unix(0, 0), 0, 10)
+		assert.EqualError(t, err, "get segments: theRecordRepoError")
+	})
+
+	tt.Run("OpenSegmentError", func(t *testing.T) {
+		rr := &recordRepoMock{segments: []recordrepo.Segment{{IndexID: 1, Path: "thePath"}}}
+
+		a, err := recordarchiver.New(&indexRepoMock{}, rr, &storeMock{}, nil, time.Minute, 3, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.History(context.Background(), "foo", "a", time.Unix(0, 0), 0, 10)
+		assert.EqualError(t, err, "open segment thePath: theOpenError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		a, _ := archive(t)
+
+		res, err := a.History(context.Background(), "foo", "a", time.Unix(0, 0), 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []recordrepo.Record{
+			rev(6, "a", `{"v": 6}`), rev(4, "a", `{"v": 4}`), rev(3, "a", `{"v": 3}`), rev(1, "a", `{"v": 1}`),
+		}, res)
+	})
+
+	tt.Run("OkCursorAndLimit", func(t *testing.T) {
+		a, _ := archive(t)
+
+		res, err := a.History(context.Background(), "foo", "a", time.Unix(0, 0), 6, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []recordrepo.Record{rev(4, "a", `{"v": 4}`), rev(3, "a", `{"v": 3}`)}, res)
+	})
+
+	tt.Run("OkSince", func(t *testing.T) {
+		a, _ := archive(t)
+
+		res, err := a.History(context.Background(), "foo", "b", time.Unix(3, 0), 0, 10)
+		require.NoError(t, err)
+		assert.Equal(t, []recordrepo.Record{rev(5, "b", `{"v": 5}`)}, res)
+	})
+}
+
+func TestArchiver_Revisions(tt *testing.T) {
+	tt.Run("NoStore", func(t *testing.T) {
+		a, err := recordarchiver.New(&indexRepoMock{}, &recordRepoMock{}, nil, nil, time.Minute, 3, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		res, err := a.Revisions(context.Background(), 1, []string{"a"})
+		require.NoError(t, err)
+		assert.Empty(t, res)
+	})
+
+	tt.Run("SegmentsError", func(t *testing.T) {
+		rr := &recordRepoMock{segmentsErr: errors.New("theRecordRepoError")}
+
+		a, err := recordarchiver.New(&indexRepoMock{}, rr, &storeMock{}, nil, time.Minute, 3, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Revisions(context.Background(), 1, []string{"a"})
+		assert.EqualError(t, err, "get segments: theRecordRepoError")
+	})
+
+	tt.Run("OpenSegmentError", func(t *testing.T) {
+		rr := &recordRepoMock{segments: []recordrepo.Segment{{IndexID: 1, Path: "thePath", RecordIDs: []string{"a"}}}}
+
+		a, err := recordarchiver.New(&indexRepoMock{}, rr, &storeMock{}, nil, time.Minute, 3, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Revisions(context.Background(), 1, []string{"a"})
+		assert.EqualError(t, err, "open segment thePath: theOpenError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{batches: [][]recordrepo.Record{
+			{rev(1, "a", `{"v": 1}`), rev(2, "b", `{"v": 2}`), rev(3, "c", `{"v": 3}`)},
+			{rev(4, "a", `{"v": 4}`), rev(5, "c", `{"v": 5}`), rev(6, "a", `{"v": 6}`)},
+		}}
+
+		a, err := recordarchiver.New(ir, rr, recordarchiver.NewDirStore(t.TempDir()), map[string]recordarchiver.Policy{
+			"foo": {OlderThan: "1h"},
+		}, time.Minute, 3, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = a.Archive(context.Background())
+		require.NoError(t, err)
+
+		res, err := a.Revisions(context.Background(), 1, []string{"a", "b"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]recordrepo.Record{
+			"a": {rev(1, "a", `{"v": 1}`), rev(4, "a", `{"v": 4}`), rev(6, "a", `{"v": 6}`)},
+			"b": {rev(2, "b", `{"v": 2}`)},
+		}, res)
+	})
+}
+
+func TestArchiver_Archives(t *testing.T) {
+	a, err := recordarchiver.New(&indexRepoMock{}, &recordRepoMock{}, &storeMock{}, map[string]recordarchiver.Policy{
+		"^foo": {OlderThan: "1h"},
+	}, time.Minute, 3, now, zerolog.Nop())
+	require.NoError(t, err)
+
+	assert.True(t, a.Archives("fooBar"))
+	assert.False(t, a.Archives("bar"))
+}
+
+func TestArchiver_Run(tt *testing.T) {
+	tt.Run("ArchiveError", func(t *testing.T) {
+		lb := &syncBuilder{}
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{archivableErr: errors.New("theRecordRepoError")}
+
+		a, err := recordarchiver.New(ir, rr, &storeMock{}, map[string]recordarchiver.Policy{
+			"foo": {OlderThan: "1h"},
+		}, time.Millisecond*10, 10, now, zerolog.New(lb))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			a.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return rr.archivableNum() >= 2 }, time.Second, time.Millisecond*5)
+		cancel()
+		<-done
+
+		assert.Contains(t, lb.String(), `{"level":"error","error":"archive index foo: get archivable revisions: `+
+			`theRecordRepoError","message":"history archiving failed"}`)
+	})
+}
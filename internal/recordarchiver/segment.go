@@ -0,0 +1,75 @@
+package recordarchiver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+// segmentLine is a single revision within a segment file. Data is kept as a string to preserve it byte for byte.
+type segmentLine struct {
+	Rev       uint64    `json:"rev"`
+	ID        string    `json:"id"`
+	Data      string    `json:"data"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// encodeSegment encodes revisions as gzip-compressed NDJSON.
+func encodeSegment(revs []recordrepo.Record) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	enc := json.NewEncoder(zw)
+
+	for _, rev := range revs {
+		if err := enc.Encode(segmentLine{Rev: rev.Rev, ID: rev.ID, Data: rev.Data, CreatedAt: rev.CreatedAt}); err != nil {
+			return nil, fmt.Errorf("encode revision %d: %w", rev.Rev, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("close gzip writer: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeSegment reads revisions from a gzip-compressed NDJSON stream.
+func decodeSegment(r io.Reader, indexID uint64) ([]recordrepo.Record, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open gzip reader: %w", err)
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	res := make([]recordrepo.Record, 0)
+
+	for {
+		ln := segmentLine{}
+		if err := dec.Decode(&ln); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("decode revision: %w", err)
+		}
+
+		res = append(res, recordrepo.Record{
+			ID:        ln.ID,
+			IndexID:   indexID,
+			Rev:       ln.Rev,
+			Data:      ln.Data,
+			CreatedAt: ln.CreatedAt,
+		})
+	}
+
+	if err := zr.Close(); err != nil {
+		return nil, fmt.Errorf("close gzip reader: %w", err)
+	}
+
+	return res, nil
+}
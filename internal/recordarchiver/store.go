@@ -0,0 +1,67 @@
+package recordarchiver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Store keeps archive segment files. Names are slash-separated relative paths.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+// DirStore keeps archive segment files in a local directory. It may as well serve as a stand-in for an S3-compatible
+// store mounted into the file system.
+type DirStore struct {
+	dir string
+}
+
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+// Put writes a file atomically: it is either written completely or not written at all.
+func (s *DirStore) Put(_ context.Context, name string, data []byte) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(name))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil { //nolint:mnd // ok
+		return fmt.Errorf("create dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".segment-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+
+		return fmt.Errorf("write temp file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *DirStore) Open(_ context.Context, name string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+
+	return f, nil
+}
@@ -0,0 +1,46 @@
+package recordarchiver_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordarchiver"
+)
+
+func TestDirStore(tt *testing.T) {
+	tt.Run("OpenNotExisting", func(t *testing.T) {
+		s := recordarchiver.NewDirStore(t.TempDir())
+
+		_, err := s.Open(context.Background(), "foo/bar")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		dir := t.TempDir()
+		s := recordarchiver.NewDirStore(dir)
+
+		require.NoError(t, s.Put(context.Background(), "foo/bar", []byte("first")))
+		require.NoError(t, s.Put(context.Background(), "foo/bar", []byte("second")))
+
+		f, err := s.Open(context.Background(), "foo/bar")
+		require.NoError(t, err)
+
+		defer func() {
+			_ = f.Close()
+		}()
+
+		b, err := io.ReadAll(f)
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(b))
+
+		entries, err := os.ReadDir(filepath.Join(dir, "foo"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1) // no temp files left
+	})
+}
@@ -0,0 +1,221 @@
+package recordcompactor
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+)
+
+// Policy defines which history revisions of records of indices whose names match a pattern are retained. The current
+// revision of a record is always retained; other revisions are retained if any of the rules applies to them.
+type Policy struct {
+	KeepRevisions uint32 `json:"keep_revisions" yaml:"keep_revisions"` // number of last revisions to keep
+	KeepFor       string `json:"keep_for" yaml:"keep_for"`             // duration to keep revisions for, e.g. "720h"
+}
+
+// Retention is a resolved retention policy of an index.
+type Retention struct {
+	KeepRevisions uint32
+	KeepFor       time.Duration
+}
+
+type policy struct {
+	indexRe *regexp.Regexp
+	Retention
+}
+
+type indexRepo interface {
+	List(ctx context.Context, req indexrepo.ListRequest) ([]indexrepo.Index, string, error)
+}
+
+type recordRepo interface {
+	DeleteOldRevisions(
+		ctx context.Context,
+		indexID uint64,
+		keep uint32,
+		before time.Time,
+		after uint64,
+		limit uint32,
+	) (uint64, uint64, error)
+}
+
+// Compactor periodically deletes history revisions which are not retained by their index's policy.
+type Compactor struct {
+	ir        indexRepo
+	rr        recordRepo
+	policies  []policy
+	archived  func(index string) bool
+	interval  time.Duration
+	batchSize uint32
+	now       func() time.Time
+	l         zerolog.Logger
+}
+
+// New creates a compactor. The policies map binds retention policies to index name regexp patterns. The archived
+// function reports whether an index's history is archived; retention policies are not applied to such indices, since
+// archived revisions can not be compacted. The compactor runs every interval and checks batchSize revisions per
+// database query.
+func New(
+	ir indexRepo,
+	rr recordRepo,
+	policies map[string]Policy,
+	archived func(index string) bool,
+	interval time.Duration,
+	batchSize uint32,
+	now func() time.Time,
+	l zerolog.Logger,
+) (*Compactor, error) {
+	res := make([]policy, 0, len(policies))
+
+	for pattern, pol := range policies {
+		p := policy{Retention: Retention{KeepRevisions: pol.KeepRevisions}}
+
+		var err error
+		if p.indexRe, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("retention policy %q: %w", pattern, err)
+		}
+
+		if pol.KeepFor != "" {
+			if p.KeepFor, err = time.ParseDuration(pol.KeepFor); err != nil {
+				return nil, fmt.Errorf("retention policy %q: keep for: %w", pattern, err)
+			}
+
+			if p.KeepFor < 0 {
+				return nil, fmt.Errorf("retention policy %q: keep for: must not be negative", pattern)
+			}
+		}
+
+		if p.KeepRevisions == 0 && p.KeepFor == 0 {
+			return nil, fmt.Errorf("retention policy %q: either keep revisions or keep for must be set", pattern)
+		}
+
+		res = append(res, p)
+	}
+
+	return &Compactor{
+		ir:        ir,
+		rr:        rr,
+		policies:  res,
+		archived:  archived,
+		interval:  interval,
+		batchSize: batchSize,
+		now:       now,
+		l:         l,
+	}, nil
+}
+
+// RetentionFor returns the retention policy of an index. If several policies match the index name, they are merged so
+// that every revision retained by any of them is retained.
+func (c *Compactor) RetentionFor(index string) (Retention, bool) {
+	res, ok := Retention{}, false
+
+	for _, p := range c.policies {
+		if !p.indexRe.MatchString(index) {
+			continue
+		}
+
+		res.KeepRevisions = max(res.KeepRevisions, p.KeepRevisions)
+		res.KeepFor = max(res.KeepFor, p.KeepFor)
+		ok = true
+	}
+
+	return res, ok
+}
+
+// Compact applies retention policies to the history of all the indices and returns the number of deleted revisions.
+func (c *Compactor) Compact(ctx context.Context) (uint64, error) {
+	if len(c.policies) == 0 {
+		return 0, nil
+	}
+
+	indices, _, err := c.ir.List(ctx, indexrepo.ListRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("list indices: %w", err)
+	}
+
+	total := uint64(0)
+
+	for _, idx := range indices {
+		ret, ok := c.RetentionFor(idx.Name)
+		if !ok {
+			continue
+		}
+
+		if c.archived(idx.Name) {
+			c.l.Warn().Str("index", idx.Name).Msg("history is archived, retention policy is not applied")
+			continue
+		}
+
+		n, err := c.CompactIndex(ctx, idx.ID, ret, nil)
+		total += n
+
+		if n > 0 {
+			c.l.Info().Str("index", idx.Name).Uint64("count", n).Msg("history revisions deleted")
+		}
+
+		if err != nil {
+			return total, fmt.Errorf("compact index %s: %w", idx.Name, err)
+		}
+	}
+
+	return total, nil
+}
+
+// CompactIndex deletes history revisions of an index which are not retained, batch by batch, and returns their number.
+// Batches follow each other in the order of revisions, and each of them is deleted in its own transaction, so pushes
+// are not blocked for long. If progress is not nil, it is
+// called after each batch with the number of revisions deleted so far.
+func (c *Compactor) CompactIndex(
+	ctx context.Context,
+	indexID uint64,
+	ret Retention,
+	progress func(deleted uint64),
+) (uint64, error) {
+	before := c.now().Add(-ret.KeepFor)
+	total, after := uint64(0), uint64(0)
+
+	for {
+		n, last, err := c.rr.DeleteOldRevisions(ctx, indexID, ret.KeepRevisions, before, after, c.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("delete old revisions: %w", err)
+		}
+
+		total += n
+
+		if progress != nil {
+			progress(total)
+		}
+
+		if last == 0 {
+			return total, nil
+		}
+
+		after = last
+
+		if err := ctx.Err(); err != nil {
+			return total, err //nolint:wrapcheck // ok
+		}
+	}
+}
+
+// Run compacts history every interval. It blocks until the context is done.
+func (c *Compactor) Run(ctx context.Context) {
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := c.Compact(ctx); err != nil && ctx.Err() == nil {
+				c.l.Error().Err(err).Msg("history compaction failed")
+			}
+		}
+	}
+}
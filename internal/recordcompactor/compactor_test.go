@@ -0,0 +1,304 @@
+package recordcompactor_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordcompactor"
+)
+
+type indexRepoMock struct {
+	indices []indexrepo.Index
+	err     error
+}
+
+func (m *indexRepoMock) List(_ context.Context, _ indexrepo.ListRequest) ([]indexrepo.Index, string, error) {
+	return m.indices, "", m.err
+}
+
+type deleteCall struct {
+	indexID uint64
+	keep    uint32
+	before  time.Time
+	after   uint64
+	limit   uint32
+}
+
+type deleteResult struct {
+	deleted uint64
+	last    uint64
+}
+
+type recordRepoMock struct {
+	mu      sync.Mutex
+	results []deleteResult // results of consecutive calls
+	err     error
+	calls   []deleteCall
+}
+
+func (m *recordRepoMock) DeleteOldRevisions(
+	_ context.Context,
+	indexID uint64,
+	keep uint32,
+	before time.Time,
+	after uint64,
+	limit uint32,
+) (uint64, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, deleteCall{indexID: indexID, keep: keep, before: before, after: after, limit: limit})
+
+	if len(m.results) == 0 {
+		return 0, 0, m.err
+	}
+
+	res := m.results[0]
+	m.results = m.results[1:]
+
+	return res.deleted, res.last, nil
+}
+
+func (m *recordRepoMock) callsNum() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.calls)
+}
+
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.String()
+}
+
+func now() time.Time {
+	return time.Unix(100000, 0)
+}
+
+func TestNew(tt *testing.T) {
+	tt.Run("InvalidPattern", func(t *testing.T) {
+		_, err := recordcompactor.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordcompactor.Policy{
+			"(": {KeepRevisions: 1},
+		}, notArchived, time.Minute, 10, now, zerolog.Nop())
+
+		assert.ErrorContains(t, err, `retention policy "(": error parsing regexp`)
+	})
+
+	tt.Run("InvalidDuration", func(t *testing.T) {
+		_, err := recordcompactor.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordcompactor.Policy{
+			"foo": {KeepFor: "abc"},
+		}, notArchived, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `retention policy "foo": keep for: time: invalid duration "abc"`)
+	})
+
+	tt.Run("NegativeDuration", func(t *testing.T) {
+		_, err := recordcompactor.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordcompactor.Policy{
+			"foo": {KeepFor: "-1h"},
+		}, notArchived, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `retention policy "foo": keep for: must not be negative`)
+	})
+
+	tt.Run("EmptyPolicy", func(t *testing.T) {
+		_, err := recordcompactor.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordcompactor.Policy{
+			"foo": {},
+		}, notArchived, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `retention policy "foo": either keep revisions or keep for must be set`)
+	})
+}
+
+func TestCompactor_RetentionFor(tt *testing.T) {
+	c, err := recordcompactor.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordcompactor.Policy{
+		"^foo": {KeepRevisions: 10},
+		"bar$": {KeepRevisions: 5, KeepFor: "24h"},
+	}, notArchived, time.Minute, 10, now, zerolog.Nop())
+	require.NoError(tt, err)
+
+	tt.Run("NoMatch", func(t *testing.T) {
+		_, ok := c.RetentionFor("baz")
+		assert.False(t, ok)
+	})
+
+	tt.Run("SingleMatch", func(t *testing.T) {
+		ret, ok := c.RetentionFor("bar")
+		require.True(t, ok)
+		assert.Equal(t, recordcompactor.Retention{KeepRevisions: 5, KeepFor: 24 * time.Hour}, ret)
+	})
+
+	tt.Run("Merged", func(t *testing.T) {
+		ret, ok := c.RetentionFor("foobar")
+		require.True(t, ok)
+		assert.Equal(t, recordcompactor.Retention{KeepRevisions: 10, KeepFor: 24 * time.Hour}, ret)
+	})
+}
+
+func TestCompactor_CompactIndex(tt *testing.T) {
+	tt.Run("RecordRepoError", func(t *testing.T) {
+		rr := &recordRepoMock{results: []deleteResult{{deleted: 10, last: 100}}, err: errors.New("theRecordRepoError")}
+
+		c, err := recordcompactor.New(&indexRepoMock{}, rr, nil, notArchived, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		n, err := c.CompactIndex(context.Background(), 1, recordcompactor.Retention{KeepRevisions: 1}, nil)
+		assert.EqualError(t, err, "delete old revisions: theRecordRepoError")
+		assert.Equal(t, uint64(10), n)
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		rr := &recordRepoMock{results: []deleteResult{
+			{deleted: 10, last: 100},
+			{deleted: 0, last: 200},
+			{deleted: 3, last: 0},
+		}}
+
+		c, err := recordcompactor.New(&indexRepoMock{}, rr, nil, notArchived, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		progress := make([]uint64, 0)
+		n, err := c.CompactIndex(context.Background(), 1,
+			recordcompactor.Retention{KeepRevisions: 3, KeepFor: time.Hour},
+			func(deleted uint64) { progress = append(progress, deleted) },
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(13), n)
+		assert.Equal(t, []uint64{10, 10, 13}, progress)
+
+		c1 := deleteCall{indexID: 1, keep: 3, before: now().Add(-time.Hour), limit: 10}
+		c2, c3 := c1, c1
+		c2.after, c3.after = 100, 200
+		assert.Equal(t, []deleteCall{c1, c2, c3}, rr.calls)
+	})
+}
+
+func TestCompactor_Compact(tt *testing.T) {
+	policies := map[string]recordcompactor.Policy{
+		"^foo$": {KeepRevisions: 2},
+		"^bar$": {KeepFor: "2h"},
+	}
+
+	tt.Run("IndexRepoError", func(t *testing.T) {
+		ir := &indexRepoMock{err: errors.New("theIndexRepoError")}
+
+		c, err := recordcompactor.New(ir, &recordRepoMock{}, policies, notArchived, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = c.Compact(context.Background())
+		assert.EqualError(t, err, "list indices: theIndexRepoError")
+	})
+
+	tt.Run("RecordRepoError", func(t *testing.T) {
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{err: errors.New("theRecordRepoError")}
+
+		c, err := recordcompactor.New(ir, rr, policies, notArchived, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = c.Compact(context.Background())
+		assert.EqualError(t, err, "compact index foo: delete old revisions: theRecordRepoError")
+	})
+
+	tt.Run("NoPolicies", func(t *testing.T) {
+		ir := &indexRepoMock{err: errors.New("theIndexRepoError")}
+		rr := &recordRepoMock{}
+
+		c, err := recordcompactor.New(ir, rr, nil, notArchived, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		n, err := c.Compact(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		assert.Zero(t, rr.callsNum())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir := &indexRepoMock{indices: []indexrepo.Index{
+			{ID: 1, Name: "foo"},
+			{ID: 2, Name: "baz"},
+			{ID: 3, Name: "bar"},
+			{ID: 4, Name: "qux"},
+		}}
+		rr := &recordRepoMock{results: []deleteResult{{deleted: 4, last: 17}}}
+		archived := func(index string) bool { return index == "qux" }
+
+		c, err := recordcompactor.New(ir, rr, map[string]recordcompactor.Policy{
+			"^foo$": {KeepRevisions: 2},
+			"^bar$": {KeepFor: "2h"},
+			"^qux$": {KeepRevisions: 1},
+		}, archived, time.Minute, 10, now, zerolog.New(lb))
+		require.NoError(t, err)
+
+		n, err := c.Compact(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4), n)
+
+		assert.Equal(t, []deleteCall{
+			{indexID: 1, keep: 2, before: now(), limit: 10},
+			{indexID: 1, keep: 2, before: now(), after: 17, limit: 10},
+			{indexID: 3, keep: 0, before: now().Add(-2 * time.Hour), limit: 10},
+		}, rr.calls)
+
+		assert.Equal(t, `{"level":"info","index":"foo","count":4,"message":"history revisions deleted"}`+"\n"+
+			`{"level":"warn","index":"qux","message":"history is archived, retention policy is not applied"}`+"\n",
+			lb.String())
+	})
+}
+
+func notArchived(string) bool {
+	return false
+}
+
+func TestCompactor_Run(tt *testing.T) {
+	tt.Run("CompactError", func(t *testing.T) {
+		lb := &syncBuilder{}
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{err: errors.New("theRecordRepoError")}
+
+		c, err := recordcompactor.New(ir, rr, map[string]recordcompactor.Policy{
+			"foo": {KeepRevisions: 1},
+		}, notArchived, time.Millisecond*10, 10, now, zerolog.New(lb))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			c.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return rr.callsNum() >= 2 }, time.Second, time.Millisecond*5)
+		cancel()
+		<-done
+
+		assert.Contains(t, lb.String(), `{"level":"error","error":"compact index foo: delete old revisions: `+
+			`theRecordRepoError","message":"history compaction failed"}`)
+	})
+}
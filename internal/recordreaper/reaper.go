@@ -0,0 +1,76 @@
+package recordreaper
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type recordRepo interface {
+	DeleteExpired(ctx context.Context, limit uint32) (uint64, error)
+}
+
+// Reaper periodically deletes expired records.
+type Reaper struct {
+	repo      recordRepo
+	interval  time.Duration
+	batchSize uint32
+	l         zerolog.Logger
+}
+
+// New creates a reaper which deletes expired records every interval, batchSize records per database query.
+func New(repo recordRepo, interval time.Duration, batchSize uint32, l zerolog.Logger) *Reaper {
+	return &Reaper{
+		repo:      repo,
+		interval:  interval,
+		batchSize: batchSize,
+		l:         l,
+	}
+}
+
+// Reap deletes all the records expired by now, batch by batch, and returns their number. Each batch is deleted in its
+// own transaction, so a large number of expired records doesn't lock the table for long.
+func (r *Reaper) Reap(ctx context.Context) (uint64, error) {
+	total := uint64(0)
+
+	for {
+		n, err := r.repo.DeleteExpired(ctx, r.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("delete expired records: %w", err)
+		}
+
+		total += n
+
+		if n < uint64(r.batchSize) {
+			return total, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return total, err //nolint:wrapcheck // ok
+		}
+	}
+}
+
+// Run reaps expired records every interval. It blocks until the context is done.
+func (r *Reaper) Run(ctx context.Context) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n, err := r.Reap(ctx)
+			if n > 0 {
+				r.l.Info().Uint64("count", n).Msg("expired records deleted")
+			}
+
+			if err != nil && ctx.Err() == nil {
+				r.l.Error().Err(err).Msg("expired records reap failed")
+			}
+		}
+	}
+}
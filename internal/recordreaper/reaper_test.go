@@ -0,0 +1,146 @@
+package recordreaper_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordreaper"
+)
+
+type recordRepoMock struct {
+	mu      sync.Mutex
+	results []uint64 // number of records deleted by consecutive calls
+	err     error
+	limits  []uint32
+}
+
+func (m *recordRepoMock) DeleteExpired(_ context.Context, limit uint32) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limits = append(m.limits, limit)
+
+	if len(m.results) == 0 {
+		return 0, m.err
+	}
+
+	n := m.results[0]
+	m.results = m.results[1:]
+
+	return n, nil
+}
+
+func (m *recordRepoMock) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.limits)
+}
+
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.String()
+}
+
+func TestReaper_Reap(tt *testing.T) {
+	tt.Run("Error", func(t *testing.T) {
+		repo := &recordRepoMock{results: []uint64{10}, err: errors.New("theRepoError")}
+
+		r := recordreaper.New(repo, time.Minute, 10, zerolog.Nop())
+		n, err := r.Reap(context.Background())
+
+		assert.EqualError(t, err, "delete expired records: theRepoError")
+		assert.Equal(t, uint64(10), n)
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		repo := &recordRepoMock{results: []uint64{10, 10, 3}}
+
+		r := recordreaper.New(repo, time.Minute, 10, zerolog.Nop())
+		n, err := r.Reap(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(23), n)
+		assert.Equal(t, []uint32{10, 10, 10}, repo.limits)
+	})
+
+	tt.Run("OkNothingExpired", func(t *testing.T) {
+		repo := &recordRepoMock{}
+
+		r := recordreaper.New(repo, time.Minute, 10, zerolog.Nop())
+		n, err := r.Reap(context.Background())
+
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		assert.Equal(t, 1, repo.calls())
+	})
+}
+
+func TestReaper_Run(tt *testing.T) {
+	tt.Run("Ok", func(t *testing.T) {
+		lb := &syncBuilder{}
+		repo := &recordRepoMock{results: []uint64{5}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		r := recordreaper.New(repo, time.Millisecond*10, 10, zerolog.New(lb))
+
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return repo.calls() >= 2 }, time.Second, time.Millisecond*5)
+		cancel()
+		<-done
+
+		assert.Contains(t, lb.String(), `{"level":"info","count":5,"message":"expired records deleted"}`)
+	})
+
+	tt.Run("ReapError", func(t *testing.T) {
+		lb := &syncBuilder{}
+		repo := &recordRepoMock{err: errors.New("theRepoError")}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		r := recordreaper.New(repo, time.Millisecond*10, 10, zerolog.New(lb))
+
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return repo.calls() >= 2 }, time.Second, time.Millisecond*5)
+		cancel()
+		<-done
+
+		assert.Contains(t, lb.String(),
+			`{"level":"error","error":"delete expired records: theRepoError","message":"expired records reap failed"}`)
+	})
+}
@@ -0,0 +1,209 @@
+package recordrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// Segment describes a file with archived history revisions of an index.
+type Segment struct {
+	ID        uint64
+	IndexID   uint64
+	Path      string // path of the file within the archive store
+	MinRev    uint64
+	MaxRev    uint64
+	Revisions uint32
+	RecordIDs []string // IDs of records whose revisions are in the segment
+	CreatedAt time.Time
+}
+
+// ArchivableRevisions returns a batch of index's history revisions created before the given time, ordered by revision.
+// Current revisions of records are never returned.
+func (r *Repository) ArchivableRevisions(
+	ctx context.Context,
+	indexID uint64,
+	before time.Time,
+	limit uint32,
+) ([]Record, error) {
+	if limit == 0 {
+		return nil, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"}
+	}
+
+	q := `SELECT l.id, l.record_id, l.data, l.created_at FROM record_log l
+WHERE l.index_id=$1 AND l.created_at < $2 AND NOT EXISTS (SELECT 1 FROM record r WHERE r.log_id=l.id)
+ORDER BY l.id LIMIT $3`
+
+	rows, err := r.db.QueryContext(ctx, q, indexID, before.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("db query: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]Record, 0)
+
+	for rows.Next() {
+		rec := Record{IndexID: indexID}
+		if err := rows.Scan(&rec.Rev, &rec.ID, &rec.Data, &rec.CreatedAt); err != nil {
+			return nil, fmt.Errorf("db scan: %w", err)
+		}
+
+		res = append(res, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db rows iteration: %w", err)
+	}
+
+	return res, nil
+}
+
+// ArchiveRevisions registers a segment in the manifest and deletes the archived revisions from the history, in a single
+// transaction.
+func (r *Repository) ArchiveRevisions(ctx context.Context, seg Segment, revs []uint64) error {
+	if len(revs) == 0 {
+		return apperrors.InvalidArgError{Subj: "revisions", Reason: "must not be empty"}
+	}
+
+	recIDs, err := json.Marshal(seg.RecordIDs)
+	if err != nil {
+		return fmt.Errorf("marshal record ids: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db begin: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO record_log_segment (index_id, path, min_rev, max_rev, revisions, record_ids)
+VALUES ($1, $2, $3, $4, $5, $6)`, seg.IndexID, seg.Path, seg.MinRev, seg.MaxRev, seg.Revisions, recIDs)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("insert segment: %w", err)
+	}
+
+	del, err := tx.PrepareContext(ctx, `DELETE FROM record_log WHERE id=$1 AND index_id=$2`)
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("prepare delete revision: %w", err)
+	}
+
+	defer func() {
+		_ = del.Close()
+	}()
+
+	for _, rev := range revs {
+		if _, err := del.ExecContext(ctx, rev, seg.IndexID); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("delete revision %d: %w", rev, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("db commit: %w", err)
+	}
+
+	return nil
+}
+
+// Segments returns archive segments which contain revisions of a record, ordered by the maximum revision descending.
+// If cursor is not zero, only segments containing revisions lower than the cursor are returned.
+func (r *Repository) Segments(ctx context.Context, index, id string, cursor uint64) ([]Segment, error) {
+	if err := r.indexNameValidator.Validate(index); err != nil {
+		return nil, err //nolint:wrapcheck // ok
+	}
+
+	if err := r.recordIDValidator.Validate(id); err != nil {
+		return nil, err //nolint:wrapcheck // ok
+	}
+
+	q := `SELECT id, index_id, path, min_rev, max_rev, revisions, created_at FROM record_log_segment
+WHERE index_id=(SELECT id FROM index WHERE name=$1 LIMIT 1) AND record_ids ? $2`
+	args := []any{index, id}
+
+	if cursor != 0 {
+		args = append(args, cursor)
+		q += " AND min_rev<$3"
+	}
+
+	q += " ORDER BY max_rev DESC"
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db query: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]Segment, 0)
+
+	for rows.Next() {
+		seg := Segment{}
+		if err := rows.Scan(&seg.ID, &seg.IndexID, &seg.Path, &seg.MinRev, &seg.MaxRev, &seg.Revisions,
+			&seg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("db scan: %w", err)
+		}
+
+		res = append(res, seg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db rows iteration: %w", err)
+	}
+
+	return res, nil
+}
+
+// IndexSegments returns archive segments of an index which contain revisions of any of the records, ordered by the
+// maximum revision.
+func (r *Repository) IndexSegments(ctx context.Context, indexID uint64, ids []string) ([]Segment, error) {
+	if len(ids) == 0 {
+		return nil, apperrors.InvalidArgError{Subj: "record ids", Reason: "must not be empty"}
+	}
+
+	args := []any{indexID}
+	ph := make([]string, len(ids))
+
+	for i, id := range ids {
+		args = append(args, id)
+		ph[i] = fmt.Sprintf("$%d", i+2)
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT id, index_id, path, min_rev, max_rev, revisions, created_at
+FROM record_log_segment WHERE index_id=$1 AND record_ids ?| ARRAY[`+strings.Join(ph, ", ")+`] ORDER BY max_rev`,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("db query: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]Segment, 0)
+
+	for rows.Next() {
+		seg := Segment{}
+		if err := rows.Scan(&seg.ID, &seg.IndexID, &seg.Path, &seg.MinRev, &seg.MaxRev, &seg.Revisions,
+			&seg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("db scan: %w", err)
+		}
+
+		res = append(res, seg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db rows iteration: %w", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,268 @@
+package recordrepo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_ArchivableRevisions(tt *testing.T) {
+	before := time.Unix(123, 0).UTC()
+
+	tt.Run("ZeroLimit", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.ArchivableRevisions(context.Background(), 1, before, 0)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"})
+	})
+
+	tt.Run("DbQueryError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT l.id`).
+			WithArgs(1, before, 100).
+			WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.ArchivableRevisions(context.Background(), 1, before, 100)
+
+		assert.EqualError(t, err, "db query: theDbError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT l.id, l.record_id, l.data, l.created_at FROM record_log l\s+`+
+			`WHERE l.index_id=\$1 AND l.created_at < \$2 AND NOT EXISTS \(SELECT 1 FROM record r WHERE r.log_id=l.id\)\s+`+
+			`ORDER BY l.id LIMIT \$3`).
+			WithArgs(1, before, 100).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "record_id", "data", "created_at"}).
+				AddRow(11, "foo", `{"v":1}`, time.Unix(1, 0)).
+				AddRow(12, "bar", `{"v":2}`, time.Unix(2, 0)))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		res, err := repo.ArchivableRevisions(context.Background(), 1, before, 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, []recordrepo.Record{
+			{ID: "foo", IndexID: 1, Rev: 11, Data: `{"v":1}`, CreatedAt: time.Unix(1, 0)},
+			{ID: "bar", IndexID: 1, Rev: 12, Data: `{"v":2}`, CreatedAt: time.Unix(2, 0)},
+		}, res)
+	})
+}
+
+func TestRecordRepository_ArchiveRevisions(tt *testing.T) {
+	seg := recordrepo.Segment{
+		IndexID:   1,
+		Path:      "thePath",
+		MinRev:    11,
+		MaxRev:    12,
+		Revisions: 2,
+		RecordIDs: []string{"foo", "bar"},
+	}
+
+	tt.Run("EmptyRevisions", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.ArchiveRevisions(context.Background(), seg, nil)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "revisions", Reason: "must not be empty"})
+	})
+
+	tt.Run("InsertSegmentError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectExec(`INSERT INTO record_log_segment`).WillReturnError(errors.New("theDbError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.ArchiveRevisions(context.Background(), seg, []uint64{11, 12})
+
+		assert.EqualError(t, err, "insert segment: theDbError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("DeleteRevisionError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectExec(`INSERT INTO record_log_segment`).WillReturnResult(sqlmock.NewResult(1, 1))
+		dbm.ExpectPrepare(`DELETE FROM record_log`).
+			ExpectExec().WithArgs(11, 1).WillReturnError(errors.New("theDbError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.ArchiveRevisions(context.Background(), seg, []uint64{11, 12})
+
+		assert.EqualError(t, err, "delete revision 11: theDbError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectExec(`INSERT INTO record_log_segment \(index_id, path, min_rev, max_rev, revisions, record_ids\)\s+`+
+			`VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\)`).
+			WithArgs(1, "thePath", 11, 12, 2, []byte(`["foo","bar"]`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		prep := dbm.ExpectPrepare(`DELETE FROM record_log WHERE id=\$1 AND index_id=\$2`)
+		prep.ExpectExec().WithArgs(11, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		prep.ExpectExec().WithArgs(12, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.ArchiveRevisions(context.Background(), seg, []uint64{11, 12})
+
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
+
+func TestRecordRepository_Segments(tt *testing.T) {
+	tt.Run("IndexNameValidationError", func(t *testing.T) {
+		indexNameValidator := &stringValidatorMock{}
+		indexNameValidator.ValidateFunc = func(s string) error {
+			return fmt.Errorf("theIndexNameValidationError")
+		}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, indexNameValidator, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.Segments(context.Background(), "theIndexName", "theRecordID", 0)
+
+		require.EqualError(t, err, "theIndexNameValidationError")
+	})
+
+	tt.Run("RecordIDValidationError", func(t *testing.T) {
+		indexNameValidator := &stringValidatorMock{}
+		indexNameValidator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		recordIDValidator := &stringValidatorMock{}
+		recordIDValidator.ValidateFunc = func(s string) error {
+			return fmt.Errorf("theRecordIDValidationError")
+		}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		_, err = repo.Segments(context.Background(), "theIndexName", "theRecordID", 0)
+
+		require.EqualError(t, err, "theRecordIDValidationError")
+	})
+
+	tt.Run("DbQueryError", func(t *testing.T) {
+		validator := &stringValidatorMock{}
+		validator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id`).WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, validator, validator, zerolog.Nop())
+		_, err = repo.Segments(context.Background(), "theIndexName", "theRecordID", 0)
+
+		require.EqualError(t, err, "db query: theDbError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		validator := &stringValidatorMock{}
+		validator.ValidateFunc = func(s string) error {
+			return nil
+		}
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id, index_id, path, min_rev, max_rev, revisions, created_at FROM record_log_segment\s+`+
+			`WHERE index_id=\(SELECT id FROM index WHERE name=\$1 LIMIT 1\) AND record_ids \? \$2 AND min_rev<\$3 `+
+			`ORDER BY max_rev DESC`).
+			WithArgs("theIndexName", "theRecordID", 100).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "index_id", "path", "min_rev", "max_rev", "revisions", "created_at",
+			}).AddRow(2, 1, "thePath2", 50, 60, 5, time.Unix(2, 0)).
+				AddRow(1, 1, "thePath1", 10, 20, 5, time.Unix(1, 0)))
+
+		repo := recordrepo.New(db, validator, validator, zerolog.Nop())
+		res, err := repo.Segments(context.Background(), "theIndexName", "theRecordID", 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, []recordrepo.Segment{
+			{ID: 2, IndexID: 1, Path: "thePath2", MinRev: 50, MaxRev: 60, Revisions: 5, CreatedAt: time.Unix(2, 0)},
+			{ID: 1, IndexID: 1, Path: "thePath1", MinRev: 10, MaxRev: 20, Revisions: 5, CreatedAt: time.Unix(1, 0)},
+		}, res)
+	})
+}
+
+func TestRecordRepository_IndexSegments(tt *testing.T) {
+	tt.Run("EmptyIDs", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.IndexSegments(context.Background(), 1, nil)
+
+		assert.EqualError(t, err, "invalid record ids: must not be empty")
+	})
+
+	tt.Run("DbQueryError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT .+ FROM record_log_segment`).WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.IndexSegments(context.Background(), 1, []string{"theRecordID"})
+
+		assert.EqualError(t, err, "db query: theDbError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT id, index_id, path, min_rev, max_rev, revisions, created_at\s+FROM record_log_segment `+
+			`WHERE index_id=\$1 AND record_ids \?\| ARRAY\[\$2, \$3\] ORDER BY max_rev`).
+			WithArgs(1, "theRecordID1", "theRecordID2").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "index_id", "path", "min_rev", "max_rev", "revisions", "created_at",
+			}).AddRow(1, 1, "thePath1", 10, 20, 5, time.Unix(1, 0)))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		res, err := repo.IndexSegments(context.Background(), 1, []string{"theRecordID1", "theRecordID2"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []recordrepo.Segment{
+			{ID: 1, IndexID: 1, Path: "thePath1", MinRev: 10, MaxRev: 20, Revisions: 5, CreatedAt: time.Unix(1, 0)},
+		}, res)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,259 @@
+package recordrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// Batch operation types.
+const (
+	BatchPut    = "put"
+	BatchPatch  = "patch"
+	BatchDelete = "delete"
+)
+
+// Batch operation result statuses.
+const (
+	BatchCreated   = "created"
+	BatchUpdated   = "updated"
+	BatchUnchanged = "unchanged"
+	BatchDeleted   = "deleted"
+	BatchNotFound  = "not_found"
+)
+
+type BatchOp struct {
+	Type        string
+	IndexID     uint64
+	ID          string
+	Data        string                            // put only
+	Patch       func(data string) (string, error) // patch only; returns the new data given the current one
+	ExpiresAt   sql.NullTime                      // put and patch only
+	ExpectedRev uint64                            // the record's current revision must be this one unless zero
+	MustExist   bool
+}
+
+type BatchResult struct {
+	ID     string
+	Rev    uint64 // zero for deleted and not found records
+	Status string
+}
+
+// PreconditionError is returned when a precondition of a batch operation or a copy doesn't hold. Writes an index mode
+// doesn't allow fail with indexrepo.ModeError instead.
+type PreconditionError struct {
+	Reason string
+}
+
+func (e PreconditionError) Error() string {
+	return "precondition failed: " + e.Reason
+}
+
+// Batch runs the operations in order within a single transaction, so either all of them are applied or none.
+// Records are locked as the operations reach them, preconditions are checked against the records' state left by the
+// preceding operations. Deleting a record removes it from the current records only, its history is kept.
+//
+// Modes of the records' indices are respected the same way Push does; besides, records of insert-only indices can't
+// be deleted.
+func (r *Repository) Batch(ctx context.Context, ops []BatchOp) ([]BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, apperrors.InvalidArgError{Subj: "operations", Reason: "must not be empty"}
+	}
+
+	for i, op := range ops {
+		if err := r.checkBatchOp(i, op); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("db begin: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback() // no-op after commit
+	}()
+
+	indexIDs := make([]uint64, 0, len(ops))
+	for _, op := range ops {
+		indexIDs = append(indexIDs, op.IndexID)
+	}
+
+	modes, err := indexModes(ctx, tx, indexIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	lockKeys := make([]int64, 0)
+
+	for _, op := range ops {
+		if modes[op.IndexID].insertOnly {
+			lockKeys = append(lockKeys, recordLockKey(op.IndexID, op.ID))
+		}
+	}
+
+	if err := lockRecords(lockKeys, txExec(ctx, tx)); err != nil {
+		return nil, err
+	}
+
+	res := make([]BatchResult, 0, len(ops))
+
+	for i, op := range ops {
+		opRes, err := r.batchOp(ctx, tx, op, modes[op.IndexID])
+		if err != nil {
+			return nil, fmt.Errorf("operation %d, id=%s: %w", i, op.ID, err)
+		}
+
+		res = append(res, opRes)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return res, nil
+}
+
+func (r *Repository) checkBatchOp(i int, op BatchOp) error {
+	if op.IndexID == 0 {
+		return apperrors.InvalidArgError{Subj: fmt.Sprintf("operation %d", i), Reason: "zero index id"}
+	}
+
+	if err := r.recordIDValidator.Validate(op.ID); err != nil {
+		return err //nolint:wrapcheck // ok
+	}
+
+	switch op.Type {
+	case BatchPut:
+		if op.Data == "" {
+			return apperrors.InvalidArgError{Subj: "record data", Reason: "must not be empty"}
+		}
+	case BatchPatch:
+		if op.Patch == nil {
+			return apperrors.InvalidArgError{Subj: fmt.Sprintf("operation %d", i), Reason: "nil patch"}
+		}
+	case BatchDelete:
+	default:
+		return apperrors.InvalidArgError{Subj: fmt.Sprintf("operation %d", i), Reason: "unknown type " + op.Type}
+	}
+
+	return nil
+}
+
+func (r *Repository) batchOp(ctx context.Context, tx *sql.Tx, op BatchOp, mode indexMode) (BatchResult, error) {
+	res := BatchResult{ID: op.ID}
+
+	if mode.readOnly {
+		return res, ErrReadOnly
+	}
+
+	rev, data := uint64(0), ""
+	q := `SELECT r.log_id, r.data FROM record r WHERE r.index_id=$1 AND r.id=$2 AND ` + notExpired + ` FOR UPDATE`
+
+	err := tx.QueryRowContext(ctx, q, op.IndexID, op.ID).Scan(&rev, &data)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return res, fmt.Errorf("db select: %w", err)
+	}
+
+	exists := err == nil
+
+	switch {
+	case op.MustExist && !exists:
+		return res, PreconditionError{Reason: "record doesn't exist"}
+	case op.ExpectedRev != 0 && op.ExpectedRev != rev:
+		return res, PreconditionError{Reason: fmt.Sprintf("expected rev %d, current rev %d", op.ExpectedRev, rev)}
+	}
+
+	switch op.Type {
+	case BatchDelete:
+		if !exists {
+			res.Status = BatchNotFound
+			return res, nil
+		}
+
+		if mode.insertOnly {
+			return res, errInsertOnly(op.ID)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM record WHERE index_id=$1 AND id=$2`, op.IndexID, op.ID); err != nil {
+			return res, fmt.Errorf("db delete: %w", err)
+		}
+
+		res.Status = BatchDeleted
+
+		return res, nil
+	case BatchPatch:
+		if !exists {
+			return res, apperrors.NotFoundError{Subj: "record"}
+		}
+
+		if data, err = op.Patch(data); err != nil {
+			return res, err
+		}
+	default:
+		data = op.Data
+	}
+
+	return r.batchWrite(ctx, tx, op, data, exists, mode)
+}
+
+// batchWrite only touches the record if its data doesn't change, otherwise writes the data to the history and upserts
+// the record, the same way Push does.
+func (r *Repository) batchWrite(
+	ctx context.Context,
+	tx *sql.Tx,
+	op BatchOp,
+	data string,
+	exists bool,
+	mode indexMode,
+) (BatchResult, error) {
+	res := BatchResult{ID: op.ID}
+
+	if exists {
+		q := `UPDATE record SET touched_at=now(), expires_at=$3, stale_at=NULL
+WHERE index_id=$1 AND id=$2 AND checksum=` + checksumExpr("$4::JSONB") + ` RETURNING log_id`
+
+		err := tx.QueryRowContext(ctx, q, op.IndexID, op.ID, op.ExpiresAt, data).Scan(&res.Rev)
+		if err == nil {
+			res.Status = BatchUnchanged
+			return res, nil
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return res, fmt.Errorf("db touch: %w", err)
+		}
+
+		if mode.insertOnly {
+			return res, errInsertOnly(op.ID)
+		}
+	}
+
+	q := `INSERT INTO record_log (index_id, record_id, data) VALUES ($1, $2, $3::JSONB) RETURNING id`
+	if err := tx.QueryRowContext(ctx, q, op.IndexID, op.ID, data).Scan(&res.Rev); err != nil {
+		return res, fmt.Errorf("db insert log: %w", err)
+	}
+
+	q = `INSERT INTO record (id, index_id, log_id, checksum, data, expires_at)
+VALUES ($1, $2, $3, ` + checksumExpr("$4::JSONB") + `, $4::JSONB, $5)
+ON CONFLICT (id, index_id) DO UPDATE SET log_id=EXCLUDED.log_id, checksum=EXCLUDED.checksum, data=EXCLUDED.data,
+expires_at=EXCLUDED.expires_at, stale_at=NULL, updated_at=now(), touched_at=now()`
+	if _, err := tx.ExecContext(ctx, q, op.ID, op.IndexID, res.Rev, data, op.ExpiresAt); err != nil {
+		return res, fmt.Errorf("db upsert: %w", err)
+	}
+
+	if mode.noHistory {
+		q = `DELETE FROM record_log WHERE index_id=$1 AND record_id=$2 AND id<>$3`
+		if _, err := tx.ExecContext(ctx, q, op.IndexID, op.ID, res.Rev); err != nil {
+			return res, fmt.Errorf("db prune history: %w", err)
+		}
+	}
+
+	res.Status = BatchCreated
+	if exists {
+		res.Status = BatchUpdated
+	}
+
+	return res, nil
+}
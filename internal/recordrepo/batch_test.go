@@ -0,0 +1,419 @@
+package recordrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_Batch(tt *testing.T) {
+	tt.Run("EmptyOperations", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), nil)
+		require.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "operations", Reason: "must not be empty"})
+	})
+
+	tt.Run("ZeroIndexID", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "invalid operation 0: zero index id")
+	})
+
+	tt.Run("RecordIDValidationError", func(t *testing.T) {
+		recordIDValidator := &stringValidatorMock{}
+		recordIDValidator.ValidateFunc = func(s string) error {
+			assert.Equal(t, "theRecordID", s)
+			return errors.New("theRecordIDValidationError")
+		}
+
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, recordIDValidator, zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "theRecordIDValidationError")
+	})
+
+	tt.Run("EmptyPutData", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPut, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "invalid record data: must not be empty")
+	})
+
+	tt.Run("NilPatch", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPatch, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "invalid operation 0: nil patch")
+	})
+
+	tt.Run("UnknownType", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: "theType", IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "invalid operation 0: unknown type theType")
+	})
+
+	tt.Run("DbBeginError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin().WillReturnError(errors.New("theBeginError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "db begin: theBeginError")
+	})
+
+	tt.Run("DbSelectError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnError(errors.New("theSelectError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "operation 0, id=theRecordID: db select: theSelectError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("MustExistFailed", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPut, IndexID: 123, ID: "theRecordID", Data: `{}`, MustExist: true},
+		})
+		require.ErrorIs(t, err, recordrepo.PreconditionError{Reason: "record doesn't exist"})
+		require.EqualError(t, err, "operation 0, id=theRecordID: precondition failed: record doesn't exist")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("ExpectedRevFailed", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}).AddRow(7, `{}`))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID", ExpectedRev: 5},
+		})
+		require.EqualError(t, err, "operation 0, id=theRecordID: precondition failed: expected rev 5, current rev 7")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("PatchNotFound", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPatch, IndexID: 123, ID: "theRecordID", Patch: func(string) (string, error) {
+				return "", errors.New("must not be called")
+			}},
+		})
+		require.ErrorIs(t, err, apperrors.NotFoundError{Subj: "record"})
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("PatchError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}).AddRow(7, `{"foo": "bar"}`))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPatch, IndexID: 123, ID: "theRecordID", Patch: func(data string) (string, error) {
+				assert.Equal(t, `{"foo": "bar"}`, data)
+				return "", errors.New("thePatchError")
+			}},
+		})
+		require.EqualError(t, err, "operation 0, id=theRecordID: thePatchError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("ReadOnlyIndex", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)}, []driver.Value{123, true, false, false})
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "operation 0, id=theRecordID: index is read-only")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("InsertOnlyIndexDelete", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)}, []driver.Value{123, false, true, false})
+		expectLockRecords(dbm, 1)
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}).AddRow(7, `{}`))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "operation 0, id=theRecordID: record theRecordID exists in an insert-only index")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("InsertOnlyIndexRecordChanged", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)}, []driver.Value{123, false, true, false})
+		expectLockRecords(dbm, 1)
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}).AddRow(7, `{}`))
+		dbm.ExpectQuery(`UPDATE record SET touched_at=now\(\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id"}))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPut, IndexID: 123, ID: "theRecordID", Data: `{"a":1}`},
+		})
+		require.EqualError(t, err, "operation 0, id=theRecordID: record theRecordID exists in an insert-only index")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("OkNoHistoryIndex", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)}, []driver.Value{123, false, false, true})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}))
+		dbm.ExpectQuery(`INSERT INTO record_log`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`DELETE FROM record_log WHERE index_id=\$1 AND record_id=\$2 AND id<>\$3`).
+			WithArgs(uint64(123), "theRecordID", uint64(8)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbm.ExpectCommit()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		res, err := repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPut, IndexID: 123, ID: "theRecordID", Data: `{}`},
+		})
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
+		assert.Equal(t, []recordrepo.BatchResult{{ID: "theRecordID", Rev: 8, Status: recordrepo.BatchCreated}}, res)
+	})
+
+	tt.Run("DbUpsertError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}))
+		dbm.ExpectQuery(`INSERT INTO record_log`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WillReturnError(errors.New("theUpsertError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPut, IndexID: 123, ID: "theRecordID", Data: `{}`},
+		})
+		require.EqualError(t, err, "operation 0, id=theRecordID: db upsert: theUpsertError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("DbCommitError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}))
+		dbm.ExpectCommit().WillReturnError(errors.New("theCommitError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		_, err = repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID"},
+		})
+		require.EqualError(t, err, "commit: theCommitError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123), uint64(234)})
+
+		// Put of a new record
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r WHERE r.index_id=\$1 AND r.id=\$2 AND `+
+			`\(r.expires_at IS NULL OR r.expires_at > now\(\)\) FOR UPDATE`).
+			WithArgs(uint64(123), "theRecordID1").
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}))
+		dbm.ExpectQuery(`INSERT INTO record_log \(index_id, record_id, data\) VALUES \(\$1, \$2, \$3::JSONB\) RETURNING id`).
+			WithArgs(uint64(123), "theRecordID1", `{"foo":"bar"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		dbm.ExpectExec(`INSERT INTO record \(id, index_id, log_id, checksum, data, expires_at\)
+VALUES \(\$1, \$2, \$3, sha256\(convert_to\(\$4::JSONB::TEXT, 'UTF8'\)\), \$4::JSONB, \$5\)
+ON CONFLICT \(id, index_id\) DO UPDATE`).
+			WithArgs("theRecordID1", uint64(123), uint64(10), `{"foo":"bar"}`, sql.NullTime{}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// Patch which doesn't change the data
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WithArgs(uint64(234), "theRecordID2").
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}).AddRow(5, `{"foo": "baz"}`))
+		dbm.ExpectQuery(`UPDATE record SET touched_at=now\(\), expires_at=\$3, stale_at=NULL
+WHERE index_id=\$1 AND id=\$2 AND checksum=sha256\(convert_to\(\$4::JSONB::TEXT, 'UTF8'\)\) RETURNING log_id`).
+			WithArgs(uint64(234), "theRecordID2", sql.NullTime{}, `{"foo": "baz"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id"}).AddRow(5))
+
+		// Put of an existing record
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WithArgs(uint64(234), "theRecordID3").
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}).AddRow(6, `{}`))
+		dbm.ExpectQuery(`UPDATE record SET touched_at=now\(\)`).
+			WithArgs(uint64(234), "theRecordID3", sql.NullTime{}, `{"a":1}`).
+			WillReturnRows(sqlmock.NewRows([]string{"log_id"}))
+		dbm.ExpectQuery(`INSERT INTO record_log`).
+			WithArgs(uint64(234), "theRecordID3", `{"a":1}`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WithArgs("theRecordID3", uint64(234), uint64(11), `{"a":1}`, sql.NullTime{}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// Delete of an existing record
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WithArgs(uint64(123), "theRecordID4").
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}).AddRow(4, `{}`))
+		dbm.ExpectExec(`DELETE FROM record WHERE index_id=\$1 AND id=\$2`).
+			WithArgs(uint64(123), "theRecordID4").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		// Delete of a missing record
+		dbm.ExpectQuery(`SELECT r.log_id, r.data FROM record r`).
+			WithArgs(uint64(123), "theRecordID5").
+			WillReturnRows(sqlmock.NewRows([]string{"log_id", "data"}))
+
+		dbm.ExpectCommit()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		res, err := repo.Batch(context.Background(), []recordrepo.BatchOp{
+			{Type: recordrepo.BatchPut, IndexID: 123, ID: "theRecordID1", Data: `{"foo":"bar"}`},
+			{Type: recordrepo.BatchPatch, IndexID: 234, ID: "theRecordID2", ExpectedRev: 5, Patch: func(data string) (string, error) {
+				return data, nil
+			}},
+			{Type: recordrepo.BatchPut, IndexID: 234, ID: "theRecordID3", Data: `{"a":1}`, MustExist: true},
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID4"},
+			{Type: recordrepo.BatchDelete, IndexID: 123, ID: "theRecordID5"},
+		})
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
+
+		assert.Equal(t, []recordrepo.BatchResult{
+			{ID: "theRecordID1", Rev: 10, Status: recordrepo.BatchCreated},
+			{ID: "theRecordID2", Rev: 5, Status: recordrepo.BatchUnchanged},
+			{ID: "theRecordID3", Rev: 11, Status: recordrepo.BatchUpdated},
+			{ID: "theRecordID4", Status: recordrepo.BatchDeleted},
+			{ID: "theRecordID5", Status: recordrepo.BatchNotFound},
+		}, res)
+	})
+}
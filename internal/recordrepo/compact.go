@@ -0,0 +1,53 @@
+package recordrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// DeleteOldRevisions checks a batch of up to limit index's history revisions following the after revision and deletes
+// the ones which are not retained. A revision is retained if it is the current revision of a record, or it is one of
+// the last keep revisions of a record, or it was created at or after the given time. Zero keep retains no revisions by
+// count. It returns the number of deleted revisions and the last checked revision to continue after, which is zero
+// once all the revisions are checked.
+//
+// Batches are paged by revision, so each of them only scans its own revisions and at most keep newer revisions of
+// their records, however long the history is.
+func (r *Repository) DeleteOldRevisions(
+	ctx context.Context,
+	indexID uint64,
+	keep uint32,
+	before time.Time,
+	after uint64,
+	limit uint32,
+) (uint64, uint64, error) {
+	if limit == 0 {
+		return 0, 0, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"}
+	}
+
+	q := `WITH batch AS (
+	SELECT id, record_id, created_at FROM record_log WHERE index_id=$1 AND id > $4 ORDER BY id LIMIT $5
+), deleted AS (
+	DELETE FROM record_log WHERE id IN (
+		SELECT b.id FROM batch b
+		WHERE b.created_at < $3 AND NOT EXISTS (SELECT 1 FROM record r WHERE r.log_id=b.id)
+		AND (SELECT count(*) FROM (
+			SELECT 1 FROM record_log n WHERE n.index_id=$1 AND n.record_id=b.record_id AND n.id > b.id LIMIT $2
+		) newer) >= $2
+	) RETURNING id
+)
+SELECT (SELECT count(*) FROM deleted),
+	CASE WHEN (SELECT count(*) FROM batch) < $5 THEN 0 ELSE (SELECT max(id) FROM batch) END`
+
+	deleted, last := uint64(0), uint64(0)
+
+	err := r.db.QueryRowContext(ctx, q, indexID, keep, before.UTC(), after, limit).Scan(&deleted, &last)
+	if err != nil {
+		return 0, 0, fmt.Errorf("db query: %w", err)
+	}
+
+	return deleted, last, nil
+}
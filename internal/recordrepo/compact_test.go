@@ -0,0 +1,69 @@
+package recordrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_DeleteOldRevisions(tt *testing.T) {
+	before := time.Unix(123, 0).UTC()
+
+	tt.Run("ZeroLimit", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.DeleteOldRevisions(context.Background(), 1, 5, before, 0, 0)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"})
+	})
+
+	tt.Run("DbQueryError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`WITH batch AS`).
+			WithArgs(1, 5, before, 0, 100).
+			WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.DeleteOldRevisions(context.Background(), 1, 5, before, 0, 100)
+
+		assert.EqualError(t, err, "db query: theDbError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`WITH batch AS \(\s*`+
+			`SELECT id, record_id, created_at FROM record_log WHERE index_id=\$1 AND id > \$4 ORDER BY id LIMIT \$5\s*`+
+			`\), deleted AS \(\s*DELETE FROM record_log WHERE id IN \(\s*SELECT b.id FROM batch b\s*`+
+			`WHERE b.created_at < \$3 AND NOT EXISTS \(SELECT 1 FROM record r WHERE r.log_id=b.id\)\s*`+
+			`AND \(SELECT count\(\*\) FROM \(\s*SELECT 1 FROM record_log n `+
+			`WHERE n.index_id=\$1 AND n.record_id=b.record_id AND n.id > b.id LIMIT \$2\s*\) newer\) >= \$2\s*`+
+			`\) RETURNING id\s*\)\s*`+
+			`SELECT \(SELECT count\(\*\) FROM deleted\),\s*`+
+			`CASE WHEN \(SELECT count\(\*\) FROM batch\) < \$5 THEN 0 ELSE \(SELECT max\(id\) FROM batch\) END`).
+			WithArgs(1, 5, before, 200, 100).
+			WillReturnRows(sqlmock.NewRows([]string{"deleted", "last"}).AddRow(42, 300))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		n, last, err := repo.DeleteOldRevisions(context.Background(), 1, 5, before, 200, 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), n)
+		assert.Equal(t, uint64(300), last)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
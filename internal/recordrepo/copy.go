@@ -0,0 +1,324 @@
+package recordrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/searchquery"
+)
+
+type CopyRequest struct {
+	SourceIndexID uint64
+	TargetIndexID uint64
+	Query         string
+	WithHistory   bool
+	Cursor        string // ID of the last record of the previous batch; empty for the first batch
+	Limit         uint32
+	Archive       ArchiveReader // archived history is copied too if it is not nil
+}
+
+// copyHistoryChunk is the maximum number of revisions inserted into the target's history by a single query.
+const copyHistoryChunk = 1000
+
+type copySource struct {
+	id        string
+	logID     uint64
+	data      string
+	createdAt time.Time
+	updatedAt time.Time
+	touchedAt time.Time
+	expiresAt sql.NullTime
+}
+
+// Copy copies a batch of current records from one index to another within a single transaction. It returns the
+// number of processed records and the cursor to be used to copy the next batch; empty cursor means there is nothing
+// left to copy.
+//
+// Records are paged by ID, so records changed while the copying runs are copied once, in the state the batch sees
+// them. The first batch checks the target index is empty, with the index row locked, so no records can be written to
+// the target before the batch commits. Records written to the target by clients after that are kept: source records
+// with the same IDs are skipped, but counted as processed.
+//
+// If the history is copied, the revisions are inserted into the target's history in the order of their original IDs,
+// archived ones included, so the archived history of the source becomes the target's history in the database.
+func (r *Repository) Copy(ctx context.Context, req CopyRequest) (uint64, string, error) {
+	if req.SourceIndexID == 0 || req.TargetIndexID == 0 {
+		return 0, "", apperrors.InvalidArgError{Subj: "index id", Reason: "must not be zero"}
+	}
+
+	if req.Limit == 0 {
+		return 0, "", apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"}
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("db begin: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback() // no-op after commit
+	}()
+
+	if err := lockCopyTarget(ctx, tx, req); err != nil {
+		return 0, "", err
+	}
+
+	sources, err := r.selectCopySources(ctx, tx, req)
+	if err != nil {
+		return 0, "", err
+	}
+
+	for _, src := range sources {
+		if err := r.copyRecord(ctx, tx, req, src); err != nil {
+			return 0, "", fmt.Errorf("copy record %s: %w", src.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", fmt.Errorf("db commit: %w", err)
+	}
+
+	newCursor := ""
+	if len(sources) == int(req.Limit) {
+		newCursor = sources[len(sources)-1].id
+	}
+
+	return uint64(len(sources)), newCursor, nil
+}
+
+// lockCopyTarget locks the target index row and checks the index is writable. The first batch locks the row
+// exclusively, since pushes lock it in share mode, and checks the index is empty.
+func lockCopyTarget(ctx context.Context, tx *sql.Tx, req CopyRequest) error {
+	lock := "SHARE"
+	if req.Cursor == "" {
+		lock = "UPDATE"
+	}
+
+	readOnly := false
+
+	err := tx.QueryRowContext(ctx, `SELECT read_only FROM index WHERE id=$1 FOR `+lock, req.TargetIndexID).
+		Scan(&readOnly)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return apperrors.NotFoundError{Subj: "target index"}
+	case err != nil:
+		return fmt.Errorf("db lock target index: %w", err)
+	case readOnly:
+		return ErrReadOnly
+	case req.Cursor != "":
+		return nil
+	}
+
+	notEmpty := false
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM record WHERE index_id=$1)`, req.TargetIndexID).
+		Scan(&notEmpty); err != nil {
+		return fmt.Errorf("db check target index: %w", err)
+	}
+
+	if notEmpty {
+		return PreconditionError{Reason: "target index is not empty"}
+	}
+
+	return nil
+}
+
+func (r *Repository) selectCopySources(ctx context.Context, tx *sql.Tx, req CopyRequest) ([]copySource, error) {
+	q := `SELECT r.id, r.log_id, r.data, r.created_at, r.updated_at, r.touched_at, r.expires_at FROM record r
+WHERE r.index_id=$1 AND r.id>$2 AND ` + notExpired
+	args := []any{req.SourceIndexID, req.Cursor}
+
+	if req.Query != "" {
+		pq, err := searchquery.Parse(req.Query)
+		if err != nil {
+			return nil, fmt.Errorf("search query: %w", err)
+		}
+
+		q += " AND (" + pq.String("r.data", len(args)+1) + ")"
+		args = append(args, pq.Args()...)
+	}
+
+	q += fmt.Sprintf(" ORDER BY r.id LIMIT $%d", len(args)+1)
+	args = append(args, req.Limit)
+
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db query: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]copySource, 0, req.Limit)
+
+	for rows.Next() {
+		src := copySource{}
+		if err := rows.Scan(&src.id, &src.logID, &src.data, &src.createdAt, &src.updatedAt, &src.touchedAt, &src.expiresAt); err != nil {
+			return nil, fmt.Errorf("db scan: %w", err)
+		}
+
+		res = append(res, src)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db rows iteration: %w", err)
+	}
+
+	return res, nil
+}
+
+// copyRecord copies a record into the target index along with its history, if it is requested.
+func (r *Repository) copyRecord(
+	ctx context.Context,
+	tx *sql.Tx,
+	req CopyRequest,
+	src copySource,
+) error {
+	var (
+		logIDs []any
+		err    error
+	)
+
+	if req.WithHistory {
+		logIDs, err = copyHistory(ctx, tx, req, src)
+	} else {
+		logIDs, err = queryLogIDs(ctx, tx, `INSERT INTO record_log (index_id, record_id, data, created_at)
+SELECT $1, record_id, data, created_at FROM record_log WHERE id=$2 RETURNING id`, req.TargetIndexID, src.logID)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(logIDs) == 0 {
+		return errors.New("no log entries copied")
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO record (id, index_id, log_id, checksum, data, created_at, updated_at, touched_at, expires_at)
+VALUES ($1, $2, $3, `+checksumExpr("$4::JSONB")+`, $4, $5, $6, $7, $8) ON CONFLICT (id, index_id) DO NOTHING`,
+		src.id, req.TargetIndexID, logIDs[len(logIDs)-1], src.data, src.createdAt, src.updatedAt, src.touchedAt,
+		src.expiresAt)
+	if err != nil {
+		return fmt.Errorf("insert record db query: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("insert record get db rows affected: %w", err)
+	}
+
+	if n != 0 {
+		return nil
+	}
+
+	// The record has been written to the target by a client, so its copied history is discarded
+	for start := 0; start < len(logIDs); start += copyHistoryChunk {
+		chunk := logIDs[start:min(start+copyHistoryChunk, len(logIDs))]
+
+		ph := make([]string, len(chunk))
+		for i := range chunk {
+			ph[i] = fmt.Sprintf("$%d", i+1)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM record_log WHERE id IN (`+strings.Join(ph, ", ")+`)`,
+			chunk...); err != nil {
+			return fmt.Errorf("delete log db query: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// copyHistory inserts the history revisions of a source record into the target's history, oldest first, and returns
+// IDs of the inserted entries. Revisions newer than the record's current one are skipped, so the last inserted entry
+// is the current revision. The revisions are inserted in chunks as they are read, so the history is never loaded into
+// memory at once.
+func copyHistory(ctx context.Context, tx *sql.Tx, req CopyRequest, src copySource) ([]any, error) {
+	// The source history is locked, so its revisions can't be archived until the batch commits. Otherwise revisions
+	// archived after the archive has been read would be lost.
+	if _, err := tx.ExecContext(ctx, `SELECT count(*) FROM (SELECT 1 FROM record_log
+WHERE index_id=$1 AND record_id=$2 AND id<=$3 FOR SHARE) l`, req.SourceIndexID, src.id, src.logID); err != nil {
+		return nil, fmt.Errorf("lock history db query: %w", err)
+	}
+
+	res := make([]any, 0)
+	chunk := make([]Record, 0, copyHistoryChunk)
+
+	insert := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		ph := make([]string, len(chunk))
+		args := make([]any, 0, len(chunk)*4)
+
+		for i, rev := range chunk {
+			ph[i] = fmt.Sprintf("($%d, $%d, $%d, $%d)", i*4+1, i*4+2, i*4+3, i*4+4)
+			args = append(args, req.TargetIndexID, src.id, rev.Data, rev.CreatedAt)
+		}
+
+		// Entries get their IDs in the order of the values
+		ids, err := queryLogIDs(ctx, tx, `INSERT INTO record_log (index_id, record_id, data, created_at) VALUES `+
+			strings.Join(ph, ", ")+` RETURNING id`, args...)
+		if err != nil {
+			return err
+		}
+
+		res = append(res, ids...)
+		chunk = chunk[:0]
+
+		return nil
+	}
+
+	err := recordHistory(ctx, tx, req.Archive, req.SourceIndexID, src.id, src.logID, func(rev Record) error {
+		chunk = append(chunk, rev)
+		if len(chunk) < copyHistoryChunk {
+			return nil
+		}
+
+		return insert()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := insert(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func queryLogIDs(ctx context.Context, tx *sql.Tx, q string, args ...any) ([]any, error) {
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("insert log db query: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]any, 0, 1)
+
+	for rows.Next() {
+		logID := uint64(0)
+		if err := rows.Scan(&logID); err != nil {
+			return nil, fmt.Errorf("insert log db scan: %w", err)
+		}
+
+		res = append(res, logID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("insert log db rows iteration: %w", err)
+	}
+
+	return res, nil
+}
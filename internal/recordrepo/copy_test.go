@@ -0,0 +1,409 @@
+package recordrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_Copy(tt *testing.T) {
+	sourceRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "log_id", "data", "created_at", "updated_at", "touched_at", "expires_at"}).
+			AddRow("theRecord1", 11, `{"foo":1}`, time.Unix(1, 0), time.Unix(2, 0), time.Unix(3, 0), nil).
+			AddRow("theRecord2", 22, `{"foo":2}`, time.Unix(4, 0), time.Unix(5, 0), time.Unix(6, 0), time.Unix(7, 0))
+	}
+
+	tt.Run("ZeroIndexID", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{TargetIndexID: 2, Limit: 2})
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "index id", Reason: "must not be zero"})
+	})
+
+	tt.Run("ZeroLimit", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2})
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"})
+	})
+
+	tt.Run("DbBeginError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin().WillReturnError(errors.New("theBeginError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 2})
+
+		assert.EqualError(t, err, "db begin: theBeginError")
+	})
+
+	tt.Run("DbLockTargetError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT read_only FROM index WHERE id=\$1 FOR UPDATE`).
+			WithArgs(2).
+			WillReturnError(errors.New("theLockError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 2})
+
+		assert.EqualError(t, err, "db lock target index: theLockError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("TargetNotFound", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT read_only FROM index`).
+			WillReturnRows(sqlmock.NewRows([]string{"read_only"}))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 2})
+
+		assert.ErrorIs(t, err, apperrors.NotFoundError{Subj: "target index"})
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("TargetReadOnly", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		// Later batches lock the target in share mode only
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT read_only FROM index WHERE id=\$1 FOR SHARE`).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"read_only"}).AddRow(true))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{
+			SourceIndexID: 1, TargetIndexID: 2, Cursor: "theRecord0", Limit: 2,
+		})
+
+		assert.ErrorIs(t, err, recordrepo.ErrReadOnly)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("TargetNotEmpty", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, true)
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 2})
+
+		assert.ErrorIs(t, err, recordrepo.PreconditionError{Reason: "target index is not empty"})
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("DbSelectError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).
+			WithArgs(1, "", 2).
+			WillReturnError(errors.New("theSelectError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 2})
+
+		assert.EqualError(t, err, "db query: theSelectError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("DbInsertLogError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).WillReturnRows(sourceRows())
+		dbm.ExpectQuery(`INSERT INTO record_log`).
+			WithArgs(2, 11).
+			WillReturnError(errors.New("theInsertError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 2})
+
+		assert.EqualError(t, err, "copy record theRecord1: insert log db query: theInsertError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("DbInsertRecordError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).WillReturnRows(sourceRows())
+		dbm.ExpectQuery(`INSERT INTO record_log`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(33))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WillReturnError(errors.New("theInsertError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 2})
+
+		assert.EqualError(t, err, "copy record theRecord1: insert record db query: theInsertError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("DbLockHistoryError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).WillReturnRows(sourceRows())
+		dbm.ExpectExec(`SELECT count\(\*\) FROM \(SELECT 1 FROM record_log .+ FOR SHARE\) l`).
+			WillReturnError(errors.New("theLockError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{
+			SourceIndexID: 1, TargetIndexID: 2, WithHistory: true, Limit: 2,
+		})
+
+		assert.EqualError(t, err, "copy record theRecord1: lock history db query: theLockError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("DbSelectHistoryError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).WillReturnRows(sourceRows())
+		dbm.ExpectExec(`SELECT count\(\*\) FROM \(SELECT 1 FROM record_log`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WillReturnError(errors.New("theSelectError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{
+			SourceIndexID: 1, TargetIndexID: 2, WithHistory: true, Limit: 2,
+		})
+
+		assert.EqualError(t, err, "copy record theRecord1: db query history: theSelectError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("ArchiveError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).WillReturnRows(sourceRows())
+		dbm.ExpectExec(`SELECT count\(\*\) FROM \(SELECT 1 FROM record_log`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, _, err = repo.Copy(context.Background(), recordrepo.CopyRequest{
+			SourceIndexID: 1,
+			TargetIndexID: 2,
+			WithHistory:   true,
+			Limit:         2,
+			Archive:       &archiveReaderMock{err: errors.New("theArchiveError")},
+		})
+
+		assert.EqualError(t, err, "copy record theRecord1: get archived history: theArchiveError")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("OkFullBatch", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT read_only FROM index WHERE id=\$1 FOR SHARE`).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"read_only"}).AddRow(false))
+		dbm.ExpectQuery(`SELECT .+, r.expires_at FROM record r WHERE r.index_id=\$1 AND r.id>\$2 AND \(r.expires_at IS NULL OR r.expires_at > now\(\)\) AND \(\(r.data->'foo'\)::int > \$3\) ORDER BY r.id LIMIT \$4`).
+			WithArgs(1, "theRecord0", 0, 2).
+			WillReturnRows(sourceRows())
+		dbm.ExpectExec(`SELECT count\(\*\) FROM \(SELECT 1 FROM record_log\s+`+
+			`WHERE index_id=\$1 AND record_id=\$2 AND id<=\$3 FOR SHARE\) l`).
+			WithArgs(1, "theRecord1", 11).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		// The revision 10 has been archived after the history has been locked
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log\s+`+
+			`WHERE index_id=\$1 AND record_id=\$2 AND id>\$3 AND id<=\$4 ORDER BY id LIMIT \$5`).
+			WithArgs(1, "theRecord1", 0, 11, 1000).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(10, `{"foo":0}`, time.Unix(1, 0)).
+				AddRow(11, `{"foo":1}`, time.Unix(2, 0)))
+		dbm.ExpectQuery(`INSERT INTO record_log \(index_id, record_id, data, created_at\) `+
+			`VALUES \(\$1, \$2, \$3, \$4\), \(\$5, \$6, \$7, \$8\), \(\$9, \$10, \$11, \$12\) RETURNING id`).
+			WithArgs(2, "theRecord1", `{"foo":-1}`, time.Unix(0, 0), 2, "theRecord1", `{"foo":0}`, time.Unix(1, 0),
+				2, "theRecord1", `{"foo":1}`, time.Unix(2, 0)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(33).AddRow(34).AddRow(35))
+		dbm.ExpectExec(`INSERT INTO record .+ VALUES \(\$1, \$2, \$3, sha256\(convert_to\(\$4::JSONB::TEXT, 'UTF8'\)\), \$4, .+\) ON CONFLICT \(id, index_id\) DO NOTHING`).
+			WithArgs("theRecord1", 2, 35, `{"foo":1}`, time.Unix(1, 0), time.Unix(2, 0), time.Unix(3, 0), nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectExec(`SELECT count\(\*\) FROM \(SELECT 1 FROM record_log`).
+			WithArgs(1, "theRecord2", 22).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WithArgs(1, "theRecord2", 0, 22, 1000).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(22, `{"foo":2}`, time.Unix(4, 0)))
+		dbm.ExpectQuery(`INSERT INTO record_log .+ VALUES \(\$1, \$2, \$3, \$4\) RETURNING id`).
+			WithArgs(2, "theRecord2", `{"foo":2}`, time.Unix(4, 0)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(36))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WithArgs("theRecord2", 2, 36, `{"foo":2}`, time.Unix(4, 0), time.Unix(5, 0), time.Unix(6, 0), time.Unix(7, 0)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit()
+
+		archive := &archiveReaderMock{revs: map[string][]recordrepo.Record{
+			"theRecord1": {
+				{ID: "theRecord1", IndexID: 1, Rev: 5, Data: `{"foo":-1}`, CreatedAt: time.Unix(0, 0)},
+				{ID: "theRecord1", IndexID: 1, Rev: 10, Data: `{"foo":0}`, CreatedAt: time.Unix(1, 0)},
+			},
+		}}
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		n, cursor, err := repo.Copy(context.Background(), recordrepo.CopyRequest{
+			SourceIndexID: 1,
+			TargetIndexID: 2,
+			Query:         "foo>0",
+			WithHistory:   true,
+			Cursor:        "theRecord0",
+			Limit:         2,
+			Archive:       archive,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), n)
+		assert.Equal(t, "theRecord2", cursor)
+		assert.Equal(t, []string{"theRecord1", "theRecord2"}, archive.ids)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("OkRecordWrittenToTarget", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		// The first record has been pushed to the target meanwhile, its copied history is discarded
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).WillReturnRows(sourceRows())
+		dbm.ExpectExec(`SELECT count\(\*\) FROM \(SELECT 1 FROM record_log`).WillReturnResult(sqlmock.NewResult(0, 2))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(10, `{"foo":0}`, time.Unix(1, 0)).
+				AddRow(11, `{"foo":1}`, time.Unix(2, 0)))
+		dbm.ExpectQuery(`INSERT INTO record_log`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(33).AddRow(34))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WithArgs("theRecord1", 2, 34, `{"foo":1}`, time.Unix(1, 0), time.Unix(2, 0), time.Unix(3, 0), nil).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		dbm.ExpectExec(`DELETE FROM record_log WHERE id IN \(\$1, \$2\)`).
+			WithArgs(33, 34).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		dbm.ExpectExec(`SELECT count\(\*\) FROM \(SELECT 1 FROM record_log`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(22, `{"foo":2}`, time.Unix(4, 0)))
+		dbm.ExpectQuery(`INSERT INTO record_log`).
+			WithArgs(2, "theRecord2", `{"foo":2}`, time.Unix(4, 0)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(35))
+		dbm.ExpectExec(`INSERT INTO record`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		n, cursor, err := repo.Copy(context.Background(), recordrepo.CopyRequest{
+			SourceIndexID: 1, TargetIndexID: 2, WithHistory: true, Limit: 3,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), n)
+		assert.Empty(t, cursor)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("OkLastBatch", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		expectCopyTarget(dbm, false)
+		dbm.ExpectQuery(`SELECT .+ FROM record r`).WillReturnRows(sourceRows())
+		dbm.ExpectQuery(`INSERT INTO record_log .+ WHERE id=\$2 RETURNING id`).
+			WithArgs(2, 11).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(33))
+		dbm.ExpectExec(`INSERT INTO record`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectQuery(`INSERT INTO record_log .+ WHERE id=\$2 RETURNING id`).
+			WithArgs(2, 22).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(34))
+		dbm.ExpectExec(`INSERT INTO record`).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		n, cursor, err := repo.Copy(context.Background(), recordrepo.CopyRequest{SourceIndexID: 1, TargetIndexID: 2, Limit: 3})
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), n)
+		assert.Empty(t, cursor)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
+
+// expectCopyTarget expects the first copy batch's lock of the writable target index and the check of its emptiness.
+func expectCopyTarget(dbm sqlmock.Sqlmock, notEmpty bool) {
+	dbm.ExpectQuery(`SELECT read_only FROM index WHERE id=\$1 FOR UPDATE`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"read_only"}).AddRow(false))
+	dbm.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM record WHERE index_id=\$1\)`).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(notEmpty))
+}
+
+type archiveReaderMock struct {
+	revs map[string][]recordrepo.Record
+	err  error
+	ids  []string
+}
+
+func (m *archiveReaderMock) Revisions(
+	_ context.Context,
+	_ uint64,
+	ids []string,
+) (map[string][]recordrepo.Record, error) {
+	m.ids = append(m.ids, ids...)
+
+	return m.revs, m.err
+}
@@ -0,0 +1,31 @@
+package recordrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashep/ujds/internal/searchquery"
+)
+
+// Count returns the number of an index's records, optionally filtered by a search query.
+func (r *Repository) Count(ctx context.Context, indexID uint64, query string) (uint64, error) {
+	q := `SELECT count(*) FROM record r WHERE r.index_id=$1`
+	args := []any{indexID}
+
+	if query != "" {
+		pq, err := searchquery.Parse(query)
+		if err != nil {
+			return 0, fmt.Errorf("search query: %w", err)
+		}
+
+		q += " AND (" + pq.String("r.data", len(args)+1) + ")"
+		args = append(args, pq.Args()...)
+	}
+
+	var cnt uint64
+	if err := r.db.QueryRowContext(ctx, q, args...).Scan(&cnt); err != nil {
+		return 0, fmt.Errorf("db scan: %w", err)
+	}
+
+	return cnt, nil
+}
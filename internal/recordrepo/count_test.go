@@ -0,0 +1,55 @@
+package recordrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_Count(tt *testing.T) {
+	tt.Run("InvalidSearchQuery", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.Count(context.Background(), 123, "foo bar")
+
+		assert.EqualError(t, err, "search query: operator expected at position 4: foo ")
+	})
+
+	tt.Run("DbScanError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT count\(\*\) FROM record r WHERE r.index_id=\$1`).
+			WithArgs(123).
+			WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.Count(context.Background(), 123, "")
+
+		assert.EqualError(t, err, "db scan: theDbError")
+	})
+
+	tt.Run("OkWithSearch", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectQuery(`SELECT count\(\*\) FROM record r WHERE r.index_id=\$1 AND \(\(r.data->'foo'\)::int = \$2\)`).
+			WithArgs(123, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(234))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		cnt, err := repo.Count(context.Background(), 123, "foo=1")
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(234), cnt)
+	})
+}
@@ -0,0 +1,32 @@
+package recordrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// DeleteExpired deletes a batch of expired records of all indices. The history of deleted records is kept. Records of
+// read-only and insert-only indices are never deleted. It returns the number of deleted records.
+func (r *Repository) DeleteExpired(ctx context.Context, limit uint32) (uint64, error) {
+	if limit == 0 {
+		return 0, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"}
+	}
+
+	q := `DELETE FROM record WHERE (id, index_id) IN (
+SELECT r.id, r.index_id FROM record r JOIN index i ON i.id=r.index_id
+WHERE r.expires_at <= now() AND NOT (i.read_only OR i.insert_only) LIMIT $1 FOR SHARE OF i)`
+
+	res, err := r.db.ExecContext(ctx, q, limit)
+	if err != nil {
+		return 0, fmt.Errorf("db exec: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get db rows affected: %w", err)
+	}
+
+	return uint64(n), nil //nolint:gosec // ok
+}
@@ -0,0 +1,59 @@
+package recordrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_DeleteExpired(tt *testing.T) {
+	tt.Run("ZeroLimit", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.DeleteExpired(context.Background(), 0)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"})
+	})
+
+	tt.Run("DbExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM record`).
+			WithArgs(100).
+			WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.DeleteExpired(context.Background(), 100)
+
+		assert.EqualError(t, err, "db exec: theDbError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM record WHERE \(id, index_id\) IN \(\s*SELECT r.id, r.index_id FROM record r ` +
+			`JOIN index i ON i.id=r.index_id\s*WHERE r.expires_at <= now\(\) ` +
+			`AND NOT \(i.read_only OR i.insert_only\) LIMIT \$1 FOR SHARE OF i\)`).
+			WithArgs(100).
+			WillReturnResult(sqlmock.NewResult(0, 42))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		n, err := repo.DeleteExpired(context.Background(), 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), n)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,118 @@
+package recordrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// ExportRecord is a current record along with its history revisions, oldest first.
+type ExportRecord struct {
+	Record
+	History []Record
+}
+
+// Export calls fn for each current record of the index, ordered by ID. All the records are read within a single
+// repeatable read transaction, so they make a consistent snapshot of the index. If withHistory is set, the history
+// revisions are attached to each record, including the ones read from the archive if it is not nil.
+func (r *Repository) Export(
+	ctx context.Context,
+	indexID uint64,
+	withHistory bool,
+	archive ArchiveReader,
+	batchSize uint32,
+	fn func(rec ExportRecord) error,
+) error {
+	if batchSize == 0 {
+		return apperrors.InvalidArgError{Subj: "batch size", Reason: "must not be zero"}
+	}
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("db begin: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	lastID := ""
+
+	for {
+		records, err := r.exportBatch(ctx, tx, indexID, lastID, batchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(records) == 0 {
+			break
+		}
+
+		for _, rec := range records {
+			// The history is read per record, so only a single record's history is kept in memory
+			if withHistory {
+				if err := recordHistory(ctx, tx, archive, indexID, rec.ID, rec.Rev, func(rev Record) error {
+					rec.History = append(rec.History, rev)
+					return nil
+				}); err != nil {
+					return err
+				}
+			}
+
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+
+		if len(records) < int(batchSize) {
+			break
+		}
+
+		lastID = records[len(records)-1].ID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("db commit: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Repository) exportBatch(
+	ctx context.Context,
+	tx *sql.Tx,
+	indexID uint64,
+	lastID string,
+	limit uint32,
+) ([]ExportRecord, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT r.id, r.log_id, r.data, r.created_at, r.updated_at, r.touched_at,
+r.expires_at, r.stale_at FROM record r
+WHERE r.index_id=$1 AND r.id>$2 AND `+notExpired+` ORDER BY r.id LIMIT $3`, indexID, lastID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("db query records: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]ExportRecord, 0)
+
+	for rows.Next() {
+		rec := ExportRecord{Record: Record{IndexID: indexID}}
+		if err := rows.Scan(&rec.ID, &rec.Rev, &rec.Data, &rec.CreatedAt, &rec.UpdatedAt, &rec.TouchedAt,
+			&rec.ExpiresAt, &rec.StaleAt); err != nil {
+			return nil, fmt.Errorf("db scan record: %w", err)
+		}
+
+		res = append(res, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db rows: %w", err)
+	}
+
+	return res, nil
+}
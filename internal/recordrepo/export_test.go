@@ -0,0 +1,208 @@
+package recordrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_Export(tt *testing.T) {
+	collect := func(res *[]recordrepo.ExportRecord) func(rec recordrepo.ExportRecord) error {
+		return func(rec recordrepo.ExportRecord) error {
+			*res = append(*res, rec)
+			return nil
+		}
+	}
+
+	recordCols := []string{"id", "log_id", "data", "created_at", "updated_at", "touched_at", "expires_at", "stale_at"}
+
+	tt.Run("ZeroBatchSize", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.Export(context.Background(), 1, false, nil, 0, nil)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "batch size", Reason: "must not be zero"})
+	})
+
+	tt.Run("DbQueryError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT r.id, r.log_id, r.data`).
+			WithArgs(1, "", 2).
+			WillReturnError(errors.New("theDbError"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.Export(context.Background(), 1, false, nil, 2, nil)
+
+		assert.EqualError(t, err, "db query records: theDbError")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("CallbackError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		now := time.Unix(123, 0).UTC()
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT r.id, r.log_id, r.data`).
+			WithArgs(1, "", 2).
+			WillReturnRows(sqlmock.NewRows(recordCols).AddRow("a", 1, `{}`, now, now, now, nil, nil))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.Export(context.Background(), 1, false, nil, 2, func(recordrepo.ExportRecord) error {
+			return errors.New("theCallbackError")
+		})
+
+		assert.EqualError(t, err, "theCallbackError")
+		assert.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		now := time.Unix(123, 0).UTC()
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT r.id, r.log_id, r.data`).
+			WithArgs(1, "", 2).
+			WillReturnRows(sqlmock.NewRows(recordCols).
+				AddRow("a", 2, `{"v":2}`, now, now, now, nil, nil).
+				AddRow("c", 3, `{"v":3}`, now, now, now, now, nil))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log\s+`+
+			`WHERE index_id=\$1 AND record_id=\$2 AND id>\$3 AND id<=\$4 ORDER BY id LIMIT \$5`).
+			WithArgs(1, "a", 0, 2, 1000).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(1, `{"v":1}`, now).
+				AddRow(2, `{"v":2}`, now))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WithArgs(1, "c", 0, 3, 1000).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(3, `{"v":3}`, now))
+		dbm.ExpectQuery(`SELECT r.id, r.log_id, r.data`).
+			WithArgs(1, "c", 2).
+			WillReturnRows(sqlmock.NewRows(recordCols).
+				AddRow("d", 5, `{"v":5}`, now, now, now, nil, nil))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WithArgs(1, "d", 0, 5, 1000).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(5, `{"v":5}`, now))
+		dbm.ExpectCommit()
+
+		res := make([]recordrepo.ExportRecord, 0)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.Export(context.Background(), 1, true, nil, 2, collect(&res))
+
+		require.NoError(t, err)
+		assert.NoError(t, dbm.ExpectationsWereMet())
+
+		require.Len(t, res, 3)
+		assert.Equal(t, "a", res[0].ID)
+		assert.Equal(t, uint64(2), res[0].Rev)
+		assert.Equal(t, []recordrepo.Record{
+			{ID: "a", IndexID: 1, Rev: 1, Data: `{"v":1}`, CreatedAt: now},
+			{ID: "a", IndexID: 1, Rev: 2, Data: `{"v":2}`, CreatedAt: now},
+		}, res[0].History)
+		assert.Equal(t, "c", res[1].ID)
+		assert.True(t, res[1].ExpiresAt.Valid)
+		assert.Len(t, res[1].History, 1)
+		assert.Equal(t, "d", res[2].ID)
+		assert.Len(t, res[2].History, 1)
+	})
+
+	tt.Run("OkHistoryChunks", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		now := time.Unix(123, 0).UTC()
+
+		chunk := sqlmock.NewRows([]string{"id", "data", "created_at"})
+		for i := 1; i <= 1000; i++ {
+			chunk.AddRow(i, `{}`, now)
+		}
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT r.id, r.log_id, r.data`).
+			WithArgs(1, "", 2).
+			WillReturnRows(sqlmock.NewRows(recordCols).
+				AddRow("a", 1001, `{}`, now, now, now, nil, nil))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WithArgs(1, "a", 0, 1001, 1000).
+			WillReturnRows(chunk)
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WithArgs(1, "a", 1000, 1001, 1000).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).AddRow(1001, `{}`, now))
+		dbm.ExpectCommit()
+
+		res := make([]recordrepo.ExportRecord, 0)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.Export(context.Background(), 1, true, nil, 2, collect(&res))
+
+		require.NoError(t, err)
+		assert.NoError(t, dbm.ExpectationsWereMet())
+
+		require.Len(t, res, 1)
+		require.Len(t, res[0].History, 1001)
+		assert.Equal(t, uint64(1001), res[0].History[1000].Rev)
+	})
+
+	tt.Run("OkArchivedHistory", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		now := time.Unix(123, 0).UTC()
+
+		dbm.ExpectBegin()
+		dbm.ExpectQuery(`SELECT r.id, r.log_id, r.data`).
+			WithArgs(1, "", 2).
+			WillReturnRows(sqlmock.NewRows(recordCols).
+				AddRow("a", 3, `{"v":3}`, now, now, now, nil, nil))
+		dbm.ExpectQuery(`SELECT id, data, created_at FROM record_log`).
+			WithArgs(1, "a", 0, 3, 1000).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "data", "created_at"}).
+				AddRow(2, `{"v":2}`, now).
+				AddRow(3, `{"v":3}`, now))
+		dbm.ExpectCommit()
+
+		// The revision 2 has been archived after the export has begun
+		archive := &archiveReaderMock{revs: map[string][]recordrepo.Record{
+			"a": {
+				{ID: "a", IndexID: 1, Rev: 1, Data: `{"v":1}`, CreatedAt: now},
+				{ID: "a", IndexID: 1, Rev: 2, Data: `{"v":2}`, CreatedAt: now},
+			},
+		}}
+
+		res := make([]recordrepo.ExportRecord, 0)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		err = repo.Export(context.Background(), 1, true, archive, 2, collect(&res))
+
+		require.NoError(t, err)
+		assert.NoError(t, dbm.ExpectationsWereMet())
+
+		require.Len(t, res, 1)
+		assert.Equal(t, []recordrepo.Record{
+			{ID: "a", IndexID: 1, Rev: 1, Data: `{"v":1}`, CreatedAt: now},
+			{ID: "a", IndexID: 1, Rev: 2, Data: `{"v":2}`, CreatedAt: now},
+			{ID: "a", IndexID: 1, Rev: 3, Data: `{"v":3}`, CreatedAt: now},
+		}, res[0].History)
+	})
+}
@@ -2,6 +2,7 @@ package recordrepo
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -23,10 +24,10 @@ func (r *Repository) Find(ctx context.Context, req FindRequest) ([]Record, uint6
 		return nil, 0, err //nolint:wrapcheck // ok
 	}
 
-	q := `SELECT r.id, r.index_id, r.log_id, l.data, r.created_at, r.updated_at, r.touched_at FROM record r
+	q := `SELECT r.id, r.index_id, r.log_id, l.data, r.created_at, r.updated_at, r.touched_at, r.expires_at, r.stale_at FROM record r
 		LEFT JOIN record_log l ON r.log_id = l.id
 		LEFT JOIN index i ON r.index_id = i.id
-		WHERE `
+		WHERE ` + notExpired + ` AND `
 	qArgs := []any{}
 
 	if req.Query != "" {
@@ -38,7 +39,7 @@ func (r *Repository) Find(ctx context.Context, req FindRequest) ([]Record, uint6
 		qArgs = pq.Args()
 		ql := len(qArgs)
 
-		q += pq.String("r.data", 1)
+		q += "(" + pq.String("r.data", 1) + ")"
 		q += fmt.Sprintf(` AND i.name=$%d AND r.updated_at >= $%d AND l.id > $%d`, ql+1, ql+2, ql+3)
 		qArgs = append(qArgs, req.Index, req.Since, req.Cursor)
 	} else {
@@ -70,9 +71,10 @@ func (r *Repository) Find(ctx context.Context, req FindRequest) ([]Record, uint6
 
 	records := make([]Record, 0)
 	recID, indexID, logID, data, crAt, upAt, tcAt := "", uint64(0), uint64(0), "", time.Time{}, time.Time{}, time.Time{}
+	exAt, stAt := sql.NullTime{}, sql.NullTime{}
 
 	for rows.Next() {
-		if err := rows.Scan(&recID, &indexID, &logID, &data, &crAt, &upAt, &tcAt); err != nil {
+		if err := rows.Scan(&recID, &indexID, &logID, &data, &crAt, &upAt, &tcAt, &exAt, &stAt); err != nil {
 			return nil, 0, fmt.Errorf("db scan: %w", err)
 		}
 
@@ -84,6 +86,8 @@ func (r *Repository) Find(ctx context.Context, req FindRequest) ([]Record, uint6
 			CreatedAt: crAt,
 			UpdatedAt: upAt,
 			TouchedAt: tcAt,
+			ExpiresAt: exAt,
+			StaleAt:   stAt,
 		})
 	}
 
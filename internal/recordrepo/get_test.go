@@ -2,9 +2,11 @@ package recordrepo_test
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ashep/go-apperrors"
@@ -106,4 +108,37 @@ func TestRecordRepository_Get(tt *testing.T) {
 		require.EqualError(t, err, "db scan: theSQLError")
 	})
 
+	tt.Run("Ok", func(t *testing.T) {
+		indexNameValidator := &stringValidatorMock{}
+		indexNameValidator.ValidateFunc = func(s string) error { return nil }
+
+		recordIDValidator := &stringValidatorMock{}
+		recordIDValidator.ValidateFunc = func(s string) error { return nil }
+
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.
+			ExpectQuery(`SELECT .+, r.expires_at, r.stale_at FROM record r .+ `+
+				`WHERE i.name=\$1 AND r.id=\$2 AND \(r.expires_at IS NULL OR r.expires_at > now\(\)\)`).
+			WithArgs("theIndexName", "theRecordID").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"index_id", "log_id", "data", "created_at", "updated_at", "touched_at", "expires_at", "stale_at",
+			}).AddRow(1, 2, `{"foo":"bar"}`, time.Unix(3, 0), time.Unix(4, 0), time.Unix(5, 0), time.Unix(6, 0), nil))
+
+		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+
+		rec, err := repo.Get(context.Background(), "theIndexName", "theRecordID")
+		require.NoError(t, err)
+		assert.Equal(t, recordrepo.Record{
+			ID:        "theRecordID",
+			IndexID:   1,
+			Rev:       2,
+			Data:      `{"foo":"bar"}`,
+			CreatedAt: time.Unix(3, 0),
+			UpdatedAt: time.Unix(4, 0),
+			TouchedAt: time.Unix(5, 0),
+			ExpiresAt: sql.NullTime{Time: time.Unix(6, 0), Valid: true},
+		}, rec)
+	})
 }
@@ -2,10 +2,17 @@ package recordrepo
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 )
 
+// ArchiveReader reads history revisions moved to the archive.
+type ArchiveReader interface {
+	// Revisions returns archived revisions of the index's records with the given IDs, keyed by record ID, oldest first.
+	Revisions(ctx context.Context, indexID uint64, ids []string) (map[string][]Record, error)
+}
+
 func (r *Repository) History( //nolint:cyclop // TODO: calculated cyclomatic complexity for function History is 12, max is 10
 	ctx context.Context,
 	index string,
@@ -75,3 +82,118 @@ WHERE index_id=(SELECT id FROM index WHERE name=$1 LIMIT 1) AND record_id=$2`
 
 	return records, newCursor, nil
 }
+
+// historyChunk is the maximum number of history revisions read from the database by a single query.
+const historyChunk = 1000
+
+// recordHistory calls fn for each history revision of a record up to maxRev, oldest first. Revisions are read from the
+// database in chunks by ID, so the history is never loaded into memory at once. If archive is not nil, archived
+// revisions of the record are merged in. They must be read after the database ones can't be archived anymore, i.e.
+// within a repeatable read snapshot or with the record's history locked, so revisions archived meanwhile are read twice
+// rather than lost.
+func recordHistory(
+	ctx context.Context,
+	tx *sql.Tx,
+	archive ArchiveReader,
+	indexID uint64,
+	id string,
+	maxRev uint64,
+	fn func(rev Record) error,
+) error {
+	var archived []Record
+
+	if archive != nil {
+		revs, err := archive.Revisions(ctx, indexID, []string{id})
+		if err != nil {
+			return fmt.Errorf("get archived history: %w", err)
+		}
+
+		archived = revs[id]
+	}
+
+	last := uint64(0)
+	emit := func(rev Record) error {
+		// Archived revisions are duplicated if they are still in the database or if segments overlap
+		if rev.Rev <= last || rev.Rev > maxRev {
+			return nil
+		}
+
+		last = rev.Rev
+
+		return fn(rev)
+	}
+
+	after := uint64(0)
+
+	for {
+		revs, err := historyChunkAfter(ctx, tx, indexID, id, after, maxRev)
+		if err != nil {
+			return err
+		}
+
+		for _, rev := range revs {
+			for len(archived) != 0 && archived[0].Rev < rev.Rev {
+				if err := emit(archived[0]); err != nil {
+					return err
+				}
+
+				archived = archived[1:]
+			}
+
+			if err := emit(rev); err != nil {
+				return err
+			}
+		}
+
+		if len(revs) < historyChunk {
+			break
+		}
+
+		after = revs[len(revs)-1].Rev
+	}
+
+	for _, rev := range archived {
+		if err := emit(rev); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// historyChunkAfter returns a chunk of history revisions of a record with IDs in (after, maxRev], ordered by ID.
+func historyChunkAfter(
+	ctx context.Context,
+	tx *sql.Tx,
+	indexID uint64,
+	id string,
+	after uint64,
+	maxRev uint64,
+) ([]Record, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, data, created_at FROM record_log
+WHERE index_id=$1 AND record_id=$2 AND id>$3 AND id<=$4 ORDER BY id LIMIT $5`, indexID, id, after, maxRev, historyChunk)
+	if err != nil {
+		return nil, fmt.Errorf("db query history: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]Record, 0)
+
+	for rows.Next() {
+		rev := Record{ID: id, IndexID: indexID}
+		if err := rows.Scan(&rev.Rev, &rev.Data, &rev.CreatedAt); err != nil {
+			return nil, fmt.Errorf("db scan history: %w", err)
+		}
+
+		res = append(res, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db history rows: %w", err)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,259 @@
+package recordrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ImportRow is a record to import along with its line number within the import stream.
+type ImportRow struct {
+	RecordUpdate
+	Line uint64
+}
+
+// ImportDuplicate is an imported row which was skipped because a later row has the same record ID.
+type ImportDuplicate struct {
+	Line         uint64
+	ID           string
+	SupersededBy uint64 // line of the row imported instead
+}
+
+type ImportResult struct {
+	Created    uint64
+	Updated    uint64
+	Unchanged  uint64
+	Duplicates []ImportDuplicate
+}
+
+// Import stages the rows returned by next into a temporary table with COPY and merges them into the index set-wise,
+// all in a single transaction. The next func must return io.EOF after the last row; any other error aborts the import.
+// If a record ID occurs more than once, only its last row is imported. The index's mode is respected the same way Push
+// does.
+func (r *Repository) Import(
+	ctx context.Context,
+	indexID uint64,
+	next func() (ImportRow, error),
+) (ImportResult, error) {
+	if indexID == 0 {
+		return ImportResult{}, apperrors.InvalidArgError{Subj: "index id", Reason: "must not be zero"}
+	}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("db conn: %w", err)
+	}
+
+	defer func() {
+		if err := conn.Close(); err != nil {
+			r.l.Error().Err(err).Msg("db conn close failed")
+		}
+	}()
+
+	var res ImportResult
+
+	err = conn.Raw(func(driverConn any) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("db driver doesn't support copy")
+		}
+
+		res, err = r.importTx(ctx, sc.Conn(), indexID, next)
+
+		return err
+	})
+	if err != nil {
+		return ImportResult{}, err //nolint:wrapcheck // ok
+	}
+
+	return res, nil
+}
+
+func (r *Repository) importTx(
+	ctx context.Context,
+	conn *pgx.Conn,
+	indexID uint64,
+	next func() (ImportRow, error),
+) (ImportResult, error) {
+	res := ImportResult{Duplicates: make([]ImportDuplicate, 0)}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return res, fmt.Errorf("db begin: %w", err)
+	}
+
+	defer func() {
+		_ = tx.Rollback(ctx) // no-op after commit
+	}()
+
+	mode := indexMode{}
+
+	err = tx.QueryRow(ctx, `SELECT read_only, insert_only, no_history FROM index WHERE id=$1 FOR SHARE`, indexID).
+		Scan(&mode.readOnly, &mode.insertOnly, &mode.noHistory)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return res, fmt.Errorf("db query index mode: %w", err)
+	}
+
+	if mode.readOnly {
+		return res, ErrReadOnly
+	}
+
+	if _, err = tx.Exec(ctx, `CREATE TEMP TABLE import_stage (
+	line BIGINT NOT NULL,
+	id VARCHAR(64) NOT NULL,
+	data JSONB NOT NULL,
+	expires_at TIMESTAMP
+) ON COMMIT DROP`); err != nil {
+		return res, fmt.Errorf("create stage table: %w", err)
+	}
+
+	src := &importSource{indexID: indexID, next: next, idValidator: r.recordIDValidator, lockKeys: mode.insertOnly}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"import_stage"}, []string{"line", "id", "data", "expires_at"}, src)
+	if src.err != nil {
+		return res, src.err
+	} else if err != nil {
+		return res, fmt.Errorf("copy rows: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `DELETE FROM import_stage s
+USING (SELECT id, max(line) AS line FROM import_stage GROUP BY id HAVING count(*) > 1) d
+WHERE s.id = d.id AND s.line < d.line
+RETURNING s.line, s.id, d.line`)
+	if err != nil {
+		return res, fmt.Errorf("delete duplicates: %w", err)
+	}
+
+	for rows.Next() {
+		dup := ImportDuplicate{}
+		if err = rows.Scan(&dup.Line, &dup.ID, &dup.SupersededBy); err != nil {
+			rows.Close()
+			return res, fmt.Errorf("scan duplicate: %w", err)
+		}
+
+		res.Duplicates = append(res.Duplicates, dup)
+	}
+
+	if err = rows.Err(); err != nil {
+		return res, fmt.Errorf("delete duplicates rows: %w", err)
+	}
+
+	if mode.insertOnly {
+		err = lockRecords(src.keys, func(q string, args ...any) error {
+			_, err := tx.Exec(ctx, q, args...)
+			return err //nolint:wrapcheck // ok
+		})
+		if err != nil {
+			return res, err
+		}
+
+		id := ""
+
+		err = tx.QueryRow(ctx, `SELECT s.id FROM import_stage s JOIN record r ON r.index_id = $1 AND r.id = s.id
+WHERE r.checksum <> `+checksumExpr("s.data")+` AND `+notExpired+` ORDER BY s.line LIMIT 1`, indexID).Scan(&id)
+		if err == nil {
+			return res, errInsertOnly(id)
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			return res, fmt.Errorf("check insert-only: %w", err)
+		}
+	}
+
+	// The records' data didn't change, just touch them as Push does
+	tag, err := tx.Exec(ctx, `UPDATE record r SET touched_at=now(), expires_at=s.expires_at, stale_at=NULL
+FROM import_stage s WHERE r.index_id = $1 AND r.id = s.id AND r.checksum = `+checksumExpr("s.data"), indexID)
+	if err != nil {
+		return res, fmt.Errorf("touch records: %w", err)
+	}
+
+	res.Unchanged = uint64(tag.RowsAffected()) //nolint:gosec // ok
+
+	err = tx.QueryRow(ctx, `WITH stage AS (
+	SELECT line, id, data, expires_at, `+checksumExpr("data")+` AS checksum FROM import_stage
+), logs AS (
+	INSERT INTO record_log (index_id, record_id, data)
+	SELECT $1, s.id, s.data FROM stage s
+	WHERE NOT EXISTS (SELECT 1 FROM record r WHERE r.index_id = $1 AND r.id = s.id AND r.checksum = s.checksum)
+	ORDER BY s.line
+	RETURNING id, record_id
+), upserted AS (
+	INSERT INTO record (id, index_id, log_id, checksum, data, expires_at)
+	SELECT s.id, $1, l.id, s.checksum, s.data, s.expires_at FROM logs l JOIN stage s ON s.id = l.record_id
+	ON CONFLICT (id, index_id) DO UPDATE SET log_id=EXCLUDED.log_id, checksum=EXCLUDED.checksum, data=EXCLUDED.data,
+	expires_at=EXCLUDED.expires_at, stale_at=NULL, updated_at=now(), touched_at=now()
+	RETURNING xmax = 0 AS created
+)
+SELECT count(*) FILTER (WHERE created), count(*) FILTER (WHERE NOT created) FROM upserted`, indexID).
+		Scan(&res.Created, &res.Updated)
+	if err != nil {
+		return res, fmt.Errorf("merge records: %w", err)
+	}
+
+	if mode.noHistory {
+		if _, err = tx.Exec(ctx, `DELETE FROM record_log l USING record r, import_stage s
+WHERE r.index_id = $1 AND r.id = s.id AND l.index_id = $1 AND l.record_id = r.id AND l.id <> r.log_id`, indexID); err != nil {
+			return res, fmt.Errorf("prune history: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return res, fmt.Errorf("commit: %w", err)
+	}
+
+	return res, nil
+}
+
+// importSource adapts the import rows func to pgx.CopyFromSource.
+type importSource struct {
+	indexID     uint64
+	next        func() (ImportRow, error)
+	idValidator stringValidator
+	lockKeys    bool    // collect lock keys of the rows
+	keys        []int64 // see lockRecords
+	row         ImportRow
+	err         error
+}
+
+func (s *importSource) Next() bool {
+	row, err := s.next()
+	if errors.Is(err, io.EOF) {
+		return false
+	} else if err != nil {
+		s.err = err
+		return false
+	}
+
+	row.IndexID = s.indexID
+	s.row = row
+
+	return true
+}
+
+func (s *importSource) Values() ([]any, error) {
+	if err := s.idValidator.Validate(s.row.ID); err != nil {
+		return nil, err //nolint:wrapcheck // ok
+	}
+
+	if s.row.Data == "" {
+		return nil, apperrors.InvalidArgError{Subj: "record data", Reason: "must not be empty"}
+	}
+
+	if s.lockKeys {
+		s.keys = append(s.keys, recordLockKey(s.indexID, s.row.ID))
+	}
+
+	var expiresAt any
+	if s.row.ExpiresAt.Valid {
+		expiresAt = s.row.ExpiresAt.Time
+	}
+
+	return []any{int64(s.row.Line), s.row.ID, s.row.Data, expiresAt}, nil //nolint:gosec // ok
+}
+
+func (s *importSource) Err() error {
+	return s.err
+}
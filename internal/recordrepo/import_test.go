@@ -0,0 +1,39 @@
+package recordrepo_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_Import(tt *testing.T) {
+	eof := func() (recordrepo.ImportRow, error) { return recordrepo.ImportRow{}, io.EOF }
+
+	tt.Run("ZeroIndexID", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.Import(context.Background(), 0, eof)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "index id", Reason: "must not be zero"})
+	})
+
+	tt.Run("DriverDoesNotSupportCopy", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.Import(context.Background(), 1, eof)
+
+		assert.EqualError(t, err, "db driver doesn't support copy")
+	})
+}
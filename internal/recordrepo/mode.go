@@ -0,0 +1,106 @@
+package recordrepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+)
+
+// indexMode is the write restrictions of an index, see indexrepo.Mode.
+type indexMode struct {
+	readOnly   bool
+	insertOnly bool
+	noHistory  bool
+}
+
+// ErrReadOnly is returned on attempts to write records of a read-only index.
+var ErrReadOnly = indexrepo.ModeError{Reason: "index is read-only"} //nolint:gochecknoglobals // ok
+
+// errInsertOnly returns the error of an attempt to change an existing record of an insert-only index.
+func errInsertOnly(id string) error {
+	return indexrepo.ModeError{Reason: fmt.Sprintf("record %s exists in an insert-only index", id)}
+}
+
+// indexModes returns the modes of the indices. The indices are locked in share mode, so their modes don't change
+// until the transaction ends.
+func indexModes(ctx context.Context, tx *sql.Tx, indexIDs []uint64) (map[uint64]indexMode, error) {
+	res := make(map[uint64]indexMode, len(indexIDs))
+	args := make([]any, 0, len(indexIDs))
+	ph := make([]string, 0, len(indexIDs))
+
+	for _, id := range indexIDs {
+		if _, ok := res[id]; ok {
+			continue
+		}
+
+		res[id] = indexMode{}
+		args = append(args, id)
+		ph = append(ph, fmt.Sprintf("$%d", len(args)))
+	}
+
+	q := `SELECT id, read_only, insert_only, no_history FROM index WHERE id IN (` + strings.Join(ph, ", ") +
+		`) ORDER BY id FOR SHARE`
+
+	rows, err := tx.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("db query index modes: %w", err)
+	}
+
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		id, m := uint64(0), indexMode{}
+		if err := rows.Scan(&id, &m.readOnly, &m.insertOnly, &m.noHistory); err != nil {
+			return nil, fmt.Errorf("db scan index modes: %w", err)
+		}
+
+		res[id] = m
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("db index modes rows: %w", err)
+	}
+
+	return res, nil
+}
+
+// recordLockKey returns the key of the advisory lock of a record, see lockRecords.
+func recordLockKey(indexID uint64, id string) int64 {
+	h := fnv.New64a()
+	_ = binary.Write(h, binary.LittleEndian, indexID)
+	_, _ = h.Write([]byte(id))
+
+	return int64(h.Sum64()) //nolint:gosec // wrapping is fine for a lock key
+}
+
+// lockRecords takes transaction-level advisory locks of the records with the given lock keys. Writes to insert-only
+// indices check whether records exist before writing them, and row locks don't cover records which don't exist yet, so
+// without these locks concurrent writes of a new record could both pass the check. Keys are locked in ascending order,
+// so transactions locking intersecting sets of records can't deadlock.
+func lockRecords(keys []int64, exec func(q string, args ...any) error) error {
+	keys = slices.Compact(slices.Sorted(slices.Values(keys)))
+
+	for start := 0; start < len(keys); start += pushChunkSize {
+		chunk := keys[start:min(start+pushChunkSize, len(keys))]
+
+		args := make([]any, 0, len(chunk))
+		for _, k := range chunk {
+			args = append(args, k)
+		}
+
+		q := `SELECT pg_advisory_xact_lock(v.k) FROM (VALUES ` + valuesList(len(chunk), "BIGINT") + `) v (k) ORDER BY v.k`
+		if err := exec(q, args...); err != nil {
+			return fmt.Errorf("db lock records: %w", err)
+		}
+	}
+
+	return nil
+}
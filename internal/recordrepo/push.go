@@ -5,64 +5,71 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/ashep/go-apperrors"
-	"github.com/rs/zerolog"
 )
 
-type statements struct {
-	getLog       *sql.Stmt
-	insertLog    *sql.Stmt
-	upsertRecord *sql.Stmt
-	touchRecord  *sql.Stmt
-}
+// pushChunkSize is the max number of updates handled by a single multi-row statement, it keeps the number of
+// statement parameters well below the PostgreSQL limit.
+const pushChunkSize = 1000
 
-func (s *statements) Close(l zerolog.Logger) {
-	if err := s.getLog.Close(); err != nil {
-		l.Error().Err(err).Msg("prepared statement close failed")
+// Push saves the updates within a single transaction. Updates which don't change records' data only touch them, the
+// rest are written to the history and upserted. Updates are handled set-wise, in chunks of a single statement each, so
+// the number of round trips doesn't depend on the number of updates.
+//
+// Modes of the records' indices are respected: records of read-only indices can't be pushed, records of insert-only
+// indices can't get data other than they have, and only the current revisions of no-history indices' records are kept.
+func (r *Repository) Push(ctx context.Context, updates []RecordUpdate) error {
+	if len(updates) == 0 {
+		return apperrors.InvalidArgError{Subj: "updates", Reason: "must not be empty"}
 	}
 
-	if err := s.insertLog.Close(); err != nil {
-		l.Error().Err(err).Msg("prepared statement close failed")
+	rounds, err := r.pushRounds(updates)
+	if err != nil {
+		return err
 	}
 
-	if err := s.upsertRecord.Close(); err != nil {
-		l.Error().Err(err).Msg("prepared statement close failed")
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("db begin: %w", err)
 	}
 
-	if err := s.touchRecord.Close(); err != nil {
-		l.Error().Err(err).Msg("prepared statement close failed")
-	}
-}
+	defer func() {
+		_ = tx.Rollback() // no-op after commit
+	}()
 
-func (r *Repository) Push(ctx context.Context, updates []RecordUpdate) error {
-	var err error
-
-	if len(updates) == 0 {
-		return apperrors.InvalidArgError{Subj: "updates", Reason: "must not be empty"}
+	indexIDs := make([]uint64, 0, len(updates))
+	for _, upd := range updates {
+		indexIDs = append(indexIDs, upd.IndexID)
 	}
 
-	tx, err := r.db.Begin()
+	modes, err := indexModes(ctx, tx, indexIDs)
 	if err != nil {
-		return fmt.Errorf("db begin: %w", err)
+		return err
 	}
 
-	stmt, err := r.prepareStatements(ctx, tx)
-	if err != nil {
-		_ = tx.Rollback()
-		return fmt.Errorf("prepare statements: %w", err)
-	}
+	lockKeys := make([]int64, 0)
 
-	defer stmt.Close(r.l)
+	for i, upd := range updates {
+		if modes[upd.IndexID].readOnly {
+			return fmt.Errorf("record %d, id=%s: %w", i, upd.ID, ErrReadOnly)
+		}
 
-	for i, rec := range updates {
-		if rec.IndexID == 0 {
-			return apperrors.InvalidArgError{Subj: fmt.Sprintf("record %d", i), Reason: "zero index id"}
+		if modes[upd.IndexID].insertOnly {
+			lockKeys = append(lockKeys, recordLockKey(upd.IndexID, upd.ID))
 		}
+	}
 
-		if err = r.upsertOrTouch(ctx, stmt, rec); err != nil {
-			_ = tx.Rollback()
-			return err
+	if err := lockRecords(lockKeys, txExec(ctx, tx)); err != nil {
+		return err
+	}
+
+	for _, round := range rounds {
+		for start := 0; start < len(round); start += pushChunkSize {
+			if err := r.pushModeChunk(ctx, tx, round[start:min(start+pushChunkSize, len(round))], modes); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -73,103 +80,192 @@ func (r *Repository) Push(ctx context.Context, updates []RecordUpdate) error {
 	return nil
 }
 
-func (r *Repository) prepareStatements(ctx context.Context, tx *sql.Tx) (*statements, error) {
-	getLog, err := tx.PrepareContext(ctx, `SELECT log_id FROM record WHERE checksum=$1`)
-	if err != nil {
-		return nil, fmt.Errorf("get record by log id: %w", err)
+// pushRounds checks the updates and splits them into rounds, so each record occurs at most once per round. A multi-row
+// upsert can't change a row twice, so repeated updates of a record go to subsequent rounds and are applied in the order
+// they were pushed.
+func (r *Repository) pushRounds(updates []RecordUpdate) ([][]RecordUpdate, error) {
+	type recordKey struct {
+		indexID uint64
+		id      string
 	}
 
-	insertLog, err := tx.PrepareContext(ctx, `INSERT INTO record_log (index_id, record_id, data)
-		VALUES ($1, $2, $3) RETURNING id`)
-	if err != nil {
-		return nil, fmt.Errorf("insert record log: %w", err)
-	}
+	seen := make(map[recordKey]int, len(updates))
+	rounds := make([][]RecordUpdate, 0, 1)
 
-	upsertRecord, err := tx.PrepareContext(ctx, `INSERT INTO record (id, index_id, log_id, checksum, data)
-VALUES ($1, $2, $3, $4, $5)
-ON CONFLICT (id, index_id) DO UPDATE SET log_id=$3, checksum=$4, data=$5, updated_at=now(), touched_at=now()`)
-	if err != nil {
-		return nil, fmt.Errorf("insert record: %w", err)
-	}
+	for i, upd := range updates {
+		if upd.IndexID == 0 {
+			return nil, apperrors.InvalidArgError{Subj: fmt.Sprintf("record %d", i), Reason: "zero index id"}
+		}
 
-	touchRecord, err := tx.PrepareContext(ctx, `UPDATE record SET touched_at=now() WHERE log_id=$1`)
-	if err != nil {
-		return nil, fmt.Errorf("update record touch time: %w", err)
+		if err := r.recordIDValidator.Validate(upd.ID); err != nil {
+			return nil, err //nolint:wrapcheck // ok
+		}
+
+		if upd.Data == "" {
+			return nil, apperrors.InvalidArgError{Subj: "record data", Reason: "must not be empty"}
+		}
+
+		k := recordKey{indexID: upd.IndexID, id: upd.ID}
+		n := seen[k]
+		seen[k] = n + 1
+
+		if n == len(rounds) {
+			rounds = append(rounds, make([]RecordUpdate, 0))
+		}
+
+		rounds[n] = append(rounds[n], upd)
 	}
 
-	return &statements{
-		getLog:       getLog,
-		insertLog:    insertLog,
-		upsertRecord: upsertRecord,
-		touchRecord:  touchRecord,
-	}, nil
+	return rounds, nil
 }
 
-func (r *Repository) upsertOrTouch(ctx context.Context, stmt *statements, upd RecordUpdate) error {
-	if err := r.recordIDValidator.Validate(upd.ID); err != nil {
-		return err //nolint:wrapcheck // ok
+// pushModeChunk pushes a chunk of updates, respecting the modes of the records' indices.
+func (r *Repository) pushModeChunk(
+	ctx context.Context,
+	tx *sql.Tx,
+	updates []RecordUpdate,
+	modes map[uint64]indexMode,
+) error {
+	insertOnly := make([]RecordUpdate, 0)
+	noHistory := make([]RecordUpdate, 0)
+
+	for _, upd := range updates {
+		if modes[upd.IndexID].insertOnly {
+			insertOnly = append(insertOnly, upd)
+		}
+
+		if modes[upd.IndexID].noHistory {
+			noHistory = append(noHistory, upd)
+		}
 	}
 
-	if upd.Data == "" {
-		return apperrors.InvalidArgError{Subj: "record data", Reason: "must not be empty"}
+	if len(insertOnly) != 0 {
+		if err := r.checkInsertOnly(ctx, tx, insertOnly); err != nil {
+			return err
+		}
 	}
 
-	row := stmt.getLog.QueryRowContext(ctx, upd.Checksum())
+	if err := r.pushChunk(ctx, tx, updates); err != nil {
+		return err
+	}
 
-	logID := uint64(0)
-	if err := row.Scan(&logID); errors.Is(err, sql.ErrNoRows) {
-		// There is no record with requested checksum exists, try to insert it
-		if err := r.upsert(ctx, stmt.insertLog, stmt.upsertRecord, upd); err != nil {
-			return fmt.Errorf("upsert record: %w", err)
-		}
-	} else if err != nil {
-		return fmt.Errorf("get record by checksum scan: %w", err)
-	} else {
-		// There is a record with the same checksum exists, just touch it
-		if err := r.touch(ctx, stmt.touchRecord, logID); err != nil {
-			return fmt.Errorf("touch record: %w", err)
+	if len(noHistory) != 0 {
+		if err := r.pruneHistory(ctx, tx, noHistory); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (r *Repository) upsert(
-	ctx context.Context,
-	insertLogStmt *sql.Stmt,
-	upsertRecordStmt *sql.Stmt,
-	upd RecordUpdate,
-) error {
-	var logID uint64
+// checkInsertOnly returns an error if any of the updates changes the data of an existing record. Updates with the same
+// data are allowed, they only touch records, so retries of pushes are safe. The records must be locked with
+// lockRecords, so they can't be created or changed concurrently between the check and the write.
+func (r *Repository) checkInsertOnly(ctx context.Context, tx *sql.Tx, updates []RecordUpdate) error {
+	args := make([]any, 0, len(updates)*3) //nolint:mnd // number of columns
+	for _, upd := range updates {
+		args = append(args, upd.ID, upd.IndexID, upd.Data)
+	}
+
+	q := `SELECT r.id FROM record r
+JOIN (VALUES ` + valuesList(len(updates), "VARCHAR", "BIGINT", "JSONB") + `) v (id, index_id, data)
+ON r.index_id = v.index_id AND r.id = v.id
+WHERE r.checksum <> ` + checksumExpr("v.data") + ` AND ` + notExpired + ` LIMIT 1`
 
-	row := insertLogStmt.QueryRowContext(ctx, upd.IndexID, upd.ID, upd.Data)
-	if err := row.Scan(&logID); err != nil && errors.Is(err, sql.ErrNoRows) {
+	id := ""
+
+	err := tx.QueryRowContext(ctx, q, args...).Scan(&id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
 		return nil
-	} else if err != nil {
-		return fmt.Errorf("insert log db query: %w", err)
+	case err != nil:
+		return fmt.Errorf("db check insert-only: %w", err)
 	}
 
-	if _, err := upsertRecordStmt.ExecContext(ctx, upd.ID, upd.IndexID, logID, upd.Checksum(), upd.Data); err != nil {
-		return fmt.Errorf("insert record db query: %w", err)
+	return errInsertOnly(id)
+}
+
+// pruneHistory deletes all the history of the updated records except their current revisions.
+func (r *Repository) pruneHistory(ctx context.Context, tx *sql.Tx, updates []RecordUpdate) error {
+	args := make([]any, 0, len(updates)*2) //nolint:mnd // number of columns
+	for _, upd := range updates {
+		args = append(args, upd.ID, upd.IndexID)
+	}
+
+	q := `DELETE FROM record_log l USING record r,
+(VALUES ` + valuesList(len(updates), "VARCHAR", "BIGINT") + `) v (id, index_id)
+WHERE r.index_id = v.index_id AND r.id = v.id AND l.index_id = r.index_id AND l.record_id = r.id AND l.id <> r.log_id`
+
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
+		return fmt.Errorf("db prune history: %w", err)
 	}
 
 	return nil
 }
 
-func (r *Repository) touch(ctx context.Context, stmt *sql.Stmt, logID uint64) error {
-	res, err := stmt.ExecContext(ctx, logID)
-	if err != nil {
+// pushChunk touches the records whose data didn't change, the rest of the updates are written to the history and
+// upserted. All parts of the statement see the records as they were before it, so the touched and the upserted ones
+// never intersect.
+func (r *Repository) pushChunk(ctx context.Context, tx *sql.Tx, updates []RecordUpdate) error {
+	args := make([]any, 0, len(updates)*4) //nolint:mnd // number of columns
+	for _, upd := range updates {
+		args = append(args, upd.ID, upd.IndexID, upd.Data, upd.ExpiresAt)
+	}
+
+	q := `WITH v (id, index_id, data, expires_at) AS (
+	VALUES ` + valuesList(len(updates), "VARCHAR", "BIGINT", "JSONB", "TIMESTAMP") + `
+), u AS (
+	SELECT v.*, ` + checksumExpr("v.data") + ` AS checksum FROM v
+), touched AS (
+	UPDATE record r SET touched_at=now(), expires_at=u.expires_at, stale_at=NULL FROM u
+	WHERE r.index_id = u.index_id AND r.id = u.id AND r.checksum = u.checksum
+), logs AS (
+	INSERT INTO record_log (index_id, record_id, data) SELECT u.index_id, u.id, u.data FROM u
+	WHERE NOT EXISTS (SELECT 1 FROM record r WHERE r.index_id = u.index_id AND r.id = u.id AND r.checksum = u.checksum)
+	RETURNING id, index_id, record_id
+)
+INSERT INTO record (id, index_id, log_id, checksum, data, expires_at)
+SELECT u.id, u.index_id, l.id, u.checksum, u.data, u.expires_at FROM logs l
+JOIN u ON u.index_id = l.index_id AND u.id = l.record_id
+ON CONFLICT (id, index_id) DO UPDATE SET log_id=EXCLUDED.log_id, checksum=EXCLUDED.checksum, data=EXCLUDED.data,
+expires_at=EXCLUDED.expires_at, stale_at=NULL, updated_at=now(), touched_at=now()`
+
+	if _, err := tx.ExecContext(ctx, q, args...); err != nil {
 		return fmt.Errorf("db exec: %w", err)
 	}
 
-	ra, err := res.RowsAffected()
-	if err != nil {
-		return fmt.Errorf("get db rows affected: %w", err)
+	return nil
+}
+
+// txExec returns the func executing statements within the transaction.
+func txExec(ctx context.Context, tx *sql.Tx) func(q string, args ...any) error {
+	return func(q string, args ...any) error {
+		_, err := tx.ExecContext(ctx, q, args...)
+		return err //nolint:wrapcheck // ok
 	}
+}
+
+// valuesList returns a VALUES list of n rows, each row has a typed placeholder per column.
+func valuesList(n int, types ...string) string {
+	b := strings.Builder{}
+
+	for i := range n {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteByte('(')
 
-	if ra == 0 {
-		return errors.New("no rows affected")
+		for j, t := range types {
+			if j != 0 {
+				b.WriteString(", ")
+			}
+
+			fmt.Fprintf(&b, "$%d::%s", i*len(types)+j+1, t)
+		}
+
+		b.WriteByte(')')
 	}
 
-	return nil
+	return b.String()
 }
@@ -3,8 +3,10 @@ package recordrepo_test
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ashep/go-apperrors"
@@ -12,10 +14,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ashep/ujds/internal/indexrepo"
 	"github.com/ashep/ujds/internal/recordrepo"
 )
 
-//nolint:maintidx // this is the test
 func TestRecordRepository_Push(tt *testing.T) {
 	tt.Run("EmptyUpdates", func(t *testing.T) {
 		indexNameValidator := &stringValidatorMock{}
@@ -33,110 +35,11 @@ func TestRecordRepository_Push(tt *testing.T) {
 		})
 	})
 
-	tt.Run("DbBeginError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-		recordIDValidator := &stringValidatorMock{}
-
-		db, dbm, err := sqlmock.New()
-		require.NoError(t, err)
-
-		dbm.ExpectBegin().WillReturnError(errors.New("theBeginError"))
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
-
-		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{{}})
-		require.EqualError(t, err, "db begin: theBeginError")
-	})
-
-	tt.Run("DbPrepareSelectError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-		recordIDValidator := &stringValidatorMock{}
-
-		db, dbm, err := sqlmock.New()
-		require.NoError(t, err)
-
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record").
-			WillReturnError(errors.New("thePrepareSelectError"))
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
-
-		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{{}})
-		require.EqualError(t, err, "prepare statements: get record by log id: thePrepareSelectError")
-	})
-
-	tt.Run("DbPrepareInsertRecordLogError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-		recordIDValidator := &stringValidatorMock{}
-
-		db, dbm, err := sqlmock.New()
-		require.NoError(t, err)
-
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log").
-			WillReturnError(errors.New("thePrepareInsertRecordLogError"))
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
-
-		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{{}})
-		require.EqualError(t, err, "prepare statements: insert record log: thePrepareInsertRecordLogError")
-	})
-
-	tt.Run("DbPrepareInsertRecordError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-		recordIDValidator := &stringValidatorMock{}
-
-		db, dbm, err := sqlmock.New()
-		require.NoError(t, err)
-
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`).
-			WillReturnError(errors.New("thePrepareInsertRecordError"))
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
-
-		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{{}})
-		require.EqualError(t, err, "prepare statements: insert record: thePrepareInsertRecordError")
-	})
-
-	tt.Run("DbPrepareTouchRecordError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-		recordIDValidator := &stringValidatorMock{}
-
-		db, dbm, err := sqlmock.New()
-		require.NoError(t, err)
-
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record `)
-		dbm.ExpectPrepare(`UPDATE record`).
-			WillReturnError(errors.New("thePrepareTouchRecordError"))
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
-
-		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{{}})
-		require.EqualError(t, err, "prepare statements: update record touch time: thePrepareTouchRecordError")
-	})
-
 	tt.Run("ZeroIndexID", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
-		recordIDValidator := &stringValidatorMock{}
-
-		db, dbm, err := sqlmock.New()
+		db, _, err := sqlmock.New()
 		require.NoError(t, err)
 
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
 			{IndexID: 0, ID: "theRecordID"},
@@ -145,24 +48,16 @@ func TestRecordRepository_Push(tt *testing.T) {
 	})
 
 	tt.Run("RecordIDValidationError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
 		recordIDValidator := &stringValidatorMock{}
 		recordIDValidator.ValidateFunc = func(s string) error {
 			assert.Equal(t, s, "theRecordID")
 			return errors.New("theRecordIDValidationError")
 		}
 
-		db, dbm, err := sqlmock.New()
+		db, _, err := sqlmock.New()
 		require.NoError(t, err)
 
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, recordIDValidator, zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
 			{IndexID: 123, ID: "theRecordID"},
@@ -171,24 +66,10 @@ func TestRecordRepository_Push(tt *testing.T) {
 	})
 
 	tt.Run("EmptyRecordData", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
-
-		db, dbm, err := sqlmock.New()
+		db, _, err := sqlmock.New()
 		require.NoError(t, err)
 
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
 			{IndexID: 123, ID: "theRecordID", Data: ""},
@@ -196,251 +77,246 @@ func TestRecordRepository_Push(tt *testing.T) {
 		require.EqualError(t, err, "invalid record data: must not be empty")
 	})
 
-	tt.Run("DbGetRecordByChecksumError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
-
+	tt.Run("DbBeginError", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
-		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		dbm.ExpectQuery("SELECT log_id FROM record").
-			WillReturnError(errors.New("theSelectError"))
+		dbm.ExpectBegin().WillReturnError(errors.New("theBeginError"))
 
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
 			{IndexID: 123, ID: "theRecordID", Data: `{"foo":"bar"}`},
 		})
-
-		require.EqualError(t, err, "get record by checksum scan: theSelectError")
+		require.EqualError(t, err, "db begin: theBeginError")
 	})
 
-	tt.Run("DbInsertLogError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
-
+	tt.Run("DbExecError", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
 		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectExec(`INSERT INTO record`).
+			WillReturnError(errors.New("theExecError"))
+		dbm.ExpectRollback()
 
-		dbm.ExpectQuery("SELECT log_id FROM record").
-			WillReturnError(sql.ErrNoRows)
-		dbm.ExpectQuery("INSERT INTO record_log").
-			WillReturnError(errors.New("theInsertLogError"))
-
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
 			{IndexID: 123, ID: "theRecordID", Data: `{"foo":"bar"}`},
 		})
-
-		require.EqualError(t, err, "upsert record: insert log db query: theInsertLogError")
+		require.EqualError(t, err, "db exec: theExecError")
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
-	tt.Run("DbInsertRecordError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
-
+	tt.Run("DbCommitError", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
-		inertRecLogRows := sqlmock.NewRows([]string{"id"})
-		inertRecLogRows.AddRow(234)
-
 		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		dbm.ExpectQuery("SELECT log_id FROM record").
-			WillReturnError(sql.ErrNoRows)
-		dbm.ExpectQuery("INSERT INTO record_log").
-			WillReturnRows(inertRecLogRows)
-		dbm.ExpectExec("INSERT INTO record").
-			WillReturnError(errors.New("theInsertRecordError"))
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectExec(`INSERT INTO record`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbm.ExpectCommit().
+			WillReturnError(errors.New("theCommitError"))
 
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
 			{IndexID: 123, ID: "theRecordID", Data: `{"foo":"bar"}`},
 		})
-
-		require.EqualError(t, err, "upsert record: insert record db query: theInsertRecordError")
+		require.EqualError(t, err, "commit: theCommitError")
 	})
 
-	tt.Run("DbCommitError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
+	tt.Run("ReadOnlyIndex", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
 
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123), uint64(234)}, []driver.Value{234, true, false, false})
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
+
+		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"bar"}`},
+			{IndexID: 234, ID: "theRecordID2", Data: `{"foo":"bar"}`},
+		})
+		require.ErrorAs(t, err, &indexrepo.ModeError{})
+		require.EqualError(t, err, "record 1, id=theRecordID2: index is read-only")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
 
+	tt.Run("DbLockRecordsError", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
-		inertRecLogRows := sqlmock.NewRows([]string{"id"})
-		inertRecLogRows.AddRow(234)
-
+		// Repeated updates of a record take a single lock
 		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		dbm.ExpectQuery("SELECT log_id FROM record").
-			WillReturnError(sql.ErrNoRows)
-		dbm.ExpectQuery("INSERT INTO record_log").
-			WillReturnRows(inertRecLogRows)
-		dbm.ExpectExec("INSERT INTO record").
-			WillReturnResult(sqlmock.NewResult(345, 1))
-		dbm.ExpectCommit().
-			WillReturnError(errors.New("theCommitError"))
+		expectIndexModes(dbm, []driver.Value{uint64(123)}, []driver.Value{123, false, true, false})
+		dbm.ExpectExec(`SELECT pg_advisory_xact_lock\(v.k\) FROM \(VALUES \(\$1::BIGINT\)\) v \(k\) ORDER BY v.k`).
+			WithArgs(sqlmock.AnyArg()).
+			WillReturnError(errors.New("theLockError"))
+		dbm.ExpectRollback()
 
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
-			{IndexID: 123, ID: "theRecordID", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"baz"}`},
 		})
-
-		require.EqualError(t, err, "commit: theCommitError")
+		require.EqualError(t, err, "db lock records: theLockError")
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
-	tt.Run("Ok", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
+	tt.Run("InsertOnlyIndexRecordChanged", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
 
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
+		// Only updates of insert-only indices are checked
+		dbm.ExpectBegin()
+		expectIndexModes(dbm, []driver.Value{uint64(123), uint64(234)}, []driver.Value{234, false, true, false})
+		expectLockRecords(dbm, 1)
+		dbm.ExpectQuery(`SELECT r.id FROM record r
+JOIN \(VALUES \(\$1::VARCHAR, \$2::BIGINT, \$3::JSONB\)\) v \(id, index_id, data\)
+ON r.index_id = v.index_id AND r.id = v.id
+WHERE r.checksum <> sha256\(convert_to\(v.data::TEXT, 'UTF8'\)\) AND \(r.expires_at IS NULL OR r.expires_at > now\(\)\) LIMIT 1`).
+			WithArgs("theRecordID2", uint64(234), `{"foo":"bar"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("theRecordID2"))
+		dbm.ExpectRollback()
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
+		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"bar"}`},
+			{IndexID: 234, ID: "theRecordID2", Data: `{"foo":"bar"}`},
+		})
+		require.EqualError(t, err, "record theRecordID2 exists in an insert-only index")
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+
+	tt.Run("OkInsertOnlyAndNoHistoryIndices", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
-		inertRecLogRows := sqlmock.NewRows([]string{"id"})
-		inertRecLogRows.AddRow(234)
-
 		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		dbm.ExpectQuery("SELECT log_id FROM record").
-			WillReturnError(sql.ErrNoRows)
-		dbm.ExpectQuery("INSERT INTO record_log").
-			WillReturnRows(inertRecLogRows)
-		dbm.ExpectExec("INSERT INTO record").
-			WillReturnResult(sqlmock.NewResult(345, 1))
+		expectIndexModes(dbm, []driver.Value{uint64(123), uint64(234)},
+			[]driver.Value{123, false, true, false},
+			[]driver.Value{234, false, false, true},
+		)
+		expectLockRecords(dbm, 1)
+		dbm.ExpectQuery(`SELECT r.id FROM record r`).
+			WithArgs("theRecordID1", uint64(123), `{"foo":"bar"}`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WithArgs("theRecordID1", uint64(123), `{"foo":"bar"}`, sql.NullTime{},
+				"theRecordID2", uint64(234), `{"foo":"bar"}`, sql.NullTime{}).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		dbm.ExpectExec(`DELETE FROM record_log l USING record r,
+\(VALUES \(\$1::VARCHAR, \$2::BIGINT\)\) v \(id, index_id\)
+WHERE r.index_id = v.index_id AND r.id = v.id AND l.index_id = r.index_id AND l.record_id = r.id AND l.id <> r.log_id`).
+			WithArgs("theRecordID2", uint64(234)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		dbm.ExpectCommit()
 
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
-			{IndexID: 123, ID: "theRecordID", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"bar"}`},
+			{IndexID: 234, ID: "theRecordID2", Data: `{"foo":"bar"}`},
 		})
-
 		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
-	tt.Run("DbUpdateTouchedAtExecError", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
-
+	tt.Run("Ok", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
-		selectLogRows := sqlmock.NewRows([]string{"log_id"})
-		selectLogRows.AddRow(234)
+		expiresAt := sql.NullTime{Time: time.Unix(123, 0), Valid: true}
 
+		// Data is compared with the checksum of the same record only
 		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		dbm.ExpectQuery(`SELECT log_id FROM record`).
-			WillReturnRows(selectLogRows)
-		dbm.ExpectExec(`UPDATE record SET touched_at`).
-			WillReturnError(errors.New("theDbUpdateTouchedAtError"))
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectExec(`WITH v \(id, index_id, data, expires_at\) AS \(
+	VALUES \(\$1::VARCHAR, \$2::BIGINT, \$3::JSONB, \$4::TIMESTAMP\), \(\$5::VARCHAR, \$6::BIGINT, \$7::JSONB, \$8::TIMESTAMP\)
+\), u AS \(
+	SELECT v.\*, sha256\(convert_to\(v.data::TEXT, 'UTF8'\)\) AS checksum FROM v
+\), touched AS \(
+	UPDATE record r SET touched_at=now\(\), expires_at=u.expires_at, stale_at=NULL FROM u
+	WHERE r.index_id = u.index_id AND r.id = u.id AND r.checksum = u.checksum
+\), logs AS \(
+	INSERT INTO record_log .+ FROM u
+	WHERE NOT EXISTS \(SELECT 1 FROM record r WHERE r.index_id = u.index_id AND r.id = u.id AND r.checksum = u.checksum\)`).
+			WithArgs("theRecordID1", uint64(123), `{"foo":"bar"}`, sql.NullTime{},
+				"theRecordID2", uint64(123), `{"foo":"bar"}`, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		dbm.ExpectCommit()
 
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
-			{IndexID: 123, ID: "theRecordID", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID2", Data: `{"foo":"bar"}`, ExpiresAt: expiresAt},
 		})
-
-		require.EqualError(t, err, "touch record: db exec: theDbUpdateTouchedAtError")
+		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 
-	tt.Run("OkRecordAlreadyExists", func(t *testing.T) {
-		indexNameValidator := &stringValidatorMock{}
-
-		recordIDValidator := &stringValidatorMock{}
-		recordIDValidator.ValidateFunc = func(s string) error {
-			assert.Equal(t, s, "theRecordID")
-			return nil
-		}
-
+	tt.Run("OkRepeatedRecord", func(t *testing.T) {
 		db, dbm, err := sqlmock.New()
 		require.NoError(t, err)
 
-		selectLogRows := sqlmock.NewRows([]string{"log_id"})
-		selectLogRows.AddRow(234)
-
+		// The second update of a record is applied after the first one, within its own round
 		dbm.ExpectBegin()
-		dbm.ExpectPrepare("SELECT log_id FROM record")
-		dbm.ExpectPrepare("INSERT INTO record_log")
-		dbm.ExpectPrepare(`INSERT INTO record`)
-		dbm.ExpectPrepare(`UPDATE record`)
-
-		dbm.ExpectQuery(`SELECT log_id FROM record`).
-			WillReturnRows(selectLogRows)
-		dbm.ExpectExec(`UPDATE record SET touched_at=now()`).
-			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectIndexModes(dbm, []driver.Value{uint64(123)})
+		dbm.ExpectExec(`INSERT INTO record`).
+			WithArgs("theRecordID1", uint64(123), `{"foo":"bar"}`, sql.NullTime{},
+				"theRecordID2", uint64(123), `{"foo":"bar"}`, sql.NullTime{}).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		dbm.ExpectExec(`INSERT INTO record`).
+			WithArgs("theRecordID1", uint64(123), `{"foo":"baz"}`, sql.NullTime{}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		dbm.ExpectCommit()
 
-		repo := recordrepo.New(db, indexNameValidator, recordIDValidator, zerolog.Nop())
+		repo := recordrepo.New(db, &stringValidatorMock{}, okRecordIDValidator(), zerolog.Nop())
 
 		err = repo.Push(context.Background(), []recordrepo.RecordUpdate{
-			{IndexID: 123, ID: "theRecordID", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID2", Data: `{"foo":"bar"}`},
+			{IndexID: 123, ID: "theRecordID1", Data: `{"foo":"baz"}`},
 		})
-
 		require.NoError(t, err)
+		require.NoError(t, dbm.ExpectationsWereMet())
 	})
 }
+
+// expectIndexModes expects the query of the indices' modes. Each mode is a row of an index ID, read_only, insert_only
+// and no_history values; indices without a row get the default mode.
+func expectIndexModes(dbm sqlmock.Sqlmock, indexIDs []driver.Value, modes ...[]driver.Value) {
+	rows := sqlmock.NewRows([]string{"id", "read_only", "insert_only", "no_history"})
+	for _, m := range modes {
+		rows.AddRow(m...)
+	}
+
+	dbm.ExpectQuery(`SELECT id, read_only, insert_only, no_history FROM index WHERE id IN \(.+\) ORDER BY id FOR SHARE`).
+		WithArgs(indexIDs...).
+		WillReturnRows(rows)
+}
+
+// expectLockRecords expects locking of n records of insert-only indices.
+func expectLockRecords(dbm sqlmock.Sqlmock, n int) {
+	args := make([]driver.Value, n)
+	for i := range args {
+		args[i] = sqlmock.AnyArg()
+	}
+
+	dbm.ExpectExec(`SELECT pg_advisory_xact_lock\(v.k\) FROM \(VALUES .+\) v \(k\) ORDER BY v.k`).
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func okRecordIDValidator() *stringValidatorMock {
+	return &stringValidatorMock{ValidateFunc: func(string) error { return nil }}
+}
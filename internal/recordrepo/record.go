@@ -1,26 +1,26 @@
 package recordrepo
 
 import (
-	"crypto/sha256"
-	"encoding/binary"
+	"database/sql"
 	"time"
 )
 
-type RecordUpdate struct {
-	ID      string
-	IndexID uint64
-	Data    string
-}
+// notExpired is the SQL condition which excludes records that have expired but have not been deleted yet.
+const notExpired = `(r.expires_at IS NULL OR r.expires_at > now())`
 
-func (rec *RecordUpdate) Checksum() []byte {
-	indexIDBytes := make([]byte, 8) //nolint:mnd // it's ok
-	binary.LittleEndian.PutUint64(indexIDBytes, rec.IndexID)
-
-	sumSrc := append([]byte(rec.Data), indexIDBytes...)
-	sumSrc = append(sumSrc, []byte(rec.ID)...)
-	sum := sha256.Sum256(sumSrc)
+// checksumExpr returns the SQL expression of the checksum of JSONB data. A record's checksum is the hash of its data
+// only; the data is hashed in the canonical form JSONB is stored in, so the same documents have the same checksum
+// regardless of their formatting. Pushed data is compared with the checksum of the same record, never with other
+// records' ones.
+func checksumExpr(data string) string {
+	return "sha256(convert_to(" + data + "::TEXT, 'UTF8'))"
+}
 
-	return sum[:]
+type RecordUpdate struct {
+	ID        string
+	IndexID   uint64
+	Data      string
+	ExpiresAt sql.NullTime // not a part of the checksum, pushing the same data only moves the expiry time
 }
 
 type Record struct {
@@ -31,4 +31,6 @@ type Record struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	TouchedAt time.Time
+	ExpiresAt sql.NullTime
+	StaleAt   sql.NullTime // when the record was flagged as stale by the sweeper
 }
@@ -0,0 +1,53 @@
+package recordrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+)
+
+// DeleteNotTouched deletes a batch of index's records which have not been touched since the given time. The history of
+// deleted records is kept. Records of read-only and insert-only indices are never deleted. It returns the number of
+// deleted records.
+func (r *Repository) DeleteNotTouched(ctx context.Context, indexID uint64, since time.Time, limit uint32) (uint64, error) {
+	q := `DELETE FROM record WHERE (id, index_id) IN (
+SELECT r.id, r.index_id FROM record r JOIN index i ON i.id=r.index_id
+WHERE r.index_id=$1 AND r.touched_at < $2 AND NOT (i.read_only OR i.insert_only) LIMIT $3 FOR SHARE OF i)`
+
+	return r.sweepNotTouched(ctx, q, indexID, since, limit)
+}
+
+// FlagNotTouched marks a batch of index's records which have not been touched since the given time as stale. Records
+// which are already flagged are skipped. It returns the number of flagged records.
+func (r *Repository) FlagNotTouched(ctx context.Context, indexID uint64, since time.Time, limit uint32) (uint64, error) {
+	q := `UPDATE record SET stale_at=now() WHERE (id, index_id) IN (
+SELECT id, index_id FROM record WHERE index_id=$1 AND touched_at < $2 AND stale_at IS NULL LIMIT $3)`
+
+	return r.sweepNotTouched(ctx, q, indexID, since, limit)
+}
+
+func (r *Repository) sweepNotTouched(
+	ctx context.Context,
+	q string,
+	indexID uint64,
+	since time.Time,
+	limit uint32,
+) (uint64, error) {
+	if limit == 0 {
+		return 0, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"}
+	}
+
+	res, err := r.db.ExecContext(ctx, q, indexID, since.UTC(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("db exec: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("get db rows affected: %w", err)
+	}
+
+	return uint64(n), nil //nolint:gosec // ok
+}
@@ -0,0 +1,107 @@
+package recordrepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ashep/go-apperrors"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+)
+
+func TestRecordRepository_DeleteNotTouched(tt *testing.T) {
+	since := time.Unix(123, 0).UTC()
+
+	tt.Run("ZeroLimit", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.DeleteNotTouched(context.Background(), 1, since, 0)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"})
+	})
+
+	tt.Run("DbExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM record`).
+			WithArgs(1, since, 100).
+			WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.DeleteNotTouched(context.Background(), 1, since, 100)
+
+		assert.EqualError(t, err, "db exec: theDbError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`DELETE FROM record WHERE \(id, index_id\) IN \(\s*SELECT r.id, r.index_id FROM record r `+
+			`JOIN index i ON i.id=r.index_id\s*WHERE r.index_id=\$1 AND r.touched_at < \$2 `+
+			`AND NOT \(i.read_only OR i.insert_only\) LIMIT \$3 FOR SHARE OF i\)`).
+			WithArgs(1, since, 100).
+			WillReturnResult(sqlmock.NewResult(0, 42))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		n, err := repo.DeleteNotTouched(context.Background(), 1, since, 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(42), n)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
+
+func TestRecordRepository_FlagNotTouched(tt *testing.T) {
+	since := time.Unix(123, 0).UTC()
+
+	tt.Run("ZeroLimit", func(t *testing.T) {
+		db, _, err := sqlmock.New()
+		require.NoError(t, err)
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.FlagNotTouched(context.Background(), 1, since, 0)
+
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{Subj: "limit", Reason: "must not be zero"})
+	})
+
+	tt.Run("DbExecError", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE record`).
+			WithArgs(1, since, 100).
+			WillReturnError(errors.New("theDbError"))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		_, err = repo.FlagNotTouched(context.Background(), 1, since, 100)
+
+		assert.EqualError(t, err, "db exec: theDbError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		db, dbm, err := sqlmock.New()
+		require.NoError(t, err)
+
+		dbm.ExpectExec(`UPDATE record SET stale_at=now\(\) WHERE \(id, index_id\) IN \(\s*SELECT id, index_id FROM record `+
+			`WHERE index_id=\$1 AND touched_at < \$2 AND stale_at IS NULL LIMIT \$3\)`).
+			WithArgs(1, since, 100).
+			WillReturnResult(sqlmock.NewResult(0, 7))
+
+		repo := recordrepo.New(db, &stringValidatorMock{}, &stringValidatorMock{}, zerolog.Nop())
+		n, err := repo.FlagNotTouched(context.Background(), 1, since, 100)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(7), n)
+		require.NoError(t, dbm.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,214 @@
+package recordsweeper
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/ashep/go-app/prommetrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+)
+
+// Sweep policy actions.
+const (
+	ActionDelete = "delete"
+	ActionFlag   = "flag"
+)
+
+// Policy defines what to do with records of indices whose names match a pattern if they have not been touched for
+// a while.
+type Policy struct {
+	NotTouchedFor string `json:"not_touched_for" yaml:"not_touched_for"` // duration, e.g. "72h"
+	Action        string `json:"action" yaml:"action"`                   // "delete" or "flag"
+}
+
+type policy struct {
+	pattern       string
+	indexRe       *regexp.Regexp
+	notTouchedFor time.Duration
+	action        string
+}
+
+type indexRepo interface {
+	List(ctx context.Context, req indexrepo.ListRequest) ([]indexrepo.Index, string, error)
+}
+
+type recordRepo interface {
+	DeleteNotTouched(ctx context.Context, indexID uint64, since time.Time, limit uint32) (uint64, error)
+	FlagNotTouched(ctx context.Context, indexID uint64, since time.Time, limit uint32) (uint64, error)
+}
+
+// Sweeper periodically deletes or flags records which have not been touched for longer than their index's policy
+// allows.
+type Sweeper struct {
+	ir        indexRepo
+	rr        recordRepo
+	policies  []policy
+	interval  time.Duration
+	batchSize uint32
+	now       func() time.Time
+	l         zerolog.Logger
+}
+
+// New creates a sweeper. The policies map binds sweep policies to index name regexp patterns. The sweeper runs every
+// interval and processes batchSize records per database query.
+func New(
+	ir indexRepo,
+	rr recordRepo,
+	policies map[string]Policy,
+	interval time.Duration,
+	batchSize uint32,
+	now func() time.Time,
+	l zerolog.Logger,
+) (*Sweeper, error) {
+	res := make([]policy, 0, len(policies))
+
+	for pattern, pol := range policies {
+		p := policy{pattern: pattern, action: pol.Action}
+
+		var err error
+		if p.indexRe, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("sweep policy %q: %w", pattern, err)
+		}
+
+		if p.notTouchedFor, err = time.ParseDuration(pol.NotTouchedFor); err != nil {
+			return nil, fmt.Errorf("sweep policy %q: not touched for: %w", pattern, err)
+		}
+
+		if p.notTouchedFor <= 0 {
+			return nil, fmt.Errorf("sweep policy %q: not touched for: must be positive", pattern)
+		}
+
+		switch pol.Action {
+		case ActionDelete, ActionFlag:
+		default:
+			return nil, fmt.Errorf("sweep policy %q: unknown action: %s", pattern, pol.Action)
+		}
+
+		res = append(res, p)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].pattern < res[j].pattern })
+
+	return &Sweeper{
+		ir:        ir,
+		rr:        rr,
+		policies:  res,
+		interval:  interval,
+		batchSize: batchSize,
+		now:       now,
+		l:         l,
+	}, nil
+}
+
+// PolicyFor returns the sweep policy applied to an index. If several policies match the index name, the most
+// conservative one is chosen: the one with the longest threshold, and flagging rather than deleting on a tie.
+func (s *Sweeper) PolicyFor(index string) (time.Duration, string, bool) {
+	var res *policy
+
+	for i := range s.policies {
+		p := &s.policies[i]
+		if !p.indexRe.MatchString(index) {
+			continue
+		}
+
+		if res == nil || p.notTouchedFor > res.notTouchedFor ||
+			(p.notTouchedFor == res.notTouchedFor && p.action == ActionFlag) {
+			res = p
+		}
+	}
+
+	if res == nil {
+		return 0, "", false
+	}
+
+	return res.notTouchedFor, res.action, true
+}
+
+// Sweep applies sweep policies to records of all the indices and returns the number of swept records.
+func (s *Sweeper) Sweep(ctx context.Context) (uint64, error) {
+	if len(s.policies) == 0 {
+		return 0, nil
+	}
+
+	indices, _, err := s.ir.List(ctx, indexrepo.ListRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("list indices: %w", err)
+	}
+
+	total := uint64(0)
+
+	for _, idx := range indices {
+		d, action, ok := s.PolicyFor(idx.Name)
+		if !ok {
+			continue
+		}
+
+		n, err := s.sweepIndex(ctx, idx, s.now().Add(-d), action)
+		total += n
+
+		if n > 0 {
+			s.l.Info().Str("index", idx.Name).Str("action", action).Uint64("count", n).Msg("stale records swept")
+		}
+
+		if err != nil {
+			return total, fmt.Errorf("sweep index %s: %w", idx.Name, err)
+		}
+	}
+
+	return total, nil
+}
+
+func (s *Sweeper) sweepIndex(ctx context.Context, idx indexrepo.Index, since time.Time, action string) (uint64, error) {
+	sweep := s.rr.DeleteNotTouched
+	if action == ActionFlag {
+		sweep = s.rr.FlagNotTouched
+	}
+
+	lbs := prometheus.Labels{"index": idx.Name, "action": action}
+	total := uint64(0)
+
+	for {
+		n, err := sweep(ctx, idx.ID, since, s.batchSize)
+		if err != nil {
+			return total, fmt.Errorf("%s not touched records: %w", action, err)
+		}
+
+		total += n
+
+		if n > 0 {
+			prommetrics.GetCounter("ujds_sweeper_records_swept_total", "Number of stale records swept.", lbs).
+				With(lbs).Add(float64(n))
+		}
+
+		if n < uint64(s.batchSize) {
+			return total, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return total, err //nolint:wrapcheck // ok
+		}
+	}
+}
+
+// Run sweeps stale records every interval. It blocks until the context is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if _, err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+				s.l.Error().Err(err).Msg("stale records sweep failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package recordsweeper_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordsweeper"
+)
+
+type indexRepoMock struct {
+	indices []indexrepo.Index
+	err     error
+}
+
+func (m *indexRepoMock) List(_ context.Context, _ indexrepo.ListRequest) ([]indexrepo.Index, string, error) {
+	return m.indices, "", m.err
+}
+
+type sweepCall struct {
+	action  string
+	indexID uint64
+	since   time.Time
+	limit   uint32
+}
+
+type recordRepoMock struct {
+	mu      sync.Mutex
+	results []uint64 // number of records swept by consecutive calls
+	err     error
+	calls   []sweepCall
+}
+
+func (m *recordRepoMock) DeleteNotTouched(_ context.Context, indexID uint64, since time.Time, limit uint32) (uint64, error) {
+	return m.sweep("delete", indexID, since, limit)
+}
+
+func (m *recordRepoMock) FlagNotTouched(_ context.Context, indexID uint64, since time.Time, limit uint32) (uint64, error) {
+	return m.sweep("flag", indexID, since, limit)
+}
+
+func (m *recordRepoMock) sweep(action string, indexID uint64, since time.Time, limit uint32) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls = append(m.calls, sweepCall{action: action, indexID: indexID, since: since, limit: limit})
+
+	if len(m.results) == 0 {
+		return 0, m.err
+	}
+
+	n := m.results[0]
+	m.results = m.results[1:]
+
+	return n, nil
+}
+
+func (m *recordRepoMock) callsNum() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.calls)
+}
+
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.b.String()
+}
+
+func now() time.Time {
+	return time.Unix(100000, 0)
+}
+
+func TestNew(tt *testing.T) {
+	tt.Run("InvalidPattern", func(t *testing.T) {
+		_, err := recordsweeper.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordsweeper.Policy{
+			"(": {NotTouchedFor: "1h", Action: "delete"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.ErrorContains(t, err, `sweep policy "(": error parsing regexp`)
+	})
+
+	tt.Run("InvalidDuration", func(t *testing.T) {
+		_, err := recordsweeper.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordsweeper.Policy{
+			"foo": {NotTouchedFor: "abc", Action: "delete"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `sweep policy "foo": not touched for: time: invalid duration "abc"`)
+	})
+
+	tt.Run("NonPositiveDuration", func(t *testing.T) {
+		_, err := recordsweeper.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordsweeper.Policy{
+			"foo": {NotTouchedFor: "0s", Action: "delete"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `sweep policy "foo": not touched for: must be positive`)
+	})
+
+	tt.Run("UnknownAction", func(t *testing.T) {
+		_, err := recordsweeper.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordsweeper.Policy{
+			"foo": {NotTouchedFor: "1h", Action: "archive"},
+		}, time.Minute, 10, now, zerolog.Nop())
+
+		assert.EqualError(t, err, `sweep policy "foo": unknown action: archive`)
+	})
+}
+
+func TestSweeper_PolicyFor(tt *testing.T) {
+	s, err := recordsweeper.New(&indexRepoMock{}, &recordRepoMock{}, map[string]recordsweeper.Policy{
+		"^foo":    {NotTouchedFor: "1h", Action: "delete"},
+		"^foo.ba": {NotTouchedFor: "2h", Action: "delete"},
+		"bar$":    {NotTouchedFor: "2h", Action: "flag"},
+	}, time.Minute, 10, now, zerolog.Nop())
+	require.NoError(tt, err)
+
+	tt.Run("NoMatch", func(t *testing.T) {
+		_, _, ok := s.PolicyFor("baz")
+		assert.False(t, ok)
+	})
+
+	tt.Run("SingleMatch", func(t *testing.T) {
+		d, action, ok := s.PolicyFor("foo")
+		require.True(t, ok)
+		assert.Equal(t, time.Hour, d)
+		assert.Equal(t, "delete", action)
+	})
+
+	tt.Run("LongestThreshold", func(t *testing.T) {
+		d, action, ok := s.PolicyFor("foo.baz")
+		require.True(t, ok)
+		assert.Equal(t, 2*time.Hour, d)
+		assert.Equal(t, "delete", action)
+	})
+
+	tt.Run("FlagOnTie", func(t *testing.T) {
+		d, action, ok := s.PolicyFor("foo.bar")
+		require.True(t, ok)
+		assert.Equal(t, 2*time.Hour, d)
+		assert.Equal(t, "flag", action)
+	})
+}
+
+func TestSweeper_Sweep(tt *testing.T) {
+	policies := map[string]recordsweeper.Policy{
+		"^foo$": {NotTouchedFor: "1h", Action: "delete"},
+		"^bar$": {NotTouchedFor: "2h", Action: "flag"},
+	}
+
+	tt.Run("IndexRepoError", func(t *testing.T) {
+		ir := &indexRepoMock{err: errors.New("theIndexRepoError")}
+
+		s, err := recordsweeper.New(ir, &recordRepoMock{}, policies, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		_, err = s.Sweep(context.Background())
+		assert.EqualError(t, err, "list indices: theIndexRepoError")
+	})
+
+	tt.Run("RecordRepoError", func(t *testing.T) {
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{results: []uint64{10}, err: errors.New("theRecordRepoError")}
+
+		s, err := recordsweeper.New(ir, rr, policies, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		n, err := s.Sweep(context.Background())
+		assert.EqualError(t, err, "sweep index foo: delete not touched records: theRecordRepoError")
+		assert.Equal(t, uint64(10), n)
+	})
+
+	tt.Run("NoPolicies", func(t *testing.T) {
+		ir := &indexRepoMock{err: errors.New("theIndexRepoError")}
+		rr := &recordRepoMock{}
+
+		s, err := recordsweeper.New(ir, rr, nil, time.Minute, 10, now, zerolog.Nop())
+		require.NoError(t, err)
+
+		n, err := s.Sweep(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		assert.Zero(t, rr.callsNum())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir := &indexRepoMock{indices: []indexrepo.Index{
+			{ID: 1, Name: "foo"},
+			{ID: 2, Name: "baz"},
+			{ID: 3, Name: "bar"},
+		}}
+		rr := &recordRepoMock{results: []uint64{10, 2, 3}}
+
+		s, err := recordsweeper.New(ir, rr, policies, time.Minute, 10, now, zerolog.New(lb))
+		require.NoError(t, err)
+
+		n, err := s.Sweep(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(15), n)
+
+		assert.Equal(t, []sweepCall{
+			{action: "delete", indexID: 1, since: now().Add(-time.Hour), limit: 10},
+			{action: "delete", indexID: 1, since: now().Add(-time.Hour), limit: 10},
+			{action: "flag", indexID: 3, since: now().Add(-2 * time.Hour), limit: 10},
+		}, rr.calls)
+
+		assert.Equal(t,
+			`{"level":"info","index":"foo","action":"delete","count":12,"message":"stale records swept"}`+"\n"+
+				`{"level":"info","index":"bar","action":"flag","count":3,"message":"stale records swept"}`+"\n",
+			lb.String())
+	})
+}
+
+func TestSweeper_Run(tt *testing.T) {
+	tt.Run("SweepError", func(t *testing.T) {
+		lb := &syncBuilder{}
+		ir := &indexRepoMock{indices: []indexrepo.Index{{ID: 1, Name: "foo"}}}
+		rr := &recordRepoMock{err: errors.New("theRecordRepoError")}
+
+		s, err := recordsweeper.New(ir, rr, map[string]recordsweeper.Policy{
+			"foo": {NotTouchedFor: "1h", Action: "flag"},
+		}, time.Millisecond*10, 10, now, zerolog.New(lb))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan struct{})
+		go func() {
+			s.Run(ctx)
+			close(done)
+		}()
+
+		require.Eventually(t, func() bool { return rr.callsNum() >= 2 }, time.Second, time.Millisecond*5)
+		cancel()
+		<-done
+
+		assert.Contains(t, lb.String(), `{"level":"error","error":"sweep index foo: flag not touched records: `+
+			`theRecordRepoError","message":"stale records sweep failed"}`)
+	})
+}
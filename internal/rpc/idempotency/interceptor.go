@@ -0,0 +1,232 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/idempotencyrepo"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// KeyHeader is the request header the idempotency key is passed in.
+	KeyHeader = "Idempotency-Key"
+
+	// ReplayedHeader is the response header set to "true" if the response is a replay of a stored one.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	keyLenMax = 255
+)
+
+type keyRepo interface {
+	Reserve(
+		ctx context.Context,
+		key, procedure string,
+		requestHash []byte,
+		window, lease time.Duration,
+	) (idempotencyrepo.Key, bool, error)
+	Complete(ctx context.Context, key idempotencyrepo.Key, response []byte) error
+	Release(ctx context.Context, key idempotencyrepo.Key) error
+	DeleteExpired(ctx context.Context, window time.Duration) (uint64, error)
+}
+
+// ReplayFunc makes a response of a procedure from a stored one.
+type ReplayFunc func(b []byte) (connect.AnyResponse, error)
+
+// Replay returns the ReplayFunc of procedures responding with messages of type T.
+func Replay[T any, PT interface {
+	*T
+	proto.Message
+}]() ReplayFunc {
+	return func(b []byte) (connect.AnyResponse, error) {
+		msg := PT(new(T))
+		if err := proto.Unmarshal(b, msg); err != nil {
+			return nil, fmt.Errorf("unmarshal response: %w", err)
+		}
+
+		return connect.NewResponse((*T)(msg)), nil
+	}
+}
+
+// Interceptor makes unary procedures idempotent. The first successful response to a request with an idempotency key
+// is stored for the window and replayed to the requests with the same key and procedure; reusing a key with a
+// different request is rejected. Failed requests are not stored, so they can be retried with the same key. A request
+// which is not completed within the lease, e.g. because the server has crashed, can be retried with the same key too.
+type Interceptor struct {
+	repo       keyRepo
+	window     time.Duration
+	lease      time.Duration
+	procedures map[string]ReplayFunc
+	now        func() time.Time
+	l          zerolog.Logger
+}
+
+// New creates an interceptor applied to the given procedures only.
+func New(
+	repo keyRepo,
+	window, lease time.Duration,
+	procedures map[string]ReplayFunc,
+	now func() time.Time,
+	l zerolog.Logger,
+) *Interceptor {
+	return &Interceptor{
+		repo:       repo,
+		window:     window,
+		lease:      lease,
+		procedures: procedures,
+		now:        now,
+		l:          l,
+	}
+}
+
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		key := req.Header().Get(KeyHeader)
+		replay, ok := i.procedures[req.Spec().Procedure]
+
+		if key == "" || !ok {
+			return next(ctx, req)
+		}
+
+		if len(key) > keyLenMax {
+			return nil, connect.NewError(connect.CodeInvalidArgument,
+				apperrors.InvalidArgError{Subj: KeyHeader, Reason: fmt.Sprintf("must not be longer than %d", keyLenMax)})
+		}
+
+		return i.handle(ctx, req, key, replay, next)
+	}
+}
+
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+func (i *Interceptor) handle(
+	ctx context.Context,
+	req connect.AnyRequest,
+	key string,
+	replay ReplayFunc,
+	next connect.UnaryFunc,
+) (connect.AnyResponse, error) {
+	proc := req.Spec().Procedure
+
+	msg, ok := req.Any().(proto.Message)
+	if !ok {
+		return nil, i.newInternalError(req, errors.New("request is not a proto message"), "request hash failed")
+	}
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, i.newInternalError(req, err, "request hash failed")
+	}
+
+	hash := sha256.Sum256(b)
+
+	stored, reserved, err := i.repo.Reserve(ctx, key, proc, hash[:], i.window, i.lease)
+	if errors.Is(err, idempotencyrepo.ErrReleased) {
+		return nil, connect.NewError(connect.CodeAborted,
+			errors.New("request with the same idempotency key has just failed, retry it"))
+	} else if err != nil {
+		return nil, i.newInternalError(req, err, "idempotency key reserve failed")
+	}
+
+	if !reserved {
+		return i.replay(req, stored, hash[:], replay)
+	}
+
+	// The request is processed, so the key is updated even if the client is gone
+	ctx2 := context.WithoutCancel(ctx)
+
+	res, err := next(ctx, req)
+	if err != nil {
+		if rErr := i.repo.Release(ctx2, stored); rErr != nil {
+			i.l.Error().Err(rErr).Str("proc", proc).Msg("idempotency key release failed")
+		}
+
+		return nil, err
+	}
+
+	resMsg, ok := res.Any().(proto.Message)
+	if !ok {
+		i.l.Error().Str("proc", proc).Msg("response is not a proto message")
+		return res, nil
+	}
+
+	b, err = proto.Marshal(resMsg)
+	if err == nil {
+		err = i.repo.Complete(ctx2, stored, b)
+	}
+
+	// The request succeeded, so its response is returned anyway; retries with the key fail until it expires
+	if err != nil {
+		i.l.Error().Err(err).Str("proc", proc).Msg("idempotency key complete failed")
+	}
+
+	return res, nil
+}
+
+func (i *Interceptor) replay(
+	req connect.AnyRequest,
+	stored idempotencyrepo.Key,
+	hash []byte,
+	replay ReplayFunc,
+) (connect.AnyResponse, error) {
+	if !bytes.Equal(stored.RequestHash, hash) {
+		return nil, connect.NewError(connect.CodeInvalidArgument,
+			apperrors.InvalidArgError{Subj: KeyHeader, Reason: "already used with a different request"})
+	}
+
+	if stored.Response == nil {
+		return nil, connect.NewError(connect.CodeAborted,
+			errors.New("request with the same idempotency key is in progress"))
+	}
+
+	res, err := replay(stored.Response)
+	if err != nil {
+		return nil, i.newInternalError(req, err, "response replay failed")
+	}
+
+	res.Header().Set(ReplayedHeader, "true")
+
+	return res, nil
+}
+
+// Run deletes expired keys every window. It blocks until the context is done.
+func (i *Interceptor) Run(ctx context.Context) {
+	t := time.NewTicker(i.window)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			n, err := i.repo.DeleteExpired(ctx, i.window)
+			if n > 0 {
+				i.l.Debug().Uint64("count", n).Msg("expired idempotency keys deleted")
+			}
+
+			if err != nil && ctx.Err() == nil {
+				i.l.Error().Err(err).Msg("expired idempotency keys delete failed")
+			}
+		}
+	}
+}
+
+func (i *Interceptor) newInternalError(req connect.AnyRequest, err error, msg string) error {
+	c := i.now().Unix()
+	i.l.Error().Err(err).Str("proc", req.Spec().Procedure).Int64("err_code", c).Msg(msg)
+
+	return connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+}
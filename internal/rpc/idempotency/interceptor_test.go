@@ -0,0 +1,242 @@
+package idempotency_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/idempotencyrepo"
+	"github.com/ashep/ujds/internal/rpc/idempotency"
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	"github.com/ashep/ujds/sdk/proto/ujds/index/v1/v1connect"
+)
+
+type keyRepoMock struct {
+	mu         sync.Mutex
+	keys       map[string]idempotencyrepo.Key
+	reserveErr error
+}
+
+func (m *keyRepoMock) Reserve(
+	_ context.Context,
+	key, procedure string,
+	requestHash []byte,
+	_, _ time.Duration,
+) (idempotencyrepo.Key, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reserveErr != nil {
+		return idempotencyrepo.Key{}, false, m.reserveErr
+	}
+
+	if k, ok := m.keys[procedure+key]; ok {
+		return k, false, nil
+	}
+
+	k := idempotencyrepo.Key{Key: key, Procedure: procedure, RequestHash: requestHash}
+	m.keys[procedure+key] = k
+
+	return k, true, nil
+}
+
+func (m *keyRepoMock) Complete(_ context.Context, key idempotencyrepo.Key, response []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := m.keys[key.Procedure+key.Key]
+	k.Response = append([]byte{}, response...)
+	m.keys[key.Procedure+key.Key] = k
+
+	return nil
+}
+
+func (m *keyRepoMock) Release(_ context.Context, key idempotencyrepo.Key) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.keys, key.Procedure+key.Key)
+
+	return nil
+}
+
+func (m *keyRepoMock) DeleteExpired(context.Context, time.Duration) (uint64, error) {
+	return 0, nil
+}
+
+type handlerMock struct {
+	v1connect.UnimplementedIndexServiceHandler
+
+	calls uint32
+	err   error
+}
+
+func (h *handlerMock) SetSchema(
+	context.Context,
+	*connect.Request[proto.SetSchemaRequest],
+) (*connect.Response[proto.SetSchemaResponse], error) {
+	h.calls++
+
+	if h.err != nil {
+		return nil, h.err
+	}
+
+	return connect.NewResponse(&proto.SetSchemaResponse{Version: h.calls}), nil
+}
+
+func (h *handlerMock) Clear(
+	context.Context,
+	*connect.Request[proto.ClearRequest],
+) (*connect.Response[proto.ClearResponse], error) {
+	h.calls++
+
+	return connect.NewResponse(&proto.ClearResponse{}), nil
+}
+
+func newClient(t *testing.T, repo *keyRepoMock, h *handlerMock) v1connect.IndexServiceClient {
+	t.Helper()
+
+	icp := idempotency.New(repo, time.Hour, time.Minute, map[string]idempotency.ReplayFunc{
+		v1connect.IndexServiceSetSchemaProcedure: idempotency.Replay[proto.SetSchemaResponse](),
+	}, func() time.Time { return time.Unix(123, 0) }, zerolog.Nop())
+
+	mux := http.NewServeMux()
+	mux.Handle(v1connect.NewIndexServiceHandler(h, connect.WithInterceptors(icp)))
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return v1connect.NewIndexServiceClient(srv.Client(), srv.URL)
+}
+
+func setSchema(cli v1connect.IndexServiceClient, key, schema string) (*connect.Response[proto.SetSchemaResponse], error) {
+	req := connect.NewRequest(&proto.SetSchemaRequest{Name: "theIndex", Schema: schema})
+	if key != "" {
+		req.Header().Set(idempotency.KeyHeader, key)
+	}
+
+	return cli.SetSchema(context.Background(), req) //nolint:wrapcheck // ok
+}
+
+func TestInterceptor(tt *testing.T) {
+	tt.Run("NoKey", func(t *testing.T) {
+		h := &handlerMock{}
+		cli := newClient(t, &keyRepoMock{keys: map[string]idempotencyrepo.Key{}}, h)
+
+		for range 2 {
+			_, err := setSchema(cli, "", `{}`)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, uint32(2), h.calls)
+	})
+
+	tt.Run("NotIdempotentProcedure", func(t *testing.T) {
+		h := &handlerMock{}
+		cli := newClient(t, &keyRepoMock{keys: map[string]idempotencyrepo.Key{}}, h)
+
+		for range 2 {
+			req := connect.NewRequest(&proto.ClearRequest{Name: "theIndex"})
+			req.Header().Set(idempotency.KeyHeader, "theKey")
+			_, err := cli.Clear(context.Background(), req)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, uint32(2), h.calls)
+	})
+
+	tt.Run("KeyTooLong", func(t *testing.T) {
+		cli := newClient(t, &keyRepoMock{keys: map[string]idempotencyrepo.Key{}}, &handlerMock{})
+
+		_, err := setSchema(cli, strings.Repeat("k", 256), `{}`)
+		assert.EqualError(t, err, "invalid_argument: invalid Idempotency-Key: must not be longer than 255")
+	})
+
+	tt.Run("ReserveError", func(t *testing.T) {
+		cli := newClient(t, &keyRepoMock{reserveErr: errors.New("theReserveError")}, &handlerMock{})
+
+		_, err := setSchema(cli, "theKey", `{}`)
+		assert.EqualError(t, err, "internal: err_code: 123")
+	})
+
+	tt.Run("ReleasedWhileReserving", func(t *testing.T) {
+		cli := newClient(t, &keyRepoMock{reserveErr: idempotencyrepo.ErrReleased}, &handlerMock{})
+
+		_, err := setSchema(cli, "theKey", `{}`)
+		assert.EqualError(t, err, "aborted: request with the same idempotency key has just failed, retry it")
+	})
+
+	tt.Run("Replayed", func(t *testing.T) {
+		h := &handlerMock{}
+		cli := newClient(t, &keyRepoMock{keys: map[string]idempotencyrepo.Key{}}, h)
+
+		res, err := setSchema(cli, "theKey", `{}`)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), res.Msg.Version)
+		assert.Empty(t, res.Header().Get(idempotency.ReplayedHeader))
+
+		res, err = setSchema(cli, "theKey", `{}`)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(1), res.Msg.Version)
+		assert.Equal(t, "true", res.Header().Get(idempotency.ReplayedHeader))
+
+		// Another key is another request
+		res, err = setSchema(cli, "theOtherKey", `{}`)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(2), res.Msg.Version)
+
+		assert.Equal(t, uint32(2), h.calls)
+	})
+
+	tt.Run("DifferentRequest", func(t *testing.T) {
+		h := &handlerMock{}
+		cli := newClient(t, &keyRepoMock{keys: map[string]idempotencyrepo.Key{}}, h)
+
+		_, err := setSchema(cli, "theKey", `{}`)
+		require.NoError(t, err)
+
+		_, err = setSchema(cli, "theKey", `{"type":"object"}`)
+		assert.EqualError(t, err, "invalid_argument: invalid Idempotency-Key: already used with a different request")
+		assert.Equal(t, uint32(1), h.calls)
+	})
+
+	tt.Run("InProgress", func(t *testing.T) {
+		repo := &keyRepoMock{keys: map[string]idempotencyrepo.Key{}}
+		cli := newClient(t, repo, &handlerMock{})
+
+		_, err := setSchema(cli, "theKey", `{}`)
+		require.NoError(t, err)
+
+		k := repo.keys[v1connect.IndexServiceSetSchemaProcedure+"theKey"]
+		k.Response = nil
+		repo.keys[v1connect.IndexServiceSetSchemaProcedure+"theKey"] = k
+
+		_, err = setSchema(cli, "theKey", `{}`)
+		assert.EqualError(t, err, "aborted: request with the same idempotency key is in progress")
+	})
+
+	tt.Run("FailedRequestReleased", func(t *testing.T) {
+		h := &handlerMock{err: connect.NewError(connect.CodeUnavailable, errors.New("theError"))}
+		repo := &keyRepoMock{keys: map[string]idempotencyrepo.Key{}}
+		cli := newClient(t, repo, h)
+
+		_, err := setSchema(cli, "theKey", `{}`)
+		assert.EqualError(t, err, "unavailable: theError")
+		assert.Empty(t, repo.keys)
+
+		h.err = nil
+		res, err := setSchema(cli, "theKey", `{}`)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(2), res.Msg.Version)
+	})
+}
@@ -0,0 +1,102 @@
+package indexhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/validation"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+const (
+	checkSchemaBatchSize     = 500
+	checkSchemaSampleDefault = 10
+	checkSchemaSampleMax     = 100
+)
+
+// CheckSchema validates the index's current records against a candidate schema. Progress is streamed after each
+// batch of records, so big indices can be checked without hitting request timeouts.
+func (h *Handler) CheckSchema(
+	ctx context.Context,
+	req *connect.Request[proto.CheckSchemaRequest],
+	stream *connect.ServerStream[proto.CheckSchemaResponse],
+) error {
+	sch, err := validation.CompileSchema(json.RawMessage(req.Msg.Schema))
+	if err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	index, err := h.repo.Get(ctx, req.Msg.Name)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return connect.NewError(connect.CodeNotFound, err)
+	case err != nil:
+		return h.newInternalError(req, err, "index repo get failed")
+	}
+
+	total, err := h.records.Count(ctx, index.ID, "")
+	if err != nil {
+		return h.newInternalError(req, err, "record repo count failed")
+	}
+
+	sampleSize := req.Msg.SampleSize
+	if sampleSize == 0 {
+		sampleSize = checkSchemaSampleDefault
+	} else if sampleSize > checkSchemaSampleMax {
+		sampleSize = checkSchemaSampleMax
+	}
+
+	checked, failed, sampled, cursor := uint64(0), uint64(0), uint32(0), uint64(0)
+
+	for {
+		records, cur, err := h.records.Find(ctx, recordrepo.FindRequest{
+			Index:  index.Name,
+			Cursor: cursor,
+			Limit:  checkSchemaBatchSize,
+		})
+		if err != nil {
+			return h.newInternalError(req, err, "record repo find failed")
+		}
+
+		failures := make([]*proto.CheckSchemaResponse_Failure, 0)
+
+		for _, rec := range records {
+			checked++
+
+			vErr := sch.Validate(rec.Data)
+			if vErr == nil {
+				continue
+			}
+
+			failed++
+
+			if sampled < sampleSize {
+				failures = append(failures, &proto.CheckSchemaResponse_Failure{RecordId: rec.ID, Error: vErr.Error()})
+				sampled++
+			}
+		}
+
+		if err := stream.Send(&proto.CheckSchemaResponse{
+			Total:    total,
+			Checked:  checked,
+			Failed:   failed,
+			Failures: failures,
+		}); err != nil {
+			return err //nolint:wrapcheck // ok
+		}
+
+		if cur == 0 {
+			return nil
+		}
+
+		cursor = cur
+	}
+}
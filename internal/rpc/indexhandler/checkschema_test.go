@@ -0,0 +1,158 @@
+package indexhandler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/rpc/indexhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	indexconnect "github.com/ashep/ujds/sdk/proto/ujds/index/v1/v1connect"
+)
+
+// checkSchema calls the server streaming CheckSchema RPC through a test server and collects all the responses.
+func checkSchema(t *testing.T, h *indexhandler.Handler, req *proto.CheckSchemaRequest) ([]*proto.CheckSchemaResponse, error) {
+	t.Helper()
+
+	path, handler := indexconnect.NewIndexServiceHandler(h)
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := indexconnect.NewIndexServiceClient(srv.Client(), srv.URL)
+
+	stream, err := cli.CheckSchema(context.Background(), connect.NewRequest(req))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	res := make([]*proto.CheckSchemaResponse, 0)
+	for stream.Receive() {
+		res = append(res, stream.Msg())
+	}
+
+	return res, stream.Err()
+}
+
+func TestIndexHandler_CheckSchema(tt *testing.T) {
+	tt.Run("InvalidSchema", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := indexhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := checkSchema(t, h, &proto.CheckSchemaRequest{Name: "theIndex", Schema: `{]`})
+
+		assert.EqualError(t, err, "invalid_argument: invalid json schema: invalid character ']' looking for beginning of object key string")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := checkSchema(t, h, &proto.CheckSchemaRequest{Name: "theIndex", Schema: `{}`})
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoFindError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123, Name: "theIndex"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Count", mock.Anything, uint64(123), "").
+			Return(uint64(10), nil)
+		rr.On("Find", mock.Anything, mock.Anything).
+			Return([]recordrepo.Record(nil), uint64(0), errors.New("theFindError"))
+
+		h := indexhandler.New(rm, rr, nil, nil, nil, nil, nil, now, l)
+		_, err := checkSchema(t, h, &proto.CheckSchemaRequest{Name: "theIndex", Schema: `{}`})
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theFindError","proc":"/ujds.index.v1.IndexService/CheckSchema","err_code":123456789,"message":"record repo find failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123, Name: "theIndex"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Count", mock.Anything, uint64(123), "").
+			Return(uint64(4), nil)
+		rr.On("Find", mock.Anything, recordrepo.FindRequest{Index: "theIndex", Limit: 500}).
+			Return([]recordrepo.Record{
+				{ID: "rec1", Data: `{"title":"foo"}`, Rev: 1},
+				{ID: "rec2", Data: `{}`, Rev: 2},
+			}, uint64(2), nil)
+		rr.On("Find", mock.Anything, recordrepo.FindRequest{Index: "theIndex", Cursor: 2, Limit: 500}).
+			Return([]recordrepo.Record{
+				{ID: "rec3", Data: `{"title":1}`, Rev: 3},
+				{ID: "rec4", Data: `{"author":"bar"}`, Rev: 4},
+			}, uint64(0), nil)
+
+		h := indexhandler.New(rm, rr, nil, nil, nil, nil, nil, now, l)
+		res, err := checkSchema(t, h, &proto.CheckSchemaRequest{
+			Name:       "theIndex",
+			Schema:     `{"required":["title"],"properties":{"title":{"type":"string"}}}`,
+			SampleSize: 2,
+		})
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		require.Len(t, res, 2)
+
+		assert.Equal(t, uint64(4), res[0].Total)
+		assert.Equal(t, uint64(2), res[0].Checked)
+		assert.Equal(t, uint64(1), res[0].Failed)
+		require.Len(t, res[0].Failures, 1)
+		assert.Equal(t, "rec2", res[0].Failures[0].RecordId)
+		assert.Equal(t, "invalid json: (root): missing property 'title'", res[0].Failures[0].Error)
+
+		assert.Equal(t, uint64(4), res[1].Total)
+		assert.Equal(t, uint64(4), res[1].Checked)
+		assert.Equal(t, uint64(3), res[1].Failed)
+		require.Len(t, res[1].Failures, 1)
+		assert.Equal(t, "rec3", res[1].Failures[0].RecordId)
+		assert.Equal(t, "invalid json: title: got number, want string", res[1].Failures[0].Error)
+	})
+}
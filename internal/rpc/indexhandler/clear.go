@@ -6,6 +6,7 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
 
 	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 )
@@ -19,6 +20,8 @@ func (h *Handler) Clear(
 	switch {
 	case errors.As(err, &apperrors.InvalidArgError{}):
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &indexrepo.ModeError{}):
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
 	case err != nil:
 		return nil, h.newInternalError(req, err, "index repo clear failed")
 	}
@@ -9,6 +9,7 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
 	"github.com/ashep/ujds/internal/rpc/indexhandler"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -29,7 +30,7 @@ func TestIndexHandler_Clear(tt *testing.T) {
 		rm.On("Clear", mock.Anything, mock.Anything).
 			Return(apperrors.InvalidArgError{Subj: "theSubj", Reason: "theReason"})
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Clear(context.Background(), connect.NewRequest(&proto.ClearRequest{
 			Name: "theIndexName",
 		}))
@@ -38,6 +39,25 @@ func TestIndexHandler_Clear(tt *testing.T) {
 		assert.Empty(t, lb.String())
 	})
 
+	tt.Run("RepoModeError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Clear", mock.Anything, "theIndexName").
+			Return(indexrepo.ModeError{Reason: "index is read-only"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Clear(context.Background(), connect.NewRequest(&proto.ClearRequest{
+			Name: "theIndexName",
+		}))
+
+		assert.EqualError(t, err, "failed_precondition: index is read-only")
+		assert.Empty(t, lb.String())
+	})
+
 	tt.Run("RepoInternalError", func(t *testing.T) {
 		now := func() time.Time { return time.Unix(123456789, 0) }
 		lb := &strings.Builder{}
@@ -48,7 +68,7 @@ func TestIndexHandler_Clear(tt *testing.T) {
 		rm.On("Clear", mock.Anything, mock.Anything).
 			Return(errors.New("theRepoError"))
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Clear(context.Background(), connect.NewRequest(&proto.ClearRequest{
 			Name: "theIndexName",
 		}))
@@ -67,7 +87,7 @@ func TestIndexHandler_Clear(tt *testing.T) {
 		rm.On("Clear", mock.Anything, mock.Anything).
 			Return(nil)
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Clear(context.Background(), connect.NewRequest(&proto.ClearRequest{
 			Name: "theIndexName",
 		}))
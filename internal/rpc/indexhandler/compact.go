@@ -0,0 +1,58 @@
+package indexhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/jobrunner"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+const compactJobKind = "index_compact"
+
+// Compact starts a job which deletes history revisions of the index not retained by its retention policy.
+func (h *Handler) Compact(
+	ctx context.Context,
+	req *connect.Request[proto.CompactRequest],
+) (*connect.Response[proto.CompactResponse], error) {
+	idx, err := h.repo.Get(ctx, req.Msg.Name)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case err != nil:
+		return nil, h.newInternalError(req, err, "index repo get failed")
+	}
+
+	ret, ok := h.compactor.RetentionFor(idx.Name)
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, apperrors.NotFoundError{Subj: "retention policy"})
+	}
+
+	// Retention can not be applied to archived revisions
+	if h.archive.Archives(idx.Name) {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, errors.New("index history is archived"))
+	}
+
+	jobID, err := h.jobs.Start(ctx, compactJobKind, func(ctx context.Context, progress jobrunner.ProgressFunc) error {
+		progress(0, 0)
+
+		_, err := h.compactor.CompactIndex(ctx, idx.ID, ret, func(deleted uint64) { progress(deleted, deleted) })
+		if err != nil {
+			return fmt.Errorf("compact index: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, h.newInternalError(req, err, "job start failed")
+	}
+
+	return connect.NewResponse(&proto.CompactResponse{JobId: jobID}), nil
+}
@@ -0,0 +1,213 @@
+package indexhandler_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/jobrunner"
+	"github.com/ashep/ujds/internal/recordcompactor"
+	"github.com/ashep/ujds/internal/rpc/indexhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func TestIndexHandler_Compact(tt *testing.T) {
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Compact(context.Background(), connect.NewRequest(&proto.CompactRequest{Name: "theIndex"}))
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("IndexRepoError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{}, errors.New("theRepoError"))
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Compact(context.Background(), connect.NewRequest(&proto.CompactRequest{Name: "theIndex"}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theRepoError","proc":"","err_code":123456789,"message":"index repo get failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("PolicyNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 1, Name: "theIndex"}, nil)
+
+		cm := &compactorMock{}
+		defer cm.AssertExpectations(t)
+		cm.On("RetentionFor", "theIndex").Return(recordcompactor.Retention{}, false)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, cm, nil, now, l)
+		_, err := h.Compact(context.Background(), connect.NewRequest(&proto.CompactRequest{Name: "theIndex"}))
+
+		assert.EqualError(t, err, "not_found: retention policy is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("IndexArchived", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 1, Name: "theIndex"}, nil)
+
+		cm := &compactorMock{}
+		defer cm.AssertExpectations(t)
+		cm.On("RetentionFor", "theIndex").Return(recordcompactor.Retention{KeepRevisions: 3}, true)
+
+		am := &archiveMock{}
+		defer am.AssertExpectations(t)
+		am.On("Archives", "theIndex").Return(true)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, cm, am, now, l)
+		_, err := h.Compact(context.Background(), connect.NewRequest(&proto.CompactRequest{Name: "theIndex"}))
+
+		assert.EqualError(t, err, "failed_precondition: index history is archived")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("JobStartError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 1, Name: "theIndex"}, nil)
+
+		cm := &compactorMock{}
+		defer cm.AssertExpectations(t)
+		cm.On("RetentionFor", "theIndex").Return(recordcompactor.Retention{KeepRevisions: 3}, true)
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Start", mock.Anything, "index_compact", mock.Anything).Return(uint64(0), errors.New("theJobError"))
+
+		am := &archiveMock{}
+		defer am.AssertExpectations(t)
+		am.On("Archives", "theIndex").Return(false)
+
+		h := indexhandler.New(rm, nil, jr, nil, nil, cm, am, now, l)
+		_, err := h.Compact(context.Background(), connect.NewRequest(&proto.CompactRequest{Name: "theIndex"}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theJobError","proc":"","err_code":123456789,"message":"job start failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("TaskCompactError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 1, Name: "theIndex"}, nil)
+
+		ret := recordcompactor.Retention{KeepRevisions: 3}
+
+		cm := &compactorMock{}
+		defer cm.AssertExpectations(t)
+		cm.On("RetentionFor", "theIndex").Return(ret, true)
+		cm.On("CompactIndex", mock.Anything, uint64(1), ret, mock.Anything).
+			Return(uint64(0), errors.New("theCompactError"))
+
+		var task jobrunner.Task
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Start", mock.Anything, "index_compact", mock.Anything).
+			Run(func(args mock.Arguments) { task = args.Get(2).(jobrunner.Task) }).
+			Return(uint64(345), nil)
+
+		am := &archiveMock{}
+		defer am.AssertExpectations(t)
+		am.On("Archives", "theIndex").Return(false)
+
+		h := indexhandler.New(rm, nil, jr, nil, nil, cm, am, now, l)
+		_, err := h.Compact(context.Background(), connect.NewRequest(&proto.CompactRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		err = task(context.Background(), func(uint64, uint64) {})
+		assert.EqualError(t, err, "compact index: theCompactError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 1, Name: "theIndex"}, nil)
+
+		ret := recordcompactor.Retention{KeepRevisions: 3, KeepFor: time.Hour}
+
+		cm := &compactorMock{}
+		defer cm.AssertExpectations(t)
+		cm.On("RetentionFor", "theIndex").Return(ret, true)
+		cm.On("CompactIndex", mock.Anything, uint64(1), ret, mock.Anything).
+			Run(func(args mock.Arguments) {
+				progress := args.Get(3).(func(uint64))
+				progress(10)
+				progress(12)
+			}).
+			Return(uint64(12), nil)
+
+		var task jobrunner.Task
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Start", mock.Anything, "index_compact", mock.Anything).
+			Run(func(args mock.Arguments) { task = args.Get(2).(jobrunner.Task) }).
+			Return(uint64(345), nil)
+
+		am := &archiveMock{}
+		defer am.AssertExpectations(t)
+		am.On("Archives", "theIndex").Return(false)
+
+		h := indexhandler.New(rm, nil, jr, nil, nil, cm, am, now, l)
+		res, err := h.Compact(context.Background(), connect.NewRequest(&proto.CompactRequest{Name: "theIndex"}))
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(345), res.Msg.JobId)
+		assert.Empty(t, lb.String())
+
+		progress := make([][2]uint64, 0)
+		require.NoError(t, task(context.Background(), func(processed, total uint64) {
+			progress = append(progress, [2]uint64{processed, total})
+		}))
+		assert.Equal(t, [][2]uint64{{0, 0}, {10, 10}, {12, 12}}, progress)
+	})
+}
@@ -0,0 +1,159 @@
+package indexhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/jobrunner"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/searchquery"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+const (
+	copyJobKind   = "index_copy"
+	copyBatchSize = 500
+)
+
+func (h *Handler) Copy(
+	ctx context.Context,
+	req *connect.Request[proto.CopyRequest],
+) (*connect.Response[proto.CopyResponse], error) {
+	if req.Msg.Source == req.Msg.Target {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("source and target must differ"))
+	}
+
+	if req.Msg.Search != "" {
+		if _, err := searchquery.Parse(req.Msg.Search); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("search query: %w", err))
+		}
+	}
+
+	src, err := h.repo.Get(ctx, req.Msg.Source)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case err != nil:
+		return nil, h.newInternalError(req, err, "index repo get failed")
+	}
+
+	dst, created, err := h.copyTarget(ctx, req, src)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := h.records.Count(ctx, src.ID, req.Msg.Search)
+	if err != nil {
+		return nil, h.newInternalError(req, err, "record repo count failed")
+	}
+
+	copyReq := recordrepo.CopyRequest{
+		SourceIndexID: src.ID,
+		TargetIndexID: dst.ID,
+		Query:         req.Msg.Search,
+		WithHistory:   req.Msg.WithHistory,
+		Limit:         copyBatchSize,
+		Archive:       h.archive,
+	}
+
+	jobID, err := h.jobs.Start(ctx, copyJobKind, func(ctx context.Context, progress jobrunner.ProgressFunc) error {
+		processed := uint64(0)
+		progress(processed, total)
+
+		for {
+			n, cursor, err := h.records.Copy(ctx, copyReq)
+			if err != nil {
+				return fmt.Errorf("copy records: %w", err)
+			}
+
+			processed += n
+			progress(processed, max(processed, total))
+
+			if cursor == "" {
+				break
+			}
+
+			copyReq.Cursor = cursor
+		}
+
+		// A created target is made read-only only when it is filled, since records can't be copied into such an index
+		if created && src.Mode.ReadOnly {
+			readOnly := true
+			if err := h.repo.Upsert(ctx, indexrepo.UpsertRequest{
+				Name:     dst.Name,
+				Title:    dst.Title.String,
+				ReadOnly: &readOnly,
+			}); err != nil {
+				return fmt.Errorf("set target index read-only: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, h.newInternalError(req, err, "job start failed")
+	}
+
+	return connect.NewResponse(&proto.CopyResponse{JobId: jobID}), nil
+}
+
+// copyTarget returns the target index and whether it has been created. An existing target index must be empty and
+// writable. A created one gets the source's title, TTL, labels, metadata and modes, except for the read-only mode,
+// which is set once the records are copied.
+func (h *Handler) copyTarget(
+	ctx context.Context,
+	req *connect.Request[proto.CopyRequest],
+	src indexrepo.Index,
+) (indexrepo.Index, bool, error) {
+	dst, err := h.repo.Get(ctx, req.Msg.Target)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return indexrepo.Index{}, false, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		upsertReq := indexrepo.UpsertRequest{
+			Name:       req.Msg.Target,
+			Title:      src.Title.String,
+			TTL:        &src.TTL,
+			InsertOnly: &src.Mode.InsertOnly,
+			NoHistory:  &src.Mode.NoHistory,
+			Labels:     src.Labels,
+			Metadata:   &src.Metadata,
+		}
+
+		if err = h.repo.Upsert(ctx, upsertReq); err != nil {
+			return indexrepo.Index{}, false, h.newInternalError(req, err, "index repo upsert failed")
+		}
+
+		if dst, err = h.repo.Get(ctx, req.Msg.Target); err != nil {
+			return indexrepo.Index{}, false, h.newInternalError(req, err, "index repo get failed")
+		}
+
+		return dst, true, nil
+	case err != nil:
+		return indexrepo.Index{}, false, h.newInternalError(req, err, "index repo get failed")
+	}
+
+	if dst.Mode.ReadOnly {
+		return indexrepo.Index{}, false, connect.NewError(connect.CodeFailedPrecondition, errors.New("target index is read-only"))
+	}
+
+	cnt, err := h.records.Count(ctx, dst.ID, "")
+	if err != nil {
+		return indexrepo.Index{}, false, h.newInternalError(req, err, "record repo count failed")
+	}
+
+	if cnt != 0 {
+		return indexrepo.Index{}, false, connect.NewError(connect.CodeFailedPrecondition, errors.New("target index is not empty"))
+	}
+
+	return dst, false, nil
+}
@@ -0,0 +1,267 @@
+package indexhandler_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/jobrunner"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/rpc/indexhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func TestIndexHandler_Copy(tt *testing.T) {
+	tt.Run("SameSourceAndTarget", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := indexhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source: "theIndex",
+			Target: "theIndex",
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: source and target must differ")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("InvalidSearchQuery", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := indexhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+			Search: "foo bar",
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: search query: operator expected at position 4: foo ")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("SourceNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theSource").
+			Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("TargetNotEmpty", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theSource").Return(indexrepo.Index{ID: 1, Name: "theSource"}, nil)
+		rm.On("Get", mock.Anything, "theTarget").Return(indexrepo.Index{ID: 2, Name: "theTarget"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Count", mock.Anything, uint64(2), "").Return(uint64(1), nil)
+
+		h := indexhandler.New(rm, rr, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+
+		assert.EqualError(t, err, "failed_precondition: target index is not empty")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("TargetReadOnly", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theSource").Return(indexrepo.Index{ID: 1, Name: "theSource"}, nil)
+		rm.On("Get", mock.Anything, "theTarget").Return(indexrepo.Index{
+			ID:   2,
+			Name: "theTarget",
+			Mode: indexrepo.Mode{ReadOnly: true},
+		}, nil)
+
+		h := indexhandler.New(rm, &recordRepoMock{}, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+
+		assert.EqualError(t, err, "failed_precondition: target index is read-only")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("JobStartError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theSource").Return(indexrepo.Index{ID: 1, Name: "theSource"}, nil)
+		rm.On("Get", mock.Anything, "theTarget").Return(indexrepo.Index{ID: 2, Name: "theTarget"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Count", mock.Anything, uint64(2), "").Return(uint64(0), nil)
+		rr.On("Count", mock.Anything, uint64(1), "").Return(uint64(10), nil)
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Start", mock.Anything, "index_copy", mock.Anything).Return(uint64(0), errors.New("theJobError"))
+
+		h := indexhandler.New(rm, rr, jr, nil, nil, nil, nil, now, l)
+		_, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theJobError","proc":"","err_code":123456789,"message":"job start failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("OkNewTarget", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theSource").
+			Return(indexrepo.Index{
+				ID:       1,
+				Name:     "theSource",
+				Title:    sql.NullString{String: "theTitle", Valid: true},
+				Mode:     indexrepo.Mode{ReadOnly: true, NoHistory: true},
+				Labels:   map[string]string{"team": "search"},
+				Metadata: json.RawMessage(`{"owner":"John"}`),
+			}, nil)
+		rm.On("Get", mock.Anything, "theTarget").
+			Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"}).Once()
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{
+			Name:       "theTarget",
+			Title:      "theTitle",
+			TTL:        ptr(time.Duration(0)),
+			InsertOnly: ptr(false),
+			NoHistory:  ptr(true),
+			Labels:     map[string]string{"team": "search"},
+			Metadata:   ptr(json.RawMessage(`{"owner":"John"}`)),
+		}).Return(nil).Once()
+		rm.On("Get", mock.Anything, "theTarget").
+			Return(indexrepo.Index{
+				ID:    2,
+				Name:  "theTarget",
+				Title: sql.NullString{String: "theTitle", Valid: true},
+				Mode:  indexrepo.Mode{NoHistory: true},
+			}, nil).Once()
+		// The target is made read-only after the records are copied
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{
+			Name:     "theTarget",
+			Title:    "theTitle",
+			ReadOnly: ptr(true),
+		}).Return(nil).Once()
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Count", mock.Anything, uint64(1), "foo=1").Return(uint64(3), nil)
+		rr.On("Copy", mock.Anything, recordrepo.CopyRequest{
+			SourceIndexID: 1, TargetIndexID: 2, Query: "foo=1", WithHistory: true, Limit: 500,
+		}).Return(uint64(2), "theRecord2", nil)
+		rr.On("Copy", mock.Anything, recordrepo.CopyRequest{
+			SourceIndexID: 1, TargetIndexID: 2, Query: "foo=1", WithHistory: true, Cursor: "theRecord2", Limit: 500,
+		}).Return(uint64(1), "", nil)
+
+		var task jobrunner.Task
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Start", mock.Anything, "index_copy", mock.Anything).
+			Run(func(args mock.Arguments) { task = args.Get(2).(jobrunner.Task) }).
+			Return(uint64(345), nil)
+
+		h := indexhandler.New(rm, rr, jr, nil, nil, nil, nil, now, l)
+		res, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source:      "theSource",
+			Target:      "theTarget",
+			Search:      "foo=1",
+			WithHistory: true,
+		}))
+
+		require.NoError(t, err)
+		assert.Equal(t, uint64(345), res.Msg.JobId)
+		assert.Empty(t, lb.String())
+
+		progress := make([][2]uint64, 0)
+		require.NoError(t, task(context.Background(), func(processed, total uint64) {
+			progress = append(progress, [2]uint64{processed, total})
+		}))
+		assert.Equal(t, [][2]uint64{{0, 3}, {2, 3}, {3, 3}}, progress)
+	})
+
+	tt.Run("TaskCopyError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theSource").Return(indexrepo.Index{ID: 1, Name: "theSource"}, nil)
+		rm.On("Get", mock.Anything, "theTarget").Return(indexrepo.Index{ID: 2, Name: "theTarget"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Count", mock.Anything, uint64(2), "").Return(uint64(0), nil)
+		rr.On("Count", mock.Anything, uint64(1), "").Return(uint64(10), nil)
+		rr.On("Copy", mock.Anything, mock.Anything).Return(uint64(0), "", errors.New("theCopyError"))
+
+		var task jobrunner.Task
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Start", mock.Anything, "index_copy", mock.Anything).
+			Run(func(args mock.Arguments) { task = args.Get(2).(jobrunner.Task) }).
+			Return(uint64(345), nil)
+
+		h := indexhandler.New(rm, rr, jr, nil, nil, nil, nil, now, l)
+		_, err := h.Copy(context.Background(), connect.NewRequest(&proto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+		require.NoError(t, err)
+
+		err = task(context.Background(), func(uint64, uint64) {})
+		assert.EqualError(t, err, "copy records: theCopyError")
+	})
+}
@@ -0,0 +1,247 @@
+package indexhandler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/recordrepo"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+const (
+	exportBatchSize = 1000
+	exportChunkSize = 64 * 1024
+
+	// exportNDJSONProc is the procedure name of the NDJSON export endpoint used in logs.
+	exportNDJSONProc = "/export"
+)
+
+// exportLine is a line of the NDJSON export. Its id, expires_at and data fields match the NDJSON import line, so an
+// export can be imported back as is.
+type exportLine struct {
+	ID        string           `json:"id"`
+	Rev       uint64           `json:"rev"`
+	CreatedAt int64            `json:"created_at"`
+	UpdatedAt int64            `json:"updated_at"`
+	TouchedAt int64            `json:"touched_at"`
+	ExpiresAt int64            `json:"expires_at,omitempty"`
+	StaleAt   int64            `json:"stale_at,omitempty"`
+	Data      json.RawMessage  `json:"data"`
+	History   []exportRevision `json:"history,omitempty"`
+}
+
+type exportRevision struct {
+	Rev       uint64          `json:"rev"`
+	CreatedAt int64           `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// streamWriter sends everything written to it as a message of the export stream.
+type streamWriter func(p []byte) error
+
+func (f streamWriter) Write(p []byte) (int, error) {
+	if err := f(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// trackingWriter remembers the first error of the underlying writer, so export failures caused by the client going
+// away can be told apart from the other ones.
+type trackingWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+
+	return n, err //nolint:wrapcheck // ok
+}
+
+// Export streams a consistent snapshot of the index's current records as NDJSON, optionally gzip-compressed, one
+// record per line.
+func (h *Handler) Export(
+	ctx context.Context,
+	req *connect.Request[proto.ExportRequest],
+	stream *connect.ServerStream[proto.ExportResponse],
+) error {
+	idx, err := h.repo.Get(ctx, req.Msg.Name)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return connect.NewError(connect.CodeNotFound, err)
+	case err != nil:
+		return h.newInternalError(req, err, "index repo get failed")
+	}
+
+	tw := &trackingWriter{w: streamWriter(func(p []byte) error {
+		return stream.Send(&proto.ExportResponse{Data: p})
+	})}
+
+	if err := h.export(ctx, idx.ID, req.Msg.WithHistory, req.Msg.Gzip, tw); err != nil {
+		if tw.err != nil {
+			return tw.err
+		}
+
+		return h.newInternalError(req, err, "index export failed")
+	}
+
+	return nil
+}
+
+// ExportNDJSON is the HTTP counterpart of Export. It responds with the export of the index given in the path as a
+// file download; the history and gzip query parameters turn on the options of the same names.
+func (h *Handler) ExportNDJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	withHistory, err := parseBoolParam(r, "history")
+	if err != nil {
+		_ = connect.NewErrorWriter().Write(w, r, connect.NewError(connect.CodeInvalidArgument, err))
+		return
+	}
+
+	gz, err := parseBoolParam(r, "gzip")
+	if err != nil {
+		_ = connect.NewErrorWriter().Write(w, r, connect.NewError(connect.CodeInvalidArgument, err))
+		return
+	}
+
+	idx, err := h.repo.Get(r.Context(), r.PathValue("index"))
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		_ = connect.NewErrorWriter().Write(w, r, connect.NewError(connect.CodeInvalidArgument, err))
+		return
+	case errors.As(err, &apperrors.NotFoundError{}):
+		_ = connect.NewErrorWriter().Write(w, r, connect.NewError(connect.CodeNotFound, err))
+		return
+	case err != nil:
+		c := h.now().Unix()
+		h.l.Error().Err(err).Str("proc", exportNDJSONProc).Int64("err_code", c).Msg("index repo get failed")
+		_ = connect.NewErrorWriter().Write(w, r, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c)))
+
+		return
+	}
+
+	fileName, contentType := idx.Name+".ndjson", "application/x-ndjson"
+	if gz {
+		fileName, contentType = fileName+".gz", "application/gzip"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+	// The status is sent with the first chunk, so errors can only be logged and the response gets truncated
+	tw := &trackingWriter{w: w}
+
+	if err := h.export(r.Context(), idx.ID, withHistory, gz, tw); err != nil {
+		if tw.err != nil {
+			h.l.Warn().Err(tw.err).Str("proc", exportNDJSONProc).Msg("response write failed")
+			return
+		}
+
+		h.l.Error().Err(err).Str("proc", exportNDJSONProc).Msg("index export failed")
+	}
+}
+
+// export writes the index's records to w as NDJSON.
+func (h *Handler) export(ctx context.Context, indexID uint64, withHistory, gz bool, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, exportChunkSize)
+	out := io.Writer(bw)
+
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(bw)
+		out = zw
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+
+	write := func(rec recordrepo.ExportRecord) error {
+		if err := enc.Encode(exportLineFromRecord(rec)); err != nil {
+			return fmt.Errorf("encode record: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := h.records.Export(ctx, indexID, withHistory, h.archive, exportBatchSize, write); err != nil {
+		return fmt.Errorf("export records: %w", err)
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("gzip close: %w", err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+
+	return nil
+}
+
+func exportLineFromRecord(rec recordrepo.ExportRecord) exportLine {
+	l := exportLine{
+		ID:        rec.ID,
+		Rev:       rec.Rev,
+		CreatedAt: rec.CreatedAt.Unix(),
+		UpdatedAt: rec.UpdatedAt.Unix(),
+		TouchedAt: rec.TouchedAt.Unix(),
+		Data:      json.RawMessage(rec.Data),
+	}
+
+	if rec.ExpiresAt.Valid {
+		l.ExpiresAt = rec.ExpiresAt.Time.Unix()
+	}
+
+	if rec.StaleAt.Valid {
+		l.StaleAt = rec.StaleAt.Time.Unix()
+	}
+
+	for _, rev := range rec.History {
+		l.History = append(l.History, exportRevision{
+			Rev:       rev.Rev,
+			CreatedAt: rev.CreatedAt.Unix(),
+			Data:      json.RawMessage(rev.Data),
+		})
+	}
+
+	return l
+}
+
+func parseBoolParam(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, apperrors.InvalidArgError{Subj: name, Reason: "must be a boolean"}
+	}
+
+	return b, nil
+}
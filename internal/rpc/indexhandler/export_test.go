@@ -0,0 +1,263 @@
+package indexhandler_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/rpc/indexhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	indexconnect "github.com/ashep/ujds/sdk/proto/ujds/index/v1/v1connect"
+)
+
+// export calls the server streaming Export RPC through a test server and joins the received chunks.
+func export(t *testing.T, h *indexhandler.Handler, req *proto.ExportRequest) ([]byte, error) {
+	t.Helper()
+
+	path, handler := indexconnect.NewIndexServiceHandler(h)
+	mux := http.NewServeMux()
+	mux.Handle(path, handler)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := indexconnect.NewIndexServiceClient(srv.Client(), srv.URL)
+
+	stream, err := cli.Export(context.Background(), connect.NewRequest(req))
+	require.NoError(t, err)
+
+	defer func() {
+		_ = stream.Close()
+	}()
+
+	res := make([]byte, 0)
+	for stream.Receive() {
+		res = append(res, stream.Msg().Data...)
+	}
+
+	return res, stream.Err()
+}
+
+func TestIndexHandler_Export(tt *testing.T) {
+	records := []recordrepo.ExportRecord{
+		{
+			Record: recordrepo.Record{
+				ID:        "theRecordID1",
+				Rev:       2,
+				Data:      `{"foo":"<bar>"}`,
+				CreatedAt: time.Unix(111, 0),
+				UpdatedAt: time.Unix(112, 0),
+				TouchedAt: time.Unix(113, 0),
+				ExpiresAt: sql.NullTime{Time: time.Unix(114, 0), Valid: true},
+			},
+			History: []recordrepo.Record{
+				{ID: "theRecordID1", Rev: 1, Data: `{"foo":1}`, CreatedAt: time.Unix(110, 0)},
+				{ID: "theRecordID1", Rev: 2, Data: `{"foo":"<bar>"}`, CreatedAt: time.Unix(111, 0)},
+			},
+		},
+		{
+			Record: recordrepo.Record{
+				ID:        "theRecordID2",
+				Rev:       3,
+				Data:      `{}`,
+				CreatedAt: time.Unix(221, 0),
+				UpdatedAt: time.Unix(222, 0),
+				TouchedAt: time.Unix(223, 0),
+			},
+		},
+	}
+
+	expected := `{"id":"theRecordID1","rev":2,"created_at":111,"updated_at":112,"touched_at":113,"expires_at":114,` +
+		`"data":{"foo":"<bar>"},"history":[{"rev":1,"created_at":110,"data":{"foo":1}},` +
+		`{"rev":2,"created_at":111,"data":{"foo":"<bar>"}}]}` + "\n" +
+		`{"id":"theRecordID2","rev":3,"created_at":221,"updated_at":222,"touched_at":223,"data":{}}` + "\n"
+
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := export(t, h, &proto.ExportRequest{Name: "theIndex"})
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoExportError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 123, Name: "theIndex"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Export", mock.Anything, uint64(123), false, uint32(1000)).
+			Return([]recordrepo.ExportRecord(nil), errors.New("theExportError"))
+
+		h := indexhandler.New(rm, rr, nil, nil, nil, nil, nil, now, l)
+		_, err := export(t, h, &proto.ExportRequest{Name: "theIndex"})
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"export records: theExportError","proc":"/ujds.index.v1.IndexService/Export","err_code":123456789,"message":"index export failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 123, Name: "theIndex"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Export", mock.Anything, uint64(123), true, uint32(1000)).Return(records, nil)
+
+		h := indexhandler.New(rm, rr, nil, nil, nil, nil, nil, now, l)
+		res, err := export(t, h, &proto.ExportRequest{Name: "theIndex", WithHistory: true})
+
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(res))
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkGzip", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 123, Name: "theIndex"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Export", mock.Anything, uint64(123), true, uint32(1000)).Return(records, nil)
+
+		h := indexhandler.New(rm, rr, nil, nil, nil, nil, nil, now, l)
+		res, err := export(t, h, &proto.ExportRequest{Name: "theIndex", WithHistory: true, Gzip: true})
+		require.NoError(t, err)
+
+		zr, err := gzip.NewReader(bytes.NewReader(res))
+		require.NoError(t, err)
+		b, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		assert.Equal(t, expected, string(b))
+		assert.Empty(t, lb.String())
+	})
+}
+
+func TestIndexHandler_ExportNDJSON(tt *testing.T) {
+	newRequest := func(method, query string) *http.Request {
+		req := httptest.NewRequest(method, "/export/theIndex"+query, nil)
+		req.SetPathValue("index", "theIndex")
+
+		return req
+	}
+
+	tt.Run("MethodNotAllowed", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := indexhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ExportNDJSON(w, newRequest(http.MethodPost, ""))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("InvalidParam", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := indexhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ExportNDJSON(w, newRequest(http.MethodGet, "?gzip=maybe"))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.JSONEq(t, `{"code":"invalid_argument","message":"invalid gzip: must be a boolean"}`, w.Body.String())
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ExportNDJSON(w, newRequest(http.MethodGet, ""))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 123, Name: "theIndex"}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Export", mock.Anything, uint64(123), false, uint32(1000)).Return([]recordrepo.ExportRecord{
+			{Record: recordrepo.Record{ID: "theRecordID", Rev: 1, Data: `{}`, CreatedAt: time.Unix(1, 0),
+				UpdatedAt: time.Unix(2, 0), TouchedAt: time.Unix(3, 0)}},
+		}, nil)
+
+		h := indexhandler.New(rm, rr, nil, nil, nil, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ExportNDJSON(w, newRequest(http.MethodGet, "?gzip=1"))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/gzip", w.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename="theIndex.ndjson.gz"`, w.Header().Get("Content-Disposition"))
+
+		zr, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		b, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		assert.Equal(t, `{"id":"theRecordID","rev":1,"created_at":1,"updated_at":2,"touched_at":3,"data":{}}`+"\n", string(b))
+		assert.Empty(t, lb.String())
+	})
+}
@@ -5,16 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/ashep/go-apperrors"
 
+	"github.com/ashep/ujds/internal/validation"
 	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 )
 
-// draft07Schema is the dialect the server validates against (xeipuuv/gojsonschema
-// supports up to draft-07). It is stamped onto returned schemas that don't declare one.
-const draft07Schema = "http://json-schema.org/draft-07/schema#"
+// defaultDialect is the dialect the server assumes for schemas that don't declare one.
+// It is stamped onto returned schemas so clients see the effective dialect.
+const defaultDialect = validation.DefaultDialect
 
 // catchAllPattern is the synthetic ".*" -> "{}" entry the config always injects so that
 // every record is at least valid JSON. It carries no validation constraints, so it is
@@ -38,12 +40,28 @@ func (h *Handler) Get(
 
 	schemas := h.schemas.SchemasFor(index.Name)
 	res := &proto.GetResponse{
-		Name:      index.Name,
-		Title:     index.Title.String,
-		CreatedAt: uint64(index.CreatedAt.Unix()), //nolint:gosec // ok
-		UpdatedAt: uint64(index.UpdatedAt.Unix()), //nolint:gosec // ok
-		Schemas:   make([]string, 0, len(schemas)),
+		Name:       index.Name,
+		Title:      index.Title.String,
+		CreatedAt:  uint64(index.CreatedAt.Unix()), //nolint:gosec // ok
+		UpdatedAt:  uint64(index.UpdatedAt.Unix()), //nolint:gosec // ok
+		Schemas:    make([]string, 0, len(schemas)),
+		Ttl:        uint64(index.TTL / time.Second), //nolint:gosec // ok
+		ReadOnly:   index.Mode.ReadOnly,
+		InsertOnly: index.Mode.InsertOnly,
+		NoHistory:  index.Mode.NoHistory,
+		Labels:     index.Labels,
+		Metadata:   string(index.Metadata),
 	}
+
+	if req.Msg.Stats != nil {
+		stats, err := h.repo.Stats(ctx, index.Name, statsRequest(req.Msg.Stats))
+		if err != nil {
+			return nil, h.newInternalError(req, err, "index repo stats failed")
+		}
+
+		res.Stats = statsToProto(stats)
+	}
+
 	for _, s := range schemas {
 		if s.Pattern == catchAllPattern {
 			continue
@@ -52,13 +70,24 @@ func (h *Handler) Get(
 		res.Schemas = append(res.Schemas, withDialect(s.Schema))
 	}
 
+	if index.SchemaVersion != 0 {
+		sch, err := h.repo.GetSchema(ctx, index.Name, index.SchemaVersion)
+		if err != nil {
+			return nil, h.newInternalError(req, err, "index repo get schema failed")
+		}
+
+		res.Schemas = append(res.Schemas, withDialect(sch.Schema))
+		res.SchemaVersion = sch.Version
+	}
+
 	return connect.NewResponse(res), nil
 }
 
 // withDialect returns the schema with a "$schema" dialect declaration. If the schema
-// already declares one it is returned unchanged; otherwise the draft-07 dialect is
-// prepended (the original key order of the rest is preserved). Non-object schemas
-// (e.g. malformed input or a boolean schema) are returned as-is.
+// already declares one it is returned unchanged, since the declared dialect is the one
+// honoured on validation; otherwise the default dialect is prepended, preserving the
+// key order of the rest. Non-object schemas (e.g. malformed input or a boolean schema)
+// are returned as-is.
 func withDialect(raw json.RawMessage) string {
 	var obj map[string]json.RawMessage
 	if err := json.Unmarshal(raw, &obj); err != nil || obj == nil {
@@ -70,10 +99,10 @@ func withDialect(raw json.RawMessage) string {
 	}
 
 	if len(obj) == 0 {
-		return `{"$schema":"` + draft07Schema + `"}`
+		return `{"$schema":"` + defaultDialect + `"}`
 	}
 
 	inner := bytes.TrimSpace(raw)
 
-	return `{"$schema":"` + draft07Schema + `",` + string(inner[1:])
+	return `{"$schema":"` + defaultDialect + `",` + string(inner[1:])
 }
@@ -33,7 +33,7 @@ func TestIndexHandler_Get(tt *testing.T) {
 		rm.On("Get", mock.Anything, mock.Anything).
 			Return(indexrepo.Index{}, apperrors.InvalidArgError{Subj: "theSubj", Reason: "theReason"})
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
 			Name: "theIndexName",
 		}))
@@ -52,7 +52,7 @@ func TestIndexHandler_Get(tt *testing.T) {
 		rm.On("Get", mock.Anything, mock.Anything).
 			Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "theSubj"})
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
 			Name: "theIndexName",
 		}))
@@ -71,7 +71,7 @@ func TestIndexHandler_Get(tt *testing.T) {
 		rm.On("Get", mock.Anything, mock.Anything).
 			Return(indexrepo.Index{}, errors.New("theRepoError"))
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
 			Name: "theIndexName",
 		}))
@@ -92,9 +92,20 @@ func TestIndexHandler_Get(tt *testing.T) {
 				ID:        123,
 				Name:      "theIndexName",
 				Title:     sql.NullString{String: "theIndexTitle", Valid: true},
+				TTL:       time.Hour,
+				Mode:      indexrepo.Mode{ReadOnly: true},
+				Labels:    map[string]string{"team": "search"},
+				Metadata:  json.RawMessage(`{"owner": "John"}`),
 				CreatedAt: time.Unix(123, 0),
 				UpdatedAt: time.Unix(234, 0),
 			}, nil)
+		rm.On("Stats", mock.Anything, "theIndexName", indexrepo.StatsRequest{}).
+			Return(indexrepo.Stats{
+				Records:        2,
+				HistoryRecords: 5,
+				DataSize:       1024,
+				LastPushAt:     sql.NullTime{Time: time.Unix(345, 0), Valid: true},
+			}, nil)
 
 		sm := &schemaMock{}
 		defer sm.AssertExpectations(t)
@@ -103,9 +114,10 @@ func TestIndexHandler_Get(tt *testing.T) {
 			{Pattern: "theIndex.*", Schema: json.RawMessage(`{"type":"object","required":["title"]}`)},
 		})
 
-		h := indexhandler.New(rm, sm, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
 		res, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
-			Name: "theIndexName",
+			Name:  "theIndexName",
+			Stats: &proto.StatsOptions{},
 		}))
 
 		require.NoError(t, err)
@@ -115,8 +127,158 @@ func TestIndexHandler_Get(tt *testing.T) {
 		assert.Equal(t, "theIndexTitle", res.Msg.Title)
 		assert.Equal(t, uint64(time.Unix(123, 0).Unix()), res.Msg.CreatedAt)
 		assert.Equal(t, uint64(time.Unix(234, 0).Unix()), res.Msg.UpdatedAt)
+		assert.Equal(t, uint64(3600), res.Msg.Ttl)
+		assert.True(t, res.Msg.ReadOnly)
+		assert.False(t, res.Msg.InsertOnly)
+		assert.False(t, res.Msg.NoHistory)
+		assert.Equal(t, map[string]string{"team": "search"}, res.Msg.Labels)
+		assert.Equal(t, `{"owner": "John"}`, res.Msg.Metadata)
 		assert.Equal(t, []string{
 			`{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","required":["title"]}`,
 		}, res.Msg.Schemas)
+		assert.Equal(t, &proto.IndexStats{
+			Records:        2,
+			HistoryRecords: 5,
+			DataSize:       1024,
+			LastPushAt:     345,
+		}, res.Msg.Stats)
+	})
+
+	tt.Run("StatsInternalError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndexName").
+			Return(indexrepo.Index{ID: 123, Name: "theIndexName"}, nil)
+		rm.On("Stats", mock.Anything, "theIndexName", mock.Anything).
+			Return(indexrepo.Stats{}, errors.New("theStatsError"))
+
+		sm := &schemaMock{}
+		sm.On("SchemasFor", "theIndexName").Return([]validation.Schema(nil))
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
+			Name:  "theIndexName",
+			Stats: &proto.StatsOptions{},
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theStatsError","proc":"","err_code":123456789,"message":"index repo stats failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("OkWithoutStats", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndexName").
+			Return(indexrepo.Index{ID: 123, Name: "theIndexName"}, nil)
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("SchemasFor", "theIndexName").Return([]validation.Schema(nil))
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		res, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
+			Name: "theIndexName",
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Nil(t, res.Msg.Stats)
+		rm.AssertNotCalled(t, "Stats", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	tt.Run("OkWithStatsOptions", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		notTouchedSince := time.Unix(1000, 0)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndexName").
+			Return(indexrepo.Index{ID: 123, Name: "theIndexName"}, nil)
+		rm.On("Stats", mock.Anything, "theIndexName", indexrepo.StatsRequest{
+			NotTouchedSince: &notTouchedSince,
+			Approximate:     true,
+		}).Return(indexrepo.Stats{Records: 3, NotTouched: 2}, nil)
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("SchemasFor", "theIndexName").Return([]validation.Schema(nil))
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		res, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
+			Name:  "theIndexName",
+			Stats: &proto.StatsOptions{NotTouchedSince: 1000, Approximate: true},
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Equal(t, &proto.IndexStats{Records: 3, NotTouched: 2}, res.Msg.Stats)
+	})
+
+	tt.Run("OkWithStoredSchema", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndexName").
+			Return(indexrepo.Index{ID: 123, Name: "theIndexName", SchemaVersion: 2}, nil)
+		rm.On("GetSchema", mock.Anything, "theIndexName", uint32(2)).
+			Return(indexrepo.Schema{Version: 2, Schema: json.RawMessage(`{"required":["author"]}`)}, nil)
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("SchemasFor", "theIndexName").Return([]validation.Schema{
+			{Pattern: "theIndex.*", Schema: json.RawMessage(`{"required":["title"]}`)},
+		})
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		res, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
+			Name: "theIndexName",
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Equal(t, uint32(2), res.Msg.SchemaVersion)
+		assert.Equal(t, []string{
+			`{"$schema":"http://json-schema.org/draft-07/schema#","required":["title"]}`,
+			`{"$schema":"http://json-schema.org/draft-07/schema#","required":["author"]}`,
+		}, res.Msg.Schemas)
+	})
+
+	tt.Run("GetSchemaInternalError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Get", mock.Anything, "theIndexName").
+			Return(indexrepo.Index{ID: 123, Name: "theIndexName", SchemaVersion: 2}, nil)
+		rm.On("GetSchema", mock.Anything, "theIndexName", uint32(2)).
+			Return(indexrepo.Schema{}, errors.New("theGetSchemaError"))
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("SchemasFor", "theIndexName").Return([]validation.Schema(nil))
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
+			Name: "theIndexName",
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theGetSchemaError","proc":"","err_code":123456789,"message":"index repo get schema failed"}`+"\n", lb.String())
 	})
 }
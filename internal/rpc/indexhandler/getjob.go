@@ -0,0 +1,38 @@
+package indexhandler
+
+import (
+	"context"
+	"errors"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func (h *Handler) GetJob(
+	ctx context.Context,
+	req *connect.Request[proto.GetJobRequest],
+) (*connect.Response[proto.GetJobResponse], error) {
+	job, err := h.jobs.Get(ctx, req.Msg.Id)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case err != nil:
+		return nil, h.newInternalError(req, err, "job get failed")
+	}
+
+	return connect.NewResponse(&proto.GetJobResponse{
+		Id:        job.ID,
+		Kind:      job.Kind,
+		Status:    job.Status,
+		Processed: job.Processed,
+		Total:     job.Total,
+		Error:     job.Error.String,
+		CreatedAt: uint64(job.CreatedAt.Unix()), //nolint:gosec // ok
+		UpdatedAt: uint64(job.UpdatedAt.Unix()), //nolint:gosec // ok
+	}), nil
+}
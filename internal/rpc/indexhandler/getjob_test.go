@@ -0,0 +1,88 @@
+package indexhandler_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/jobrepo"
+	"github.com/ashep/ujds/internal/rpc/indexhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func TestIndexHandler_GetJob(tt *testing.T) {
+	tt.Run("NotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Get", mock.Anything, uint64(123)).Return(jobrepo.Job{}, apperrors.NotFoundError{Subj: "job"})
+
+		h := indexhandler.New(nil, nil, jr, nil, nil, nil, nil, now, l)
+		_, err := h.GetJob(context.Background(), connect.NewRequest(&proto.GetJobRequest{Id: 123}))
+
+		assert.EqualError(t, err, "not_found: job is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("InternalError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Get", mock.Anything, uint64(123)).Return(jobrepo.Job{}, errors.New("theJobError"))
+
+		h := indexhandler.New(nil, nil, jr, nil, nil, nil, nil, now, l)
+		_, err := h.GetJob(context.Background(), connect.NewRequest(&proto.GetJobRequest{Id: 123}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theJobError","proc":"","err_code":123456789,"message":"job get failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		jr := &jobRunnerMock{}
+		defer jr.AssertExpectations(t)
+		jr.On("Get", mock.Anything, uint64(123)).Return(jobrepo.Job{
+			ID:        123,
+			Kind:      "theKind",
+			Status:    jobrepo.StatusFailed,
+			Processed: 12,
+			Total:     34,
+			Error:     sql.NullString{String: "theError", Valid: true},
+			CreatedAt: time.Unix(234, 0),
+			UpdatedAt: time.Unix(345, 0),
+		}, nil)
+
+		h := indexhandler.New(nil, nil, jr, nil, nil, nil, nil, now, l)
+		res, err := h.GetJob(context.Background(), connect.NewRequest(&proto.GetJobRequest{Id: 123}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Equal(t, uint64(123), res.Msg.Id)
+		assert.Equal(t, "theKind", res.Msg.Kind)
+		assert.Equal(t, "failed", res.Msg.Status)
+		assert.Equal(t, uint64(12), res.Msg.Processed)
+		assert.Equal(t, uint64(34), res.Msg.Total)
+		assert.Equal(t, "theError", res.Msg.Error)
+		assert.Equal(t, uint64(234), res.Msg.CreatedAt)
+		assert.Equal(t, uint64(345), res.Msg.UpdatedAt)
+	})
+}
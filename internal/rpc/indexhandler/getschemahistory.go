@@ -0,0 +1,42 @@
+package indexhandler
+
+import (
+	"context"
+	"errors"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func (h *Handler) GetSchemaHistory(
+	ctx context.Context,
+	req *connect.Request[proto.GetSchemaHistoryRequest],
+) (*connect.Response[proto.GetSchemaHistoryResponse], error) {
+	if req.Msg.Limit == 0 || req.Msg.Limit > listLimitMax {
+		req.Msg.Limit = listLimitMax
+	}
+
+	schemas, cur, err := h.repo.SchemaHistory(ctx, req.Msg.Name, req.Msg.Cursor, req.Msg.Limit)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case err != nil:
+		return nil, h.newInternalError(req, err, "index repo schema history failed")
+	}
+
+	items := make([]*proto.GetSchemaHistoryResponse_Schema, len(schemas))
+	for i, sch := range schemas {
+		items[i] = &proto.GetSchemaHistoryResponse_Schema{
+			Version:   sch.Version,
+			Schema:    string(sch.Schema),
+			CreatedAt: uint64(sch.CreatedAt.Unix()), //nolint:gosec // ok
+		}
+	}
+
+	return connect.NewResponse(&proto.GetSchemaHistoryResponse{Schemas: items, Cursor: cur}), nil
+}
@@ -0,0 +1,91 @@
+package indexhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/rpc/indexhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func TestIndexHandler_GetSchemaHistory(tt *testing.T) {
+	tt.Run("RepoNotFoundError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("SchemaHistory", mock.Anything, "theIndex", uint64(0), uint32(500)).
+			Return([]indexrepo.Schema(nil), uint64(0), apperrors.NotFoundError{Subj: "index"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.GetSchemaHistory(context.Background(), connect.NewRequest(&proto.GetSchemaHistoryRequest{
+			Name: "theIndex",
+		}))
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RepoInternalError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("SchemaHistory", mock.Anything, "theIndex", mock.Anything, mock.Anything).
+			Return([]indexrepo.Schema(nil), uint64(0), errors.New("theRepoError"))
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.GetSchemaHistory(context.Background(), connect.NewRequest(&proto.GetSchemaHistoryRequest{
+			Name: "theIndex",
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theRepoError","proc":"","err_code":123456789,"message":"index repo schema history failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("SchemaHistory", mock.Anything, "theIndex", uint64(5), uint32(2)).
+			Return([]indexrepo.Schema{
+				{Version: 4, Schema: json.RawMessage(`{"type":"object"}`), CreatedAt: time.Unix(400, 0)},
+				{Version: 3, Schema: json.RawMessage(`{}`), CreatedAt: time.Unix(300, 0)},
+			}, uint64(3), nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		res, err := h.GetSchemaHistory(context.Background(), connect.NewRequest(&proto.GetSchemaHistoryRequest{
+			Name:   "theIndex",
+			Cursor: 5,
+			Limit:  2,
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Equal(t, uint64(3), res.Msg.Cursor)
+		require.Len(t, res.Msg.Schemas, 2)
+		assert.Equal(t, uint32(4), res.Msg.Schemas[0].Version)
+		assert.Equal(t, `{"type":"object"}`, res.Msg.Schemas[0].Schema)
+		assert.Equal(t, uint64(400), res.Msg.Schemas[0].CreatedAt)
+		assert.Equal(t, uint32(3), res.Msg.Schemas[1].Version)
+	})
+}
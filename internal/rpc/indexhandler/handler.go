@@ -2,40 +2,111 @@ package indexhandler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"connectrpc.com/connect"
 	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/jobrepo"
+	"github.com/ashep/ujds/internal/jobrunner"
+	"github.com/ashep/ujds/internal/recordcompactor"
+	"github.com/ashep/ujds/internal/recordrepo"
 	"github.com/ashep/ujds/internal/validation"
 	"github.com/rs/zerolog"
 )
 
+const listLimitMax = 500
+
 type indexRepo interface {
-	Upsert(ctx context.Context, name, title string) error
+	Upsert(ctx context.Context, req indexrepo.UpsertRequest) error
 	Get(ctx context.Context, name string) (indexrepo.Index, error)
-	List(ctx context.Context) ([]indexrepo.Index, error)
+	List(ctx context.Context, req indexrepo.ListRequest) ([]indexrepo.Index, string, error)
 	Clear(ctx context.Context, name string) error
+	Stats(ctx context.Context, name string, req indexrepo.StatsRequest) (indexrepo.Stats, error)
+	IndicesStats(ctx context.Context, ids []uint64, req indexrepo.StatsRequest) (map[uint64]indexrepo.Stats, error)
+	SetSchema(ctx context.Context, name string, schema json.RawMessage) (uint32, error)
+	GetSchema(ctx context.Context, name string, version uint32) (indexrepo.Schema, error)
+	SchemaHistory(ctx context.Context, name string, cursor uint64, limit uint32) ([]indexrepo.Schema, uint64, error)
+}
+
+type recordRepo interface {
+	Count(ctx context.Context, indexID uint64, query string) (uint64, error)
+	Copy(ctx context.Context, req recordrepo.CopyRequest) (uint64, string, error)
+	Find(ctx context.Context, req recordrepo.FindRequest) ([]recordrepo.Record, uint64, error)
+	Export(
+		ctx context.Context,
+		indexID uint64,
+		withHistory bool,
+		archive recordrepo.ArchiveReader,
+		batchSize uint32,
+		fn func(rec recordrepo.ExportRecord) error,
+	) error
+}
+
+type jobRunner interface {
+	Start(ctx context.Context, kind string, task jobrunner.Task) (uint64, error)
+	Get(ctx context.Context, id uint64) (jobrepo.Job, error)
 }
 
 type schemaProvider interface {
 	SchemasFor(name string) []validation.Schema
+	CheckSchema(schema json.RawMessage) error
 }
 
 type nameValidator interface {
 	Validate(name string) error
 }
 
+type historyCompactor interface {
+	RetentionFor(index string) (recordcompactor.Retention, bool)
+	CompactIndex(
+		ctx context.Context,
+		indexID uint64,
+		ret recordcompactor.Retention,
+		progress func(deleted uint64),
+	) (uint64, error)
+}
+
+type historyArchive interface {
+	Archives(index string) bool
+	Revisions(ctx context.Context, indexID uint64, ids []string) (map[string][]recordrepo.Record, error)
+}
+
 type Handler struct {
 	repo      indexRepo
+	records   recordRepo
+	jobs      jobRunner
 	schemas   schemaProvider
 	nameValid nameValidator
+	compactor historyCompactor
+	archive   historyArchive
 	now       func() time.Time
 	l         zerolog.Logger
 }
 
-func New(repo indexRepo, schemas schemaProvider, nameValid nameValidator, now func() time.Time, l zerolog.Logger) *Handler {
-	return &Handler{repo: repo, schemas: schemas, nameValid: nameValid, now: now, l: l}
+func New(
+	repo indexRepo,
+	records recordRepo,
+	jobs jobRunner,
+	schemas schemaProvider,
+	nameValid nameValidator,
+	compactor historyCompactor,
+	archive historyArchive,
+	now func() time.Time,
+	l zerolog.Logger,
+) *Handler {
+	return &Handler{
+		repo:      repo,
+		records:   records,
+		jobs:      jobs,
+		schemas:   schemas,
+		nameValid: nameValid,
+		compactor: compactor,
+		archive:   archive,
+		now:       now,
+		l:         l,
+	}
 }
 
 func (h *Handler) newInternalError(req connect.AnyRequest, err error, msg string) error {
@@ -2,8 +2,13 @@ package indexhandler_test
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/jobrepo"
+	"github.com/ashep/ujds/internal/jobrunner"
+	"github.com/ashep/ujds/internal/recordcompactor"
+	"github.com/ashep/ujds/internal/recordrepo"
 	"github.com/ashep/ujds/internal/validation"
 	"github.com/stretchr/testify/mock"
 )
@@ -21,8 +26,13 @@ func (m *schemaMock) SchemasFor(name string) []validation.Schema {
 	return args.Get(0).([]validation.Schema)
 }
 
-func (m *repoMock) Upsert(ctx context.Context, name, title string) error {
-	args := m.Called(ctx, name, title)
+func (m *schemaMock) CheckSchema(schema json.RawMessage) error {
+	args := m.Called(schema)
+	return args.Error(0)
+}
+
+func (m *repoMock) Upsert(ctx context.Context, req indexrepo.UpsertRequest) error {
+	args := m.Called(ctx, req)
 	return args.Error(0)
 }
 
@@ -31,12 +41,136 @@ func (m *repoMock) Get(ctx context.Context, name string) (indexrepo.Index, error
 	return args.Get(0).(indexrepo.Index), args.Error(1)
 }
 
-func (m *repoMock) List(ctx context.Context) ([]indexrepo.Index, error) {
-	args := m.Called(ctx)
-	return args.Get(0).([]indexrepo.Index), args.Error(1)
+func (m *repoMock) List(ctx context.Context, req indexrepo.ListRequest) ([]indexrepo.Index, string, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]indexrepo.Index), args.String(1), args.Error(2)
 }
 
 func (m *repoMock) Clear(ctx context.Context, name string) error {
 	args := m.Called(ctx, name)
 	return args.Error(0)
 }
+
+func (m *repoMock) SetSchema(ctx context.Context, name string, schema json.RawMessage) (uint32, error) {
+	args := m.Called(ctx, name, schema)
+	return args.Get(0).(uint32), args.Error(1)
+}
+
+func (m *repoMock) GetSchema(ctx context.Context, name string, version uint32) (indexrepo.Schema, error) {
+	args := m.Called(ctx, name, version)
+	return args.Get(0).(indexrepo.Schema), args.Error(1)
+}
+
+func (m *repoMock) SchemaHistory(
+	ctx context.Context,
+	name string,
+	cursor uint64,
+	limit uint32,
+) ([]indexrepo.Schema, uint64, error) {
+	args := m.Called(ctx, name, cursor, limit)
+	return args.Get(0).([]indexrepo.Schema), args.Get(1).(uint64), args.Error(2)
+}
+
+func (m *repoMock) Stats(ctx context.Context, name string, req indexrepo.StatsRequest) (indexrepo.Stats, error) {
+	args := m.Called(ctx, name, req)
+	return args.Get(0).(indexrepo.Stats), args.Error(1)
+}
+
+func (m *repoMock) IndicesStats(
+	ctx context.Context,
+	ids []uint64,
+	req indexrepo.StatsRequest,
+) (map[uint64]indexrepo.Stats, error) {
+	args := m.Called(ctx, ids, req)
+	return args.Get(0).(map[uint64]indexrepo.Stats), args.Error(1)
+}
+
+type recordRepoMock struct {
+	mock.Mock
+}
+
+func (m *recordRepoMock) Count(ctx context.Context, indexID uint64, query string) (uint64, error) {
+	args := m.Called(ctx, indexID, query)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *recordRepoMock) Copy(ctx context.Context, req recordrepo.CopyRequest) (uint64, string, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).(uint64), args.String(1), args.Error(2)
+}
+
+func (m *recordRepoMock) Find(ctx context.Context, req recordrepo.FindRequest) ([]recordrepo.Record, uint64, error) {
+	args := m.Called(ctx, req)
+	return args.Get(0).([]recordrepo.Record), args.Get(1).(uint64), args.Error(2)
+}
+
+// Export passes the records returned by the mock to fn the way the repo does.
+func (m *recordRepoMock) Export(
+	ctx context.Context,
+	indexID uint64,
+	withHistory bool,
+	_ recordrepo.ArchiveReader,
+	batchSize uint32,
+	fn func(rec recordrepo.ExportRecord) error,
+) error {
+	args := m.Called(ctx, indexID, withHistory, batchSize)
+
+	for _, rec := range args.Get(0).([]recordrepo.ExportRecord) {
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+
+	return args.Error(1)
+}
+
+type jobRunnerMock struct {
+	mock.Mock
+}
+
+func (m *jobRunnerMock) Start(ctx context.Context, kind string, task jobrunner.Task) (uint64, error) {
+	args := m.Called(ctx, kind, task)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (m *jobRunnerMock) Get(ctx context.Context, id uint64) (jobrepo.Job, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(jobrepo.Job), args.Error(1)
+}
+
+type compactorMock struct {
+	mock.Mock
+}
+
+func (m *compactorMock) RetentionFor(index string) (recordcompactor.Retention, bool) {
+	args := m.Called(index)
+	return args.Get(0).(recordcompactor.Retention), args.Bool(1)
+}
+
+func (m *compactorMock) CompactIndex(
+	ctx context.Context,
+	indexID uint64,
+	ret recordcompactor.Retention,
+	progress func(deleted uint64),
+) (uint64, error) {
+	args := m.Called(ctx, indexID, ret, progress)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+type archiveMock struct {
+	mock.Mock
+}
+
+func (m *archiveMock) Archives(index string) bool {
+	args := m.Called(index)
+	return args.Bool(0)
+}
+
+func (m *archiveMock) Revisions(
+	ctx context.Context,
+	indexID uint64,
+	ids []string,
+) (map[string][]recordrepo.Record, error) {
+	args := m.Called(ctx, indexID, ids)
+	return args.Get(0).(map[string][]recordrepo.Record), args.Error(1)
+}
@@ -2,11 +2,12 @@ package indexhandler
 
 import (
 	"context"
-	"fmt"
-	"regexp"
-	"strings"
+	"errors"
 
 	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/labelselector"
 
 	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 )
@@ -15,66 +16,83 @@ func (h *Handler) List(
 	ctx context.Context,
 	req *connect.Request[proto.ListRequest],
 ) (*connect.Response[proto.ListResponse], error) {
-	patterns, err := h.makeIndexNameFilter(req.Msg.GetFilter())
-	if err != nil {
-		return nil, h.newInternalError(req, err, "index name filter build failed")
+	if req.Msg.Limit > listLimitMax {
+		req.Msg.Limit = listLimitMax
 	}
 
-	indices, err := h.repo.List(ctx)
-	if err != nil {
-		return nil, h.newInternalError(req, err, "index repo list failed")
+	var sort indexrepo.ListSort
+
+	switch req.Msg.Sort {
+	case proto.ListSort_LIST_SORT_NAME:
+		sort = indexrepo.SortByName
+	case proto.ListSort_LIST_SORT_CREATED_AT:
+		sort = indexrepo.SortByCreatedAt
+	case proto.ListSort_LIST_SORT_UPDATED_AT:
+		sort = indexrepo.SortByUpdatedAt
+	default:
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("invalid sort"))
 	}
 
-	respData := make([]*proto.ListResponse_Index, 0)
-
-	for _, idx := range indices {
-		if h.filterIndexName(patterns, idx.Name) {
-			respData = append(respData, &proto.ListResponse_Index{Name: idx.Name, Title: idx.Title.String})
-		}
-	}
-
-	return connect.NewResponse(&proto.ListResponse{
-		Indices: respData,
-	}), nil
-}
-
-func (h *Handler) makeIndexNameFilter(reqFilter *proto.ListRequestFilter) ([]*regexp.Regexp, error) {
-	if reqFilter == nil {
-		return nil, nil
+	labels, err := labelselector.Parse(req.Msg.GetFilter().GetLabels())
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, apperrors.InvalidArgError{
+			Subj:   "label selector",
+			Reason: err.Error(),
+		})
 	}
 
-	names := reqFilter.GetNames()
-	if len(names) == 0 {
-		return nil, nil
+	indices, cur, err := h.repo.List(ctx, indexrepo.ListRequest{
+		Filter: indexrepo.IndexFilter{
+			Names:  req.Msg.GetFilter().GetNames(),
+			Title:  req.Msg.GetFilter().GetTitle(),
+			Labels: labels,
+		},
+		Sort:   sort,
+		Desc:   req.Msg.SortDesc,
+		Cursor: req.Msg.Cursor,
+		Limit:  req.Msg.Limit,
+	})
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case err != nil:
+		return nil, h.newInternalError(req, err, "index repo list failed")
 	}
 
-	patterns := make([]*regexp.Regexp, len(names))
-
-	for i, pat := range names {
-		pat = strings.ReplaceAll(pat, ".", "\\.")
-		pat = strings.ReplaceAll(pat, "*", ".*")
+	var stats map[uint64]indexrepo.Stats
 
-		re, err := regexp.Compile("^" + pat + "$")
-		if err != nil {
-			return nil, fmt.Errorf("invalid index name pattern: %w", err)
+	if req.Msg.Stats != nil {
+		ids := make([]uint64, len(indices))
+		for i, idx := range indices {
+			ids[i] = idx.ID
 		}
 
-		patterns[i] = re
+		if stats, err = h.repo.IndicesStats(ctx, ids, statsRequest(req.Msg.Stats)); err != nil {
+			return nil, h.newInternalError(req, err, "index repo stats failed")
+		}
 	}
 
-	return patterns, nil
-}
+	respData := make([]*proto.ListResponse_Index, 0, len(indices))
 
-func (h *Handler) filterIndexName(patterns []*regexp.Regexp, name string) bool {
-	if len(patterns) == 0 {
-		return true
-	}
+	for _, idx := range indices {
+		item := &proto.ListResponse_Index{
+			Name:      idx.Name,
+			Title:     idx.Title.String,
+			CreatedAt: uint64(idx.CreatedAt.Unix()), //nolint:gosec // ok
+			UpdatedAt: uint64(idx.UpdatedAt.Unix()), //nolint:gosec // ok
+			Labels:    idx.Labels,
+			Metadata:  string(idx.Metadata),
+		}
 
-	for _, p := range patterns {
-		if p.MatchString(name) {
-			return true
+		if stats != nil {
+			item.Stats = statsToProto(stats[idx.ID])
 		}
+
+		respData = append(respData, item)
 	}
 
-	return false
+	return connect.NewResponse(&proto.ListResponse{
+		Indices: respData,
+		Cursor:  cur,
+	}), nil
 }
@@ -3,13 +3,16 @@ package indexhandler_test
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
 	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/labelselector"
 	"github.com/ashep/ujds/internal/rpc/indexhandler"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -27,10 +30,10 @@ func TestIndexHandler_List(tt *testing.T) {
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("List", mock.Anything).
-			Return([]indexrepo.Index(nil), errors.New("theRepoListError"))
+		rm.On("List", mock.Anything, mock.Anything).
+			Return([]indexrepo.Index(nil), "", errors.New("theRepoListError"))
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{}))
 
 		assert.EqualError(t, err, "internal: err_code: 123456789")
@@ -44,7 +47,7 @@ func TestIndexHandler_List(tt *testing.T) {
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("List", mock.Anything).
+		rm.On("List", mock.Anything, indexrepo.ListRequest{}).
 			Return([]indexrepo.Index{
 				{
 					ID:        123,
@@ -60,59 +63,191 @@ func TestIndexHandler_List(tt *testing.T) {
 					CreatedAt: time.Unix(432, 0),
 					UpdatedAt: time.Unix(543, 0),
 				},
-			}, nil)
+			}, "", nil)
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		res, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{}))
 
 		require.NoError(t, err)
 		require.Len(t, res.Msg.Indices, 2)
 		assert.Empty(t, lb.String())
+		assert.Zero(t, res.Msg.Cursor)
 
 		assert.Equal(t, "theIndex1", res.Msg.Indices[0].Name)
 		assert.Equal(t, "theTitle1", res.Msg.Indices[0].Title)
+		assert.Equal(t, uint64(234), res.Msg.Indices[0].CreatedAt)
+		assert.Equal(t, uint64(345), res.Msg.Indices[0].UpdatedAt)
 
 		assert.Equal(t, "theIndex2", res.Msg.Indices[1].Name)
 		assert.Equal(t, "theTitle2", res.Msg.Indices[1].Title)
+		assert.Equal(t, uint64(432), res.Msg.Indices[1].CreatedAt)
+		assert.Equal(t, uint64(543), res.Msg.Indices[1].UpdatedAt)
+	})
+
+	tt.Run("RepoInvalidArgError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("List", mock.Anything, indexrepo.ListRequest{Cursor: "theCursor"}).
+			Return([]indexrepo.Index(nil), "", apperrors.InvalidArgError{Subj: "cursor", Reason: "malformed"})
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{Cursor: "theCursor"}))
+
+		assert.EqualError(t, err, "invalid_argument: invalid cursor: malformed")
+		assert.Empty(t, lb.String())
 	})
 
-	tt.Run("OkWithFilter", func(t *testing.T) {
+	tt.Run("LimitOverMax", func(t *testing.T) {
 		now := func() time.Time { return time.Unix(123456789, 0) }
 		lb := &strings.Builder{}
 		l := zerolog.New(lb)
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("List", mock.Anything).
+		rm.On("List", mock.Anything, indexrepo.ListRequest{Limit: 500}).
+			Return([]indexrepo.Index{}, "theNextCursor", nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		res, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{Limit: 501}))
+
+		require.NoError(t, err)
+		assert.Equal(t, "theNextCursor", res.Msg.Cursor)
+	})
+
+	tt.Run("InvalidSort", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := indexhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{
+			Sort: proto.ListSort(123),
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: invalid sort")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("InvalidLabelSelector", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := indexhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{
+			Filter: &proto.ListRequestFilter{Labels: "team=search,,env=prod"},
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: invalid label selector: empty requirement")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkWithFilterSortAndPagination", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		sel, err := labelselector.Parse("team=search")
+		require.NoError(t, err)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("List", mock.Anything, indexrepo.ListRequest{
+			Filter: indexrepo.IndexFilter{Names: []string{"theIndex2*"}, Title: "theTitle", Labels: sel},
+			Sort:   indexrepo.SortByCreatedAt,
+			Desc:   true,
+			Cursor: "theCursor",
+			Limit:  1,
+		}).
 			Return([]indexrepo.Index{
-				{
-					ID:        123,
-					Name:      "theIndex1Foo",
-					Title:     sql.NullString{String: "theTitle1", Valid: true},
-					CreatedAt: time.Unix(234, 0),
-					UpdatedAt: time.Unix(345, 0),
-				},
 				{
 					ID:        321,
 					Name:      "theIndex2Bar",
 					Title:     sql.NullString{String: "theTitle2", Valid: true},
+					Labels:    map[string]string{"team": "search"},
+					Metadata:  json.RawMessage(`{"owner": "John"}`),
 					CreatedAt: time.Unix(432, 0),
 					UpdatedAt: time.Unix(543, 0),
 				},
-			}, nil)
+			}, "theNextCursor", nil)
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		res, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{
 			Filter: &proto.ListRequestFilter{
-				Names: []string{"theIndex2*"},
+				Names:  []string{"theIndex2*"},
+				Title:  "theTitle",
+				Labels: "team=search",
 			},
+			Sort:     proto.ListSort_LIST_SORT_CREATED_AT,
+			SortDesc: true,
+			Cursor:   "theCursor",
+			Limit:    1,
 		}))
 
 		require.NoError(t, err)
 		require.Len(t, res.Msg.Indices, 1)
 		assert.Empty(t, lb.String())
+		assert.Equal(t, "theNextCursor", res.Msg.Cursor)
 
 		assert.Equal(t, "theIndex2Bar", res.Msg.Indices[0].Name)
 		assert.Equal(t, "theTitle2", res.Msg.Indices[0].Title)
+		assert.Equal(t, map[string]string{"team": "search"}, res.Msg.Indices[0].Labels)
+		assert.Equal(t, `{"owner": "John"}`, res.Msg.Indices[0].Metadata)
+	})
+
+	tt.Run("StatsInternalError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("List", mock.Anything, mock.Anything).
+			Return([]indexrepo.Index{{ID: 123, Name: "theIndex1"}}, "", nil)
+		rm.On("IndicesStats", mock.Anything, []uint64{123}, mock.Anything).
+			Return(map[uint64]indexrepo.Stats(nil), errors.New("theStatsError"))
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{
+			Stats: &proto.StatsOptions{},
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theStatsError","proc":"","err_code":123456789,"message":"index repo stats failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("OkWithStats", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("List", mock.Anything, mock.Anything).
+			Return([]indexrepo.Index{
+				{ID: 123, Name: "theIndex1"},
+				{ID: 321, Name: "theIndex2"},
+			}, "", nil)
+		rm.On("IndicesStats", mock.Anything, []uint64{123, 321}, indexrepo.StatsRequest{Approximate: true}).
+			Return(map[uint64]indexrepo.Stats{
+				123: {Records: 1, DataSize: 10},
+				321: {Records: 2, HistoryRecords: 3, DataSize: 20},
+			}, nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		res, err := h.List(context.Background(), connect.NewRequest(&proto.ListRequest{
+			Stats: &proto.StatsOptions{Approximate: true},
+		}))
+
+		require.NoError(t, err)
+		require.Len(t, res.Msg.Indices, 2)
+		assert.Empty(t, lb.String())
+
+		assert.Equal(t, &proto.IndexStats{Records: 1, DataSize: 10}, res.Msg.Indices[0].Stats)
+		assert.Equal(t, &proto.IndexStats{Records: 2, HistoryRecords: 3, DataSize: 20}, res.Msg.Indices[1].Stats)
 	})
 }
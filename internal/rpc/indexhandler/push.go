@@ -2,19 +2,56 @@ package indexhandler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
 
 	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 )
 
+// Push creates an index or updates an existing one. Fields which are not set in the request are left unchanged.
 func (h *Handler) Push(
 	ctx context.Context,
 	req *connect.Request[proto.PushRequest],
 ) (*connect.Response[proto.PushResponse], error) {
-	err := h.repo.Upsert(ctx, req.Msg.Name, req.Msg.Title)
+	upsertReq := indexrepo.UpsertRequest{
+		Name:       req.Msg.Name,
+		Title:      req.Msg.Title,
+		ReadOnly:   req.Msg.ReadOnly,
+		InsertOnly: req.Msg.InsertOnly,
+		NoHistory:  req.Msg.NoHistory,
+	}
+
+	if req.Msg.Ttl != nil {
+		if *req.Msg.Ttl > uint64(indexrepo.MaxTTL/time.Second) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, apperrors.InvalidArgError{
+				Subj:   "ttl",
+				Reason: fmt.Sprintf("must be between 0 and %d seconds", indexrepo.MaxTTL/time.Second),
+			})
+		}
+
+		ttl := time.Duration(*req.Msg.Ttl) * time.Second //nolint:gosec // checked above
+		upsertReq.TTL = &ttl
+	}
+
+	if req.Msg.Labels != nil {
+		upsertReq.Labels = req.Msg.Labels.Values
+		if upsertReq.Labels == nil {
+			upsertReq.Labels = map[string]string{}
+		}
+	}
+
+	if req.Msg.Metadata != nil {
+		metadata := json.RawMessage(*req.Msg.Metadata)
+		upsertReq.Metadata = &metadata
+	}
+
+	err := h.repo.Upsert(ctx, upsertReq)
 
 	switch {
 	case errors.As(err, &apperrors.InvalidArgError{}):
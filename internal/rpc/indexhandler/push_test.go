@@ -2,6 +2,7 @@ package indexhandler_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
@@ -9,6 +10,7 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
 	"github.com/ashep/ujds/internal/rpc/indexhandler"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
@@ -25,10 +27,10 @@ func TestIndexHandler_Push(tt *testing.T) {
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("Upsert", mock.Anything, mock.Anything, mock.Anything).
+		rm.On("Upsert", mock.Anything, mock.Anything).
 			Return(apperrors.InvalidArgError{Subj: "theSubj", Reason: "theReason"})
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Name: "theIndexName",
 		}))
@@ -44,10 +46,10 @@ func TestIndexHandler_Push(tt *testing.T) {
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("Upsert", mock.Anything, mock.Anything, mock.Anything).
+		rm.On("Upsert", mock.Anything, mock.Anything).
 			Return(errors.New("theRepoError"))
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Name: "theIndexName",
 		}))
@@ -63,10 +65,10 @@ func TestIndexHandler_Push(tt *testing.T) {
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("Upsert", mock.Anything, mock.Anything, mock.Anything).
+		rm.On("Upsert", mock.Anything, mock.Anything).
 			Return(apperrors.NotFoundError{Subj: "theNotFoundSubj"})
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Name: "theIndexName",
 		}))
@@ -82,10 +84,10 @@ func TestIndexHandler_Push(tt *testing.T) {
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("Upsert", mock.Anything, "theIndexName", "").
-			Return(nil)
+		// Fields which are not set are left unchanged
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{Name: "theIndexName"}).Return(nil)
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Name:  "theIndexName",
 			Title: "",
@@ -102,10 +104,10 @@ func TestIndexHandler_Push(tt *testing.T) {
 
 		rm := &repoMock{}
 		defer rm.AssertExpectations(t)
-		rm.On("Upsert", mock.Anything, "theIndexName", "theIndexTitle").
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{Name: "theIndexName", Title: "theIndexTitle"}).
 			Return(nil)
 
-		h := indexhandler.New(rm, nil, nil, now, l)
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Name:  "theIndexName",
 			Title: "theIndexTitle",
@@ -114,4 +116,120 @@ func TestIndexHandler_Push(tt *testing.T) {
 		assert.NoError(t, err)
 		assert.Empty(t, lb.String())
 	})
+	tt.Run("TTLTooLong", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
+			Name: "theIndexName",
+			Ttl:  ptr(uint64(1 << 31)),
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: invalid ttl: must be between 0 and 2147483647 seconds")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkWithTTL", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{Name: "theIndexName", TTL: ptr(time.Hour)}).
+			Return(nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
+			Name: "theIndexName",
+			Ttl:  ptr(uint64(3600)),
+		}))
+
+		assert.NoError(t, err)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkWithMode", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{
+			Name:       "theIndexName",
+			ReadOnly:   ptr(false),
+			InsertOnly: ptr(true),
+			NoHistory:  ptr(true),
+		}).Return(nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
+			Name:       "theIndexName",
+			ReadOnly:   ptr(false),
+			InsertOnly: ptr(true),
+			NoHistory:  ptr(true),
+		}))
+
+		assert.NoError(t, err)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkWithLabelsAndMetadata", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{
+			Name:     "theIndexName",
+			Labels:   map[string]string{"team": "search"},
+			Metadata: ptr(json.RawMessage(`{"owner":"John"}`)),
+		}).Return(nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
+			Name:     "theIndexName",
+			Labels:   &proto.PushRequest_Labels{Values: map[string]string{"team": "search"}},
+			Metadata: ptr(`{"owner":"John"}`),
+		}))
+
+		assert.NoError(t, err)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkEmptyLabelsAndMetadata", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		// Labels and metadata which are set but empty are cleared
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("Upsert", mock.Anything, indexrepo.UpsertRequest{
+			Name:     "theIndexName",
+			Labels:   map[string]string{},
+			Metadata: ptr(json.RawMessage{}),
+		}).Return(nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
+			Name:     "theIndexName",
+			Labels:   &proto.PushRequest_Labels{},
+			Metadata: ptr(""),
+		}))
+
+		assert.NoError(t, err)
+		assert.Empty(t, lb.String())
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
 }
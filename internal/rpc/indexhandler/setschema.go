@@ -0,0 +1,38 @@
+package indexhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func (h *Handler) SetSchema(
+	ctx context.Context,
+	req *connect.Request[proto.SetSchemaRequest],
+) (*connect.Response[proto.SetSchemaResponse], error) {
+	schema := json.RawMessage(req.Msg.Schema)
+
+	if len(schema) != 0 {
+		if err := h.schemas.CheckSchema(schema); err != nil {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
+	}
+
+	version, err := h.repo.SetSchema(ctx, req.Msg.Name, schema)
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case err != nil:
+		return nil, h.newInternalError(req, err, "index repo set schema failed")
+	}
+
+	return connect.NewResponse(&proto.SetSchemaResponse{Version: version}), nil
+}
@@ -0,0 +1,135 @@
+package indexhandler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/rpc/indexhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func TestIndexHandler_SetSchema(tt *testing.T) {
+	tt.Run("InvalidSchema", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("CheckSchema", json.RawMessage(`{]`)).
+			Return(apperrors.InvalidArgError{Subj: "json schema", Reason: "theReason"})
+
+		h := indexhandler.New(nil, nil, nil, sm, nil, nil, nil, now, l)
+		_, err := h.SetSchema(context.Background(), connect.NewRequest(&proto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{]`,
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: invalid json schema: theReason")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RepoNotFoundError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("CheckSchema", mock.Anything).Return(nil)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("SetSchema", mock.Anything, "theIndex", json.RawMessage(`{}`)).
+			Return(uint32(0), apperrors.NotFoundError{Subj: "index"})
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		_, err := h.SetSchema(context.Background(), connect.NewRequest(&proto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{}`,
+		}))
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RepoInternalError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("CheckSchema", mock.Anything).Return(nil)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("SetSchema", mock.Anything, "theIndex", mock.Anything).
+			Return(uint32(0), errors.New("theRepoError"))
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		_, err := h.SetSchema(context.Background(), connect.NewRequest(&proto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{}`,
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theRepoError","proc":"","err_code":123456789,"message":"index repo set schema failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		sm := &schemaMock{}
+		defer sm.AssertExpectations(t)
+		sm.On("CheckSchema", json.RawMessage(`{"type":"object"}`)).Return(nil)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("SetSchema", mock.Anything, "theIndex", json.RawMessage(`{"type":"object"}`)).
+			Return(uint32(4), nil)
+
+		h := indexhandler.New(rm, nil, nil, sm, nil, nil, nil, now, l)
+		res, err := h.SetSchema(context.Background(), connect.NewRequest(&proto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{"type":"object"}`,
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Equal(t, uint32(4), res.Msg.Version)
+	})
+
+	tt.Run("OkDeactivate", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		rm := &repoMock{}
+		defer rm.AssertExpectations(t)
+		rm.On("SetSchema", mock.Anything, "theIndex", json.RawMessage{}).
+			Return(uint32(0), nil)
+
+		h := indexhandler.New(rm, nil, nil, nil, nil, nil, nil, now, l)
+		res, err := h.SetSchema(context.Background(), connect.NewRequest(&proto.SetSchemaRequest{
+			Name: "theIndex",
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Zero(t, res.Msg.Version)
+	})
+}
@@ -0,0 +1,37 @@
+package indexhandler
+
+import (
+	"time"
+
+	"github.com/ashep/ujds/internal/indexrepo"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+)
+
+func statsRequest(opts *proto.StatsOptions) indexrepo.StatsRequest {
+	req := indexrepo.StatsRequest{
+		Approximate: opts.GetApproximate(),
+	}
+
+	if opts.GetNotTouchedSince() != 0 {
+		t := time.Unix(opts.GetNotTouchedSince(), 0)
+		req.NotTouchedSince = &t
+	}
+
+	return req
+}
+
+func statsToProto(st indexrepo.Stats) *proto.IndexStats {
+	res := &proto.IndexStats{
+		Records:        st.Records,
+		HistoryRecords: st.HistoryRecords,
+		DataSize:       st.DataSize,
+		NotTouched:     st.NotTouched,
+	}
+
+	if st.LastPushAt.Valid {
+		res.LastPushAt = uint64(st.LastPushAt.Time.Unix()) //nolint:gosec // ok
+	}
+
+	return res
+}
@@ -0,0 +1,223 @@
+package recordhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordrepo"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+// Batch runs put, patch and delete operations on records of any indices within a single transaction. Either all the
+// operations are applied or none of them.
+func (h *Handler) Batch(
+	ctx context.Context,
+	req *connect.Request[proto.BatchRequest],
+) (*connect.Response[proto.BatchResponse], error) {
+	if len(req.Msg.GetOperations()) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("empty operations"))
+	}
+
+	cache := make(map[string]indexrepo.Index)
+	ops := make([]recordrepo.BatchOp, 0, len(req.Msg.GetOperations()))
+
+	// Data validation doesn't stop at the first invalid put, so clients get all the violations at once
+	var dataErr *connect.Error
+
+	for i, op := range req.Msg.GetOperations() {
+		co, err := h.checkBatchOp(ctx, req.Spec().Procedure, i, op, cache)
+		if err != nil {
+			return nil, err
+		}
+
+		if co.dataErr != nil {
+			if dataErr == nil {
+				dataErr = connect.NewError(
+					connect.CodeInvalidArgument,
+					fmt.Errorf("operation %d, id=%s: validation failed: %w", i, co.op.ID, co.dataErr),
+				)
+			}
+
+			if err := addValidationErrorDetail(dataErr, i, op.GetIndex(), co.op.ID, co.dataErr); err != nil {
+				c := h.now().UnixMilli()
+				h.l.Error().Err(err).Str("proc", req.Spec().Procedure).Int64("err_code", c).Msg("validation error detail failed")
+
+				return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+			}
+
+			continue
+		}
+
+		ops = append(ops, co.op)
+	}
+
+	if dataErr != nil {
+		return nil, dataErr
+	}
+
+	results, err := h.rr.Batch(ctx, ops)
+
+	cErr := &connect.Error{}
+
+	switch {
+	case errors.As(err, &cErr):
+		// Patched data validation errors are made by patch funcs
+		return nil, cErr
+	case errors.As(err, &recordrepo.PreconditionError{}), errors.As(err, &indexrepo.ModeError{}):
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	case errors.As(err, &apperrors.NotFoundError{}):
+		return nil, connect.NewError(connect.CodeNotFound, err)
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case err != nil:
+		c := h.now().UnixMilli()
+		h.l.Error().Err(err).Str("proc", req.Spec().Procedure).Int64("err_code", c).Msg("record repo batch failed")
+
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+	}
+
+	res := &proto.BatchResponse{Results: make([]*proto.BatchResponse_Result, 0, len(results))}
+	for _, r := range results {
+		res.Results = append(res.Results, &proto.BatchResponse_Result{Id: r.ID, Rev: r.Rev, Status: r.Status})
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+// checkedBatchOp is a batch operation which passed the checks of its index and ID.
+type checkedBatchOp struct {
+	op      recordrepo.BatchOp
+	dataErr error // put data validation error
+}
+
+// checkBatchOp runs the checks of an operation and converts it to the repo one. Puts are checked the same way Push
+// checks records; the data validation error is returned separately, since it doesn't prevent checking other
+// operations. Patched data can only be validated within the transaction, so it is checked by the patch func.
+func (h *Handler) checkBatchOp(
+	ctx context.Context,
+	proc string,
+	i int,
+	op *proto.BatchRequest_Operation,
+	cache map[string]indexrepo.Index,
+) (checkedBatchOp, error) {
+	subj := fmt.Sprintf("operation %d", i)
+	rec := &proto.PushRequest_Record{
+		Index:     op.GetIndex(),
+		Id:        op.GetId(),
+		ExpiresAt: op.GetExpiresAt(),
+		Data:      op.GetData(),
+	}
+
+	switch op.GetOp() {
+	case recordrepo.BatchPut:
+		cr, err := h.checkRecord(ctx, proc, subj, rec, cache)
+		if err != nil {
+			return checkedBatchOp{}, err
+		}
+
+		if cr.index.Mode.ReadOnly {
+			return checkedBatchOp{}, readOnlyError(subj, cr.id)
+		}
+
+		return checkedBatchOp{
+			op: recordrepo.BatchOp{
+				Type:        recordrepo.BatchPut,
+				IndexID:     cr.index.ID,
+				ID:          cr.id,
+				Data:        cr.data,
+				ExpiresAt:   cr.expiresAt,
+				ExpectedRev: op.GetExpectedRev(),
+				MustExist:   op.GetMustExist(),
+			},
+			dataErr: cr.dataErr,
+		}, nil
+	case recordrepo.BatchPatch, recordrepo.BatchDelete:
+	default:
+		return checkedBatchOp{}, connect.NewError(
+			connect.CodeInvalidArgument,
+			fmt.Errorf("%s: validation failed: %w", subj, apperrors.InvalidArgError{
+				Subj:   "op",
+				Reason: "must be put, patch or delete",
+			}),
+		)
+	}
+
+	// Records to patch or delete can't be addressed by a generated ID
+	if op.GetId() == "" {
+		return checkedBatchOp{}, connect.NewError(
+			connect.CodeInvalidArgument,
+			fmt.Errorf("%s: validation failed: %w", subj, apperrors.InvalidArgError{Subj: "id", Reason: "must not be empty"}),
+		)
+	}
+
+	if op.GetOp() == recordrepo.BatchDelete {
+		rec.ExpiresAt = 0
+	}
+
+	cr, err := h.checkRecordMeta(ctx, proc, subj, rec, cache)
+	if err != nil {
+		return checkedBatchOp{}, err
+	}
+
+	if cr.index.Mode.ReadOnly {
+		return checkedBatchOp{}, readOnlyError(subj, cr.id)
+	}
+
+	res := recordrepo.BatchOp{
+		Type:        op.GetOp(),
+		IndexID:     cr.index.ID,
+		ID:          cr.id,
+		ExpectedRev: op.GetExpectedRev(),
+		MustExist:   op.GetMustExist(),
+	}
+
+	if op.GetOp() == recordrepo.BatchPatch {
+		if !json.Valid([]byte(op.GetData())) {
+			return checkedBatchOp{}, connect.NewError(
+				connect.CodeInvalidArgument,
+				fmt.Errorf("%s, id=%s: validation failed: %w", subj, cr.id, apperrors.InvalidArgError{
+					Subj:   "patch",
+					Reason: "malformed json",
+				}),
+			)
+		}
+
+		res.ExpiresAt = cr.expiresAt
+		res.Patch = h.patchFunc(i, op.GetIndex(), cr.id, op.GetData())
+	}
+
+	return checkedBatchOp{op: res}, nil
+}
+
+// patchFunc returns the func which applies the patch to a record's current data and checks the result the same way
+// Push checks data. Validation errors are returned as connect errors, ready to be sent to the client.
+func (h *Handler) patchFunc(i int, index, id, patch string) func(data string) (string, error) {
+	return func(data string) (string, error) {
+		merged, err := mergePatch(data, patch)
+		if err != nil {
+			return "", fmt.Errorf("merge patch: %w", err)
+		}
+
+		res, vErr := h.checkData(index, merged)
+		if vErr != nil {
+			cErr := connect.NewError(
+				connect.CodeInvalidArgument,
+				fmt.Errorf("operation %d, id=%s: validation failed: %w", i, id, vErr),
+			)
+
+			if err := addValidationErrorDetail(cErr, i, index, id, vErr); err != nil {
+				return "", err
+			}
+
+			return "", cErr
+		}
+
+		return res, nil
+	}
+}
@@ -0,0 +1,305 @@
+package recordhandler_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/rpc/recordhandler"
+	"github.com/ashep/ujds/internal/validation"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+func TestRecordHandler_Batch(tt *testing.T) {
+	now := func() time.Time { return time.Unix(1234567890, 987654321) }
+
+	// okMocks returns the mocks which pass all the checks of records of the theIndex index
+	okMocks := func() (*indexRepoMock, *stringValidatorMock, *recordIDValidatorMock, *keyStringValidatorMock) {
+		ir := &indexRepoMock{}
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123, TTL: time.Hour}, nil)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "theIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("ValidateForIndex", "theIndex", mock.Anything).
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(0))
+
+		return ir, idxNameValidator, recIDValidator, recDataValidator
+	}
+
+	tt.Run("EmptyOperations", func(t *testing.T) {
+		lb := &strings.Builder{}
+
+		h := recordhandler.New(&indexRepoMock{}, &recordRepoMock{}, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, &sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{}))
+
+		assert.EqualError(t, err, "invalid_argument: empty operations")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("InvalidOp", func(t *testing.T) {
+		lb := &strings.Builder{}
+
+		h := recordhandler.New(&indexRepoMock{}, &recordRepoMock{}, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, &sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "theOp", Index: "theIndex", Id: "theRecordID"},
+		}}))
+
+		assert.EqualError(t, err, "invalid_argument: operation 0: validation failed: invalid op: must be put, patch or delete")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("DeleteEmptyID", func(t *testing.T) {
+		lb := &strings.Builder{}
+
+		h := recordhandler.New(&indexRepoMock{}, &recordRepoMock{}, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, &sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "delete", Index: "theIndex"},
+		}}))
+
+		assert.EqualError(t, err, "invalid_argument: operation 0: validation failed: invalid id: must not be empty")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("MalformedPatch", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+
+		h := recordhandler.New(ir, &recordRepoMock{}, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "patch", Index: "theIndex", Id: "theRecordID", Data: `{`},
+		}}))
+
+		assert.EqualError(t, err, "invalid_argument: operation 0, id=theRecordID: validation failed: invalid patch: malformed json")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("PutValidationError", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+		recDataValidator.On("ApplyDefaults", "theIndex", "theData").
+			Return("theData", nil)
+		recDataValidator.On("Validate", "theIndex", "theData").
+			Return(validation.DataError{Violations: []validation.Violation{{Pointer: "/foo", Keyword: "type", Message: "theMessage"}}})
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "delete", Index: "theIndex", Id: "theRecordID1"},
+			{Op: "put", Index: "theIndex", Id: "theRecordID2", Data: "theData"},
+		}}))
+
+		cErr := &connect.Error{}
+		require.ErrorAs(t, err, &cErr)
+		assert.Equal(t, connect.CodeInvalidArgument, cErr.Code())
+		assert.True(t, strings.HasPrefix(cErr.Message(), "operation 1, id=theRecordID2: validation failed: "))
+		require.Len(t, cErr.Details(), 1)
+
+		d, err := cErr.Details()[0].Value()
+		require.NoError(t, err)
+
+		vErr, ok := d.(*proto.ValidationError)
+		require.True(t, ok)
+		assert.Equal(t, uint32(1), vErr.GetRecord())
+		assert.Equal(t, "theRecordID2", vErr.GetRecordId())
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoPreconditionError", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Batch", mock.Anything, mock.Anything).
+			Return([]recordrepo.BatchResult(nil), fmt.Errorf("operation 0, id=theRecordID: %w",
+				recordrepo.PreconditionError{Reason: "record doesn't exist"}))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "delete", Index: "theIndex", Id: "theRecordID", MustExist: true},
+		}}))
+
+		assert.EqualError(t, err, "failed_precondition: operation 0, id=theRecordID: precondition failed: record doesn't exist")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoModeError", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Batch", mock.Anything, mock.Anything).
+			Return([]recordrepo.BatchResult(nil), indexrepo.ModeError{Reason: "record theRecordID exists in an insert-only index"})
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "delete", Index: "theIndex", Id: "theRecordID"},
+		}}))
+
+		assert.EqualError(t, err, "failed_precondition: record theRecordID exists in an insert-only index")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoNotFoundError", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Batch", mock.Anything, mock.Anything).
+			Return([]recordrepo.BatchResult(nil), fmt.Errorf("operation 0, id=theRecordID: %w",
+				apperrors.NotFoundError{Subj: "record"}))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "patch", Index: "theIndex", Id: "theRecordID", Data: `{}`},
+		}}))
+
+		assert.EqualError(t, err, "not_found: operation 0, id=theRecordID: record is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoInternalError", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Batch", mock.Anything, mock.Anything).
+			Return([]recordrepo.BatchResult(nil), errors.New("theRecordRepoError"))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "delete", Index: "theIndex", Id: "theRecordID"},
+		}}))
+
+		assert.EqualError(t, err, "internal: err_code: 1234567890987")
+		assert.Equal(t, `{"level":"error","error":"theRecordRepoError","proc":"","err_code":1234567890987,"message":"record repo batch failed"}
+`, lb.String())
+	})
+
+	tt.Run("PatchedDataValidationError", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+		recDataValidator.On("ApplyDefaults", "theIndex", `{"a":1,"b":2}`).
+			Return(`{"a":1,"b":2}`, nil)
+		recDataValidator.On("Validate", "theIndex", `{"a":1,"b":2}`).
+			Return(errors.New("theValidationError"))
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		// The repo gets the patch func's error and returns it wrapped
+		call := rr.On("Batch", mock.Anything, mock.Anything)
+		call.Run(func(args mock.Arguments) {
+			_, err := args.Get(1).([]recordrepo.BatchOp)[0].Patch(`{"a": 1}`)
+			call.Return([]recordrepo.BatchResult(nil), fmt.Errorf("operation 0, id=theRecordID: %w", err))
+		})
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "patch", Index: "theIndex", Id: "theRecordID", Data: `{"b":2}`},
+		}}))
+
+		cErr := &connect.Error{}
+		require.ErrorAs(t, err, &cErr)
+		assert.Equal(t, connect.CodeInvalidArgument, cErr.Code())
+		assert.Equal(t, "operation 0, id=theRecordID: validation failed: theValidationError", cErr.Message())
+		assert.Len(t, cErr.Details(), 1)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		lb := &strings.Builder{}
+		ir, idxNameValidator, recIDValidator, recDataValidator := okMocks()
+		recIDValidator.On("Generate", "theIndex").
+			Return("theGeneratedID", nil)
+		for _, data := range []string{`{"foo":"bar"}`, `{"a":{"b":1,"c":4},"d":3}`} {
+			recDataValidator.On("ApplyDefaults", "theIndex", data).
+				Return(data, nil)
+			recDataValidator.On("Validate", "theIndex", data).
+				Return(nil)
+		}
+
+		expiresAt := sql.NullTime{Time: now().Add(time.Hour).UTC(), Valid: true}
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Batch", mock.Anything, mock.MatchedBy(func(ops []recordrepo.BatchOp) bool {
+			if len(ops) != 3 || ops[1].Patch == nil {
+				return false
+			}
+
+			// The patch is applied to the data the repo passes
+			patched, err := ops[1].Patch(`{"a": {"b": 1, "c": 2}, "d": 3}`)
+			if err != nil || patched != `{"a":{"b":1,"c":4},"d":3}` {
+				return false
+			}
+
+			ops[1].Patch = nil
+
+			return assert.ObjectsAreEqual([]recordrepo.BatchOp{
+				{Type: "put", IndexID: 123, ID: "theGeneratedID", Data: `{"foo":"bar"}`, ExpiresAt: expiresAt},
+				{Type: "patch", IndexID: 123, ID: "theRecordID1", ExpiresAt: expiresAt, ExpectedRev: 5},
+				{Type: "delete", IndexID: 123, ID: "theRecordID2", MustExist: true},
+			}, ops)
+		})).
+			Return([]recordrepo.BatchResult{
+				{ID: "theGeneratedID", Rev: 10, Status: recordrepo.BatchCreated},
+				{ID: "theRecordID1", Rev: 11, Status: recordrepo.BatchUpdated},
+				{ID: "theRecordID2", Status: recordrepo.BatchDeleted},
+			}, nil)
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator,
+			&sweepPolicyMock{}, &archiveMock{}, now, zerolog.New(lb))
+		res, err := h.Batch(context.Background(), connect.NewRequest(&proto.BatchRequest{Operations: []*proto.BatchRequest_Operation{
+			{Op: "put", Index: "theIndex", Data: `{"foo":"bar"}`},
+			{Op: "patch", Index: "theIndex", Id: "theRecordID1", ExpectedRev: 5, Data: `{"a":{"c":4}}`},
+			{Op: "delete", Index: "theIndex", Id: "theRecordID2", MustExist: true, ExpiresAt: 1},
+		}}))
+
+		require.NoError(t, err)
+		require.Len(t, res.Msg.GetResults(), 3)
+		assert.Equal(t, "theGeneratedID", res.Msg.GetResults()[0].GetId())
+		assert.Equal(t, uint64(10), res.Msg.GetResults()[0].GetRev())
+		assert.Equal(t, "created", res.Msg.GetResults()[0].GetStatus())
+		assert.Equal(t, "updated", res.Msg.GetResults()[1].GetStatus())
+		assert.Equal(t, "deleted", res.Msg.GetResults()[2].GetStatus())
+		assert.Zero(t, res.Msg.GetResults()[2].GetRev())
+		assert.Empty(t, lb.String())
+	})
+}
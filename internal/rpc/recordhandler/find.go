@@ -63,6 +63,8 @@ func (h *Handler) Find(
 			CreatedAt: rec.CreatedAt.Unix(),
 			UpdatedAt: rec.UpdatedAt.Unix(),
 			TouchedAt: rec.TouchedAt.Unix(),
+			ExpiresAt: unixOrZero(rec.ExpiresAt),
+			StaleAt:   unixOrZero(rec.StaleAt),
 		}
 	}
 
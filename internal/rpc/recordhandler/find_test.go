@@ -36,10 +36,10 @@ func TestRecordHandler_GetAll(tt *testing.T) {
 			})
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Find(context.Background(), connect.NewRequest(&proto.FindRequest{}))
 
 		assert.EqualError(t, err, "invalid_argument: invalid theRecordRepoSubj: theRecordRepoReason")
@@ -57,10 +57,10 @@ func TestRecordHandler_GetAll(tt *testing.T) {
 			Return([]recordrepo.Record(nil), uint64(0), errors.New("theRecordRepoError"))
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Find(context.Background(), connect.NewRequest(&proto.FindRequest{}))
 
 		assert.EqualError(t, err, "internal: err_code: 123456789")
@@ -97,10 +97,10 @@ func TestRecordHandler_GetAll(tt *testing.T) {
 			}, uint64(345), nil)
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		res, err := h.Find(context.Background(), connect.NewRequest(&proto.FindRequest{
 			Index: "theIndexName",
 		}))
@@ -0,0 +1,69 @@
+package recordhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/recordrepo"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+// FindStale lists records the stale records sweeper would delete or flag if it ran now, without changing them.
+func (h *Handler) FindStale(
+	ctx context.Context,
+	req *connect.Request[proto.FindStaleRequest],
+) (*connect.Response[proto.FindStaleResponse], error) {
+	if req.Msg.Limit == 0 || req.Msg.Limit > perPageMax {
+		req.Msg.Limit = perPageMax
+	}
+
+	d, action, ok := h.sweepPolicies.PolicyFor(req.Msg.Index)
+	if !ok {
+		return nil, connect.NewError(connect.CodeNotFound, apperrors.NotFoundError{Subj: "sweep policy"})
+	}
+
+	ntSince := h.now().Add(-d)
+
+	records, cur, err := h.rr.Find(ctx, recordrepo.FindRequest{
+		Index:           req.Msg.Index,
+		NotTouchedSince: &ntSince,
+		Cursor:          req.Msg.Cursor,
+		Limit:           req.Msg.Limit,
+	})
+
+	switch {
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case err != nil:
+		c := h.now().Unix()
+		h.l.Error().Err(err).Str("proc", req.Spec().Procedure).Int64("err_code", c).Msg("record repo find failed")
+
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+	}
+
+	itemsR := make([]*proto.Record, len(records))
+	for i, rec := range records {
+		itemsR[i] = &proto.Record{
+			Id:        rec.ID,
+			Rev:       rec.Rev,
+			Index:     req.Msg.Index,
+			Data:      rec.Data,
+			CreatedAt: rec.CreatedAt.Unix(),
+			UpdatedAt: rec.UpdatedAt.Unix(),
+			TouchedAt: rec.TouchedAt.Unix(),
+			ExpiresAt: unixOrZero(rec.ExpiresAt),
+			StaleAt:   unixOrZero(rec.StaleAt),
+		}
+	}
+
+	return connect.NewResponse(&proto.FindStaleResponse{
+		Cursor:          cur,
+		Records:         itemsR,
+		Action:          action,
+		NotTouchedSince: ntSince.Unix(),
+	}), nil
+}
@@ -0,0 +1,138 @@
+package recordhandler_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/rpc/recordhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+func TestRecordHandler_FindStale(tt *testing.T) {
+	tt.Run("PolicyNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+
+		sp := &sweepPolicyMock{}
+		sp.On("PolicyFor", "theIndexName").Return(time.Duration(0), "", false)
+
+		h := recordhandler.New(&indexRepoMock{}, &recordRepoMock{}, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, sp, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.FindStale(context.Background(), connect.NewRequest(&proto.FindStaleRequest{
+			Index: "theIndexName",
+		}))
+
+		assert.EqualError(t, err, "not_found: sweep policy is not found")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoInvalidArgumentError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+
+		sp := &sweepPolicyMock{}
+		sp.On("PolicyFor", "theIndexName").Return(time.Hour, "delete", true)
+
+		rr := &recordRepoMock{}
+		rr.On("Find", mock.Anything, mock.Anything).
+			Return([]recordrepo.Record(nil), uint64(0), apperrors.InvalidArgError{
+				Subj:   "theRecordRepoSubj",
+				Reason: "theRecordRepoReason",
+			})
+
+		h := recordhandler.New(&indexRepoMock{}, rr, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, sp, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.FindStale(context.Background(), connect.NewRequest(&proto.FindStaleRequest{
+			Index: "theIndexName",
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: invalid theRecordRepoSubj: theRecordRepoReason")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoInternalError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+
+		sp := &sweepPolicyMock{}
+		sp.On("PolicyFor", "theIndexName").Return(time.Hour, "delete", true)
+
+		rr := &recordRepoMock{}
+		rr.On("Find", mock.Anything, mock.Anything).
+			Return([]recordrepo.Record(nil), uint64(0), errors.New("theRecordRepoError"))
+
+		h := recordhandler.New(&indexRepoMock{}, rr, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, sp, &archiveMock{}, now, zerolog.New(lb))
+		_, err := h.FindStale(context.Background(), connect.NewRequest(&proto.FindStaleRequest{
+			Index: "theIndexName",
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theRecordRepoError","proc":"","err_code":123456789,"message":"record repo find failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+
+		sp := &sweepPolicyMock{}
+		sp.On("PolicyFor", "theIndexName").Return(time.Hour, "flag", true)
+
+		ntSince := time.Unix(123456789-3600, 0)
+
+		rr := &recordRepoMock{}
+		rr.On("Find", mock.Anything, recordrepo.FindRequest{
+			Index:           "theIndexName",
+			NotTouchedSince: &ntSince,
+			Cursor:          12,
+			Limit:           34,
+		}).Return([]recordrepo.Record{
+			{
+				ID:        "theRecordID",
+				IndexID:   11,
+				Rev:       123,
+				Data:      `{"foo":"bar"}`,
+				CreatedAt: time.Unix(111, 0),
+				UpdatedAt: time.Unix(112, 0),
+				TouchedAt: time.Unix(113, 0),
+				StaleAt:   sql.NullTime{Time: time.Unix(114, 0), Valid: true},
+			},
+		}, uint64(345), nil)
+
+		h := recordhandler.New(&indexRepoMock{}, rr, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, sp, &archiveMock{}, now, zerolog.New(lb))
+		res, err := h.FindStale(context.Background(), connect.NewRequest(&proto.FindStaleRequest{
+			Index:  "theIndexName",
+			Cursor: 12,
+			Limit:  34,
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+
+		assert.Equal(t, uint64(345), res.Msg.Cursor)
+		assert.Equal(t, "flag", res.Msg.Action)
+		assert.Equal(t, int64(123456789-3600), res.Msg.NotTouchedSince)
+
+		require.Len(t, res.Msg.Records, 1)
+		assert.Equal(t, "theRecordID", res.Msg.Records[0].Id)
+		assert.Equal(t, "theIndexName", res.Msg.Records[0].Index)
+		assert.Equal(t, uint64(123), res.Msg.Records[0].Rev)
+		assert.Equal(t, `{"foo":"bar"}`, res.Msg.Records[0].Data)
+		assert.Equal(t, int64(113), res.Msg.Records[0].TouchedAt)
+		assert.Equal(t, int64(114), res.Msg.Records[0].StaleAt)
+		assert.Zero(t, res.Msg.Records[0].ExpiresAt)
+	})
+}
@@ -2,6 +2,7 @@ package recordhandler
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 
@@ -36,6 +37,17 @@ func (h *Handler) Get(
 		CreatedAt: rec.CreatedAt.Unix(),
 		UpdatedAt: rec.UpdatedAt.Unix(),
 		TouchedAt: rec.TouchedAt.Unix(),
+		ExpiresAt: unixOrZero(rec.ExpiresAt),
+		StaleAt:   unixOrZero(rec.StaleAt),
 		Data:      rec.Data,
 	}}), nil
 }
+
+// unixOrZero returns the UNIX timestamp of the time or zero if the time is not set.
+func unixOrZero(t sql.NullTime) int64 {
+	if !t.Valid {
+		return 0
+	}
+
+	return t.Time.Unix()
+}
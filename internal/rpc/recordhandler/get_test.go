@@ -35,10 +35,10 @@ func TestRecordHandler_Get(tt *testing.T) {
 			})
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{}))
 
 		assert.EqualError(t, err, "invalid_argument: invalid theRecordRepoSubj: theRecordRepoReason")
@@ -58,10 +58,10 @@ func TestRecordHandler_Get(tt *testing.T) {
 			})
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{}))
 
 		assert.EqualError(t, err, "not_found: theRecordRepoSubj is not found")
@@ -79,10 +79,10 @@ func TestRecordHandler_Get(tt *testing.T) {
 			Return(recordrepo.Record{}, errors.New("theRecordRepoError"))
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{}))
 
 		assert.EqualError(t, err, "internal: err_code: 123456789")
@@ -108,10 +108,10 @@ func TestRecordHandler_Get(tt *testing.T) {
 			}, nil)
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		res, err := h.Get(context.Background(), connect.NewRequest(&proto.GetRequest{
 			Index: "theIndexName",
 			Id:    "theRecordID",
@@ -2,6 +2,7 @@ package recordhandler
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/ashep/ujds/internal/indexrepo"
@@ -13,6 +14,7 @@ const perPageMax = 500
 
 type indexRepo interface {
 	Get(ctx context.Context, name string) (indexrepo.Index, error)
+	GetSchema(ctx context.Context, name string, version uint32) (indexrepo.Schema, error)
 }
 
 type recordRepo interface {
@@ -20,6 +22,8 @@ type recordRepo interface {
 	Get(ctx context.Context, index string, id string) (recordrepo.Record, error)
 	Find(ctx context.Context, req recordrepo.FindRequest) ([]recordrepo.Record, uint64, error)
 	History(ctx context.Context, index, id string, since time.Time, cursor uint64, limit uint32) ([]recordrepo.Record, uint64, error)
+	Import(ctx context.Context, indexID uint64, next func() (recordrepo.ImportRow, error)) (recordrepo.ImportResult, error)
+	Batch(ctx context.Context, ops []recordrepo.BatchOp) ([]recordrepo.BatchResult, error)
 }
 
 type stringValidator interface {
@@ -30,12 +34,34 @@ type keyStringValidator interface {
 	Validate(k, v string) error
 }
 
+type recordIDValidator interface {
+	ValidateForIndex(index, id string) error
+	Generate(index string) (string, error)
+}
+
+type recordDataValidator interface {
+	keyStringValidator
+	ApplyDefaults(index, data string) (string, error)
+	StoredSchemaVersion(index string) uint32
+	SetStoredSchema(index string, version uint32, schema json.RawMessage) error
+}
+
+type sweepPolicyProvider interface {
+	PolicyFor(index string) (time.Duration, string, bool)
+}
+
+type historyArchive interface {
+	History(ctx context.Context, index, id string, since time.Time, cursor uint64, limit uint32) ([]recordrepo.Record, error)
+}
+
 type Handler struct {
 	ir               indexRepo
 	rr               recordRepo
 	idxNameValidator stringValidator
-	recIDValidator   stringValidator
-	recJSONValidator keyStringValidator
+	recIDValidator   recordIDValidator
+	recJSONValidator recordDataValidator
+	sweepPolicies    sweepPolicyProvider
+	archive          historyArchive
 	now              func() time.Time
 	l                zerolog.Logger
 }
@@ -43,9 +69,11 @@ type Handler struct {
 func New(
 	ir indexRepo,
 	rr recordRepo,
-	idxNameValidator,
-	recIDValidator stringValidator,
-	recDataValidator keyStringValidator,
+	idxNameValidator stringValidator,
+	recIDValidator recordIDValidator,
+	recDataValidator recordDataValidator,
+	sweepPolicies sweepPolicyProvider,
+	archive historyArchive,
 	now func() time.Time,
 	l zerolog.Logger,
 ) *Handler {
@@ -55,6 +83,8 @@ func New(
 		idxNameValidator: idxNameValidator,
 		recIDValidator:   recIDValidator,
 		recJSONValidator: recDataValidator,
+		sweepPolicies:    sweepPolicies,
+		archive:          archive,
 		now:              now,
 		l:                l,
 	}
@@ -2,6 +2,9 @@ package recordhandler_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"time"
 
 	"github.com/ashep/ujds/internal/indexrepo"
@@ -18,6 +21,11 @@ func (m *indexRepoMock) Get(ctx context.Context, name string) (indexrepo.Index,
 	return args.Get(0).(indexrepo.Index), args.Error(1)
 }
 
+func (m *indexRepoMock) GetSchema(ctx context.Context, name string, version uint32) (indexrepo.Schema, error) {
+	args := m.Called(ctx, name, version)
+	return args.Get(0).(indexrepo.Schema), args.Error(1)
+}
+
 type recordRepoMock struct {
 	mock.Mock
 }
@@ -53,6 +61,35 @@ func (m *recordRepoMock) History(
 	return args.Get(0).([]recordrepo.Record), args.Get(1).(uint64), args.Error(2)
 }
 
+// Import drains the rows func the way the repo does and passes the collected rows to the mock.
+func (m *recordRepoMock) Import(
+	ctx context.Context,
+	indexID uint64,
+	next func() (recordrepo.ImportRow, error),
+) (recordrepo.ImportResult, error) {
+	rows := make([]recordrepo.ImportRow, 0)
+
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return recordrepo.ImportResult{}, err
+		}
+
+		rows = append(rows, row)
+	}
+
+	args := m.Called(ctx, indexID, rows)
+
+	return args.Get(0).(recordrepo.ImportResult), args.Error(1)
+}
+
+func (m *recordRepoMock) Batch(ctx context.Context, ops []recordrepo.BatchOp) ([]recordrepo.BatchResult, error) {
+	args := m.Called(ctx, ops)
+	return args.Get(0).([]recordrepo.BatchResult), args.Error(1)
+}
+
 type stringValidatorMock struct {
 	mock.Mock
 }
@@ -66,7 +103,61 @@ type keyStringValidatorMock struct {
 	mock.Mock
 }
 
+func (m *keyStringValidatorMock) StoredSchemaVersion(index string) uint32 {
+	args := m.Called(index)
+	return args.Get(0).(uint32)
+}
+
+func (m *keyStringValidatorMock) SetStoredSchema(index string, version uint32, schema json.RawMessage) error {
+	args := m.Called(index, version, schema)
+	return args.Error(0)
+}
+
 func (m *keyStringValidatorMock) Validate(k, v string) error {
 	args := m.Called(k, v)
 	return args.Error(0)
 }
+
+func (m *keyStringValidatorMock) ApplyDefaults(index, data string) (string, error) {
+	args := m.Called(index, data)
+	return args.String(0), args.Error(1)
+}
+
+type recordIDValidatorMock struct {
+	mock.Mock
+}
+
+func (m *recordIDValidatorMock) ValidateForIndex(index, id string) error {
+	args := m.Called(index, id)
+	return args.Error(0)
+}
+
+func (m *recordIDValidatorMock) Generate(index string) (string, error) {
+	args := m.Called(index)
+	return args.String(0), args.Error(1)
+}
+
+type sweepPolicyMock struct {
+	mock.Mock
+}
+
+func (m *sweepPolicyMock) PolicyFor(index string) (time.Duration, string, bool) {
+	args := m.Called(index)
+	return args.Get(0).(time.Duration), args.String(1), args.Bool(2)
+}
+
+type archiveMock struct {
+	mock.Mock
+}
+
+func (m *archiveMock) History(
+	ctx context.Context,
+	index string,
+	id string,
+	since time.Time,
+	cursor uint64,
+	limit uint32,
+) ([]recordrepo.Record, error) {
+	args := m.Called(ctx, index, id, since, cursor, limit)
+	return args.Get(0).([]recordrepo.Record), args.Error(1)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"connectrpc.com/connect"
@@ -33,6 +34,26 @@ func (h *Handler) History(
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
 	}
 
+	// Archived revisions may interleave with the ones in the database, e.g. if history has been copied from another
+	// index, so they are merged rather than appended
+	archived, err := h.archive.History(ctx, req.Msg.Index, req.Msg.Id, since, req.Msg.Cursor, req.Msg.Limit+1)
+	if err != nil {
+		c := h.now().Unix()
+		h.l.Error().Err(err).Str("proc", req.Spec().Procedure).Int64("err_code", c).Msg("record archive history failed")
+
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+	}
+
+	if len(archived) != 0 {
+		records = append(records, archived...)
+		sort.Slice(records, func(i, j int) bool { return records[i].Rev > records[j].Rev })
+
+		if len(records) > int(req.Msg.Limit) {
+			records = records[:req.Msg.Limit]
+			cur = records[len(records)-1].Rev
+		}
+	}
+
 	itemsR := make([]*proto.Record, len(records))
 	for i, rec := range records {
 		itemsR[i] = &proto.Record{
@@ -36,10 +36,10 @@ func TestRecordHandler_History(tt *testing.T) {
 			})
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.History(context.Background(), connect.NewRequest(&proto.HistoryRequest{}))
 
 		assert.EqualError(t, err, "invalid_argument: invalid theRecordRepoSubj: theRecordRepoReason")
@@ -57,10 +57,10 @@ func TestRecordHandler_History(tt *testing.T) {
 			Return([]recordrepo.Record(nil), uint64(0), errors.New("theRecordRepoInternalError"))
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.History(context.Background(), connect.NewRequest(&proto.HistoryRequest{}))
 
 		assert.EqualError(t, err, "internal: err_code: 123456789")
@@ -95,10 +95,14 @@ func TestRecordHandler_History(tt *testing.T) {
 			}, uint64(78), nil)
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		am := &archiveMock{}
+		am.On("History", mock.Anything, "theIndexName", "theRecordID", time.Unix(55, 0), uint64(77), uint32(67)).
+			Return([]recordrepo.Record(nil), nil)
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, am, now, l)
 		res, err := h.History(context.Background(), connect.NewRequest(&proto.HistoryRequest{
 			Index:  "theIndexName",
 			Id:     "theRecordID",
@@ -128,4 +132,61 @@ func TestRecordHandler_History(tt *testing.T) {
 		assert.Equal(t, int64(0), res.Msg.Records[1].UpdatedAt)
 		assert.Equal(t, int64(0), res.Msg.Records[1].TouchedAt)
 	})
+	tt.Run("ArchiveError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+
+		rr := &recordRepoMock{}
+		rr.On("History", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return([]recordrepo.Record{}, uint64(0), nil)
+
+		am := &archiveMock{}
+		am.On("History", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return([]recordrepo.Record(nil), errors.New("theArchiveError"))
+
+		h := recordhandler.New(&indexRepoMock{}, rr, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, &sweepPolicyMock{}, am, now, zerolog.New(lb))
+		_, err := h.History(context.Background(), connect.NewRequest(&proto.HistoryRequest{}))
+
+		assert.EqualError(t, err, "internal: err_code: 123456789")
+		assert.Equal(t, `{"level":"error","error":"theArchiveError","proc":"","err_code":123456789,"message":"record archive history failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("OkWithArchived", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+
+		rr := &recordRepoMock{}
+		rr.On("History", mock.Anything, "theIndexName", "theRecordID", time.Unix(0, 0), uint64(0), uint32(3)).
+			Return([]recordrepo.Record{
+				{ID: "theRecordID", Rev: 50, Data: "theData50"},
+				{ID: "theRecordID", Rev: 30, Data: "theData30"},
+			}, uint64(0), nil)
+
+		am := &archiveMock{}
+		am.On("History", mock.Anything, "theIndexName", "theRecordID", time.Unix(0, 0), uint64(0), uint32(4)).
+			Return([]recordrepo.Record{
+				{ID: "theRecordID", Rev: 40, Data: "theData40"},
+				{ID: "theRecordID", Rev: 20, Data: "theData20"},
+				{ID: "theRecordID", Rev: 10, Data: "theData10"},
+			}, nil)
+
+		h := recordhandler.New(&indexRepoMock{}, rr, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, &sweepPolicyMock{}, am, now, zerolog.New(lb))
+		res, err := h.History(context.Background(), connect.NewRequest(&proto.HistoryRequest{
+			Index: "theIndexName",
+			Id:    "theRecordID",
+			Limit: 3,
+		}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+		assert.Equal(t, uint64(30), res.Msg.Cursor)
+
+		require.Len(t, res.Msg.Records, 3)
+		assert.Equal(t, uint64(50), res.Msg.Records[0].Rev)
+		assert.Equal(t, uint64(40), res.Msg.Records[1].Rev)
+		assert.Equal(t, "theData40", res.Msg.Records[1].Data)
+		assert.Equal(t, uint64(30), res.Msg.Records[2].Rev)
+	})
 }
@@ -0,0 +1,265 @@
+package recordhandler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+// importErrorsMax is the max number of per-line errors returned by an import, the rest ones are only counted.
+const importErrorsMax = 1000
+
+// importNDJSONProc is the procedure name of the NDJSON import endpoint used in logs.
+const importNDJSONProc = "/import"
+
+// importLine is a record read from an import stream.
+type importLine struct {
+	num uint64 // line number, starting from 1
+	rec *proto.ImportRequest_Record
+	err error // the line couldn't be read as a record
+}
+
+// ndjsonRecord is a line of the NDJSON import.
+type ndjsonRecord struct {
+	ID        string          `json:"id"`
+	ExpiresAt int64           `json:"expires_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Import loads a stream of records into an index. Invalid records don't stop the import, they are reported as
+// per-line errors, while the valid ones are merged into the index within a single transaction.
+func (h *Handler) Import(
+	ctx context.Context,
+	stream *connect.ClientStream[proto.ImportRequest],
+) (*connect.Response[proto.ImportResponse], error) {
+	if !stream.Receive() {
+		if err := stream.Err(); err != nil {
+			return nil, err //nolint:wrapcheck // ok
+		}
+
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("empty stream"))
+	}
+
+	index := stream.Msg().GetIndex()
+	recs := stream.Msg().GetRecords()
+	num := uint64(0)
+
+	read := func() (importLine, error) {
+		for len(recs) == 0 {
+			if !stream.Receive() {
+				if err := stream.Err(); err != nil {
+					return importLine{}, err //nolint:wrapcheck // ok
+				}
+
+				return importLine{}, io.EOF
+			}
+
+			if stream.Msg().GetIndex() != "" && stream.Msg().GetIndex() != index {
+				return importLine{}, connect.NewError(connect.CodeInvalidArgument,
+					errors.New("index must not change within the stream"))
+			}
+
+			recs = stream.Msg().GetRecords()
+		}
+
+		num++
+		rec := recs[0]
+		recs = recs[1:]
+
+		return importLine{num: num, rec: rec}, nil
+	}
+
+	res, err := h.importRecords(ctx, stream.Spec().Procedure, index, read)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+// ImportNDJSON is the HTTP counterpart of Import. It reads records of the index given in the path from the request
+// body, one JSON object per line, and responds with the same result as Import does.
+func (h *Handler) ImportNDJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	br := bufio.NewReader(r.Body)
+	num := uint64(0)
+
+	read := func() (importLine, error) {
+		for {
+			b, err := br.ReadBytes('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return importLine{}, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("read body: %w", err))
+			} else if len(b) == 0 {
+				return importLine{}, io.EOF
+			}
+
+			// Blank lines are skipped, but still counted, so error line numbers match the ones of the source
+			num++
+			if b = bytes.TrimSpace(b); len(b) == 0 {
+				continue
+			}
+
+			rec := ndjsonRecord{}
+			if err := json.Unmarshal(b, &rec); err != nil {
+				return importLine{num: num, err: fmt.Errorf("malformed line: %w", err)}, nil
+			}
+
+			return importLine{num: num, rec: &proto.ImportRequest_Record{
+				Id:        rec.ID,
+				ExpiresAt: rec.ExpiresAt,
+				Data:      string(rec.Data),
+			}}, nil
+		}
+	}
+
+	res, err := h.importRecords(r.Context(), importNDJSONProc, r.PathValue("index"), read)
+	if err != nil {
+		_ = connect.NewErrorWriter().Write(w, r, err)
+		return
+	}
+
+	b, err := protojson.Marshal(res)
+	if err != nil {
+		c := h.now().UnixMilli()
+		h.l.Error().Err(err).Str("proc", importNDJSONProc).Int64("err_code", c).Msg("response marshal failed")
+		_ = connect.NewErrorWriter().Write(w, r, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c)))
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := w.Write(b); err != nil {
+		h.l.Warn().Err(err).Str("proc", importNDJSONProc).Msg("response write failed")
+	}
+}
+
+// importRecords runs the checks of Push against each record returned by read and imports the valid ones. The read
+// func must return io.EOF after the last record; any other error aborts the import.
+func (h *Handler) importRecords(
+	ctx context.Context,
+	proc string,
+	index string,
+	read func() (importLine, error),
+) (*proto.ImportResponse, error) {
+	cache := make(map[string]indexrepo.Index)
+
+	idx, err := h.getIndex(ctx, proc, index, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	if idx.Mode.ReadOnly {
+		return nil, connect.NewError(connect.CodeFailedPrecondition, recordrepo.ErrReadOnly)
+	}
+
+	res := &proto.ImportResponse{Errors: make([]*proto.ImportResponse_Error, 0)}
+
+	addErr := func(num uint64, id, msg string, violations []*proto.ValidationError_Violation) {
+		res.Failed++
+
+		if len(res.Errors) < importErrorsMax {
+			res.Errors = append(res.Errors, &proto.ImportResponse_Error{
+				Line:       num,
+				RecordId:   id,
+				Error:      msg,
+				Violations: violations,
+			})
+		}
+	}
+
+	var readErr error
+
+	next := func() (recordrepo.ImportRow, error) {
+		for {
+			l, err := read()
+			if errors.Is(err, io.EOF) {
+				return recordrepo.ImportRow{}, io.EOF
+			} else if err != nil {
+				readErr = err
+				return recordrepo.ImportRow{}, err
+			}
+
+			if l.err != nil {
+				addErr(l.num, "", l.err.Error(), nil)
+				continue
+			}
+
+			cr, err := h.checkRecord(ctx, proc, fmt.Sprintf("record %d", l.num), &proto.PushRequest_Record{
+				Index:     index,
+				Id:        l.rec.GetId(),
+				ExpiresAt: l.rec.GetExpiresAt(),
+				Data:      l.rec.GetData(),
+			}, cache)
+
+			cErr := &connect.Error{}
+
+			switch {
+			case errors.As(err, &cErr) && cErr.Code() == connect.CodeInvalidArgument:
+				addErr(l.num, l.rec.GetId(), cErr.Message(), nil)
+				continue
+			case err != nil:
+				readErr = err
+				return recordrepo.ImportRow{}, err
+			case cr.dataErr != nil:
+				addErr(l.num, cr.id, "validation failed: "+cr.dataErr.Error(), violationsToProto(cr.dataErr))
+				continue
+			case !json.Valid([]byte(cr.data)):
+				// Data isn't checked to be JSON if there is no schema, and a single malformed row would fail the COPY
+				addErr(l.num, cr.id, "validation failed: malformed json", nil)
+				continue
+			}
+
+			return recordrepo.ImportRow{
+				RecordUpdate: recordrepo.RecordUpdate{ID: cr.id, Data: cr.data, ExpiresAt: cr.expiresAt},
+				Line:         l.num,
+			}, nil
+		}
+	}
+
+	ir, err := h.rr.Import(ctx, idx.ID, next)
+
+	switch {
+	case readErr != nil:
+		return nil, readErr
+	case errors.As(err, &indexrepo.ModeError{}):
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	case errors.As(err, &apperrors.InvalidArgError{}):
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	case err != nil:
+		c := h.now().UnixMilli()
+		h.l.Error().Err(err).Str("proc", proc).Int64("err_code", c).Msg("record repo import failed")
+
+		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+	}
+
+	for _, d := range ir.Duplicates {
+		addErr(d.Line, d.ID, fmt.Sprintf("superseded by line %d", d.SupersededBy), nil)
+	}
+
+	sort.SliceStable(res.Errors, func(i, j int) bool { return res.Errors[i].Line < res.Errors[j].Line })
+
+	res.Created = ir.Created
+	res.Updated = ir.Updated
+	res.Unchanged = ir.Unchanged
+
+	return res, nil
+}
@@ -0,0 +1,166 @@
+package recordhandler_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/rpc/recordhandler"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+func TestRecordHandler_ImportNDJSON(tt *testing.T) {
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/import/theIndex", strings.NewReader(body))
+		req.SetPathValue("index", "theIndex")
+
+		return req
+	}
+
+	tt.Run("MethodNotAllowed", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := recordhandler.New(nil, nil, nil, nil, nil, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ImportNDJSON(w, httptest.NewRequest(http.MethodGet, "/import/theIndex", nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("IndexNotFound", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"})
+
+		h := recordhandler.New(ir, nil, nil, nil, nil, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ImportNDJSON(w, newRequest(`{"id":"theRecordID","data":{}}`))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"code":"not_found","message":"index is not found"}`, w.Body.String())
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("RecordRepoError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Import", mock.Anything, uint64(123), []recordrepo.ImportRow{}).
+			Return(recordrepo.ImportResult{}, errors.New("theRecordRepoError"))
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("StoredSchemaVersion", "theIndex").Return(uint32(0))
+
+		h := recordhandler.New(ir, rr, nil, nil, recDataValidator, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ImportNDJSON(w, newRequest(""))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"code":"internal","message":"err_code: 123456789000"}`, w.Body.String())
+		assert.Equal(t, `{"level":"error","error":"theRecordRepoError","proc":"/import","err_code":123456789000,"message":"record repo import failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(123456789, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Import", mock.Anything, uint64(123), []recordrepo.ImportRow{
+			{RecordUpdate: recordrepo.RecordUpdate{ID: "theRecordID1", Data: `{"foo":1}`}, Line: 1},
+			{RecordUpdate: recordrepo.RecordUpdate{ID: "theRecordID1", Data: `{"foo":4}`}, Line: 6},
+			{RecordUpdate: recordrepo.RecordUpdate{ID: "theGeneratedID", Data: `{"foo":5}`}, Line: 7},
+		}).Return(recordrepo.ImportResult{
+			Created:    1,
+			Updated:    1,
+			Duplicates: []recordrepo.ImportDuplicate{{Line: 1, ID: "theRecordID1", SupersededBy: 6}},
+		}, nil)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "theIndex").Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		defer recIDValidator.AssertExpectations(t)
+		recIDValidator.On("ValidateForIndex", "theIndex", "theInvalidID").Return(errors.New("theIDError"))
+		recIDValidator.On("ValidateForIndex", "theIndex", mock.Anything).Return(nil)
+		recIDValidator.On("Generate", "theIndex").Return("theGeneratedID", nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("StoredSchemaVersion", "theIndex").Return(uint32(0))
+		for _, d := range []string{`{"foo":1}`, `{"foo":3}`, `{"foo":4}`, `{"foo":5}`} {
+			recDataValidator.On("ApplyDefaults", "theIndex", d).Return(d, nil)
+		}
+		recDataValidator.On("Validate", "theIndex", `{"foo":3}`).Return(errors.New("theDataError"))
+		recDataValidator.On("Validate", "theIndex", mock.Anything).Return(nil)
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, nil, nil, now, l)
+		w := httptest.NewRecorder()
+		h.ImportNDJSON(w, newRequest(strings.Join([]string{
+			`{"id":"theRecordID1","data":{"foo":1}}`,
+			``,
+			`xyz`,
+			`{"id":"theInvalidID","data":{"foo":2}}`,
+			`{"id":"theRecordID2","data":{"foo":3}}`,
+			`{"id":"theRecordID1","data":{"foo":4}}`,
+			`{"data":{"foo":5}}`,
+		}, "\n")))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		assert.Empty(t, lb.String())
+
+		res := &proto.ImportResponse{}
+		require.NoError(t, protojson.Unmarshal(w.Body.Bytes(), res))
+
+		assert.Equal(t, uint64(1), res.Created)
+		assert.Equal(t, uint64(1), res.Updated)
+		assert.Equal(t, uint64(0), res.Unchanged)
+		assert.Equal(t, uint64(4), res.Failed)
+
+		require.Len(t, res.Errors, 4)
+		assert.Equal(t, uint64(1), res.Errors[0].Line)
+		assert.Equal(t, "theRecordID1", res.Errors[0].RecordId)
+		assert.Equal(t, "superseded by line 6", res.Errors[0].Error)
+		assert.Equal(t, uint64(3), res.Errors[1].Line)
+		assert.Equal(t, "malformed line: invalid character 'x' looking for beginning of value", res.Errors[1].Error)
+		assert.Equal(t, uint64(4), res.Errors[2].Line)
+		assert.Equal(t, "theInvalidID", res.Errors[2].RecordId)
+		assert.Equal(t, "record 4, id=theInvalidID: validation failed: theIDError", res.Errors[2].Error)
+		assert.Equal(t, uint64(5), res.Errors[3].Line)
+		assert.Equal(t, "theRecordID2", res.Errors[3].RecordId)
+		assert.Equal(t, "validation failed: theDataError", res.Errors[3].Error)
+		require.Len(t, res.Errors[3].Violations, 1)
+		assert.Equal(t, "theDataError", res.Errors[3].Violations[0].Message)
+	})
+}
@@ -0,0 +1,64 @@
+package recordhandler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// mergePatch applies a JSON merge patch (RFC 7386) to the document: members of patch objects replace the document's
+// ones recursively, null members remove them, and any other patch value replaces the document as a whole.
+func mergePatch(doc, patch string) (string, error) {
+	d, err := decodeJSON(doc)
+	if err != nil {
+		return "", fmt.Errorf("decode document: %w", err)
+	}
+
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return "", fmt.Errorf("decode patch: %w", err)
+	}
+
+	b, err := json.Marshal(mergeValue(d, p))
+	if err != nil {
+		return "", fmt.Errorf("encode: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func mergeValue(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+
+		t[k] = mergeValue(t[k], v)
+	}
+
+	return t
+}
+
+// decodeJSON decodes numbers as json.Number, so they are encoded back exactly as they were.
+func decodeJSON(s string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err //nolint:wrapcheck // ok
+	}
+
+	return v, nil
+}
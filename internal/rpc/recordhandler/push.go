@@ -2,13 +2,16 @@ package recordhandler
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/ashep/go-apperrors"
 	"github.com/ashep/ujds/internal/indexrepo"
 	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/validation"
 
 	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
 )
@@ -23,52 +26,177 @@ func (h *Handler) Push(
 
 	cache := make(map[string]indexrepo.Index)
 	updates := make([]recordrepo.RecordUpdate, 0)
+	ids := make([]string, 0, len(req.Msg.GetRecords()))
+
+	// Data validation doesn't stop at the first invalid record, so clients get all the violations at once
+	var dataErr *connect.Error
 
 	for i, rec := range req.Msg.GetRecords() {
-		index, err := h.getIndex(ctx, req.Spec().Procedure, rec.Index, cache)
+		cr, err := h.checkRecord(ctx, req.Spec().Procedure, fmt.Sprintf("record %d", i), rec, cache)
 		if err != nil {
 			return nil, err
 		}
 
-		if vErr := h.idxNameValidator.Validate(rec.GetIndex()); vErr != nil {
-			return nil, connect.NewError(
-				connect.CodeInvalidArgument,
-				fmt.Errorf("record %d, index=%s: validation failed: %w", i, rec.GetIndex(), vErr),
-			)
-		}
+		ids = append(ids, cr.id)
 
-		if vErr := h.recIDValidator.Validate(rec.GetId()); vErr != nil {
-			return nil, connect.NewError(
-				connect.CodeInvalidArgument,
-				fmt.Errorf("record %d, id=%s: validation failed: %w", i, rec.GetId(), vErr),
-			)
+		if cr.index.Mode.ReadOnly {
+			return nil, readOnlyError(fmt.Sprintf("record %d", i), cr.id)
 		}
 
-		if vErr := h.recJSONValidator.Validate(rec.GetIndex(), rec.GetData()); vErr != nil {
-			return nil, connect.NewError(
-				connect.CodeInvalidArgument,
-				fmt.Errorf("record %d, id=%s: validation failed: %w", i, rec.GetId(), vErr),
-			)
+		if cr.dataErr != nil {
+			if dataErr == nil {
+				dataErr = connect.NewError(
+					connect.CodeInvalidArgument,
+					fmt.Errorf("record %d, id=%s: validation failed: %w", i, cr.id, cr.dataErr),
+				)
+			}
+
+			if err := addValidationErrorDetail(dataErr, i, rec.GetIndex(), cr.id, cr.dataErr); err != nil {
+				c := h.now().UnixMilli()
+				h.l.Error().Err(err).Str("proc", req.Spec().Procedure).Int64("err_code", c).Msg("validation error detail failed")
+
+				return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+			}
+
+			continue
 		}
 
 		updates = append(updates, recordrepo.RecordUpdate{
-			ID:      rec.GetId(),
-			IndexID: index.ID,
-			Data:    rec.GetData(),
+			ID:        cr.id,
+			IndexID:   cr.index.ID,
+			Data:      cr.data,
+			ExpiresAt: cr.expiresAt,
 		})
 	}
 
+	if dataErr != nil {
+		return nil, dataErr
+	}
+
 	err := h.rr.Push(ctx, updates)
-	if errors.As(err, &apperrors.InvalidArgError{}) {
+
+	switch {
+	case errors.As(err, &indexrepo.ModeError{}):
+		return nil, connect.NewError(connect.CodeFailedPrecondition, err)
+	case errors.As(err, &apperrors.InvalidArgError{}):
 		return nil, connect.NewError(connect.CodeInvalidArgument, err)
-	} else if err != nil {
+	case err != nil:
 		c := h.now().UnixMilli()
 		h.l.Error().Err(err).Str("proc", req.Spec().Procedure).Int64("err_code", c).Msg("record repo push failed")
 
 		return nil, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
 	}
 
-	return connect.NewResponse(&proto.PushResponse{}), nil
+	return connect.NewResponse(&proto.PushResponse{Ids: ids}), nil
+}
+
+// readOnlyError returns the error of an attempt to write a record of a read-only index. The repo checks the index mode
+// as well, but records of such indices are rejected early to skip the needless validation and the transaction.
+func readOnlyError(subj, id string) error {
+	return connect.NewError(connect.CodeFailedPrecondition, fmt.Errorf("%s, id=%s: %w", subj, id, recordrepo.ErrReadOnly))
+}
+
+// checkedRecord is a pushed record which passed the checks of its index and ID.
+type checkedRecord struct {
+	index     indexrepo.Index
+	id        string       // generated if omitted in the request
+	expiresAt sql.NullTime // requested one or the index's default TTL based
+	data      string       // with defaults applied
+	dataErr   error        // record data validation error
+}
+
+// checkRecord runs all the checks of a record to be pushed. Failed index and ID checks are returned as errors, while
+// the data validation error is returned within the result, since it doesn't prevent checking other records. The subj
+// names the record in error messages.
+func (h *Handler) checkRecord(
+	ctx context.Context,
+	proc string,
+	subj string,
+	rec *proto.PushRequest_Record,
+	cache map[string]indexrepo.Index,
+) (checkedRecord, error) {
+	res, err := h.checkRecordMeta(ctx, proc, subj, rec, cache)
+	if err != nil {
+		return checkedRecord{}, err
+	}
+
+	res.data, res.dataErr = h.checkData(rec.GetIndex(), rec.GetData())
+
+	return res, nil
+}
+
+// checkRecordMeta runs the checks of a record's index, ID and expiry time.
+func (h *Handler) checkRecordMeta(
+	ctx context.Context,
+	proc string,
+	subj string,
+	rec *proto.PushRequest_Record,
+	cache map[string]indexrepo.Index,
+) (checkedRecord, error) {
+	index, err := h.getIndex(ctx, proc, rec.GetIndex(), cache)
+	if err != nil {
+		return checkedRecord{}, err
+	}
+
+	if vErr := h.idxNameValidator.Validate(rec.GetIndex()); vErr != nil {
+		return checkedRecord{}, connect.NewError(
+			connect.CodeInvalidArgument,
+			fmt.Errorf("%s, index=%s: validation failed: %w", subj, rec.GetIndex(), vErr),
+		)
+	}
+
+	id := rec.GetId()
+	if id == "" {
+		if id, err = h.recIDValidator.Generate(rec.GetIndex()); err != nil {
+			c := h.now().UnixMilli()
+			h.l.Error().Err(err).Str("proc", proc).Int64("err_code", c).Msg("record id generation failed")
+
+			return checkedRecord{}, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+		}
+	}
+
+	if vErr := h.recIDValidator.ValidateForIndex(rec.GetIndex(), id); vErr != nil {
+		return checkedRecord{}, connect.NewError(
+			connect.CodeInvalidArgument,
+			fmt.Errorf("%s, id=%s: validation failed: %w", subj, id, vErr),
+		)
+	}
+
+	res := checkedRecord{index: index, id: id, data: rec.GetData()}
+
+	switch {
+	case rec.GetExpiresAt() != 0:
+		if rec.GetExpiresAt() <= h.now().Unix() {
+			return checkedRecord{}, connect.NewError(
+				connect.CodeInvalidArgument,
+				fmt.Errorf("%s, id=%s: validation failed: %w", subj, id, apperrors.InvalidArgError{
+					Subj:   "expires_at",
+					Reason: "must be in the future",
+				}),
+			)
+		}
+
+		res.expiresAt = sql.NullTime{Time: time.Unix(rec.GetExpiresAt(), 0).UTC(), Valid: true}
+	case index.TTL > 0:
+		res.expiresAt = sql.NullTime{Time: h.now().Add(index.TTL).UTC(), Valid: true}
+	}
+
+	return res, nil
+}
+
+// checkData applies the index's defaults to record data and validates it. The data is returned as is along with the
+// validation error if it is invalid.
+func (h *Handler) checkData(index, data string) (string, error) {
+	withDefaults, err := h.recJSONValidator.ApplyDefaults(index, data)
+	if err != nil {
+		return data, err //nolint:wrapcheck // ok
+	}
+
+	if err = h.recJSONValidator.Validate(index, withDefaults); err != nil {
+		return data, err //nolint:wrapcheck // ok
+	}
+
+	return withDefaults, nil
 }
 
 func (h *Handler) getIndex(ctx context.Context, proc, name string, cache map[string]indexrepo.Index) (indexrepo.Index, error) {
@@ -94,5 +222,74 @@ func (h *Handler) getIndex(ctx context.Context, proc, name string, cache map[str
 		}
 	}
 
+	if err = h.syncStoredSchema(ctx, name, index.SchemaVersion); err != nil {
+		c := h.now().UnixMilli()
+		h.l.Error().Err(err).Str("proc", proc).Int64("err_code", c).Msg("stored schema sync failed")
+
+		return indexrepo.Index{}, connect.NewError(connect.CodeInternal, fmt.Errorf("err_code: %d", c))
+	}
+
+	cache[name] = index
+
 	return index, nil
 }
+
+// syncStoredSchema makes the record data validator use the index's active stored schema version. Schemas may be
+// changed through another server instance, so the version is checked against the index each time it is loaded.
+func (h *Handler) syncStoredSchema(ctx context.Context, name string, version uint32) error {
+	if h.recJSONValidator.StoredSchemaVersion(name) == version {
+		return nil
+	}
+
+	if version == 0 {
+		return h.recJSONValidator.SetStoredSchema(name, 0, nil) //nolint:wrapcheck // ok
+	}
+
+	sch, err := h.ir.GetSchema(ctx, name, version)
+	if err != nil {
+		return fmt.Errorf("get schema: %w", err)
+	}
+
+	if err := h.recJSONValidator.SetStoredSchema(name, sch.Version, sch.Schema); err != nil {
+		return fmt.Errorf("set schema: %w", err)
+	}
+
+	return nil
+}
+
+// addValidationErrorDetail attaches a record data validation error to the connect error as a ValidationError detail.
+func addValidationErrorDetail(cErr *connect.Error, i int, index, id string, vErr error) error {
+	d, err := connect.NewErrorDetail(&proto.ValidationError{
+		Record:     uint32(i), //nolint:gosec // ok
+		RecordId:   id,
+		Index:      index,
+		Violations: violationsToProto(vErr),
+	})
+	if err != nil {
+		return fmt.Errorf("new error detail: %w", err)
+	}
+
+	cErr.AddDetail(d)
+
+	return nil
+}
+
+// violationsToProto converts a record data validation error to violations. Errors other than schema violations, like
+// malformed JSON, become a single violation with the error message.
+func violationsToProto(vErr error) []*proto.ValidationError_Violation {
+	dErr := validation.DataError{}
+	if !errors.As(vErr, &dErr) {
+		return []*proto.ValidationError_Violation{{Message: vErr.Error()}}
+	}
+
+	res := make([]*proto.ValidationError_Violation, 0, len(dErr.Violations))
+	for _, v := range dErr.Violations {
+		res = append(res, &proto.ValidationError_Violation{
+			Pointer: v.Pointer,
+			Keyword: v.Keyword,
+			Message: v.Message,
+		})
+	}
+
+	return res
+}
@@ -2,6 +2,8 @@ package recordhandler_test
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
@@ -12,6 +14,7 @@ import (
 	"github.com/ashep/ujds/internal/indexrepo"
 	"github.com/ashep/ujds/internal/recordrepo"
 	"github.com/ashep/ujds/internal/rpc/recordhandler"
+	"github.com/ashep/ujds/internal/validation"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -33,10 +36,10 @@ func TestRecordHandler_Push(tt *testing.T) {
 		defer rr.AssertExpectations(t)
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{}))
 
 		assert.EqualError(t, err, "invalid_argument: empty records")
@@ -60,10 +63,10 @@ func TestRecordHandler_Push(tt *testing.T) {
 		defer rr.AssertExpectations(t)
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Records: []*proto.PushRequest_Record{{Index: "anIndex", Id: "anID", Data: "aData"}},
 		}))
@@ -88,10 +91,10 @@ func TestRecordHandler_Push(tt *testing.T) {
 		defer rr.AssertExpectations(t)
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Records: []*proto.PushRequest_Record{{Index: "anIndex", Id: "anID", Data: "aData"}},
 		}))
@@ -114,10 +117,10 @@ func TestRecordHandler_Push(tt *testing.T) {
 		defer rr.AssertExpectations(t)
 
 		idxNameValidator := &stringValidatorMock{}
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		recDataValidator := &keyStringValidatorMock{}
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
 			Records: []*proto.PushRequest_Record{{Index: "anIndex", Id: "anID", Data: "aData"}},
 		}))
@@ -145,17 +148,21 @@ func TestRecordHandler_Push(tt *testing.T) {
 		idxNameValidator.On("Validate", "anIndex").
 			Return(nil)
 
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		defer recIDValidator.AssertExpectations(t)
-		recIDValidator.On("Validate", "anID").
+		recIDValidator.On("ValidateForIndex", "anIndex", "anID").
 			Return(nil)
 
 		recDataValidator := &keyStringValidatorMock{}
 		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("ApplyDefaults", "anIndex", "aData").
+			Return("aData", nil)
 		recDataValidator.On("Validate", "anIndex", "aData").
 			Return(errors.New("validation error"))
+		recDataValidator.On("StoredSchemaVersion", "anIndex").
+			Return(uint32(0))
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
 			{
 				Index: "anIndex",
@@ -168,6 +175,127 @@ func TestRecordHandler_Push(tt *testing.T) {
 		assert.Empty(t, lb.String())
 	})
 
+	tt.Run("ApplyDefaultsError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		ir.On("Get", mock.Anything, "anIndex").
+			Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "anIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("ValidateForIndex", "anIndex", "anID").
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("StoredSchemaVersion", "anIndex").
+			Return(uint32(0))
+		recDataValidator.On("ApplyDefaults", "anIndex", "aData").
+			Return("", apperrors.InvalidArgError{Subj: "json schema or data", Reason: "theReason"})
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
+			Records: []*proto.PushRequest_Record{{Index: "anIndex", Id: "anID", Data: "aData"}},
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: record 0, id=anID: validation failed: invalid json schema or data: theReason")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("ExpiresAtInPast", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		ir.On("Get", mock.Anything, "anIndex").
+			Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "anIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("ValidateForIndex", "anIndex", "anID").
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("StoredSchemaVersion", "anIndex").
+			Return(uint32(0))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{
+			Records: []*proto.PushRequest_Record{{Index: "anIndex", Id: "anID", Data: "aData", ExpiresAt: 1234567890}},
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: record 0, id=anID: validation failed: invalid expires_at: must be in the future")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkExpiresAt", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 0) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123}, nil)
+		ir.On("Get", mock.Anything, "theTTLIndex").
+			Return(indexrepo.Index{ID: 234, TTL: time.Hour}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Push", mock.Anything, []recordrepo.RecordUpdate{
+			{ID: "theRecordID1", IndexID: 123, Data: "theRecordData"},
+			{ID: "theRecordID2", IndexID: 123, Data: "theRecordData",
+				ExpiresAt: sql.NullTime{Time: time.Unix(1234567900, 0).UTC(), Valid: true}},
+			{ID: "theRecordID3", IndexID: 234, Data: "theRecordData",
+				ExpiresAt: sql.NullTime{Time: time.Unix(1234567890+3600, 0).UTC(), Valid: true}},
+			{ID: "theRecordID4", IndexID: 234, Data: "theRecordData",
+				ExpiresAt: sql.NullTime{Time: time.Unix(1234567900, 0).UTC(), Valid: true}},
+		}).
+			Return(nil)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", mock.Anything).
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("ValidateForIndex", mock.Anything, mock.Anything).
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("StoredSchemaVersion", mock.Anything).
+			Return(uint32(0))
+		recDataValidator.On("ApplyDefaults", mock.Anything, "theRecordData").
+			Return("theRecordData", nil)
+		recDataValidator.On("Validate", mock.Anything, "theRecordData").
+			Return(nil)
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{Index: "theIndex", Id: "theRecordID1", Data: "theRecordData"},
+			{Index: "theIndex", Id: "theRecordID2", Data: "theRecordData", ExpiresAt: 1234567900},
+			{Index: "theTTLIndex", Id: "theRecordID3", Data: "theRecordData"},
+			{Index: "theTTLIndex", Id: "theRecordID4", Data: "theRecordData", ExpiresAt: 1234567900},
+		}}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+	})
+
 	tt.Run("RecordRepoInvalidArgError", func(t *testing.T) {
 		now := func() time.Time { return time.Unix(1234567890, 987654321) }
 		lb := &strings.Builder{}
@@ -191,17 +319,21 @@ func TestRecordHandler_Push(tt *testing.T) {
 		idxNameValidator.On("Validate", "anIndex").
 			Return(nil)
 
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		defer recIDValidator.AssertExpectations(t)
-		recIDValidator.On("Validate", "anID").
+		recIDValidator.On("ValidateForIndex", "anIndex", "anID").
 			Return(nil)
 
 		recDataValidator := &keyStringValidatorMock{}
 		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("ApplyDefaults", "anIndex", "aData").
+			Return("aData", nil)
 		recDataValidator.On("Validate", "anIndex", "aData").
 			Return(nil)
+		recDataValidator.On("StoredSchemaVersion", "anIndex").
+			Return(uint32(0))
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
 			{
 				Index: "anIndex",
@@ -234,17 +366,21 @@ func TestRecordHandler_Push(tt *testing.T) {
 		idxNameValidator.On("Validate", "anIndex").
 			Return(nil)
 
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		defer recIDValidator.AssertExpectations(t)
-		recIDValidator.On("Validate", "anID").
+		recIDValidator.On("ValidateForIndex", "anIndex", "anID").
 			Return(nil)
 
 		recDataValidator := &keyStringValidatorMock{}
 		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("ApplyDefaults", "anIndex", "aData").
+			Return("aData", nil)
 		recDataValidator.On("Validate", "anIndex", "aData").
 			Return(nil)
+		recDataValidator.On("StoredSchemaVersion", "anIndex").
+			Return(uint32(0))
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
 		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
 			{
 				Index: "anIndex",
@@ -258,6 +394,98 @@ func TestRecordHandler_Push(tt *testing.T) {
 `, lb.String())
 	})
 
+	tt.Run("RecordRepoModeError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "anIndex").
+			Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Push", mock.Anything, mock.Anything).
+			Return(indexrepo.ModeError{Reason: "theReason"})
+
+		idxNameValidator := &stringValidatorMock{}
+		defer idxNameValidator.AssertExpectations(t)
+		idxNameValidator.On("Validate", "anIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		defer recIDValidator.AssertExpectations(t)
+		recIDValidator.On("ValidateForIndex", "anIndex", "anID").
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("ApplyDefaults", "anIndex", "aData").
+			Return("aData", nil)
+		recDataValidator.On("Validate", "anIndex", "aData").
+			Return(nil)
+		recDataValidator.On("StoredSchemaVersion", "anIndex").
+			Return(uint32(0))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{
+				Index: "anIndex",
+				Id:    "anID",
+				Data:  "aData",
+			},
+		}}))
+
+		assert.EqualError(t, err, "failed_precondition: theReason")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("ReadOnlyIndex", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "anIndex").
+			Return(indexrepo.Index{ID: 123, Mode: indexrepo.Mode{ReadOnly: true}}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		idxNameValidator := &stringValidatorMock{}
+		defer idxNameValidator.AssertExpectations(t)
+		idxNameValidator.On("Validate", "anIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		defer recIDValidator.AssertExpectations(t)
+		recIDValidator.On("ValidateForIndex", "anIndex", "anID").
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("ApplyDefaults", "anIndex", "aData").
+			Return("aData", nil)
+		recDataValidator.On("Validate", "anIndex", "aData").
+			Return(nil)
+		recDataValidator.On("StoredSchemaVersion", "anIndex").
+			Return(uint32(0))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{
+				Index: "anIndex",
+				Id:    "anID",
+				Data:  "aData",
+			},
+		}}))
+
+		assert.EqualError(t, err, "failed_precondition: record 0, id=anID: index is read-only")
+		assert.Empty(t, lb.String())
+	})
+
 	tt.Run("Ok", func(t *testing.T) {
 		now := func() time.Time { return time.Unix(1234567890, 987654321) }
 		lb := &strings.Builder{}
@@ -276,7 +504,7 @@ func TestRecordHandler_Push(tt *testing.T) {
 			{
 				ID:      "theRecordID",
 				IndexID: 123,
-				Data:    "theRecordData",
+				Data:    "theDefaultedData",
 			},
 		}).
 			Return(nil)
@@ -286,18 +514,22 @@ func TestRecordHandler_Push(tt *testing.T) {
 		idxNameValidator.On("Validate", "theIndex").
 			Return(nil)
 
-		recIDValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
 		defer recIDValidator.AssertExpectations(t)
-		recIDValidator.On("Validate", "theRecordID").
+		recIDValidator.On("ValidateForIndex", "theIndex", "theRecordID").
 			Return(nil)
 
 		recDataValidator := &keyStringValidatorMock{}
 		defer recDataValidator.AssertExpectations(t)
-		recDataValidator.On("Validate", "theIndex", "theRecordData").
+		recDataValidator.On("ApplyDefaults", "theIndex", "theRecordData").
+			Return("theDefaultedData", nil)
+		recDataValidator.On("Validate", "theIndex", "theDefaultedData").
 			Return(nil)
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(0))
 
-		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, now, l)
-		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		res, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
 			{
 				Index: "theIndex",
 				Id:    "theRecordID",
@@ -306,6 +538,250 @@ func TestRecordHandler_Push(tt *testing.T) {
 		}}))
 
 		require.NoError(t, err)
+		assert.Equal(t, []string{"theRecordID"}, res.Msg.Ids)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("OkGeneratedID", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Push", mock.Anything, []recordrepo.RecordUpdate{
+			{ID: "theGeneratedID", IndexID: 123, Data: "theRecordData1"},
+			{ID: "theRecordID", IndexID: 123, Data: "theRecordData2"},
+		}).
+			Return(nil)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "theIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		defer recIDValidator.AssertExpectations(t)
+		recIDValidator.On("Generate", "theIndex").
+			Return("theGeneratedID", nil).Once()
+		recIDValidator.On("ValidateForIndex", "theIndex", "theGeneratedID").
+			Return(nil)
+		recIDValidator.On("ValidateForIndex", "theIndex", "theRecordID").
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("ApplyDefaults", "theIndex", "theRecordData1").
+			Return("theRecordData1", nil)
+		recDataValidator.On("ApplyDefaults", "theIndex", "theRecordData2").
+			Return("theRecordData2", nil)
+		recDataValidator.On("Validate", "theIndex", mock.Anything).
+			Return(nil)
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(0))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		res, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{Index: "theIndex", Data: "theRecordData1"},
+			{Index: "theIndex", Id: "theRecordID", Data: "theRecordData2"},
+		}}))
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"theGeneratedID", "theRecordID"}, res.Msg.Ids)
 		assert.Empty(t, lb.String())
 	})
+
+	tt.Run("IDGenerationError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "theIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("Generate", "theIndex").
+			Return("", errors.New("theGenerateError"))
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(0))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{Index: "theIndex", Data: "theRecordData"},
+		}}))
+
+		assert.EqualError(t, err, "internal: err_code: 1234567890987")
+		assert.Equal(t, `{"level":"error","error":"theGenerateError","proc":"","err_code":1234567890987,"message":"record id generation failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("StoredSchemaGetError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123, SchemaVersion: 2}, nil)
+		ir.On("GetSchema", mock.Anything, "theIndex", uint32(2)).
+			Return(indexrepo.Schema{}, errors.New("theGetSchemaError"))
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		idxNameValidator := &stringValidatorMock{}
+		recIDValidator := &recordIDValidatorMock{}
+
+		recDataValidator := &keyStringValidatorMock{}
+		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(1))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{Index: "theIndex", Id: "theRecordID", Data: "theRecordData"},
+		}}))
+
+		assert.EqualError(t, err, "internal: err_code: 1234567890987")
+		assert.Equal(t, `{"level":"error","error":"get schema: theGetSchemaError","proc":"","err_code":1234567890987,"message":"stored schema sync failed"}
+`, lb.String())
+	})
+
+	tt.Run("OkStoredSchemaSynced", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123, SchemaVersion: 2}, nil).
+			Once()
+		ir.On("GetSchema", mock.Anything, "theIndex", uint32(2)).
+			Return(indexrepo.Schema{Version: 2, Schema: json.RawMessage(`{"type":"object"}`)}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+		rr.On("Push", mock.Anything, mock.Anything).
+			Return(nil)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "theIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("ValidateForIndex", "theIndex", mock.Anything).
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(0)).
+			Once()
+		recDataValidator.On("SetStoredSchema", "theIndex", uint32(2), json.RawMessage(`{"type":"object"}`)).
+			Return(nil)
+		recDataValidator.On("ApplyDefaults", "theIndex", "theRecordData1").
+			Return("theRecordData1", nil)
+		recDataValidator.On("ApplyDefaults", "theIndex", "theRecordData2").
+			Return("theRecordData2", nil)
+		recDataValidator.On("Validate", "theIndex", mock.Anything).
+			Return(nil)
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{Index: "theIndex", Id: "theRecordID1", Data: "theRecordData1"},
+			{Index: "theIndex", Id: "theRecordID2", Data: "theRecordData2"},
+		}}))
+
+		require.NoError(t, err)
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("ValidationErrorDetails", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123}, nil)
+
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "theIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("ValidateForIndex", "theIndex", mock.Anything).
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		defer recDataValidator.AssertExpectations(t)
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(0))
+		recDataValidator.On("ApplyDefaults", "theIndex", "theData1").
+			Return("theData1", nil)
+		recDataValidator.On("ApplyDefaults", "theIndex", "theData2").
+			Return("theData2", nil)
+		recDataValidator.On("ApplyDefaults", "theIndex", "theData3").
+			Return("theData3", nil)
+		recDataValidator.On("Validate", "theIndex", "theData1").
+			Return(validation.DataError{Violations: []validation.Violation{
+				{Pointer: "", Keyword: "required", Message: "title is required"},
+				{Pointer: "/year", Keyword: "type", Message: "Invalid type"},
+			}})
+		recDataValidator.On("Validate", "theIndex", "theData2").
+			Return(nil)
+		recDataValidator.On("Validate", "theIndex", "theData3").
+			Return(errors.New("theOtherError"))
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Push(context.Background(), connect.NewRequest(&proto.PushRequest{Records: []*proto.PushRequest_Record{
+			{Index: "theIndex", Id: "theID1", Data: "theData1"},
+			{Index: "theIndex", Id: "theID2", Data: "theData2"},
+			{Index: "theIndex", Id: "theID3", Data: "theData3"},
+		}}))
+
+		assert.EqualError(t, err, "invalid_argument: record 0, id=theID1: validation failed: "+
+			"invalid json: (root): title is required; year: Invalid type")
+		assert.Empty(t, lb.String())
+
+		cErr := &connect.Error{}
+		require.ErrorAs(t, err, &cErr)
+		require.Len(t, cErr.Details(), 2)
+
+		d0, err := cErr.Details()[0].Value()
+		require.NoError(t, err)
+		assert.Equal(t, uint32(0), d0.(*proto.ValidationError).Record)
+		assert.Equal(t, "theID1", d0.(*proto.ValidationError).RecordId)
+		assert.Equal(t, "theIndex", d0.(*proto.ValidationError).Index)
+		require.Len(t, d0.(*proto.ValidationError).Violations, 2)
+		assert.Equal(t, "", d0.(*proto.ValidationError).Violations[0].Pointer)
+		assert.Equal(t, "required", d0.(*proto.ValidationError).Violations[0].Keyword)
+		assert.Equal(t, "title is required", d0.(*proto.ValidationError).Violations[0].Message)
+		assert.Equal(t, "/year", d0.(*proto.ValidationError).Violations[1].Pointer)
+
+		d1, err := cErr.Details()[1].Value()
+		require.NoError(t, err)
+		assert.Equal(t, uint32(2), d1.(*proto.ValidationError).Record)
+		assert.Equal(t, "theID3", d1.(*proto.ValidationError).RecordId)
+		require.Len(t, d1.(*proto.ValidationError).Violations, 1)
+		assert.Equal(t, "theOtherError", d1.(*proto.ValidationError).Violations[0].Message)
+	})
 }
@@ -0,0 +1,72 @@
+package recordhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/ujds/internal/indexrepo"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+// Validate runs the same checks of records as Push does, without writing them.
+func (h *Handler) Validate(
+	ctx context.Context,
+	req *connect.Request[proto.ValidateRequest],
+) (*connect.Response[proto.ValidateResponse], error) {
+	if len(req.Msg.GetRecords()) == 0 {
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("empty records"))
+	}
+
+	cache := make(map[string]indexrepo.Index)
+	res := &proto.ValidateResponse{
+		Valid:   true,
+		Results: make([]*proto.ValidateResponse_Result, 0, len(req.Msg.GetRecords())),
+	}
+
+	for i, rec := range req.Msg.GetRecords() {
+		r := &proto.ValidateResponse_Result{
+			Record:   uint32(i), //nolint:gosec // ok
+			RecordId: rec.GetId(),
+			Index:    rec.GetIndex(),
+			Valid:    true,
+		}
+		res.Results = append(res.Results, r)
+
+		cr, err := h.checkRecord(ctx, req.Spec().Procedure, fmt.Sprintf("record %d", i), rec, cache)
+		if err == nil && cr.index.Mode.ReadOnly {
+			r.RecordId = cr.id
+			err = readOnlyError(fmt.Sprintf("record %d", i), cr.id)
+		}
+
+		cErr := &connect.Error{}
+		switch {
+		case errors.As(err, &cErr) && isRecordErrorCode(cErr.Code()):
+			r.Valid = false
+			r.Error = cErr.Message()
+		case err != nil:
+			return nil, err
+		case cr.dataErr != nil:
+			r.RecordId = cr.id
+			r.Valid = false
+			r.Error = cr.dataErr.Error()
+			r.Violations = violationsToProto(cr.dataErr)
+		default:
+			r.RecordId = cr.id
+		}
+
+		if !r.Valid {
+			res.Valid = false
+		}
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+// isRecordErrorCode reports whether an error of the code is caused by the record itself, so it makes the record
+// invalid rather than fails the whole validation.
+func isRecordErrorCode(code connect.Code) bool {
+	return code == connect.CodeInvalidArgument || code == connect.CodeNotFound || code == connect.CodeFailedPrecondition
+}
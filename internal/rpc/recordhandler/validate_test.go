@@ -0,0 +1,158 @@
+package recordhandler_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-apperrors"
+	"github.com/ashep/ujds/internal/indexrepo"
+	"github.com/ashep/ujds/internal/rpc/recordhandler"
+	"github.com/ashep/ujds/internal/validation"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	proto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+)
+
+func TestRecordHandler_Validate(tt *testing.T) {
+	tt.Run("EmptyRecords", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		h := recordhandler.New(&indexRepoMock{}, &recordRepoMock{}, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Validate(context.Background(), connect.NewRequest(&proto.ValidateRequest{}))
+
+		assert.EqualError(t, err, "invalid_argument: empty records")
+		assert.Empty(t, lb.String())
+	})
+
+	tt.Run("IndexRepoError", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{}, errors.New("theIndexRepoError"))
+
+		h := recordhandler.New(ir, &recordRepoMock{}, &stringValidatorMock{}, &recordIDValidatorMock{},
+			&keyStringValidatorMock{}, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		_, err := h.Validate(context.Background(), connect.NewRequest(&proto.ValidateRequest{
+			Records: []*proto.PushRequest_Record{{Index: "theIndex", Id: "theRecordID", Data: "{}"}},
+		}))
+
+		assert.EqualError(t, err, "internal: err_code: 1234567890987")
+		assert.Equal(t, `{"level":"error","error":"theIndexRepoError","proc":"","err_code":1234567890987,"message":"index repo get failed"}`+"\n", lb.String())
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		now := func() time.Time { return time.Unix(1234567890, 987654321) }
+		lb := &strings.Builder{}
+		l := zerolog.New(lb)
+
+		ir := &indexRepoMock{}
+		defer ir.AssertExpectations(t)
+		ir.On("Get", mock.Anything, "theIndex").
+			Return(indexrepo.Index{ID: 123}, nil).Once()
+		ir.On("Get", mock.Anything, "theMissingIndex").
+			Return(indexrepo.Index{}, apperrors.NotFoundError{Subj: "index"})
+		ir.On("Get", mock.Anything, "theReadOnlyIndex").
+			Return(indexrepo.Index{ID: 234, Mode: indexrepo.Mode{ReadOnly: true}}, nil).Once()
+
+		// Nothing is written
+		rr := &recordRepoMock{}
+		defer rr.AssertExpectations(t)
+
+		idxNameValidator := &stringValidatorMock{}
+		idxNameValidator.On("Validate", "theIndex").
+			Return(nil)
+		idxNameValidator.On("Validate", "theReadOnlyIndex").
+			Return(nil)
+
+		recIDValidator := &recordIDValidatorMock{}
+		recIDValidator.On("ValidateForIndex", "theIndex", "theRecordID1").
+			Return(nil)
+		recIDValidator.On("ValidateForIndex", "theIndex", "theInvalidID").
+			Return(apperrors.InvalidArgError{Subj: "record id", Reason: "must be a UUID"})
+		recIDValidator.On("Generate", "theIndex").
+			Return("theGeneratedID", nil)
+		recIDValidator.On("ValidateForIndex", "theIndex", "theGeneratedID").
+			Return(nil)
+		recIDValidator.On("ValidateForIndex", "theReadOnlyIndex", "theRecordID3").
+			Return(nil)
+
+		recDataValidator := &keyStringValidatorMock{}
+		recDataValidator.On("StoredSchemaVersion", "theIndex").
+			Return(uint32(0))
+		recDataValidator.On("StoredSchemaVersion", "theReadOnlyIndex").
+			Return(uint32(0))
+		recDataValidator.On("ApplyDefaults", "theIndex", "theValidData").
+			Return("theDefaultedData", nil)
+		recDataValidator.On("ApplyDefaults", "theReadOnlyIndex", "theValidData").
+			Return("theDefaultedData", nil)
+		recDataValidator.On("Validate", "theReadOnlyIndex", "theDefaultedData").
+			Return(nil)
+		recDataValidator.On("ApplyDefaults", "theIndex", "theInvalidData").
+			Return("theInvalidData", nil)
+		recDataValidator.On("Validate", "theIndex", "theDefaultedData").
+			Return(nil)
+		recDataValidator.On("Validate", "theIndex", "theInvalidData").
+			Return(validation.DataError{Violations: []validation.Violation{
+				{Pointer: "/year", Keyword: "type", Message: "got string, want integer"},
+			}})
+
+		h := recordhandler.New(ir, rr, idxNameValidator, recIDValidator, recDataValidator, &sweepPolicyMock{}, &archiveMock{}, now, l)
+		res, err := h.Validate(context.Background(), connect.NewRequest(&proto.ValidateRequest{
+			Records: []*proto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: "theValidData"},
+				{Index: "theIndex", Data: "theInvalidData"},
+				{Index: "theIndex", Id: "theInvalidID", Data: "theValidData"},
+				{Index: "theMissingIndex", Id: "theRecordID2", Data: "theValidData"},
+				{Index: "theReadOnlyIndex", Id: "theRecordID3", Data: "theValidData"},
+			},
+		}))
+
+		require.NoError(t, err)
+		assert.False(t, res.Msg.Valid)
+		require.Len(t, res.Msg.Results, 5)
+
+		assert.Equal(t, uint32(0), res.Msg.Results[0].Record)
+		assert.Equal(t, "theRecordID1", res.Msg.Results[0].RecordId)
+		assert.Equal(t, "theIndex", res.Msg.Results[0].Index)
+		assert.True(t, res.Msg.Results[0].Valid)
+		assert.Empty(t, res.Msg.Results[0].Error)
+
+		assert.Equal(t, uint32(1), res.Msg.Results[1].Record)
+		assert.Equal(t, "theGeneratedID", res.Msg.Results[1].RecordId)
+		assert.False(t, res.Msg.Results[1].Valid)
+		assert.Equal(t, "invalid json: year: got string, want integer", res.Msg.Results[1].Error)
+		require.Len(t, res.Msg.Results[1].Violations, 1)
+		assert.Equal(t, "/year", res.Msg.Results[1].Violations[0].Pointer)
+		assert.Equal(t, "type", res.Msg.Results[1].Violations[0].Keyword)
+		assert.Equal(t, "got string, want integer", res.Msg.Results[1].Violations[0].Message)
+
+		assert.False(t, res.Msg.Results[2].Valid)
+		assert.Equal(t, "record 2, id=theInvalidID: validation failed: invalid record id: must be a UUID",
+			res.Msg.Results[2].Error)
+		assert.Empty(t, res.Msg.Results[2].Violations)
+
+		assert.Equal(t, "theMissingIndex", res.Msg.Results[3].Index)
+		assert.False(t, res.Msg.Results[3].Valid)
+		assert.Equal(t, "index is not found", res.Msg.Results[3].Error)
+
+		assert.Equal(t, "theRecordID3", res.Msg.Results[4].RecordId)
+		assert.False(t, res.Msg.Results[4].Valid)
+		assert.Equal(t, "record 4, id=theRecordID3: index is read-only", res.Msg.Results[4].Error)
+		assert.Empty(t, res.Msg.Results[4].Violations)
+
+		assert.Empty(t, lb.String())
+	})
+}
@@ -0,0 +1,242 @@
+package schemareloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+)
+
+// debounce is the time to wait for more changes of the schemas directory before reloading, since saving a file
+// usually results in several events.
+const debounce = time.Millisecond * 500
+
+type validator interface {
+	Reload(schemas map[string]json.RawMessage, dir fs.FS) error
+}
+
+// Config is the part of the configuration the schemas are made of.
+type Config struct {
+	Schemas map[string]json.RawMessage // schemas by index name patterns
+	Dir     string                     // optional schemas directory
+}
+
+// Reloader reloads record data validation schemas on SIGHUP and on changes of the schemas directory.
+type Reloader struct {
+	v    validator
+	load func() (Config, error)
+	mu   sync.Mutex // serializes reloads
+	cfg  Config
+	dirs chan string // the directory to watch after it's changed by a reload
+	l    zerolog.Logger
+}
+
+// New creates a reloader of the validator's schemas, which were made of cfg. The load func is called on SIGHUP to read
+// the configuration anew, while changes of the schemas directory are applied with the current configuration.
+func New(v validator, cfg Config, load func() (Config, error), l zerolog.Logger) *Reloader {
+	return &Reloader{
+		v:    v,
+		load: load,
+		cfg:  cfg,
+		dirs: make(chan string, 1),
+		l:    l,
+	}
+}
+
+// Reload loads the configuration and reloads the schemas. If the configuration fails to load or the schemas fail to
+// compile, the current configuration and schemas are kept.
+func (r *Reloader) Reload() error {
+	cfg, err := r.load()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.apply(cfg)
+}
+
+// reloadDir reloads the schemas of the current configuration.
+func (r *Reloader) reloadDir() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.apply(r.cfg)
+}
+
+// apply compiles the schemas of the configuration and makes it the current one. The caller must hold r.mu.
+func (r *Reloader) apply(cfg Config) error {
+	var dir fs.FS
+	if cfg.Dir != "" {
+		dir = os.DirFS(cfg.Dir)
+	}
+
+	if err := r.v.Reload(cfg.Schemas, dir); err != nil {
+		return err //nolint:wrapcheck // ok
+	}
+
+	if cfg.Dir != r.cfg.Dir {
+		// Only the latest directory matters if the watcher hasn't picked up the previous one yet
+		select {
+		case <-r.dirs:
+		default:
+		}
+
+		r.dirs <- cfg.Dir
+	}
+
+	r.cfg = cfg
+
+	return nil
+}
+
+func (r *Reloader) logReload(err error) {
+	if err != nil {
+		r.l.Error().Err(err).Msg("validation schemas reload failed, keeping the current ones")
+		return
+	}
+
+	r.l.Info().Msg("validation schemas reloaded")
+}
+
+// Run reloads the schemas on SIGHUP and on changes of the schemas directory, if it's set. It blocks until the context
+// is done.
+func (r *Reloader) Run(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	// The directory is watched on its own, so SIGHUP is handled even if watching fails
+	watchDone := make(chan struct{})
+	go func() {
+		r.watch(ctx)
+		close(watchDone)
+	}()
+
+	defer func() { <-watchDone }()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			r.l.Info().Msg("SIGHUP received")
+			r.logReload(r.Reload())
+		}
+	}
+}
+
+// watch watches the schemas directory, switching to another one each time a reload changes it.
+func (r *Reloader) watch(ctx context.Context) {
+	r.mu.Lock()
+	dir := r.cfg.Dir
+	r.mu.Unlock()
+
+	for {
+		next, ok := r.watchDir(ctx, dir)
+		if !ok {
+			return
+		}
+
+		dir = next
+	}
+}
+
+// watchDir reloads the schemas on changes of the directory until the context is done or a reload changes the
+// directory; in the latter case the new directory is returned.
+func (r *Reloader) watchDir(ctx context.Context, dir string) (string, bool) {
+	var w *fsnotify.Watcher
+
+	if dir != "" {
+		var err error
+		if w, err = r.newWatcher(dir); err != nil {
+			r.l.Error().Err(err).Msg("schemas dir watch failed")
+		}
+	}
+
+	// Receiving from nil channels blocks forever, so there are no events without a watcher
+	var (
+		events <-chan fsnotify.Event
+		errs   <-chan error
+	)
+
+	if w != nil {
+		defer func() { _ = w.Close() }()
+
+		events = w.Events
+		errs = w.Errors
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", false
+		case newDir := <-r.dirs:
+			return newDir, true
+		case ev := <-events:
+			// New subdirectories must be watched as well, since fsnotify is not recursive
+			if ev.Has(fsnotify.Create) {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					if err := addDir(w, ev.Name); err != nil {
+						r.l.Error().Err(err).Msg("schemas dir watch failed")
+					}
+				}
+			}
+
+			timer.Reset(debounce)
+		case err := <-errs:
+			r.l.Error().Err(err).Msg("schemas dir watch failed")
+		case <-timer.C:
+			r.logReload(r.reloadDir())
+		}
+	}
+}
+
+func (r *Reloader) newWatcher(dir string) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create watcher: %w", err)
+	}
+
+	if err := addDir(w, dir); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// addDir adds the directory and all its subdirectories to the watcher.
+func addDir(w *fsnotify.Watcher, dir string) error {
+	err := filepath.WalkDir(dir, func(pth string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		return w.Add(pth)
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package schemareloader_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/schemareloader"
+)
+
+type validatorMock struct {
+	mu      sync.Mutex
+	err     error
+	schemas []map[string]json.RawMessage
+	files   [][]string
+}
+
+func (m *validatorMock) Reload(schemas map[string]json.RawMessage, dir fs.FS) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var files []string
+	if dir != nil {
+		_ = fs.WalkDir(dir, ".", func(pth string, d fs.DirEntry, _ error) error {
+			if !d.IsDir() {
+				files = append(files, pth)
+			}
+			return nil
+		})
+	}
+
+	m.schemas = append(m.schemas, schemas)
+	m.files = append(m.files, files)
+
+	return m.err
+}
+
+func (m *validatorMock) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.files)
+}
+
+func (m *validatorMock) lastFiles() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.files[len(m.files)-1]
+}
+
+type syncBuilder struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.sb.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.sb.String()
+}
+
+func loadConfig(cfg schemareloader.Config, err error) func() (schemareloader.Config, error) {
+	return func() (schemareloader.Config, error) {
+		return cfg, err
+	}
+}
+
+func runReloader(t *testing.T, r *schemareloader.Reloader) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	time.Sleep(time.Millisecond * 100) // let the signal handler be registered and the watcher start
+}
+
+func TestReloader_Reload(tt *testing.T) {
+	tt.Run("LoadConfigError", func(t *testing.T) {
+		v := &validatorMock{}
+		r := schemareloader.New(v, schemareloader.Config{}, loadConfig(schemareloader.Config{},
+			errors.New("theLoadError")), zerolog.Nop())
+
+		assert.EqualError(t, r.Reload(), "load config: theLoadError")
+		assert.Zero(t, v.calls())
+	})
+
+	tt.Run("Error", func(t *testing.T) {
+		v := &validatorMock{err: errors.New("theReloadError")}
+		r := schemareloader.New(v, schemareloader.Config{}, loadConfig(schemareloader.Config{}, nil), zerolog.Nop())
+
+		assert.EqualError(t, r.Reload(), "theReloadError")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "books.json"), []byte(`{}`), 0o600))
+
+		schemas := map[string]json.RawMessage{".*": json.RawMessage(`{}`)}
+		v := &validatorMock{}
+		r := schemareloader.New(v, schemareloader.Config{}, loadConfig(schemareloader.Config{
+			Schemas: schemas,
+			Dir:     dir,
+		}, nil), zerolog.Nop())
+
+		require.NoError(t, r.Reload())
+		assert.Equal(t, []map[string]json.RawMessage{schemas}, v.schemas)
+		assert.Equal(t, []string{"books.json"}, v.lastFiles())
+	})
+}
+
+func TestReloader_Run(tt *testing.T) {
+	tt.Run("DirChanged", func(t *testing.T) {
+		dir := t.TempDir()
+		lb := &syncBuilder{}
+		v := &validatorMock{}
+		schemas := map[string]json.RawMessage{".*": json.RawMessage(`{}`)}
+
+		// Changes of the directory don't reload the configuration
+		r := schemareloader.New(v, schemareloader.Config{Schemas: schemas, Dir: dir},
+			loadConfig(schemareloader.Config{}, errors.New("theLoadError")), zerolog.New(lb))
+		runReloader(t, r)
+
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "defs"), 0o755))
+		time.Sleep(time.Millisecond * 100) // let the new subdirectory be watched
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "defs", "common.json"), []byte(`{}`), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "books.json"), []byte(`{}`), 0o600))
+
+		// Changes are debounced into a single reload
+		require.Eventually(t, func() bool { return v.calls() == 1 }, time.Second*3, time.Millisecond*50)
+		assert.Equal(t, []string{"books.json", "defs/common.json"}, v.lastFiles())
+		assert.Equal(t, []map[string]json.RawMessage{schemas}, v.schemas)
+		assert.Contains(t, lb.String(), `"message":"validation schemas reloaded"`)
+
+		// Changes in subdirectories are watched
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "defs", "common.json"), []byte(`{"type":"object"}`), 0o600))
+		require.Eventually(t, func() bool { return v.calls() == 2 }, time.Second*3, time.Millisecond*50)
+	})
+
+	tt.Run("SIGHUPReloadFailed", func(t *testing.T) {
+		lb := &syncBuilder{}
+		v := &validatorMock{err: errors.New("theReloadError")}
+		r := schemareloader.New(v, schemareloader.Config{}, loadConfig(schemareloader.Config{}, nil), zerolog.New(lb))
+		runReloader(t, r)
+
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+
+		require.Eventually(t, func() bool { return v.calls() == 1 }, time.Second*3, time.Millisecond*50)
+		require.Eventually(t, func() bool {
+			return strings.Contains(lb.String(),
+				`"error":"theReloadError","message":"validation schemas reload failed, keeping the current ones"`)
+		}, time.Second, time.Millisecond*10)
+	})
+
+	tt.Run("SIGHUPConfigChanged", func(t *testing.T) {
+		dir := t.TempDir()
+		lb := &syncBuilder{}
+		v := &validatorMock{}
+		schemas := map[string]json.RawMessage{"books": json.RawMessage(`{"type":"object"}`)}
+
+		// The reloader is started without a schemas directory, which appears in the configuration later
+		r := schemareloader.New(v, schemareloader.Config{}, loadConfig(schemareloader.Config{
+			Schemas: schemas,
+			Dir:     dir,
+		}, nil), zerolog.New(lb))
+		runReloader(t, r)
+
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGHUP))
+		require.Eventually(t, func() bool { return v.calls() == 1 }, time.Second*3, time.Millisecond*50)
+		assert.Equal(t, []map[string]json.RawMessage{schemas}, v.schemas)
+
+		// The new directory is watched
+		time.Sleep(time.Millisecond * 100)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "books.json"), []byte(`{}`), 0o600))
+		require.Eventually(t, func() bool { return v.calls() == 2 }, time.Second*3, time.Millisecond*50)
+		assert.Equal(t, []string{"books.json"}, v.lastFiles())
+	})
+}
@@ -0,0 +1,168 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// defaultsMaxDepth limits the depth of schema references followed without descending into the data, guarding against
+// reference cycles.
+const defaultsMaxDepth = 32
+
+// ApplyDefaults sets properties missing in JSON data to the default values declared in the schemas that apply to the
+// index. Data is returned as is if there is nothing to set.
+func (v *JSONValidator) ApplyDefaults(index, data string) (string, error) {
+	schemas := v.schemasFor(index)
+	if len(schemas) == 0 {
+		return data, nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(data)))
+	if err != nil {
+		return "", apperrors.InvalidArgError{
+			Subj:   "json schema or data",
+			Reason: err.Error(),
+		}
+	}
+
+	// A default set by one schema may bring in properties whose defaults are declared by another one, so the schemas
+	// are applied until there is nothing left to set.
+	changed := false
+	for range len(schemas) {
+		passChanged := false
+		for _, sch := range schemas {
+			if applyDefaults(sch.schema, doc, 0) {
+				passChanged = true
+			}
+		}
+
+		if !passChanged {
+			break
+		}
+
+		changed = true
+	}
+
+	if !changed {
+		return data, nil
+	}
+
+	return marshalJSON(doc)
+}
+
+// applyDefaults sets missing object properties to the defaults declared by the schema. It follows "$ref" and "allOf",
+// while conditional and alternative subschemas are skipped, since it's unknown which of them apply. It reports whether
+// anything was set.
+func applyDefaults(sch *jsonschema.Schema, doc any, depth int) bool {
+	if sch == nil || depth > defaultsMaxDepth {
+		return false
+	}
+
+	changed := applyDefaults(sch.Ref, doc, depth+1)
+
+	for _, s := range sch.AllOf {
+		if applyDefaults(s, doc, depth+1) {
+			changed = true
+		}
+	}
+
+	switch d := doc.(type) {
+	case map[string]any:
+		for k, p := range sch.Properties {
+			if v, ok := d[k]; ok {
+				if applyDefaults(p, v, 0) {
+					changed = true
+				}
+			} else if def := schemaDefault(p); def != nil {
+				d[k] = copyJSON(*def) // the data may be modified further, while defaults are shared
+				applyDefaults(p, d[k], 0)
+				changed = true
+			}
+		}
+	case []any:
+		for i, item := range d {
+			if applyDefaults(itemSchema(sch, i), item, 0) {
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+// schemaDefault returns the default value declared by the schema or by the schema it references.
+func schemaDefault(sch *jsonschema.Schema) *any {
+	for i := 0; sch != nil && i <= defaultsMaxDepth; i++ {
+		if sch.Default != nil {
+			return sch.Default
+		}
+
+		sch = sch.Ref
+	}
+
+	return nil
+}
+
+// copyJSON returns a deep copy of a decoded JSON value.
+func copyJSON(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		res := make(map[string]any, len(t))
+		for k, item := range t {
+			res[k] = copyJSON(item)
+		}
+
+		return res
+	case []any:
+		res := make([]any, len(t))
+		for i, item := range t {
+			res[i] = copyJSON(item)
+		}
+
+		return res
+	default:
+		return v
+	}
+}
+
+// itemSchema returns the schema of the array item at the position.
+func itemSchema(sch *jsonschema.Schema, i int) *jsonschema.Schema {
+	if i < len(sch.PrefixItems) {
+		return sch.PrefixItems[i]
+	}
+
+	if sch.Items2020 != nil {
+		return sch.Items2020
+	}
+
+	switch items := sch.Items.(type) {
+	case *jsonschema.Schema:
+		return items
+	case []*jsonschema.Schema:
+		if i < len(items) {
+			return items[i]
+		}
+	}
+
+	return nil
+}
+
+// marshalJSON encodes a document decoded by jsonschema.UnmarshalJSON in the compact form with sorted object keys.
+func marshalJSON(doc any) (string, error) {
+	buf := &bytes.Buffer{}
+
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(doc); err != nil {
+		return "", apperrors.InvalidArgError{
+			Subj:   "json data",
+			Reason: err.Error(),
+		}
+	}
+
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
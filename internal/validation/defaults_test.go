@@ -0,0 +1,86 @@
+package validation_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONValidator_ApplyDefaults(tt *testing.T) {
+	tt.Run("NoSchemas", func(t *testing.T) {
+		v := newJSONValidator(t, nil)
+
+		res, err := v.ApplyDefaults("books", `{ "b": 1,  "a": 2 }`)
+		require.NoError(t, err)
+		assert.Equal(t, `{ "b": 1,  "a": 2 }`, res)
+	})
+
+	tt.Run("NothingToSet", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			".*": json.RawMessage(`{"properties": {"year": {"default": 1970}}}`),
+		})
+
+		res, err := v.ApplyDefaults("books", `{ "year": 1974 }`)
+		require.NoError(t, err)
+		assert.Equal(t, `{ "year": 1974 }`, res)
+	})
+
+	tt.Run("MalformedData", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			".*": json.RawMessage(`{}`),
+		})
+
+		_, err := v.ApplyDefaults("books", `{]`)
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{
+			Subj:   "json schema or data",
+			Reason: "invalid character ']' looking for beginning of object key string",
+		})
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			"^books$": json.RawMessage(`{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"$defs": {"tags": {"type": "array", "default": []}},
+				"properties": {
+					"year": {"default": 1970.50},
+					"meta": {"properties": {"lang": {"default": "en"}}, "default": {"lang": "uk"}},
+					"tags": {"$ref": "#/$defs/tags"},
+					"authors": {"items": {"properties": {"role": {"default": "author"}}}}
+				},
+				"allOf": [{"properties": {"draft": {"default": false}}}],
+				"oneOf": [{"properties": {"skipped": {"default": true}}}]
+			}`),
+			"^books": json.RawMessage(`{"properties": {"meta": {"properties": {"format": {"default": "<pdf>"}}}}}`),
+		})
+
+		res, err := v.ApplyDefaults("books", `{"title": "foo", "authors": [{"name": "bar"}, {"name": "baz", "role": "editor"}]}`)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"title": "foo",
+			"year": 1970.50,
+			"meta": {"lang": "uk", "format": "<pdf>"},
+			"tags": [],
+			"authors": [{"name": "bar", "role": "author"}, {"name": "baz", "role": "editor"}],
+			"draft": false
+		}`, res)
+
+		// Defaults shared between records are not modified
+		res, err = v.ApplyDefaults("books", `{}`)
+		require.NoError(t, err)
+		assert.Contains(t, res, `"meta":{"format":"<pdf>","lang":"uk"}`)
+		assert.Contains(t, res, `"year":1970.50`)
+	})
+
+	tt.Run("StoredSchema", func(t *testing.T) {
+		v := newJSONValidator(t, nil)
+		require.NoError(t, v.SetStoredSchema("books", 1, json.RawMessage(`{"properties": {"year": {"default": 1970}}}`)))
+
+		res, err := v.ApplyDefaults("books", `{}`)
+		require.NoError(t, err)
+		assert.Equal(t, `{"year":1970}`, res)
+	})
+}
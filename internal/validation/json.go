@@ -1,14 +1,33 @@
 package validation
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
 	"regexp"
 	"sort"
+	"strings"
+	"sync"
 
 	"github.com/ashep/go-apperrors"
-	"github.com/xeipuuv/gojsonschema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
 )
 
+// DefaultDialect is the JSON schema dialect used for schemas which don't declare one with the "$schema" keyword.
+const DefaultDialect = "http://json-schema.org/draft-07/schema#"
+
+// schemaURL is the location standalone schemas are compiled at, so relative references resolve to URLs which are never
+// loaded. Each such schema is compiled by its own compiler, so there are no collisions.
+const schemaURL = "mem:///schema.json"
+
+// schemasDirURL is the location files of the schemas directory are compiled at. Schemas from the configuration are
+// compiled at the same location, so they can refer to the files as well.
+const schemasDirURL = "mem:///"
+
 // Schema is a record validation schema bound to an index name pattern.
 type Schema struct {
 	Pattern string
@@ -19,33 +38,231 @@ type schemaEntry struct {
 	pattern string
 	re      *regexp.Regexp
 	raw     json.RawMessage
-	loader  gojsonschema.JSONLoader
+	schema  *CompiledSchema
+}
+
+// CompiledSchema is a standalone JSON schema that data can be validated against.
+type CompiledSchema struct {
+	schema *jsonschema.Schema
+}
+
+// CompileSchema checks and compiles a JSON schema. The dialect declared with the "$schema" keyword is honored; draft-07
+// is assumed if there is none. Drafts 4, 6, 7, 2019-09 and 2020-12 are supported. References to external resources
+// are not resolved.
+func CompileSchema(schema json.RawMessage) (*CompiledSchema, error) {
+	sch, err := compileSchema(schema)
+	if err != nil {
+		return nil, apperrors.InvalidArgError{
+			Subj:   "json schema",
+			Reason: err.Error(),
+		}
+	}
+
+	return sch, nil
+}
+
+func newCompiler() *jsonschema.Compiler {
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft7)
+	c.UseLoader(jsonschema.SchemeURLLoader{}) // no loaders registered, so no files or URLs are ever fetched
+
+	return c
+}
+
+func compileSchema(schema json.RawMessage) (*CompiledSchema, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCompiler()
+
+	if err := c.AddResource(schemaURL, doc); err != nil {
+		return nil, err
+	}
+
+	sch, err := c.Compile(schemaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompiledSchema{schema: sch}, nil
+}
+
+// Validate validates JSON data against the schema.
+func (s *CompiledSchema) Validate(data string) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(data)))
+	if err != nil {
+		return apperrors.InvalidArgError{
+			Subj:   "json data",
+			Reason: err.Error(),
+		}
+	}
+
+	return s.validate(doc)
+}
+
+func (s *CompiledSchema) validate(doc any) error {
+	err := s.schema.Validate(doc)
+
+	vErr := &jsonschema.ValidationError{}
+	if errors.As(err, &vErr) {
+		return DataError{Violations: newViolations(vErr)}
+	} else if err != nil {
+		return apperrors.InvalidArgError{
+			Subj:   "json data",
+			Reason: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// storedSchema is an index schema stored via the API, as opposed to the ones coming from the configuration.
+type storedSchema struct {
+	version uint32
+	schema  *CompiledSchema
 }
 
 type JSONValidator struct {
-	entries []schemaEntry
+	entries   []schemaEntry
+	entriesMu sync.RWMutex
+	stored    map[string]storedSchema
+	storedMu  sync.RWMutex
+}
+
+// currentEntries returns the current schema entries. The returned slice is never modified, only replaced on reload.
+func (v *JSONValidator) currentEntries() []schemaEntry {
+	v.entriesMu.RLock()
+	defer v.entriesMu.RUnlock()
+
+	return v.entries
+}
+
+// NewJSONValidator compiles the record data validation schemas. The schemas map binds schemas to index name regexp
+// patterns. Each top-level "*.json" file of the optional dir binds a schema to the index name glob pattern made of the
+// file name without the extension, e.g. "books.json" or "books-*.json". Files in subdirectories are not bound to any
+// index, but both can be referred to with relative "$ref" from the files and the schemas of the map.
+func NewJSONValidator(schemas map[string]json.RawMessage, dir fs.FS) (*JSONValidator, error) {
+	entries, err := compileEntries(schemas, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSONValidator{
+		entries: entries,
+		stored:  make(map[string]storedSchema),
+	}, nil
 }
 
-func NewJSONValidator(schemas map[string]json.RawMessage) *JSONValidator {
+// Reload compiles the schemas the same way NewJSONValidator does and replaces the current ones with them. If any schema
+// fails to compile, the current schemas are kept. Stored schemas are not affected.
+func (v *JSONValidator) Reload(schemas map[string]json.RawMessage, dir fs.FS) error {
+	entries, err := compileEntries(schemas, dir)
+	if err != nil {
+		return err
+	}
+
+	v.entriesMu.Lock()
+	v.entries = entries
+	v.entriesMu.Unlock()
+
+	return nil
+}
+
+func compileEntries(schemas map[string]json.RawMessage, dir fs.FS) ([]schemaEntry, error) {
+	c := newCompiler()
+
 	entries := make([]schemaEntry, 0, len(schemas))
-	for pattern, sch := range schemas {
-		entries = append(entries, schemaEntry{
-			pattern: pattern,
-			re:      regexp.MustCompile(pattern),
-			raw:     sch,
-			loader:  gojsonschema.NewBytesLoader(sch),
+	urls := make([]string, 0, len(schemas))
+
+	if dir != nil {
+		err := fs.WalkDir(dir, ".", func(pth string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() || path.Ext(pth) != ".json" {
+				return nil
+			}
+
+			raw, err := fs.ReadFile(dir, pth)
+			if err != nil {
+				return err
+			}
+
+			doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+			if err != nil {
+				return fmt.Errorf("%s: %w", pth, err)
+			}
+
+			u := schemasDirURL + pth
+			if err := c.AddResource(u, doc); err != nil {
+				return fmt.Errorf("%s: %w", pth, err)
+			}
+
+			if path.Dir(pth) == "." {
+				entries = append(entries, schemaEntry{pattern: globToRegexp(strings.TrimSuffix(pth, ".json")), raw: raw})
+				urls = append(urls, u)
+			}
+
+			return nil
 		})
+		if err != nil {
+			return nil, fmt.Errorf("schemas dir: %w", err)
+		}
+	}
+
+	for pattern, sch := range schemas {
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(sch))
+		if err != nil {
+			return nil, fmt.Errorf("schema %q: %w", pattern, err)
+		}
+
+		// The query keeps the URL unique while relative references resolve against the directory root
+		u := schemasDirURL + "?pattern=" + url.QueryEscape(pattern)
+		if err := c.AddResource(u, doc); err != nil {
+			return nil, fmt.Errorf("schema %q: %w", pattern, err)
+		}
+
+		entries = append(entries, schemaEntry{pattern: pattern, raw: sch})
+		urls = append(urls, u)
 	}
 
-	sort.Slice(entries, func(i, j int) bool {
+	for i := range entries {
+		re, err := regexp.Compile(entries[i].pattern)
+		if err != nil {
+			return nil, fmt.Errorf("schema %q: %w", entries[i].pattern, err)
+		}
+
+		sch, err := c.Compile(urls[i])
+		if err != nil {
+			return nil, fmt.Errorf("schema %q: %w", entries[i].pattern, err)
+		}
+
+		entries[i].re = re
+		entries[i].schema = &CompiledSchema{schema: sch}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
 		return entries[i].pattern < entries[j].pattern
 	})
 
-	return &JSONValidator{
-		entries: entries,
+	return entries, nil
+}
+
+// globToRegexp converts an index name glob pattern, where "*" matches any sequence of characters, to a regexp.
+func globToRegexp(glob string) string {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
 	}
+
+	return "^" + strings.Join(parts, ".*") + "$"
 }
 
+// Validate validates JSON data against all the schemas that apply to the index. Data not conforming to the schemas
+// results in a DataError holding violations from all of them.
 func (v *JSONValidator) Validate(k, s string) error {
 	if s == "" {
 		return apperrors.InvalidArgError{
@@ -54,36 +271,102 @@ func (v *JSONValidator) Validate(k, s string) error {
 		}
 	}
 
-	for _, e := range v.entries {
-		if !e.re.MatchString(k) {
-			continue
+	schemas := v.schemasFor(k)
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader([]byte(s)))
+	if err != nil {
+		return apperrors.InvalidArgError{
+			Subj:   "json schema or data",
+			Reason: err.Error(),
 		}
+	}
 
-		res, err := gojsonschema.Validate(e.loader, gojsonschema.NewBytesLoader([]byte(s)))
-		if err != nil {
-			return apperrors.InvalidArgError{
-				Subj:   "json schema or data",
-				Reason: err.Error(),
-			}
+	violations := make([]Violation, 0)
+
+	for _, sch := range schemas {
+		err := sch.validate(doc)
+
+		dErr := DataError{}
+		if errors.As(err, &dErr) {
+			violations = append(violations, dErr.Violations...)
+		} else if err != nil {
+			return err
 		}
+	}
 
-		if !res.Valid() {
-			return apperrors.InvalidArgError{
-				Subj:   "json",
-				Reason: res.Errors()[0].String(),
-			}
+	if len(violations) != 0 {
+		return DataError{Violations: violations}
+	}
+
+	return nil
+}
+
+// schemasFor returns the configured schemas and the stored one that apply to the index.
+func (v *JSONValidator) schemasFor(index string) []*CompiledSchema {
+	res := make([]*CompiledSchema, 0)
+
+	for _, e := range v.currentEntries() {
+		if e.re.MatchString(index) {
+			res = append(res, e.schema)
 		}
 	}
 
+	v.storedMu.RLock()
+	if st, ok := v.stored[index]; ok {
+		res = append(res, st.schema)
+	}
+	v.storedMu.RUnlock()
+
+	return res
+}
+
+// CheckSchema checks whether the given JSON schema can be used for validation.
+func (v *JSONValidator) CheckSchema(schema json.RawMessage) error {
+	_, err := CompileSchema(schema)
+	return err
+}
+
+// StoredSchemaVersion returns the version of the stored schema currently used for the index; zero if none.
+func (v *JSONValidator) StoredSchemaVersion(index string) uint32 {
+	v.storedMu.RLock()
+	defer v.storedMu.RUnlock()
+
+	return v.stored[index].version
+}
+
+// SetStoredSchema sets the stored schema records of the index are validated against in addition to the configured
+// ones. Zero version removes the index's stored schema.
+func (v *JSONValidator) SetStoredSchema(index string, version uint32, schema json.RawMessage) error {
+	if version == 0 {
+		v.storedMu.Lock()
+		delete(v.stored, index)
+		v.storedMu.Unlock()
+
+		return nil
+	}
+
+	sch, err := CompileSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	v.storedMu.Lock()
+	v.stored[index] = storedSchema{version: version, schema: sch}
+	v.storedMu.Unlock()
+
 	return nil
 }
 
-// SchemasFor returns the schemas whose pattern matches the given index name,
-// sorted by pattern. These are exactly the schemas a record pushed to that
-// index would be validated against.
+// SchemasFor returns the configured schemas whose pattern matches the index name, sorted by pattern. Records pushed to
+// the index are validated against them and against the index's stored schema, if it is set; see SetStoredSchema.
 func (v *JSONValidator) SchemasFor(name string) []Schema {
-	res := make([]Schema, 0, len(v.entries))
-	for _, e := range v.entries {
+	entries := v.currentEntries()
+
+	res := make([]Schema, 0, len(entries))
+	for _, e := range entries {
 		if e.re.MatchString(name) {
 			res = append(res, Schema{Pattern: e.pattern, Schema: e.raw})
 		}
@@ -0,0 +1,133 @@
+package validation_test
+
+import (
+	"encoding/json"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/validation"
+)
+
+func TestJSONValidator_SchemasDir(tt *testing.T) {
+	dir := fstest.MapFS{
+		"books.json": {Data: []byte(`{
+			"properties": {"author": {"$ref": "defs/person.json"}},
+			"required": ["author"]
+		}`)},
+		"movies-*.json":    {Data: []byte(`{"properties": {"title": {"$ref": "defs/common.json#/$defs/title"}}}`)},
+		"README.md":        {Data: []byte(`not a schema`)},
+		"defs/person.json": {Data: []byte(`{"required": ["name"], "properties": {"name": {"$ref": "common.json#/$defs/title"}}}`)},
+		"defs/common.json": {Data: []byte(`{"$defs": {"title": {"type": "string", "minLength": 1}}}`)},
+	}
+
+	tt.Run("RefsBetweenFiles", func(t *testing.T) {
+		v, err := validation.NewJSONValidator(nil, dir)
+		require.NoError(t, err)
+
+		assert.NoError(t, v.Validate("books", `{"author":{"name":"foo"}}`))
+		assert.ErrorIs(t, v.Validate("books", `{"author":{"name":""}}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "author.name: minLength: got 0, want 1",
+		})
+		assert.ErrorIs(t, v.Validate("books", `{"author":{}}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "author: missing property 'name'",
+		})
+
+		assert.NoError(t, v.Validate("movies-new", `{"title":"foo"}`))
+		assert.ErrorIs(t, v.Validate("movies-old", `{"title":1}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "title: got number, want string",
+		})
+
+		// Files in subdirectories are not bound to indices, file names are anchored patterns
+		assert.NoError(t, v.Validate("person", `{}`))
+		assert.NoError(t, v.Validate("books-old", `{}`))
+		assert.NoError(t, v.Validate("movies", `{"title":1}`))
+	})
+
+	tt.Run("SchemasFor", func(t *testing.T) {
+		v, err := validation.NewJSONValidator(map[string]json.RawMessage{
+			".*": json.RawMessage(`{}`),
+		}, dir)
+		require.NoError(t, err)
+
+		assert.Equal(t, []validation.Schema{
+			{Pattern: ".*", Schema: json.RawMessage(`{}`)},
+			{Pattern: `^movies-.*$`, Schema: dir["movies-*.json"].Data},
+		}, v.SchemasFor("movies-new"))
+	})
+
+	tt.Run("ConfiguredSchemaRefersToFile", func(t *testing.T) {
+		v, err := validation.NewJSONValidator(map[string]json.RawMessage{
+			"^authors$": json.RawMessage(`{"$ref": "defs/person.json"}`),
+		}, dir)
+		require.NoError(t, err)
+
+		assert.NoError(t, v.Validate("authors", `{"name":"foo"}`))
+		assert.ErrorIs(t, v.Validate("authors", `{}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): missing property 'name'",
+		})
+	})
+
+	tt.Run("MalformedFile", func(t *testing.T) {
+		_, err := validation.NewJSONValidator(nil, fstest.MapFS{
+			"books.json": {Data: []byte(`{]`)},
+		})
+
+		assert.EqualError(t, err, "schemas dir: books.json: invalid character ']' looking for beginning of object key string")
+	})
+
+	tt.Run("UnresolvedRef", func(t *testing.T) {
+		_, err := validation.NewJSONValidator(nil, fstest.MapFS{
+			"books.json": {Data: []byte(`{"$ref": "defs/missing.json"}`)},
+		})
+
+		assert.ErrorContains(t, err, `schema "^books$": `)
+	})
+}
+
+func TestJSONValidator_Reload(tt *testing.T) {
+	tt.Run("Ok", func(t *testing.T) {
+		v, err := validation.NewJSONValidator(map[string]json.RawMessage{
+			"^books$": json.RawMessage(`{"required":["title"]}`),
+		}, nil)
+		require.NoError(t, err)
+		require.NoError(t, v.SetStoredSchema("books", 1, json.RawMessage(`{"required":["year"]}`)))
+
+		require.NoError(t, v.Reload(nil, fstest.MapFS{
+			"books.json": {Data: []byte(`{"required":["author"]}`)},
+		}))
+
+		assert.ErrorIs(t, v.Validate("books", `{"title":"foo"}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): missing property 'author'; (root): missing property 'year'",
+		})
+		assert.Equal(t, uint32(1), v.StoredSchemaVersion("books"))
+		assert.Equal(t, []validation.Schema{
+			{Pattern: "^books$", Schema: json.RawMessage(`{"required":["author"]}`)},
+		}, v.SchemasFor("books"))
+	})
+
+	tt.Run("InvalidSchemaKeepsCurrent", func(t *testing.T) {
+		v, err := validation.NewJSONValidator(map[string]json.RawMessage{
+			"^books$": json.RawMessage(`{"required":["title"]}`),
+		}, nil)
+		require.NoError(t, err)
+
+		err = v.Reload(nil, fstest.MapFS{
+			"books.json": {Data: []byte(`{"$ref":"missing.json"}`)},
+		})
+		assert.ErrorContains(t, err, `schema "^books$": `)
+
+		assert.ErrorIs(t, v.Validate("books", `{}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): missing property 'title'",
+		})
+	})
+}
@@ -11,12 +11,12 @@ import (
 
 func TestJSONValidator_SchemasFor(tt *testing.T) {
 	tt.Run("Nil", func(t *testing.T) {
-		v := validation.NewJSONValidator(nil)
+		v := newJSONValidator(t, nil)
 		assert.Empty(t, v.SchemasFor("books"))
 	})
 
 	tt.Run("MatchingOnlySortedByPattern", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"books.*":  json.RawMessage(`{"type":"object"}`),
 			".*":       json.RawMessage(`{}`),
 			"^movies$": json.RawMessage(`{"type":"array"}`),
@@ -29,7 +29,7 @@ func TestJSONValidator_SchemasFor(tt *testing.T) {
 	})
 
 	tt.Run("CatchAllOnlyWhenNoSpecificMatch", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"books.*": json.RawMessage(`{"type":"object"}`),
 			".*":      json.RawMessage(`{}`),
 		})
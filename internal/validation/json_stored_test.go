@@ -0,0 +1,90 @@
+package validation_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/validation"
+)
+
+func TestJSONValidator_CheckSchema(tt *testing.T) {
+	tt.Run("Invalid", func(t *testing.T) {
+		v := newJSONValidator(t, nil)
+
+		err := v.CheckSchema(json.RawMessage(`{"type":"foo"}`))
+		assert.ErrorAs(t, err, &apperrors.InvalidArgError{})
+		assert.ErrorContains(t, err, "invalid json schema: ")
+	})
+
+	tt.Run("Ok", func(t *testing.T) {
+		v := newJSONValidator(t, nil)
+		assert.NoError(t, v.CheckSchema(json.RawMessage(`{"type":"object"}`)))
+	})
+}
+
+func TestJSONValidator_StoredSchema(tt *testing.T) {
+	tt.Run("InvalidSchema", func(t *testing.T) {
+		v := newJSONValidator(t, nil)
+
+		err := v.SetStoredSchema("books", 1, json.RawMessage(`{]`))
+		assert.ErrorAs(t, err, &apperrors.InvalidArgError{})
+		assert.Zero(t, v.StoredSchemaVersion("books"))
+	})
+
+	tt.Run("ValidatedAfterConfigured", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			"books": json.RawMessage(`{"required":["title"]}`),
+		})
+
+		require.NoError(t, v.SetStoredSchema("books", 2, json.RawMessage(`{"required":["author"]}`)))
+		assert.Equal(t, uint32(2), v.StoredSchemaVersion("books"))
+		assert.Zero(t, v.StoredSchemaVersion("movies"))
+
+		assert.ErrorIs(t, v.Validate("books", `{"author":"foo"}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): missing property 'title'",
+		})
+		assert.ErrorIs(t, v.Validate("books", `{"title":"foo"}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): missing property 'author'",
+		})
+		assert.NoError(t, v.Validate("books", `{"title":"foo","author":"bar"}`))
+		assert.NoError(t, v.Validate("movies", `{}`))
+	})
+
+	tt.Run("Removed", func(t *testing.T) {
+		v := newJSONValidator(t, nil)
+
+		require.NoError(t, v.SetStoredSchema("books", 1, json.RawMessage(`{"required":["author"]}`)))
+		require.NoError(t, v.SetStoredSchema("books", 0, nil))
+
+		assert.Zero(t, v.StoredSchemaVersion("books"))
+		assert.NoError(t, v.Validate("books", `{}`))
+	})
+}
+
+func TestCompileSchema(tt *testing.T) {
+	tt.Run("InvalidSchema", func(t *testing.T) {
+		_, err := validation.CompileSchema(json.RawMessage(`{]`))
+		assert.ErrorAs(t, err, &apperrors.InvalidArgError{})
+	})
+
+	tt.Run("Validate", func(t *testing.T) {
+		sch, err := validation.CompileSchema(json.RawMessage(`{"required":["title"]}`))
+		require.NoError(t, err)
+
+		assert.NoError(t, sch.Validate(`{"title":"foo"}`))
+		assert.ErrorIs(t, sch.Validate(`{}`), apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): missing property 'title'",
+		})
+		assert.ErrorIs(t, sch.Validate(`{]`), apperrors.InvalidArgError{
+			Subj:   "json data",
+			Reason: "invalid character ']' looking for beginning of object key string",
+		})
+	})
+}
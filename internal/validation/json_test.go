@@ -11,9 +11,18 @@ import (
 	"github.com/ashep/ujds/internal/validation"
 )
 
+func newJSONValidator(t *testing.T, schemas map[string]json.RawMessage) *validation.JSONValidator {
+	t.Helper()
+
+	v, err := validation.NewJSONValidator(schemas, nil)
+	require.NoError(t, err)
+
+	return v
+}
+
 func Test_ValidateJSON(tt *testing.T) {
 	tt.Run("NilSchemaMap", func(t *testing.T) {
-		v := validation.NewJSONValidator(nil)
+		v := newJSONValidator(t, nil)
 		require.NotNil(t, v)
 		// Nil schema map should skip validation
 		err := v.Validate("test", `{"foo":"bar"}`)
@@ -21,7 +30,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("EmptySchemaMap", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{})
+		v := newJSONValidator(t, map[string]json.RawMessage{})
 		require.NotNil(t, v)
 		// Empty schema map should skip validation
 		err := v.Validate("test", `{"foo":"bar"}`)
@@ -29,7 +38,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("EmptyJSON", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*": json.RawMessage(`{}`),
 		})
 
@@ -41,7 +50,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("WhitespaceOnlyJSON", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*": json.RawMessage(`{}`),
 		})
 
@@ -53,19 +62,31 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("MalformedSchema", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		_, err := validation.NewJSONValidator(map[string]json.RawMessage{
 			".*": json.RawMessage("{]"),
-		})
+		}, nil)
 
-		err := v.Validate("test", `{"foo":"bar"}`)
-		assert.ErrorIs(t, err, apperrors.InvalidArgError{
-			Subj:   "json schema or data",
-			Reason: "invalid character ']' looking for beginning of object key string",
-		})
+		assert.EqualError(t, err, `schema ".*": invalid character ']' looking for beginning of object key string`)
+	})
+
+	tt.Run("InvalidSchema", func(t *testing.T) {
+		_, err := validation.NewJSONValidator(map[string]json.RawMessage{
+			".*": json.RawMessage(`{"type":"foo"}`),
+		}, nil)
+
+		assert.ErrorContains(t, err, `schema ".*": `)
+	})
+
+	tt.Run("InvalidPattern", func(t *testing.T) {
+		_, err := validation.NewJSONValidator(map[string]json.RawMessage{
+			"(": json.RawMessage(`{}`),
+		}, nil)
+
+		assert.ErrorContains(t, err, `schema "(": error parsing regexp`)
 	})
 
 	tt.Run("MalformedData", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*": json.RawMessage(`{}`),
 		})
 
@@ -77,19 +98,19 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("DataValidationError", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*": json.RawMessage(`{"properties":{"foo":{"type":"number"}}}`),
 		})
 
 		err := v.Validate("test", `{"foo":"bar"}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "foo: Invalid type. Expected: number, given: string",
+			Reason: "foo: got string, want number",
 		})
 	})
 
 	tt.Run("Ok", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*": json.RawMessage(`{"properties":{"foo":{"type":"string"}}}`),
 		})
 
@@ -98,7 +119,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("ValidJSONArray", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^array_.*": json.RawMessage(`{
 				"type": "array",
 				"items": {"type": "string"}
@@ -110,7 +131,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("InvalidJSONArrayItems", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^array_.*": json.RawMessage(`{
 				"type": "array",
 				"items": {"type": "string"}
@@ -120,12 +141,12 @@ func Test_ValidateJSON(tt *testing.T) {
 		err := v.Validate("array_test", `["foo", 123, "baz"]`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "1: Invalid type. Expected: string, given: integer",
+			Reason: "1: got number, want string",
 		})
 	})
 
 	tt.Run("NoMatchingPattern", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^user_.*": json.RawMessage(`{"properties":{"name":{"type":"string"}}}`),
 			"^post_.*": json.RawMessage(`{"properties":{"title":{"type":"string"}}}`),
 		})
@@ -136,7 +157,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("MatchingPatternWithValidData", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^user_.*": json.RawMessage(`{"properties":{"name":{"type":"string"}}}`),
 			"^post_.*": json.RawMessage(`{"properties":{"title":{"type":"string"}}}`),
 		})
@@ -149,7 +170,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("MatchingPatternWithInvalidData", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^user_.*": json.RawMessage(`{"properties":{"name":{"type":"string"}}}`),
 			"^post_.*": json.RawMessage(`{"properties":{"title":{"type":"string"}}}`),
 		})
@@ -157,18 +178,18 @@ func Test_ValidateJSON(tt *testing.T) {
 		err := v.Validate("user_123", `{"name":123}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "name: Invalid type. Expected: string, given: integer",
+			Reason: "name: got number, want string",
 		})
 
 		err = v.Validate("post_456", `{"title":456}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "title: Invalid type. Expected: string, given: integer",
+			Reason: "title: got number, want string",
 		})
 	})
 
 	tt.Run("ComplexSchemaValidation", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^product_.*": json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -188,28 +209,28 @@ func Test_ValidateJSON(tt *testing.T) {
 		err = v.Validate("product_123", `{"id":123,"name":"Widget"}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "(root): price is required",
+			Reason: "(root): missing property 'price'",
 		})
 
 		// Invalid type
 		err = v.Validate("product_123", `{"id":"not-a-number","name":"Widget","price":9.99}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "id: Invalid type. Expected: integer, given: string",
+			Reason: "id: got string, want integer",
 		})
 
 		// Invalid minimum constraint
 		err = v.Validate("product_123", `{"id":123,"name":"Widget","price":-5}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "price: Must be greater than or equal to 0",
+			Reason: "price: minimum: got -5, want 0",
 		})
 	})
 
 	tt.Run("MultipleMatchingPatterns", func(t *testing.T) {
 		// When multiple patterns match, the first matching one should be used
 		// (based on map iteration, which is non-deterministic, but at least one should match)
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*":      json.RawMessage(`{"properties":{"any":{"type":"string"}}}`),
 			"^test.*": json.RawMessage(`{"properties":{"test":{"type":"number"}}}`),
 		})
@@ -225,7 +246,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("EmptySchemaString", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*": json.RawMessage(`{}`),
 		})
 
@@ -238,7 +259,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("AdditionalPropertiesValidation", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^strict_.*": json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -256,12 +277,12 @@ func Test_ValidateJSON(tt *testing.T) {
 		err = v.Validate("strict_obj", `{"name":"test","extra":"field"}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "(root): Additional property extra is not allowed",
+			Reason: "(root): additional properties 'extra' not allowed",
 		})
 	})
 
 	tt.Run("NullValue", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			".*": json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -280,7 +301,7 @@ func Test_ValidateJSON(tt *testing.T) {
 	})
 
 	tt.Run("PrimitiveJSONValues", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^number_.*": json.RawMessage(`{"type": "number"}`),
 			"^string_.*": json.RawMessage(`{"type": "string"}`),
 			"^bool_.*":   json.RawMessage(`{"type": "boolean"}`),
@@ -303,12 +324,12 @@ func Test_ValidateJSON(tt *testing.T) {
 		err = v.Validate("number_field", `"not a number"`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "(root): Invalid type. Expected: number, given: string",
+			Reason: "(root): got string, want number",
 		})
 	})
 
 	tt.Run("NestedObjectValidation", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^user_.*": json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -344,12 +365,12 @@ func Test_ValidateJSON(tt *testing.T) {
 		}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "address: city is required",
+			Reason: "address: missing property 'city'",
 		})
 	})
 
 	tt.Run("EnumValidation", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^status_.*": json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -369,12 +390,12 @@ func Test_ValidateJSON(tt *testing.T) {
 		err = v.Validate("status_check", `{"status":"invalid"}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "status: status must be one of the following: \"active\", \"inactive\", \"pending\"",
+			Reason: "status: value must be one of 'active', 'inactive', 'pending'",
 		})
 	})
 
 	tt.Run("StringFormatValidation", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"^email_.*": json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -394,12 +415,12 @@ func Test_ValidateJSON(tt *testing.T) {
 		err = v.Validate("email_field", `{"email":"not-an-email"}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "email: Does not match format 'email'",
+			Reason: "email: 'not-an-email' is not valid email: missing @",
 		})
 	})
 
 	tt.Run("CaseInsensitivePatternMatching", func(t *testing.T) {
-		v := validation.NewJSONValidator(map[string]json.RawMessage{
+		v := newJSONValidator(t, map[string]json.RawMessage{
 			"(?i)^user_.*": json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -420,7 +441,72 @@ func Test_ValidateJSON(tt *testing.T) {
 		err = v.Validate("user_789", `{}`)
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "json",
-			Reason: "(root): name is required",
+			Reason: "(root): missing property 'name'",
+		})
+	})
+}
+
+func TestJSONValidator_Dialects(tt *testing.T) {
+	tt.Run("Draft202012", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			".*": json.RawMessage(`{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"$defs": {"name": {"type": "string", "minLength": 1}},
+				"properties": {"title": {"$ref": "#/$defs/name"}},
+				"allOf": [{"properties": {"author": {"$ref": "#/$defs/name"}}}],
+				"unevaluatedProperties": false,
+				"dependentRequired": {"author": ["title"]}
+			}`),
+		})
+
+		assert.NoError(t, v.Validate("books", `{"title":"foo","author":"bar"}`))
+
+		err := v.Validate("books", `{"title":"foo","year":1900}`)
+		dErr := validation.DataError{}
+		require.ErrorAs(t, err, &dErr)
+		assert.Equal(t, []validation.Violation{
+			{Pointer: "/year", Keyword: "unevaluatedProperties", Message: "false schema"},
+		}, dErr.Violations)
+
+		err = v.Validate("books", `{"author":"bar"}`)
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): properties 'title' required, if 'author' exists",
+		})
+
+		err = v.Validate("books", `{"title":""}`)
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "title: minLength: got 0, want 1",
+		})
+	})
+
+	tt.Run("Draft201909", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			".*": json.RawMessage(`{
+				"$schema": "https://json-schema.org/draft/2019-09/schema",
+				"properties": {"title": {"type": "string"}},
+				"unevaluatedProperties": false
+			}`),
 		})
+
+		assert.NoError(t, v.Validate("books", `{"title":"foo"}`))
+		assert.ErrorAs(t, v.Validate("books", `{"year":1900}`), &validation.DataError{})
+	})
+
+	tt.Run("DefaultDraft07", func(t *testing.T) {
+		// Keywords unknown to draft-07 are ignored
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			".*": json.RawMessage(`{"unevaluatedProperties": false}`),
+		})
+
+		assert.NoError(t, v.Validate("books", `{"year":1900}`))
+	})
+
+	tt.Run("ExternalRefNotLoaded", func(t *testing.T) {
+		for _, ref := range []string{"file:///etc/passwd", "https://example.com/schema.json", "other.json"} {
+			_, err := validation.CompileSchema(json.RawMessage(`{"$ref":"` + ref + `"}`))
+			assert.ErrorAs(t, err, &apperrors.InvalidArgError{}, ref)
+		}
 	})
 }
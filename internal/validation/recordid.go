@@ -1,15 +1,97 @@
 package validation
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+
 	"github.com/ashep/go-apperrors"
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+// RecordIDMaxLength is the maximum length of record IDs the database can store.
+const RecordIDMaxLength = 64
+
+// Record ID formats.
+const (
+	RecordIDFormatUUID = "uuid"
+	RecordIDFormatULID = "ulid"
 )
 
-type RecordIDValidator struct{}
+// Record ID generators.
+const (
+	RecordIDGenerateUUIDv7 = "uuidv7"
+	RecordIDGenerateULID   = "ulid"
+)
+
+// RecordIDRule constrains IDs of records of indices whose names match a pattern.
+type RecordIDRule struct {
+	Pattern   string `json:"pattern" yaml:"pattern"`       // regexp IDs must match
+	MaxLength int    `json:"max_length" yaml:"max_length"` // maximum ID length, up to RecordIDMaxLength
+	Format    string `json:"format" yaml:"format"`         // "uuid" or "ulid"
+	Generate  string `json:"generate" yaml:"generate"`     // "uuidv7" or "ulid" to generate IDs of records pushed without one
+}
+
+type recordIDRule struct {
+	RecordIDRule
+	indexRe *regexp.Regexp
+	idRe    *regexp.Regexp
+}
 
-func NewRecordIDValidator() *RecordIDValidator {
-	return &RecordIDValidator{}
+type RecordIDValidator struct {
+	rules []recordIDRule
 }
 
+// NewRecordIDValidator creates a record ID validator. The rules map binds ID rules to index name regexp patterns; IDs of
+// records are checked against all the rules whose pattern matches the index name.
+func NewRecordIDValidator(rules map[string]RecordIDRule) (*RecordIDValidator, error) {
+	res := make([]recordIDRule, 0, len(rules))
+
+	for pattern, rule := range rules {
+		r := recordIDRule{RecordIDRule: rule}
+
+		var err error
+		if r.indexRe, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("record id rule %q: %w", pattern, err)
+		}
+
+		if rule.Pattern != "" {
+			if r.idRe, err = regexp.Compile(rule.Pattern); err != nil {
+				return nil, fmt.Errorf("record id rule %q: pattern: %w", pattern, err)
+			}
+		}
+
+		if rule.MaxLength < 0 || rule.MaxLength > RecordIDMaxLength {
+			return nil, fmt.Errorf("record id rule %q: max length must be between 0 and %d", pattern, RecordIDMaxLength)
+		}
+
+		switch rule.Format {
+		case "", RecordIDFormatUUID, RecordIDFormatULID:
+		default:
+			return nil, fmt.Errorf("record id rule %q: unknown format: %s", pattern, rule.Format)
+		}
+
+		switch {
+		case rule.Generate == "":
+		case rule.Generate == RecordIDGenerateUUIDv7 && (rule.Format == "" || rule.Format == RecordIDFormatUUID):
+		case rule.Generate == RecordIDGenerateULID && (rule.Format == "" || rule.Format == RecordIDFormatULID):
+		default:
+			return nil, fmt.Errorf("record id rule %q: unknown generator or format mismatch: %s", pattern, rule.Generate)
+		}
+
+		res = append(res, r)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].indexRe.String() < res[j].indexRe.String()
+	})
+
+	return &RecordIDValidator{rules: res}, nil
+}
+
+// Validate checks whether the ID can be stored at all, regardless of the index.
 func (v *RecordIDValidator) Validate(s string) error {
 	if s == "" {
 		return apperrors.InvalidArgError{
@@ -18,5 +100,83 @@ func (v *RecordIDValidator) Validate(s string) error {
 		}
 	}
 
+	if utf8.RuneCountInString(s) > RecordIDMaxLength {
+		return apperrors.InvalidArgError{
+			Subj:   "record id",
+			Reason: fmt.Sprintf("must not be longer than %d characters", RecordIDMaxLength),
+		}
+	}
+
+	return nil
+}
+
+// ValidateForIndex checks the ID of a record of the index against the rules that apply to the index.
+func (v *RecordIDValidator) ValidateForIndex(index, s string) error {
+	if err := v.Validate(s); err != nil {
+		return err
+	}
+
+	for _, r := range v.rules {
+		if !r.indexRe.MatchString(index) {
+			continue
+		}
+
+		if r.MaxLength != 0 && utf8.RuneCountInString(s) > r.MaxLength {
+			return apperrors.InvalidArgError{
+				Subj:   "record id",
+				Reason: fmt.Sprintf("must not be longer than %d characters", r.MaxLength),
+			}
+		}
+
+		if r.idRe != nil && !r.idRe.MatchString(s) {
+			return apperrors.InvalidArgError{
+				Subj:   "record id",
+				Reason: "must match the regexp " + r.idRe.String(),
+			}
+		}
+
+		switch r.Format {
+		case RecordIDFormatUUID:
+			// Only the canonical form, uuid.Parse accepts a few others
+			if _, err := uuid.Parse(s); err != nil || len(s) != 36 { //nolint:mnd // ok
+				return apperrors.InvalidArgError{
+					Subj:   "record id",
+					Reason: "must be a UUID",
+				}
+			}
+		case RecordIDFormatULID:
+			if _, err := ulid.ParseStrict(s); err != nil {
+				return apperrors.InvalidArgError{
+					Subj:   "record id",
+					Reason: "must be a ULID",
+				}
+			}
+		}
+	}
+
 	return nil
 }
+
+// Generate generates an ID for a record of the index pushed without one. It returns an empty string if no rule that
+// applies to the index asks for ID generation.
+func (v *RecordIDValidator) Generate(index string) (string, error) {
+	for _, r := range v.rules {
+		if !r.indexRe.MatchString(index) {
+			continue
+		}
+
+		switch r.Generate {
+		case RecordIDGenerateUUIDv7:
+			id, err := uuid.NewV7()
+			if err != nil {
+				return "", fmt.Errorf("generate uuid: %w", err)
+			}
+
+			return id.String(), nil
+		case RecordIDGenerateULID:
+			return ulid.Make().String(), nil
+		}
+	}
+
+	return "", nil
+}
@@ -1,20 +1,144 @@
 package validation_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/ashep/go-apperrors"
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ashep/ujds/internal/validation"
 )
 
+func TestNewRecordIDValidator(tt *testing.T) {
+	tt.Run("InvalidIndexPattern", func(t *testing.T) {
+		_, err := validation.NewRecordIDValidator(map[string]validation.RecordIDRule{"(": {}})
+		assert.ErrorContains(t, err, `record id rule "(": error parsing regexp`)
+	})
+
+	tt.Run("InvalidIDPattern", func(t *testing.T) {
+		_, err := validation.NewRecordIDValidator(map[string]validation.RecordIDRule{"books": {Pattern: "("}})
+		assert.ErrorContains(t, err, `record id rule "books": pattern: error parsing regexp`)
+	})
+
+	tt.Run("InvalidMaxLength", func(t *testing.T) {
+		_, err := validation.NewRecordIDValidator(map[string]validation.RecordIDRule{"books": {MaxLength: 65}})
+		assert.EqualError(t, err, `record id rule "books": max length must be between 0 and 64`)
+	})
+
+	tt.Run("UnknownFormat", func(t *testing.T) {
+		_, err := validation.NewRecordIDValidator(map[string]validation.RecordIDRule{"books": {Format: "foo"}})
+		assert.EqualError(t, err, `record id rule "books": unknown format: foo`)
+	})
+
+	tt.Run("GeneratorFormatMismatch", func(t *testing.T) {
+		_, err := validation.NewRecordIDValidator(map[string]validation.RecordIDRule{
+			"books": {Format: "uuid", Generate: "ulid"},
+		})
+		assert.EqualError(t, err, `record id rule "books": unknown generator or format mismatch: ulid`)
+	})
+}
+
 func TestRecordIDValidator_Validate(tt *testing.T) {
 	tt.Run("Empty", func(t *testing.T) {
-		err := validation.NewRecordIDValidator().Validate("")
+		v, err := validation.NewRecordIDValidator(nil)
+		require.NoError(t, err)
+
+		err = v.Validate("")
 		assert.ErrorIs(t, err, apperrors.InvalidArgError{
 			Subj:   "record id",
 			Reason: "must not be empty",
 		})
 	})
+
+	tt.Run("TooLong", func(t *testing.T) {
+		v, err := validation.NewRecordIDValidator(nil)
+		require.NoError(t, err)
+
+		assert.NoError(t, v.Validate(strings.Repeat("ї", 64)))
+		assert.ErrorIs(t, v.Validate(strings.Repeat("a", 65)), apperrors.InvalidArgError{
+			Subj:   "record id",
+			Reason: "must not be longer than 64 characters",
+		})
+	})
+}
+
+func TestRecordIDValidator_ValidateForIndex(tt *testing.T) {
+	v, err := validation.NewRecordIDValidator(map[string]validation.RecordIDRule{
+		"^books":  {Pattern: "^[a-z0-9-]+$", MaxLength: 10},
+		"^users":  {Format: "uuid"},
+		"^events": {Format: "ulid"},
+	})
+	require.NoError(tt, err)
+
+	tt.Run("NoRules", func(t *testing.T) {
+		assert.NoError(t, v.ValidateForIndex("movies", "Any ID"))
+		assert.ErrorIs(t, v.ValidateForIndex("movies", strings.Repeat("a", 65)), apperrors.InvalidArgError{
+			Subj:   "record id",
+			Reason: "must not be longer than 64 characters",
+		})
+	})
+
+	tt.Run("PatternAndMaxLength", func(t *testing.T) {
+		assert.NoError(t, v.ValidateForIndex("books", "foo-1"))
+		assert.ErrorIs(t, v.ValidateForIndex("books", "Foo"), apperrors.InvalidArgError{
+			Subj:   "record id",
+			Reason: "must match the regexp ^[a-z0-9-]+$",
+		})
+		assert.ErrorIs(t, v.ValidateForIndex("books", "foo-bar-baz"), apperrors.InvalidArgError{
+			Subj:   "record id",
+			Reason: "must not be longer than 10 characters",
+		})
+	})
+
+	tt.Run("UUID", func(t *testing.T) {
+		assert.NoError(t, v.ValidateForIndex("users", "0190b5a4-2b7e-7c2e-9d1a-5b6c7d8e9f00"))
+		assert.ErrorIs(t, v.ValidateForIndex("users", "{0190b5a4-2b7e-7c2e-9d1a-5b6c7d8e9f00}"), apperrors.InvalidArgError{
+			Subj:   "record id",
+			Reason: "must be a UUID",
+		})
+	})
+
+	tt.Run("ULID", func(t *testing.T) {
+		assert.NoError(t, v.ValidateForIndex("events", "01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+		assert.ErrorIs(t, v.ValidateForIndex("events", "foo"), apperrors.InvalidArgError{
+			Subj:   "record id",
+			Reason: "must be a ULID",
+		})
+	})
+}
+
+func TestRecordIDValidator_Generate(tt *testing.T) {
+	v, err := validation.NewRecordIDValidator(map[string]validation.RecordIDRule{
+		"^users":  {Format: "uuid", Generate: "uuidv7"},
+		"^events": {Generate: "ulid"},
+	})
+	require.NoError(tt, err)
+
+	tt.Run("NotConfigured", func(t *testing.T) {
+		id, err := v.Generate("books")
+		require.NoError(t, err)
+		assert.Empty(t, id)
+	})
+
+	tt.Run("UUIDv7", func(t *testing.T) {
+		id, err := v.Generate("users")
+		require.NoError(t, err)
+
+		u, err := uuid.Parse(id)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), u.Version())
+		assert.NoError(t, v.ValidateForIndex("users", id))
+	})
+
+	tt.Run("ULID", func(t *testing.T) {
+		id, err := v.Generate("events")
+		require.NoError(t, err)
+
+		_, err = ulid.ParseStrict(id)
+		assert.NoError(t, err)
+	})
 }
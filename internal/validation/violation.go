@@ -0,0 +1,91 @@
+package validation
+
+import (
+	"strings"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Violation is a single JSON schema validation failure.
+type Violation struct {
+	Pointer string // JSON pointer to the invalid value; empty for the document root
+	Keyword string // schema keyword that failed, e.g. "required"
+	Message string
+}
+
+// field returns the violation location in the dotted form, e.g. "(root)" or "author.name".
+func (v Violation) field() string {
+	if v.Pointer == "" {
+		return "(root)"
+	}
+
+	return strings.ReplaceAll(strings.TrimPrefix(v.Pointer, "/"), "/", ".")
+}
+
+// DataError is returned when JSON data does not conform to a schema. It holds every violation found.
+type DataError struct {
+	Violations []Violation
+}
+
+func (e DataError) Error() string {
+	return e.Unwrap().Error()
+}
+
+// Unwrap returns the error as an invalid argument error, so callers which don't need details can treat it as such.
+func (e DataError) Unwrap() error {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.field() + ": " + v.Message
+	}
+
+	return apperrors.InvalidArgError{Subj: "json", Reason: strings.Join(msgs, "; ")}
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1") //nolint:gochecknoglobals // ok
+
+var msgPrinter = message.NewPrinter(language.English) //nolint:gochecknoglobals // ok
+
+func newViolation(e *jsonschema.ValidationError) Violation {
+	ptr := ""
+	for _, p := range e.InstanceLocation {
+		ptr += "/" + pointerEscaper.Replace(p)
+	}
+
+	kw := strings.Join(e.ErrorKind.KeywordPath(), "/")
+
+	// Some kinds carry no keyword path
+	switch e.ErrorKind.(type) {
+	case *kind.Not:
+		kw = "not"
+	case *kind.FalseSchema:
+		// A false subschema fails on behalf of the keyword it is the value of, e.g. "unevaluatedProperties"
+		kw = "false"
+		if i := strings.LastIndex(e.SchemaURL, "/"); i >= 0 && strings.Contains(e.SchemaURL, "#") {
+			kw = e.SchemaURL[i+1:]
+		}
+	}
+
+	return Violation{
+		Pointer: ptr,
+		Keyword: kw,
+		Message: e.ErrorKind.LocalizedString(msgPrinter),
+	}
+}
+
+// newViolations flattens the validation error tree into the list of its leaves.
+func newViolations(e *jsonschema.ValidationError) []Violation {
+	if len(e.Causes) == 0 {
+		return []Violation{newViolation(e)}
+	}
+
+	res := make([]Violation, 0, len(e.Causes))
+	for _, c := range e.Causes {
+		res = append(res, newViolations(c)...)
+	}
+
+	return res
+}
@@ -0,0 +1,67 @@
+package validation_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ashep/go-apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/validation"
+)
+
+func TestDataError(tt *testing.T) {
+	tt.Run("AllViolations", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			".*": json.RawMessage(`{
+				"required": ["title"],
+				"properties": {
+					"author": {"properties": {"a/b~c": {"type": "string"}}},
+					"tags": {"items": {"type": "string"}},
+					"year": {"minimum": 1900}
+				}
+			}`),
+		})
+
+		err := v.Validate("books", `{"author":{"a/b~c":1},"tags":["foo",2],"year":1800}`)
+
+		dErr := validation.DataError{}
+		require.ErrorAs(t, err, &dErr)
+		assert.ElementsMatch(t, []validation.Violation{
+			{Pointer: "", Keyword: "required", Message: "missing property 'title'"},
+			{Pointer: "/author/a~1b~0c", Keyword: "type", Message: "got number, want string"},
+			{Pointer: "/tags/1", Keyword: "type", Message: "got number, want string"},
+			{Pointer: "/year", Keyword: "minimum", Message: "minimum: got 1,800, want 1,900"},
+		}, dErr.Violations)
+	})
+
+	tt.Run("ViolationsFromConfiguredAndStoredSchemas", func(t *testing.T) {
+		v := newJSONValidator(t, map[string]json.RawMessage{
+			"books": json.RawMessage(`{"required":["title"]}`),
+		})
+		require.NoError(t, v.SetStoredSchema("books", 1, json.RawMessage(`{"required":["author"]}`)))
+
+		err := v.Validate("books", `{}`)
+
+		dErr := validation.DataError{}
+		require.ErrorAs(t, err, &dErr)
+		assert.Equal(t, []validation.Violation{
+			{Pointer: "", Keyword: "required", Message: "missing property 'title'"},
+			{Pointer: "", Keyword: "required", Message: "missing property 'author'"},
+		}, dErr.Violations)
+	})
+
+	tt.Run("InvalidArgError", func(t *testing.T) {
+		err := validation.DataError{Violations: []validation.Violation{
+			{Pointer: "", Keyword: "required", Message: "missing property 'title'"},
+			{Pointer: "/author/name", Keyword: "type", Message: "Invalid type"},
+		}}
+
+		assert.EqualError(t, err, "invalid json: (root): missing property 'title'; author.name: Invalid type")
+		assert.ErrorIs(t, err, apperrors.InvalidArgError{
+			Subj:   "json",
+			Reason: "(root): missing property 'title'; author.name: Invalid type",
+		})
+	})
+}
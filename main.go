@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/ashep/go-app/runner"
 	"github.com/ashep/ujds/internal/app"
@@ -11,7 +12,13 @@ import (
 )
 
 func main() {
-	res := runner.New(app.Run).
+	run, err := command(os.Args[1:])
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(2)
+	}
+
+	res := runner.New(run).
 		AddConsoleLogWriter().
 		LoadEnvConfig().
 		LoadConfigFile("config.yml").
@@ -23,3 +30,25 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// command returns the run func of the command given in the args; the server is run if there is no command.
+func command(args []string) (func(rt *runner.Runtime[app.Config]) error, error) {
+	if len(args) == 0 {
+		return app.Run, nil
+	}
+
+	switch args[0] {
+	case "backup", "restore":
+		if len(args) != 2 { //nolint:mnd // command and file
+			return nil, fmt.Errorf("usage: %s %s FILE", filepath.Base(os.Args[0]), args[0])
+		}
+
+		if args[0] == "backup" {
+			return app.Backup(args[1]), nil
+		}
+
+		return app.Restore(args[1]), nil
+	default:
+		return nil, fmt.Errorf("unknown command: %s", args[0])
+	}
+}
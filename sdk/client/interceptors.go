@@ -6,13 +6,32 @@ import (
 	"connectrpc.com/connect"
 )
 
-func NewAuthInterceptor(apiKey string) connect.UnaryInterceptorFunc {
-	interceptor := func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			req.Header().Set("Authorization", "Bearer "+apiKey)
-			return next(ctx, req)
-		}
+type authInterceptor struct {
+	apiKey string
+}
+
+// NewAuthInterceptor returns an interceptor which adds the authorization header to both unary and streaming
+// requests.
+func NewAuthInterceptor(apiKey string) connect.Interceptor {
+	return &authInterceptor{apiKey: apiKey}
+}
+
+func (a *authInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		req.Header().Set("Authorization", "Bearer "+a.apiKey)
+		return next(ctx, req)
 	}
+}
+
+func (a *authInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		conn.RequestHeader().Set("Authorization", "Bearer "+a.apiKey)
+
+		return conn
+	}
+}
 
-	return interceptor
+func (a *authInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
 }
@@ -41,6 +41,22 @@ const (
 	IndexServiceListProcedure = "/ujds.index.v1.IndexService/List"
 	// IndexServiceClearProcedure is the fully-qualified name of the IndexService's Clear RPC.
 	IndexServiceClearProcedure = "/ujds.index.v1.IndexService/Clear"
+	// IndexServiceCopyProcedure is the fully-qualified name of the IndexService's Copy RPC.
+	IndexServiceCopyProcedure = "/ujds.index.v1.IndexService/Copy"
+	// IndexServiceGetJobProcedure is the fully-qualified name of the IndexService's GetJob RPC.
+	IndexServiceGetJobProcedure = "/ujds.index.v1.IndexService/GetJob"
+	// IndexServiceSetSchemaProcedure is the fully-qualified name of the IndexService's SetSchema RPC.
+	IndexServiceSetSchemaProcedure = "/ujds.index.v1.IndexService/SetSchema"
+	// IndexServiceGetSchemaHistoryProcedure is the fully-qualified name of the IndexService's
+	// GetSchemaHistory RPC.
+	IndexServiceGetSchemaHistoryProcedure = "/ujds.index.v1.IndexService/GetSchemaHistory"
+	// IndexServiceCheckSchemaProcedure is the fully-qualified name of the IndexService's CheckSchema
+	// RPC.
+	IndexServiceCheckSchemaProcedure = "/ujds.index.v1.IndexService/CheckSchema"
+	// IndexServiceCompactProcedure is the fully-qualified name of the IndexService's Compact RPC.
+	IndexServiceCompactProcedure = "/ujds.index.v1.IndexService/Compact"
+	// IndexServiceExportProcedure is the fully-qualified name of the IndexService's Export RPC.
+	IndexServiceExportProcedure = "/ujds.index.v1.IndexService/Export"
 )
 
 // IndexServiceClient is a client for the ujds.index.v1.IndexService service.
@@ -49,6 +65,13 @@ type IndexServiceClient interface {
 	Get(context.Context, *connect.Request[v1.GetRequest]) (*connect.Response[v1.GetResponse], error)
 	List(context.Context, *connect.Request[v1.ListRequest]) (*connect.Response[v1.ListResponse], error)
 	Clear(context.Context, *connect.Request[v1.ClearRequest]) (*connect.Response[v1.ClearResponse], error)
+	Copy(context.Context, *connect.Request[v1.CopyRequest]) (*connect.Response[v1.CopyResponse], error)
+	GetJob(context.Context, *connect.Request[v1.GetJobRequest]) (*connect.Response[v1.GetJobResponse], error)
+	SetSchema(context.Context, *connect.Request[v1.SetSchemaRequest]) (*connect.Response[v1.SetSchemaResponse], error)
+	GetSchemaHistory(context.Context, *connect.Request[v1.GetSchemaHistoryRequest]) (*connect.Response[v1.GetSchemaHistoryResponse], error)
+	CheckSchema(context.Context, *connect.Request[v1.CheckSchemaRequest]) (*connect.ServerStreamForClient[v1.CheckSchemaResponse], error)
+	Compact(context.Context, *connect.Request[v1.CompactRequest]) (*connect.Response[v1.CompactResponse], error)
+	Export(context.Context, *connect.Request[v1.ExportRequest]) (*connect.ServerStreamForClient[v1.ExportResponse], error)
 }
 
 // NewIndexServiceClient constructs a client for the ujds.index.v1.IndexService service. By default,
@@ -86,15 +109,64 @@ func NewIndexServiceClient(httpClient connect.HTTPClient, baseURL string, opts .
 			connect.WithSchema(indexServiceMethods.ByName("Clear")),
 			connect.WithClientOptions(opts...),
 		),
+		copy: connect.NewClient[v1.CopyRequest, v1.CopyResponse](
+			httpClient,
+			baseURL+IndexServiceCopyProcedure,
+			connect.WithSchema(indexServiceMethods.ByName("Copy")),
+			connect.WithClientOptions(opts...),
+		),
+		getJob: connect.NewClient[v1.GetJobRequest, v1.GetJobResponse](
+			httpClient,
+			baseURL+IndexServiceGetJobProcedure,
+			connect.WithSchema(indexServiceMethods.ByName("GetJob")),
+			connect.WithClientOptions(opts...),
+		),
+		setSchema: connect.NewClient[v1.SetSchemaRequest, v1.SetSchemaResponse](
+			httpClient,
+			baseURL+IndexServiceSetSchemaProcedure,
+			connect.WithSchema(indexServiceMethods.ByName("SetSchema")),
+			connect.WithClientOptions(opts...),
+		),
+		getSchemaHistory: connect.NewClient[v1.GetSchemaHistoryRequest, v1.GetSchemaHistoryResponse](
+			httpClient,
+			baseURL+IndexServiceGetSchemaHistoryProcedure,
+			connect.WithSchema(indexServiceMethods.ByName("GetSchemaHistory")),
+			connect.WithClientOptions(opts...),
+		),
+		checkSchema: connect.NewClient[v1.CheckSchemaRequest, v1.CheckSchemaResponse](
+			httpClient,
+			baseURL+IndexServiceCheckSchemaProcedure,
+			connect.WithSchema(indexServiceMethods.ByName("CheckSchema")),
+			connect.WithClientOptions(opts...),
+		),
+		compact: connect.NewClient[v1.CompactRequest, v1.CompactResponse](
+			httpClient,
+			baseURL+IndexServiceCompactProcedure,
+			connect.WithSchema(indexServiceMethods.ByName("Compact")),
+			connect.WithClientOptions(opts...),
+		),
+		export: connect.NewClient[v1.ExportRequest, v1.ExportResponse](
+			httpClient,
+			baseURL+IndexServiceExportProcedure,
+			connect.WithSchema(indexServiceMethods.ByName("Export")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // indexServiceClient implements IndexServiceClient.
 type indexServiceClient struct {
-	push  *connect.Client[v1.PushRequest, v1.PushResponse]
-	get   *connect.Client[v1.GetRequest, v1.GetResponse]
-	list  *connect.Client[v1.ListRequest, v1.ListResponse]
-	clear *connect.Client[v1.ClearRequest, v1.ClearResponse]
+	push             *connect.Client[v1.PushRequest, v1.PushResponse]
+	get              *connect.Client[v1.GetRequest, v1.GetResponse]
+	list             *connect.Client[v1.ListRequest, v1.ListResponse]
+	clear            *connect.Client[v1.ClearRequest, v1.ClearResponse]
+	copy             *connect.Client[v1.CopyRequest, v1.CopyResponse]
+	getJob           *connect.Client[v1.GetJobRequest, v1.GetJobResponse]
+	setSchema        *connect.Client[v1.SetSchemaRequest, v1.SetSchemaResponse]
+	getSchemaHistory *connect.Client[v1.GetSchemaHistoryRequest, v1.GetSchemaHistoryResponse]
+	checkSchema      *connect.Client[v1.CheckSchemaRequest, v1.CheckSchemaResponse]
+	compact          *connect.Client[v1.CompactRequest, v1.CompactResponse]
+	export           *connect.Client[v1.ExportRequest, v1.ExportResponse]
 }
 
 // Push calls ujds.index.v1.IndexService.Push.
@@ -117,12 +189,54 @@ func (c *indexServiceClient) Clear(ctx context.Context, req *connect.Request[v1.
 	return c.clear.CallUnary(ctx, req)
 }
 
+// Copy calls ujds.index.v1.IndexService.Copy.
+func (c *indexServiceClient) Copy(ctx context.Context, req *connect.Request[v1.CopyRequest]) (*connect.Response[v1.CopyResponse], error) {
+	return c.copy.CallUnary(ctx, req)
+}
+
+// GetJob calls ujds.index.v1.IndexService.GetJob.
+func (c *indexServiceClient) GetJob(ctx context.Context, req *connect.Request[v1.GetJobRequest]) (*connect.Response[v1.GetJobResponse], error) {
+	return c.getJob.CallUnary(ctx, req)
+}
+
+// SetSchema calls ujds.index.v1.IndexService.SetSchema.
+func (c *indexServiceClient) SetSchema(ctx context.Context, req *connect.Request[v1.SetSchemaRequest]) (*connect.Response[v1.SetSchemaResponse], error) {
+	return c.setSchema.CallUnary(ctx, req)
+}
+
+// GetSchemaHistory calls ujds.index.v1.IndexService.GetSchemaHistory.
+func (c *indexServiceClient) GetSchemaHistory(ctx context.Context, req *connect.Request[v1.GetSchemaHistoryRequest]) (*connect.Response[v1.GetSchemaHistoryResponse], error) {
+	return c.getSchemaHistory.CallUnary(ctx, req)
+}
+
+// CheckSchema calls ujds.index.v1.IndexService.CheckSchema.
+func (c *indexServiceClient) CheckSchema(ctx context.Context, req *connect.Request[v1.CheckSchemaRequest]) (*connect.ServerStreamForClient[v1.CheckSchemaResponse], error) {
+	return c.checkSchema.CallServerStream(ctx, req)
+}
+
+// Compact calls ujds.index.v1.IndexService.Compact.
+func (c *indexServiceClient) Compact(ctx context.Context, req *connect.Request[v1.CompactRequest]) (*connect.Response[v1.CompactResponse], error) {
+	return c.compact.CallUnary(ctx, req)
+}
+
+// Export calls ujds.index.v1.IndexService.Export.
+func (c *indexServiceClient) Export(ctx context.Context, req *connect.Request[v1.ExportRequest]) (*connect.ServerStreamForClient[v1.ExportResponse], error) {
+	return c.export.CallServerStream(ctx, req)
+}
+
 // IndexServiceHandler is an implementation of the ujds.index.v1.IndexService service.
 type IndexServiceHandler interface {
 	Push(context.Context, *connect.Request[v1.PushRequest]) (*connect.Response[v1.PushResponse], error)
 	Get(context.Context, *connect.Request[v1.GetRequest]) (*connect.Response[v1.GetResponse], error)
 	List(context.Context, *connect.Request[v1.ListRequest]) (*connect.Response[v1.ListResponse], error)
 	Clear(context.Context, *connect.Request[v1.ClearRequest]) (*connect.Response[v1.ClearResponse], error)
+	Copy(context.Context, *connect.Request[v1.CopyRequest]) (*connect.Response[v1.CopyResponse], error)
+	GetJob(context.Context, *connect.Request[v1.GetJobRequest]) (*connect.Response[v1.GetJobResponse], error)
+	SetSchema(context.Context, *connect.Request[v1.SetSchemaRequest]) (*connect.Response[v1.SetSchemaResponse], error)
+	GetSchemaHistory(context.Context, *connect.Request[v1.GetSchemaHistoryRequest]) (*connect.Response[v1.GetSchemaHistoryResponse], error)
+	CheckSchema(context.Context, *connect.Request[v1.CheckSchemaRequest], *connect.ServerStream[v1.CheckSchemaResponse]) error
+	Compact(context.Context, *connect.Request[v1.CompactRequest]) (*connect.Response[v1.CompactResponse], error)
+	Export(context.Context, *connect.Request[v1.ExportRequest], *connect.ServerStream[v1.ExportResponse]) error
 }
 
 // NewIndexServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -156,6 +270,48 @@ func NewIndexServiceHandler(svc IndexServiceHandler, opts ...connect.HandlerOpti
 		connect.WithSchema(indexServiceMethods.ByName("Clear")),
 		connect.WithHandlerOptions(opts...),
 	)
+	indexServiceCopyHandler := connect.NewUnaryHandler(
+		IndexServiceCopyProcedure,
+		svc.Copy,
+		connect.WithSchema(indexServiceMethods.ByName("Copy")),
+		connect.WithHandlerOptions(opts...),
+	)
+	indexServiceGetJobHandler := connect.NewUnaryHandler(
+		IndexServiceGetJobProcedure,
+		svc.GetJob,
+		connect.WithSchema(indexServiceMethods.ByName("GetJob")),
+		connect.WithHandlerOptions(opts...),
+	)
+	indexServiceSetSchemaHandler := connect.NewUnaryHandler(
+		IndexServiceSetSchemaProcedure,
+		svc.SetSchema,
+		connect.WithSchema(indexServiceMethods.ByName("SetSchema")),
+		connect.WithHandlerOptions(opts...),
+	)
+	indexServiceGetSchemaHistoryHandler := connect.NewUnaryHandler(
+		IndexServiceGetSchemaHistoryProcedure,
+		svc.GetSchemaHistory,
+		connect.WithSchema(indexServiceMethods.ByName("GetSchemaHistory")),
+		connect.WithHandlerOptions(opts...),
+	)
+	indexServiceCheckSchemaHandler := connect.NewServerStreamHandler(
+		IndexServiceCheckSchemaProcedure,
+		svc.CheckSchema,
+		connect.WithSchema(indexServiceMethods.ByName("CheckSchema")),
+		connect.WithHandlerOptions(opts...),
+	)
+	indexServiceCompactHandler := connect.NewUnaryHandler(
+		IndexServiceCompactProcedure,
+		svc.Compact,
+		connect.WithSchema(indexServiceMethods.ByName("Compact")),
+		connect.WithHandlerOptions(opts...),
+	)
+	indexServiceExportHandler := connect.NewServerStreamHandler(
+		IndexServiceExportProcedure,
+		svc.Export,
+		connect.WithSchema(indexServiceMethods.ByName("Export")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/ujds.index.v1.IndexService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case IndexServicePushProcedure:
@@ -166,6 +322,20 @@ func NewIndexServiceHandler(svc IndexServiceHandler, opts ...connect.HandlerOpti
 			indexServiceListHandler.ServeHTTP(w, r)
 		case IndexServiceClearProcedure:
 			indexServiceClearHandler.ServeHTTP(w, r)
+		case IndexServiceCopyProcedure:
+			indexServiceCopyHandler.ServeHTTP(w, r)
+		case IndexServiceGetJobProcedure:
+			indexServiceGetJobHandler.ServeHTTP(w, r)
+		case IndexServiceSetSchemaProcedure:
+			indexServiceSetSchemaHandler.ServeHTTP(w, r)
+		case IndexServiceGetSchemaHistoryProcedure:
+			indexServiceGetSchemaHistoryHandler.ServeHTTP(w, r)
+		case IndexServiceCheckSchemaProcedure:
+			indexServiceCheckSchemaHandler.ServeHTTP(w, r)
+		case IndexServiceCompactProcedure:
+			indexServiceCompactHandler.ServeHTTP(w, r)
+		case IndexServiceExportProcedure:
+			indexServiceExportHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -190,3 +360,31 @@ func (UnimplementedIndexServiceHandler) List(context.Context, *connect.Request[v
 func (UnimplementedIndexServiceHandler) Clear(context.Context, *connect.Request[v1.ClearRequest]) (*connect.Response[v1.ClearResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.Clear is not implemented"))
 }
+
+func (UnimplementedIndexServiceHandler) Copy(context.Context, *connect.Request[v1.CopyRequest]) (*connect.Response[v1.CopyResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.Copy is not implemented"))
+}
+
+func (UnimplementedIndexServiceHandler) GetJob(context.Context, *connect.Request[v1.GetJobRequest]) (*connect.Response[v1.GetJobResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.GetJob is not implemented"))
+}
+
+func (UnimplementedIndexServiceHandler) SetSchema(context.Context, *connect.Request[v1.SetSchemaRequest]) (*connect.Response[v1.SetSchemaResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.SetSchema is not implemented"))
+}
+
+func (UnimplementedIndexServiceHandler) GetSchemaHistory(context.Context, *connect.Request[v1.GetSchemaHistoryRequest]) (*connect.Response[v1.GetSchemaHistoryResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.GetSchemaHistory is not implemented"))
+}
+
+func (UnimplementedIndexServiceHandler) CheckSchema(context.Context, *connect.Request[v1.CheckSchemaRequest], *connect.ServerStream[v1.CheckSchemaResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.CheckSchema is not implemented"))
+}
+
+func (UnimplementedIndexServiceHandler) Compact(context.Context, *connect.Request[v1.CompactRequest]) (*connect.Response[v1.CompactResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.Compact is not implemented"))
+}
+
+func (UnimplementedIndexServiceHandler) Export(context.Context, *connect.Request[v1.ExportRequest], *connect.ServerStream[v1.ExportResponse]) error {
+	return connect.NewError(connect.CodeUnimplemented, errors.New("ujds.index.v1.IndexService.Export is not implemented"))
+}
@@ -35,20 +35,32 @@ const (
 const (
 	// RecordServicePushProcedure is the fully-qualified name of the RecordService's Push RPC.
 	RecordServicePushProcedure = "/ujds.record.v1.RecordService/Push"
+	// RecordServiceValidateProcedure is the fully-qualified name of the RecordService's Validate RPC.
+	RecordServiceValidateProcedure = "/ujds.record.v1.RecordService/Validate"
 	// RecordServiceGetProcedure is the fully-qualified name of the RecordService's Get RPC.
 	RecordServiceGetProcedure = "/ujds.record.v1.RecordService/Get"
 	// RecordServiceFindProcedure is the fully-qualified name of the RecordService's Find RPC.
 	RecordServiceFindProcedure = "/ujds.record.v1.RecordService/Find"
 	// RecordServiceHistoryProcedure is the fully-qualified name of the RecordService's History RPC.
 	RecordServiceHistoryProcedure = "/ujds.record.v1.RecordService/History"
+	// RecordServiceFindStaleProcedure is the fully-qualified name of the RecordService's FindStale RPC.
+	RecordServiceFindStaleProcedure = "/ujds.record.v1.RecordService/FindStale"
+	// RecordServiceImportProcedure is the fully-qualified name of the RecordService's Import RPC.
+	RecordServiceImportProcedure = "/ujds.record.v1.RecordService/Import"
+	// RecordServiceBatchProcedure is the fully-qualified name of the RecordService's Batch RPC.
+	RecordServiceBatchProcedure = "/ujds.record.v1.RecordService/Batch"
 )
 
 // RecordServiceClient is a client for the ujds.record.v1.RecordService service.
 type RecordServiceClient interface {
 	Push(context.Context, *connect.Request[v1.PushRequest]) (*connect.Response[v1.PushResponse], error)
+	Validate(context.Context, *connect.Request[v1.ValidateRequest]) (*connect.Response[v1.ValidateResponse], error)
 	Get(context.Context, *connect.Request[v1.GetRequest]) (*connect.Response[v1.GetResponse], error)
 	Find(context.Context, *connect.Request[v1.FindRequest]) (*connect.Response[v1.FindResponse], error)
 	History(context.Context, *connect.Request[v1.HistoryRequest]) (*connect.Response[v1.HistoryResponse], error)
+	FindStale(context.Context, *connect.Request[v1.FindStaleRequest]) (*connect.Response[v1.FindStaleResponse], error)
+	Import(context.Context) *connect.ClientStreamForClient[v1.ImportRequest, v1.ImportResponse]
+	Batch(context.Context, *connect.Request[v1.BatchRequest]) (*connect.Response[v1.BatchResponse], error)
 }
 
 // NewRecordServiceClient constructs a client for the ujds.record.v1.RecordService service. By
@@ -68,6 +80,12 @@ func NewRecordServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(recordServiceMethods.ByName("Push")),
 			connect.WithClientOptions(opts...),
 		),
+		validate: connect.NewClient[v1.ValidateRequest, v1.ValidateResponse](
+			httpClient,
+			baseURL+RecordServiceValidateProcedure,
+			connect.WithSchema(recordServiceMethods.ByName("Validate")),
+			connect.WithClientOptions(opts...),
+		),
 		get: connect.NewClient[v1.GetRequest, v1.GetResponse](
 			httpClient,
 			baseURL+RecordServiceGetProcedure,
@@ -86,15 +104,37 @@ func NewRecordServiceClient(httpClient connect.HTTPClient, baseURL string, opts
 			connect.WithSchema(recordServiceMethods.ByName("History")),
 			connect.WithClientOptions(opts...),
 		),
+		findStale: connect.NewClient[v1.FindStaleRequest, v1.FindStaleResponse](
+			httpClient,
+			baseURL+RecordServiceFindStaleProcedure,
+			connect.WithSchema(recordServiceMethods.ByName("FindStale")),
+			connect.WithClientOptions(opts...),
+		),
+		_import: connect.NewClient[v1.ImportRequest, v1.ImportResponse](
+			httpClient,
+			baseURL+RecordServiceImportProcedure,
+			connect.WithSchema(recordServiceMethods.ByName("Import")),
+			connect.WithClientOptions(opts...),
+		),
+		batch: connect.NewClient[v1.BatchRequest, v1.BatchResponse](
+			httpClient,
+			baseURL+RecordServiceBatchProcedure,
+			connect.WithSchema(recordServiceMethods.ByName("Batch")),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // recordServiceClient implements RecordServiceClient.
 type recordServiceClient struct {
-	push    *connect.Client[v1.PushRequest, v1.PushResponse]
-	get     *connect.Client[v1.GetRequest, v1.GetResponse]
-	find    *connect.Client[v1.FindRequest, v1.FindResponse]
-	history *connect.Client[v1.HistoryRequest, v1.HistoryResponse]
+	push      *connect.Client[v1.PushRequest, v1.PushResponse]
+	validate  *connect.Client[v1.ValidateRequest, v1.ValidateResponse]
+	get       *connect.Client[v1.GetRequest, v1.GetResponse]
+	find      *connect.Client[v1.FindRequest, v1.FindResponse]
+	history   *connect.Client[v1.HistoryRequest, v1.HistoryResponse]
+	findStale *connect.Client[v1.FindStaleRequest, v1.FindStaleResponse]
+	_import   *connect.Client[v1.ImportRequest, v1.ImportResponse]
+	batch     *connect.Client[v1.BatchRequest, v1.BatchResponse]
 }
 
 // Push calls ujds.record.v1.RecordService.Push.
@@ -102,6 +142,11 @@ func (c *recordServiceClient) Push(ctx context.Context, req *connect.Request[v1.
 	return c.push.CallUnary(ctx, req)
 }
 
+// Validate calls ujds.record.v1.RecordService.Validate.
+func (c *recordServiceClient) Validate(ctx context.Context, req *connect.Request[v1.ValidateRequest]) (*connect.Response[v1.ValidateResponse], error) {
+	return c.validate.CallUnary(ctx, req)
+}
+
 // Get calls ujds.record.v1.RecordService.Get.
 func (c *recordServiceClient) Get(ctx context.Context, req *connect.Request[v1.GetRequest]) (*connect.Response[v1.GetResponse], error) {
 	return c.get.CallUnary(ctx, req)
@@ -117,12 +162,31 @@ func (c *recordServiceClient) History(ctx context.Context, req *connect.Request[
 	return c.history.CallUnary(ctx, req)
 }
 
+// FindStale calls ujds.record.v1.RecordService.FindStale.
+func (c *recordServiceClient) FindStale(ctx context.Context, req *connect.Request[v1.FindStaleRequest]) (*connect.Response[v1.FindStaleResponse], error) {
+	return c.findStale.CallUnary(ctx, req)
+}
+
+// Import calls ujds.record.v1.RecordService.Import.
+func (c *recordServiceClient) Import(ctx context.Context) *connect.ClientStreamForClient[v1.ImportRequest, v1.ImportResponse] {
+	return c._import.CallClientStream(ctx)
+}
+
+// Batch calls ujds.record.v1.RecordService.Batch.
+func (c *recordServiceClient) Batch(ctx context.Context, req *connect.Request[v1.BatchRequest]) (*connect.Response[v1.BatchResponse], error) {
+	return c.batch.CallUnary(ctx, req)
+}
+
 // RecordServiceHandler is an implementation of the ujds.record.v1.RecordService service.
 type RecordServiceHandler interface {
 	Push(context.Context, *connect.Request[v1.PushRequest]) (*connect.Response[v1.PushResponse], error)
+	Validate(context.Context, *connect.Request[v1.ValidateRequest]) (*connect.Response[v1.ValidateResponse], error)
 	Get(context.Context, *connect.Request[v1.GetRequest]) (*connect.Response[v1.GetResponse], error)
 	Find(context.Context, *connect.Request[v1.FindRequest]) (*connect.Response[v1.FindResponse], error)
 	History(context.Context, *connect.Request[v1.HistoryRequest]) (*connect.Response[v1.HistoryResponse], error)
+	FindStale(context.Context, *connect.Request[v1.FindStaleRequest]) (*connect.Response[v1.FindStaleResponse], error)
+	Import(context.Context, *connect.ClientStream[v1.ImportRequest]) (*connect.Response[v1.ImportResponse], error)
+	Batch(context.Context, *connect.Request[v1.BatchRequest]) (*connect.Response[v1.BatchResponse], error)
 }
 
 // NewRecordServiceHandler builds an HTTP handler from the service implementation. It returns the
@@ -138,6 +202,12 @@ func NewRecordServiceHandler(svc RecordServiceHandler, opts ...connect.HandlerOp
 		connect.WithSchema(recordServiceMethods.ByName("Push")),
 		connect.WithHandlerOptions(opts...),
 	)
+	recordServiceValidateHandler := connect.NewUnaryHandler(
+		RecordServiceValidateProcedure,
+		svc.Validate,
+		connect.WithSchema(recordServiceMethods.ByName("Validate")),
+		connect.WithHandlerOptions(opts...),
+	)
 	recordServiceGetHandler := connect.NewUnaryHandler(
 		RecordServiceGetProcedure,
 		svc.Get,
@@ -156,16 +226,42 @@ func NewRecordServiceHandler(svc RecordServiceHandler, opts ...connect.HandlerOp
 		connect.WithSchema(recordServiceMethods.ByName("History")),
 		connect.WithHandlerOptions(opts...),
 	)
+	recordServiceFindStaleHandler := connect.NewUnaryHandler(
+		RecordServiceFindStaleProcedure,
+		svc.FindStale,
+		connect.WithSchema(recordServiceMethods.ByName("FindStale")),
+		connect.WithHandlerOptions(opts...),
+	)
+	recordServiceImportHandler := connect.NewClientStreamHandler(
+		RecordServiceImportProcedure,
+		svc.Import,
+		connect.WithSchema(recordServiceMethods.ByName("Import")),
+		connect.WithHandlerOptions(opts...),
+	)
+	recordServiceBatchHandler := connect.NewUnaryHandler(
+		RecordServiceBatchProcedure,
+		svc.Batch,
+		connect.WithSchema(recordServiceMethods.ByName("Batch")),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/ujds.record.v1.RecordService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case RecordServicePushProcedure:
 			recordServicePushHandler.ServeHTTP(w, r)
+		case RecordServiceValidateProcedure:
+			recordServiceValidateHandler.ServeHTTP(w, r)
 		case RecordServiceGetProcedure:
 			recordServiceGetHandler.ServeHTTP(w, r)
 		case RecordServiceFindProcedure:
 			recordServiceFindHandler.ServeHTTP(w, r)
 		case RecordServiceHistoryProcedure:
 			recordServiceHistoryHandler.ServeHTTP(w, r)
+		case RecordServiceFindStaleProcedure:
+			recordServiceFindStaleHandler.ServeHTTP(w, r)
+		case RecordServiceImportProcedure:
+			recordServiceImportHandler.ServeHTTP(w, r)
+		case RecordServiceBatchProcedure:
+			recordServiceBatchHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -179,6 +275,10 @@ func (UnimplementedRecordServiceHandler) Push(context.Context, *connect.Request[
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.record.v1.RecordService.Push is not implemented"))
 }
 
+func (UnimplementedRecordServiceHandler) Validate(context.Context, *connect.Request[v1.ValidateRequest]) (*connect.Response[v1.ValidateResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.record.v1.RecordService.Validate is not implemented"))
+}
+
 func (UnimplementedRecordServiceHandler) Get(context.Context, *connect.Request[v1.GetRequest]) (*connect.Response[v1.GetResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.record.v1.RecordService.Get is not implemented"))
 }
@@ -190,3 +290,15 @@ func (UnimplementedRecordServiceHandler) Find(context.Context, *connect.Request[
 func (UnimplementedRecordServiceHandler) History(context.Context, *connect.Request[v1.HistoryRequest]) (*connect.Response[v1.HistoryResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.record.v1.RecordService.History is not implemented"))
 }
+
+func (UnimplementedRecordServiceHandler) FindStale(context.Context, *connect.Request[v1.FindStaleRequest]) (*connect.Response[v1.FindStaleResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.record.v1.RecordService.FindStale is not implemented"))
+}
+
+func (UnimplementedRecordServiceHandler) Import(context.Context, *connect.ClientStream[v1.ImportRequest]) (*connect.Response[v1.ImportResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.record.v1.RecordService.Import is not implemented"))
+}
+
+func (UnimplementedRecordServiceHandler) Batch(context.Context, *connect.Request[v1.BatchRequest]) (*connect.Response[v1.BatchResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("ujds.record.v1.RecordService.Batch is not implemented"))
+}
@@ -0,0 +1,13 @@
+CREATE TABLE job
+(
+    id         BIGSERIAL   NOT NULL,
+    kind       VARCHAR(64) NOT NULL,
+    status     VARCHAR(16) NOT NULL DEFAULT 'running',
+    processed  BIGINT      NOT NULL DEFAULT 0,
+    total      BIGINT      NOT NULL DEFAULT 0,
+    error      TEXT,
+    created_at TIMESTAMP   NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP   NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (id)
+);
@@ -0,0 +1,3 @@
+DROP TABLE index_schema;
+
+ALTER TABLE index DROP COLUMN schema_version;
@@ -0,0 +1,12 @@
+ALTER TABLE index ADD COLUMN schema_version INT NOT NULL DEFAULT 0;
+
+CREATE TABLE index_schema
+(
+    index_id   INT       NOT NULL,
+    version    INT       NOT NULL,
+    schema     JSONB     NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (index_id, version),
+    FOREIGN KEY (index_id) REFERENCES index (id)
+);
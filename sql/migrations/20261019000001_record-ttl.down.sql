@@ -0,0 +1,5 @@
+DROP INDEX idx_record_expires_at;
+
+ALTER TABLE record DROP COLUMN expires_at;
+
+ALTER TABLE index DROP COLUMN ttl;
@@ -0,0 +1,5 @@
+ALTER TABLE index ADD COLUMN ttl INT NOT NULL DEFAULT 0;
+
+ALTER TABLE record ADD COLUMN expires_at TIMESTAMP;
+
+CREATE INDEX idx_record_expires_at ON record (expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE record DROP COLUMN stale_at;
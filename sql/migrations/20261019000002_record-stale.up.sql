@@ -0,0 +1 @@
+ALTER TABLE record ADD COLUMN stale_at TIMESTAMP;
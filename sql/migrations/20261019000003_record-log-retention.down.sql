@@ -0,0 +1,5 @@
+DROP INDEX idx_record_log_id;
+
+DROP INDEX idx_record_log_index_id_id;
+
+DROP INDEX idx_record_log_index_id_record_id;
@@ -0,0 +1,5 @@
+CREATE INDEX idx_record_log_index_id_record_id ON record_log (index_id, record_id, id);
+
+CREATE INDEX idx_record_log_index_id_id ON record_log (index_id, id);
+
+CREATE INDEX idx_record_log_id ON record (log_id);
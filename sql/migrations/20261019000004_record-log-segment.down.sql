@@ -0,0 +1 @@
+DROP TABLE record_log_segment;
@@ -0,0 +1,16 @@
+CREATE TABLE record_log_segment
+(
+    id         BIGSERIAL    NOT NULL,
+    index_id   INT          NOT NULL,
+    path       VARCHAR(255) NOT NULL UNIQUE,
+    min_rev    BIGINT       NOT NULL,
+    max_rev    BIGINT       NOT NULL,
+    revisions  INT          NOT NULL,
+    record_ids JSONB        NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (id),
+    FOREIGN KEY (index_id) REFERENCES index (id)
+);
+
+CREATE INDEX idx_record_log_segment_record_ids ON record_log_segment USING GIN (record_ids);
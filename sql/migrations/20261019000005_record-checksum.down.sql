@@ -0,0 +1,14 @@
+-- Restores the previous checksum: sha256 of the data, the little-endian 8-byte index ID and the record ID. The data
+-- used to be hashed as pushed, while only its JSONB form is stored, so records pushed in another formatting get a
+-- different checksum, and pushing the same data to them again adds a revision once.
+UPDATE record r
+SET checksum = sha256(
+        convert_to(r.data::TEXT, 'UTF8') ||
+        decode(substr(x.h, 15, 2) || substr(x.h, 13, 2) || substr(x.h, 11, 2) || substr(x.h, 9, 2) ||
+               substr(x.h, 7, 2) || substr(x.h, 5, 2) || substr(x.h, 3, 2) || substr(x.h, 1, 2), 'hex') ||
+        convert_to(r.id, 'UTF8'))
+FROM (SELECT index_id, id, lpad(to_hex(index_id), 16, '0') AS h FROM record) x
+WHERE r.index_id = x.index_id
+  AND r.id = x.id;
+
+ALTER TABLE record ADD CONSTRAINT record_checksum_key UNIQUE (checksum);
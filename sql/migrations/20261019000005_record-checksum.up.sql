@@ -0,0 +1,3 @@
+ALTER TABLE record DROP CONSTRAINT record_checksum_key;
+
+UPDATE record SET checksum = sha256(convert_to(data::TEXT, 'UTF8'));
@@ -0,0 +1,13 @@
+CREATE TABLE idempotency_key
+(
+    key          VARCHAR(255) NOT NULL,
+    procedure    VARCHAR(255) NOT NULL,
+    request_hash BYTEA        NOT NULL,
+    response     BYTEA,
+    lease_token  UUID         NOT NULL DEFAULT gen_random_uuid(),
+    created_at   TIMESTAMP    NOT NULL DEFAULT now(),
+
+    PRIMARY KEY (key, procedure)
+);
+
+CREATE INDEX idx_idempotency_key_created_at ON idempotency_key (created_at);
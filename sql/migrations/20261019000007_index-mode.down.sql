@@ -0,0 +1,4 @@
+ALTER TABLE index
+    DROP COLUMN read_only,
+    DROP COLUMN insert_only,
+    DROP COLUMN no_history;
@@ -0,0 +1,4 @@
+ALTER TABLE index
+    ADD COLUMN read_only   BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN insert_only BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN no_history  BOOLEAN NOT NULL DEFAULT false;
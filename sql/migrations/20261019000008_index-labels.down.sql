@@ -0,0 +1,3 @@
+ALTER TABLE index
+    DROP COLUMN labels,
+    DROP COLUMN metadata;
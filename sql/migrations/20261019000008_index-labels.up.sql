@@ -0,0 +1,3 @@
+ALTER TABLE index
+    ADD COLUMN labels   JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP INDEX idx_record_index_id_touched_at;
+
+DROP FUNCTION row_estimate(TEXT);
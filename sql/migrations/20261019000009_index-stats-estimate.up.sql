@@ -0,0 +1,14 @@
+-- Returns the number of rows the planner expects the query to return. Used for approximate index statistics.
+CREATE FUNCTION row_estimate(query TEXT) RETURNS BIGINT
+    LANGUAGE plpgsql AS
+$$
+DECLARE
+    plan JSON;
+BEGIN
+    EXECUTE 'EXPLAIN (FORMAT JSON) ' || query INTO plan;
+    RETURN (plan -> 0 -> 'Plan' ->> 'Plan Rows')::BIGINT;
+END
+$$;
+
+-- Serves the last push time of an index and lookups of not touched records by the sweeper
+CREATE INDEX idx_record_index_id_touched_at ON record (index_id, touched_at);
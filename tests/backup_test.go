@@ -0,0 +1,176 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/ashep/go-app/runner"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/app"
+	"github.com/ashep/ujds/internal/recordarchiver"
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func runtime(ta *testapp.TestApp) *runner.Runtime[app.Config] {
+	cfg := ta.Config()
+
+	return &runner.Runtime[app.Config]{Ctx: context.Background(), Cfg: &cfg, Log: zerolog.Nop()}
+}
+
+func TestBackup(main *testing.T) {
+	main.Parallel()
+
+	main.Run("RestoreIntoNotEmptyDatabase", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "backup.gz")
+		require.NoError(t, app.Backup(path)(runtime(ta)))
+
+		err = app.Restore(path)(runtime(ta))
+		assert.EqualError(t, err, "restore: database is not empty")
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		src := testapp.New(t)
+		srcCli := src.Client("")
+
+		_, err := srcCli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:  "theIndex",
+			Title: "theTitle",
+		}))
+		require.NoError(t, err)
+
+		_, err = srcCli.I.SetSchema(context.Background(), connect.NewRequest(&indexproto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{"type":"object"}`,
+		}))
+		require.NoError(t, err)
+
+		for _, data := range []string{`{"v":1}`, `{"v":2}`, `{"v":3}`} {
+			_, err = srcCli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndex", Id: "theRecordID1", Data: data},
+					{Index: "theIndex", Id: "theRecordID2", Data: `{"v":0}`},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		srcHist, err := srcCli.R.History(context.Background(), connect.NewRequest(&recordproto.HistoryRequest{
+			Index: "theIndex",
+			Id:    "theRecordID1",
+		}))
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "backup.gz")
+		require.NoError(t, app.Backup(path)(runtime(src)))
+
+		dst := testapp.New(t)
+		dstCli := dst.Client("")
+		require.NoError(t, app.Restore(path)(runtime(dst)))
+
+		idx, err := dstCli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+		assert.Equal(t, "theTitle", idx.Msg.Title)
+
+		dstHist, err := dstCli.R.History(context.Background(), connect.NewRequest(&recordproto.HistoryRequest{
+			Index: "theIndex",
+			Id:    "theRecordID1",
+		}))
+		require.NoError(t, err)
+		require.Len(t, dstHist.Msg.Records, 3)
+
+		for i, rec := range srcHist.Msg.Records {
+			assert.Equal(t, rec.Rev, dstHist.Msg.Records[i].Rev)
+			assert.Equal(t, rec.Data, dstHist.Msg.Records[i].Data)
+			assert.Equal(t, rec.CreatedAt, dstHist.Msg.Records[i].CreatedAt)
+		}
+
+		// Pushing the same data only touches the records, since their checksums are restored
+		_, err = dstCli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"v":3}`},
+			},
+		}))
+		require.NoError(t, err)
+		assert.Len(t, dst.DB().GetRecordLogs("theIndex"), 4)
+
+		// New revisions continue after the restored ones
+		_, err = dstCli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"v":4}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		rec, err := dstCli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID1",
+		}))
+		require.NoError(t, err)
+		assert.Greater(t, rec.Msg.Record.Rev, srcHist.Msg.Records[0].Rev)
+
+		src.AssertNoWarnsAndErrors()
+		dst.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkArchivedHistory", func(t *testing.T) {
+		t.Parallel()
+		src := testapp.New(t, testapp.WithConfigOptionArchiver(time.Millisecond*100, t.TempDir(),
+			map[string]recordarchiver.Policy{"^theIndex$": {OlderThan: "1s"}}))
+		srcCli := src.Client("")
+
+		_, err := srcCli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		for i := range 4 {
+			_, err = srcCli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndex", Id: "theRecordID", Data: fmt.Sprintf(`{"v":%d}`, i)},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		require.Eventually(t, func() bool {
+			return len(src.DB().GetRecordLogs("theIndex")) == 1
+		}, time.Second*10, time.Millisecond*100)
+
+		path := filepath.Join(t.TempDir(), "backup.gz")
+		require.NoError(t, app.Backup(path)(runtime(src)))
+
+		// Segment files are restored into the archive dir of the target
+		dst := testapp.New(t, testapp.WithConfigOptionArchiver(time.Hour, t.TempDir(), nil))
+		dstCli := dst.Client("")
+		require.NoError(t, app.Restore(path)(runtime(dst)))
+
+		res, err := dstCli.R.History(context.Background(), connect.NewRequest(&recordproto.HistoryRequest{
+			Index: "theIndex",
+			Id:    "theRecordID",
+		}))
+		require.NoError(t, err)
+		require.Len(t, res.Msg.Records, 4)
+		assert.Equal(t, `{"v": 3}`, res.Msg.Records[0].Data)
+		assert.Equal(t, `{"v": 0}`, res.Msg.Records[3].Data)
+
+		src.AssertNoWarnsAndErrors()
+		dst.AssertNoWarnsAndErrors()
+	})
+}
@@ -0,0 +1,103 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestIdempotency(main *testing.T) {
+	main.Parallel()
+
+	pushReq := func(key, data string) *connect.Request[recordproto.PushRequest] {
+		req := connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID", Data: data},
+			},
+		})
+
+		if key != "" {
+			req.Header().Set("Idempotency-Key", key)
+		}
+
+		return req
+	}
+
+	main.Run("KeyReusedWithDifferentRequest", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), pushReq("theKey", `{"foo":"bar1"}`))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), pushReq("theKey", `{"foo":"bar2"}`))
+		assert.EqualError(t, err, "invalid_argument: invalid Idempotency-Key: already used with a different request")
+
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 1)
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("FailedRequestRetried", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		// The index doesn't exist yet
+		_, err := cli.R.Push(context.Background(), pushReq("theKey", `{"foo":"bar"}`))
+		require.EqualError(t, err, "not_found: index is not found")
+
+		_, err = cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		res, err := cli.R.Push(context.Background(), pushReq("theKey", `{"foo":"bar"}`))
+		require.NoError(t, err)
+		assert.Empty(t, res.Header().Get("Idempotent-Replayed"))
+
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 1)
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		res, err := cli.R.Push(context.Background(), pushReq("theKey", `{"foo":"bar1"}`))
+		require.NoError(t, err)
+		assert.Empty(t, res.Header().Get("Idempotent-Replayed"))
+
+		_, err = cli.R.Push(context.Background(), pushReq("", `{"foo":"bar2"}`))
+		require.NoError(t, err)
+
+		// The retry of the first push is not applied again, so it doesn't revert the record
+		res, err = cli.R.Push(context.Background(), pushReq("theKey", `{"foo":"bar1"}`))
+		require.NoError(t, err)
+		assert.Equal(t, "true", res.Header().Get("Idempotent-Replayed"))
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, `{"foo": "bar2"}`, rec.Msg.Record.Data)
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 2)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
@@ -0,0 +1,93 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestIndex_CheckSchema(main *testing.T) {
+	main.Parallel()
+
+	main.Run("InvalidAuthorization", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("anInvalidAuthToken")
+		stream, err := cli.I.CheckSchema(context.Background(), connect.NewRequest(&indexproto.CheckSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{}`,
+		}))
+		require.NoError(t, err)
+
+		assert.False(t, stream.Receive())
+		assert.EqualError(t, stream.Err(), "unauthenticated: not authorized")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("IndexNotExists", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		stream, err := cli.I.CheckSchema(context.Background(), connect.NewRequest(&indexproto.CheckSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{}`,
+		}))
+		require.NoError(t, err)
+
+		assert.False(t, stream.Receive())
+		assert.EqualError(t, stream.Err(), "not_found: index is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "rec1", Data: `{"title":"foo"}`},
+				{Index: "theIndex", Id: "rec2", Data: `{"author":"bar"}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		stream, err := cli.I.CheckSchema(context.Background(), connect.NewRequest(&indexproto.CheckSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{"required":["title"]}`,
+		}))
+		require.NoError(t, err)
+
+		var last *indexproto.CheckSchemaResponse
+		failures := make([]*indexproto.CheckSchemaResponse_Failure, 0)
+		for stream.Receive() {
+			last = stream.Msg()
+			failures = append(failures, last.Failures...)
+		}
+		require.NoError(t, stream.Err())
+		require.NotNil(t, last)
+
+		assert.Equal(t, uint64(2), last.Total)
+		assert.Equal(t, uint64(2), last.Checked)
+		assert.Equal(t, uint64(1), last.Failed)
+		require.Len(t, failures, 1)
+		assert.Equal(t, "rec2", failures[0].RecordId)
+		assert.Equal(t, "invalid json: (root): missing property 'title'", failures[0].Error)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
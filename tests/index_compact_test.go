@@ -0,0 +1,133 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordcompactor"
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestIndex_Compact(main *testing.T) {
+	main.Parallel()
+
+	pushRevisions := func(t *testing.T, ta *testapp.TestApp) {
+		t.Helper()
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		for i := range 4 {
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndex", Id: "foo", Data: fmt.Sprintf(`{"v":%d}`, i)},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "bar", Data: `{"v":0}`},
+			},
+		}))
+		require.NoError(t, err)
+	}
+
+	main.Run("IndexNotFound", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		_, err := ta.Client("").I.Compact(context.Background(), connect.NewRequest(&indexproto.CompactRequest{
+			Name: "theIndex",
+		}))
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("PolicyNotFound", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		pushRevisions(t, ta)
+
+		_, err := ta.Client("").I.Compact(context.Background(), connect.NewRequest(&indexproto.CompactRequest{
+			Name: "theIndex",
+		}))
+
+		assert.EqualError(t, err, "not_found: retention policy is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionCompactor(time.Hour, map[string]recordcompactor.Policy{
+			"^theIndex$": {KeepRevisions: 2},
+		}))
+		pushRevisions(t, ta)
+		cli := ta.Client("")
+
+		res, err := cli.I.Compact(context.Background(), connect.NewRequest(&indexproto.CompactRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			job, err := cli.I.GetJob(context.Background(), connect.NewRequest(&indexproto.GetJobRequest{Id: res.Msg.JobId}))
+			require.NoError(t, err)
+			return job.Msg.Status == "done"
+		}, time.Second*5, time.Millisecond*100)
+
+		job, err := cli.I.GetJob(context.Background(), connect.NewRequest(&indexproto.GetJobRequest{Id: res.Msg.JobId}))
+		require.NoError(t, err)
+		assert.Equal(t, "index_compact", job.Msg.Kind)
+		assert.Equal(t, uint64(2), job.Msg.Processed)
+		assert.Empty(t, job.Msg.Error)
+
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 3)
+
+		hist, err := cli.R.History(context.Background(), connect.NewRequest(&recordproto.HistoryRequest{
+			Index: "theIndex",
+			Id:    "foo",
+		}))
+		require.NoError(t, err)
+		require.Len(t, hist.Msg.Records, 2)
+		assert.Equal(t, `{"v": 3}`, hist.Msg.Records[0].Data)
+		assert.Equal(t, `{"v": 2}`, hist.Msg.Records[1].Data)
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "foo",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, `{"v": 3}`, rec.Msg.Record.Data)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkBackground", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionCompactor(time.Millisecond*100, map[string]recordcompactor.Policy{
+			"^theIndex$": {KeepRevisions: 1},
+		}))
+		pushRevisions(t, ta)
+
+		require.Eventually(t, func() bool {
+			return len(ta.DB().GetRecordLogs("theIndex")) == 2
+		}, time.Second*5, time.Millisecond*100)
+
+		assert.Len(t, ta.DB().GetRecords("theIndex"), 2)
+		ta.AssertNoWarnsAndErrors()
+	})
+}
@@ -0,0 +1,170 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestIndex_Copy(main *testing.T) {
+	main.Parallel()
+
+	main.Run("InvalidAuthorization", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("anInvalidAuthToken")
+		_, err := cli.I.Copy(context.Background(), connect.NewRequest(&indexproto.CopyRequest{}))
+
+		assert.EqualError(t, err, "unauthenticated: not authorized")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("SourceNotFound", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Copy(context.Background(), connect.NewRequest(&indexproto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("TargetNotEmpty", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		for _, name := range []string{"theSource", "theTarget"} {
+			_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: name}))
+			require.NoError(t, err)
+		}
+
+		_, err := cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{{Index: "theTarget", Id: "foo", Data: "{}"}},
+		}))
+		require.NoError(t, err)
+
+		_, err = cli.I.Copy(context.Background(), connect.NewRequest(&indexproto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+
+		assert.EqualError(t, err, "failed_precondition: target index is not empty")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:  "theSource",
+			Title: "theTitle",
+		}))
+		require.NoError(t, err)
+
+		for _, data := range []string{`{"v":1}`, `{"v":2}`} {
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theSource", Id: "foo", Data: data},
+					{Index: "theSource", Id: "bar", Data: data},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		res, err := cli.I.Copy(context.Background(), connect.NewRequest(&indexproto.CopyRequest{
+			Source:      "theSource",
+			Target:      "theTarget",
+			Search:      `v=2`,
+			WithHistory: true,
+		}))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			job, err := cli.I.GetJob(context.Background(), connect.NewRequest(&indexproto.GetJobRequest{Id: res.Msg.JobId}))
+			require.NoError(t, err)
+			return job.Msg.Status == "done"
+		}, time.Second*5, time.Millisecond*100)
+
+		job, err := cli.I.GetJob(context.Background(), connect.NewRequest(&indexproto.GetJobRequest{Id: res.Msg.JobId}))
+		require.NoError(t, err)
+		assert.Equal(t, "index_copy", job.Msg.Kind)
+		assert.Equal(t, uint64(2), job.Msg.Processed)
+		assert.Equal(t, uint64(2), job.Msg.Total)
+		assert.Empty(t, job.Msg.Error)
+
+		idx, err := cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theTarget"}))
+		require.NoError(t, err)
+		assert.Equal(t, "theTitle", idx.Msg.Title)
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{Index: "theTarget", Id: "foo"}))
+		require.NoError(t, err)
+		assert.Equal(t, `{"v": 2}`, rec.Msg.Record.Data)
+
+		assert.Len(t, ta.DB().GetRecords("theTarget"), 2)
+		assert.Len(t, ta.DB().GetRecordLogs("theTarget"), 4)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkSourceModes", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theSource"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{{Index: "theSource", Id: "foo", Data: `{"v":1}`}},
+		}))
+		require.NoError(t, err)
+
+		readOnly, insertOnly := true, true
+		_, err = cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:       "theSource",
+			ReadOnly:   &readOnly,
+			InsertOnly: &insertOnly,
+		}))
+		require.NoError(t, err)
+
+		res, err := cli.I.Copy(context.Background(), connect.NewRequest(&indexproto.CopyRequest{
+			Source: "theSource",
+			Target: "theTarget",
+		}))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			job, err := cli.I.GetJob(context.Background(), connect.NewRequest(&indexproto.GetJobRequest{Id: res.Msg.JobId}))
+			require.NoError(t, err)
+			return job.Msg.Status == "done"
+		}, time.Second*5, time.Millisecond*100)
+
+		idx, err := cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theTarget"}))
+		require.NoError(t, err)
+		assert.True(t, idx.Msg.ReadOnly)
+		assert.True(t, idx.Msg.InsertOnly)
+		assert.False(t, idx.Msg.NoHistory)
+
+		assert.Len(t, ta.DB().GetRecords("theTarget"), 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
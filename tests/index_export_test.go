@@ -0,0 +1,162 @@
+//go:build functest
+
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestIndex_Export(main *testing.T) {
+	main.Parallel()
+
+	main.Run("IndexNotFound", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		stream, err := cli.I.Export(context.Background(), connect.NewRequest(&indexproto.ExportRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		for stream.Receive() {
+		}
+
+		assert.EqualError(t, stream.Err(), "not_found: index is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkWithHistory", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		for _, data := range []string{`{"v":1}`, `{"v":2}`} {
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndex", Id: "theRecordID1", Data: data},
+					{Index: "theIndex", Id: "theRecordID2", Data: `{"v":0}`},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		stream, err := cli.I.Export(context.Background(), connect.NewRequest(&indexproto.ExportRequest{
+			Name:        "theIndex",
+			WithHistory: true,
+		}))
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		for stream.Receive() {
+			buf.Write(stream.Msg().Data)
+		}
+		require.NoError(t, stream.Err())
+
+		type line struct {
+			ID      string          `json:"id"`
+			Data    json.RawMessage `json:"data"`
+			History []struct {
+				Data json.RawMessage `json:"data"`
+			} `json:"history"`
+		}
+
+		lines := make([]line, 0)
+		sc := bufio.NewScanner(buf)
+		for sc.Scan() {
+			l := line{}
+			require.NoError(t, json.Unmarshal(sc.Bytes(), &l))
+			lines = append(lines, l)
+		}
+
+		require.Len(t, lines, 2)
+		assert.Equal(t, "theRecordID1", lines[0].ID)
+		assert.JSONEq(t, `{"v":2}`, string(lines[0].Data))
+		require.Len(t, lines[0].History, 2)
+		assert.JSONEq(t, `{"v":1}`, string(lines[0].History[0].Data))
+		assert.JSONEq(t, `{"v":2}`, string(lines[0].History[1].Data))
+		assert.Equal(t, "theRecordID2", lines[1].ID)
+		assert.Len(t, lines[1].History, 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("NDJSONReimport", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		for _, name := range []string{"theSource", "theTarget"} {
+			_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: name}))
+			require.NoError(t, err)
+		}
+
+		_, err := cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theSource", Id: "theRecordID1", Data: `{"foo":"bar1"}`},
+				{Index: "theSource", Id: "theRecordID2", Data: `{"foo":"bar2"}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodGet, ta.URL()+"/export/theSource?gzip=true", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+ta.AuthToken())
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/gzip", res.Header.Get("Content-Type"))
+
+		zr, err := gzip.NewReader(res.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		req, err = http.NewRequest(http.MethodPost, ta.URL()+"/import/theTarget", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+ta.AuthToken())
+
+		res, err = http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		ir := &recordproto.ImportResponse{}
+		require.NoError(t, protojson.Unmarshal(b, ir))
+		assert.Equal(t, uint64(2), ir.Created)
+		assert.Equal(t, uint64(0), ir.Failed)
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theTarget",
+			Id:    "theRecordID2",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, `{"foo": "bar2"}`, rec.Msg.Record.Data)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
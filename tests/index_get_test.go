@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
 	"github.com/ashep/ujds/tests/testapp"
 )
 
@@ -86,6 +87,7 @@ func TestIndex_Get(main *testing.T) {
 		assert.NotZero(t, res.Msg.CreatedAt)
 		assert.NotZero(t, res.Msg.UpdatedAt)
 		assert.Empty(t, res.Msg.Schemas)
+		assert.Nil(t, res.Msg.Stats)
 
 		ta.AssertNoWarnsAndErrors()
 	})
@@ -111,4 +113,38 @@ func TestIndex_Get(main *testing.T) {
 
 		ta.AssertNoWarnsAndErrors()
 	})
+
+	main.Run("OkWithStats", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndexName"}))
+		require.NoError(t, err)
+
+		for _, data := range []string{`{"v":1}`, `{"v":2}`} {
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndexName", Id: "foo", Data: data},
+					{Index: "theIndexName", Id: "bar", Data: `{"v":1}`},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		res, err := cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{
+			Name:  "theIndexName",
+			Stats: &indexproto.StatsOptions{},
+		}))
+
+		require.NoError(t, err)
+		require.NotNil(t, res.Msg.Stats)
+		assert.Equal(t, uint64(2), res.Msg.Stats.Records)
+		assert.Equal(t, uint64(3), res.Msg.Stats.HistoryRecords)
+		assert.NotZero(t, res.Msg.Stats.DataSize)
+		assert.NotZero(t, res.Msg.Stats.LastPushAt)
+		assert.Zero(t, res.Msg.Stats.NotTouched)
+
+		ta.AssertNoWarnsAndErrors()
+	})
 }
@@ -9,6 +9,7 @@ import (
 	"connectrpc.com/connect"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 	"github.com/ashep/ujds/tests/testapp"
@@ -72,4 +73,128 @@ func TestIndex_List(main *testing.T) {
 
 		ta.AssertNoWarnsAndErrors()
 	})
+
+	main.Run("InvalidLabelSelector", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.List(context.Background(), connect.NewRequest(&indexproto.ListRequest{
+			Filter: &indexproto.ListRequestFilter{Labels: "env in ()"},
+		}))
+
+		assert.EqualError(t, err, `invalid_argument: invalid label selector: requirement "env in ()": empty value set`)
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkWithLabelFilter", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		for name, labels := range map[string]map[string]string{
+			"theIndex1": {"team": "search", "env": "prod"},
+			"theIndex2": {"team": "search", "env": "stage"},
+			"theIndex3": {"team": "search"},
+			"theIndex4": {"team": "billing", "env": "dev"},
+		} {
+			_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+				Name:     name,
+				Labels:   &indexproto.PushRequest_Labels{Values: labels},
+				Metadata: proto.String(`{"retention": {"days": 30}}`),
+			}))
+			require.NoError(t, err)
+		}
+
+		list := func(selector string) []string {
+			res, err := cli.I.List(context.Background(), connect.NewRequest(&indexproto.ListRequest{
+				Filter: &indexproto.ListRequestFilter{Labels: selector},
+			}))
+			require.NoError(t, err)
+
+			names := make([]string, 0)
+			for _, idx := range res.Msg.Indices {
+				names = append(names, idx.Name)
+			}
+
+			return names
+		}
+
+		assert.Equal(t, []string{"theIndex2", "theIndex3"}, list("team=search,env!=prod"))
+		assert.Equal(t, []string{"theIndex1", "theIndex4"}, list("env in (prod, dev)"))
+		assert.Equal(t, []string{"theIndex3", "theIndex4"}, list("env notin (prod,stage)"))
+		assert.Equal(t, []string{"theIndex1", "theIndex2", "theIndex4"}, list("env"))
+		assert.Equal(t, []string{"theIndex3"}, list("!env"))
+		assert.Equal(t, []string{"theIndex1", "theIndex2", "theIndex3", "theIndex4"}, list(""))
+
+		res, err := cli.I.List(context.Background(), connect.NewRequest(&indexproto.ListRequest{
+			Filter: &indexproto.ListRequestFilter{Labels: "team==billing"},
+		}))
+		require.NoError(t, err)
+		require.Len(t, res.Msg.Indices, 1)
+		assert.Equal(t, map[string]string{"team": "billing", "env": "dev"}, res.Msg.Indices[0].Labels)
+		assert.JSONEq(t, `{"retention": {"days": 30}}`, res.Msg.Indices[0].Metadata)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkWithTitleFilter", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		ta.DB().InsertIndex("theIndexName1", "The Books")
+		ta.DB().InsertIndex("theIndexName2", "The Recipes")
+
+		cli := ta.Client("")
+		res, err := cli.I.List(context.Background(), connect.NewRequest(&indexproto.ListRequest{
+			Filter: &indexproto.ListRequestFilter{
+				Title: "recipe",
+			},
+		}))
+
+		require.NoError(t, err)
+		require.Len(t, res.Msg.Indices, 1)
+		assert.Equal(t, "theIndexName2", res.Msg.Indices[0].Name)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkWithSortAndPagination", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		ta.DB().InsertIndex("theIndexNameA", "theIndexTitleA")
+		ta.DB().InsertIndex("theIndexNameB", "theIndexTitleB")
+		ta.DB().InsertIndex("theIndexNameC", "theIndexTitleC")
+
+		cli := ta.Client("")
+
+		names := make([]string, 0)
+		cursor := ""
+
+		for {
+			res, err := cli.I.List(context.Background(), connect.NewRequest(&indexproto.ListRequest{
+				SortDesc: true,
+				Limit:    2,
+				Cursor:   cursor,
+			}))
+			require.NoError(t, err)
+
+			for _, idx := range res.Msg.Indices {
+				names = append(names, idx.Name)
+				assert.NotZero(t, idx.CreatedAt)
+				assert.NotZero(t, idx.UpdatedAt)
+			}
+
+			if res.Msg.Cursor == "" {
+				break
+			}
+
+			cursor = res.Msg.Cursor
+		}
+
+		assert.Equal(t, []string{"theIndexNameC", "theIndexNameB", "theIndexNameA"}, names)
+
+		ta.AssertNoWarnsAndErrors()
+	})
 }
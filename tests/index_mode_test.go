@@ -0,0 +1,224 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ashep/ujds/sdk/client"
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestIndex_Mode(main *testing.T) {
+	main.Parallel()
+
+	pushRecord := func(cli *client.Client, id, data string) error {
+		_, err := cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{{Index: "theIndex", Id: id, Data: data}},
+		}))
+
+		return err //nolint:wrapcheck // ok
+	}
+
+	main.Run("ReadOnly", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+		require.NoError(t, pushRecord(cli, "theRecord", `{"foo":"bar"}`))
+
+		_, err = cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:     "theIndex",
+			ReadOnly: proto.Bool(true),
+		}))
+		require.NoError(t, err)
+
+		idx, err := cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+		assert.True(t, idx.Msg.GetReadOnly())
+		assert.False(t, idx.Msg.GetInsertOnly())
+		assert.False(t, idx.Msg.GetNoHistory())
+
+		err = pushRecord(cli, "theRecord", `{"foo":"baz"}`)
+		assert.EqualError(t, err, "failed_precondition: record 0, id=theRecord: index is read-only")
+
+		_, err = cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{{Op: "delete", Index: "theIndex", Id: "theRecord"}},
+		}))
+		assert.EqualError(t, err, "failed_precondition: operation 0, id=theRecord: index is read-only")
+
+		stream := cli.R.Import(context.Background())
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Index:   "theIndex",
+			Records: []*recordproto.ImportRequest_Record{{Id: "theRecord2", Data: `{}`}},
+		}))
+		_, err = stream.CloseAndReceive()
+		assert.EqualError(t, err, "failed_precondition: index is read-only")
+
+		_, err = cli.I.Clear(context.Background(), connect.NewRequest(&indexproto.ClearRequest{Name: "theIndex"}))
+		assert.EqualError(t, err, "failed_precondition: index is read-only")
+
+		// Records are still readable
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecord",
+		}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"foo":"bar"}`, rec.Msg.GetRecord().GetData())
+
+		// Pushing the index without the mode keeps it
+		_, err = cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:  "theIndex",
+			Title: "theTitle",
+		}))
+		require.NoError(t, err)
+		require.Error(t, pushRecord(cli, "theRecord", `{"foo":"baz"}`))
+
+		// The mode can be switched off
+		_, err = cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:     "theIndex",
+			ReadOnly: proto.Bool(false),
+		}))
+		require.NoError(t, err)
+		require.NoError(t, pushRecord(cli, "theRecord", `{"foo":"baz"}`))
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("InsertOnly", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:       "theIndex",
+			InsertOnly: proto.Bool(true),
+		}))
+		require.NoError(t, err)
+
+		require.NoError(t, pushRecord(cli, "theRecord", `{"foo":"bar"}`))
+
+		// Pushing the same data again is fine
+		require.NoError(t, pushRecord(cli, "theRecord", `{"foo": "bar"}`))
+
+		err = pushRecord(cli, "theRecord", `{"foo":"baz"}`)
+		assert.EqualError(t, err, "failed_precondition: record theRecord exists in an insert-only index")
+
+		_, err = cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{{Op: "delete", Index: "theIndex", Id: "theRecord"}},
+		}))
+		assert.EqualError(t, err, "failed_precondition: operation 0, id=theRecord: record theRecord exists in an insert-only index")
+
+		stream := cli.R.Import(context.Background())
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Index: "theIndex",
+			Records: []*recordproto.ImportRequest_Record{
+				{Id: "theRecord2", Data: `{}`},
+				{Id: "theRecord", Data: `{"foo":"baz"}`},
+			},
+		}))
+		_, err = stream.CloseAndReceive()
+		assert.EqualError(t, err, "failed_precondition: record theRecord exists in an insert-only index")
+
+		_, err = cli.I.Clear(context.Background(), connect.NewRequest(&indexproto.ClearRequest{Name: "theIndex"}))
+		assert.EqualError(t, err, "failed_precondition: records of an insert-only index can't be deleted")
+
+		assert.Len(t, ta.DB().GetRecords("theIndex"), 1)
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("InsertOnlyConcurrentPushes", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:       "theIndex",
+			InsertOnly: proto.Bool(true),
+		}))
+		require.NoError(t, err)
+
+		// Only one of the concurrent pushes of a new record with different data succeeds
+		const n = 10
+
+		errs := make(chan error, n)
+		for i := range n {
+			go func() {
+				errs <- pushRecord(cli, "theRecord", fmt.Sprintf(`{"n":%d}`, i))
+			}()
+		}
+
+		succeeded := 0
+
+		for range n {
+			if err := <-errs; err == nil {
+				succeeded++
+			} else {
+				assert.EqualError(t, err, "failed_precondition: record theRecord exists in an insert-only index")
+			}
+		}
+
+		assert.Equal(t, 1, succeeded)
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("NoHistory", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:      "theIndex",
+			NoHistory: proto.Bool(true),
+		}))
+		require.NoError(t, err)
+
+		require.NoError(t, pushRecord(cli, "theRecord", `{"foo":"bar"}`))
+		require.NoError(t, pushRecord(cli, "theRecord", `{"foo":"baz"}`))
+
+		_, err = cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{
+				{Op: "patch", Index: "theIndex", Id: "theRecord", Data: `{"a":1}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		stream := cli.R.Import(context.Background())
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Index:   "theIndex",
+			Records: []*recordproto.ImportRequest_Record{{Id: "theRecord2", Data: `{}`}},
+		}))
+		_, err = stream.CloseAndReceive()
+		require.NoError(t, err)
+
+		require.NoError(t, pushRecord(cli, "theRecord2", `{"b":2}`))
+
+		// Only the current revisions are kept
+		logs := ta.DB().GetRecordLogs("theIndex")
+		require.Len(t, logs, 2)
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecord",
+		}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"foo":"baz","a":1}`, rec.Msg.GetRecord().GetData())
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
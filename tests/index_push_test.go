@@ -9,6 +9,7 @@ import (
 	"connectrpc.com/connect"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 	"github.com/ashep/ujds/tests/testapp"
@@ -153,4 +154,77 @@ func TestIndex_Push(main *testing.T) {
 
 		ta.AssertNoWarnsAndErrors()
 	})
+
+	main.Run("InvalidLabels", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:   "theIndexName",
+			Labels: &indexproto.PushRequest_Labels{Values: map[string]string{"team": "the team"}},
+		}))
+
+		assert.EqualError(t, err, `invalid_argument: invalid labels: label "team" value "the team" must match the regexp `+
+			`^([a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-9])?)?$`)
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("InvalidMetadata", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:     "theIndexName",
+			Metadata: proto.String(`["foo"]`),
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: invalid metadata: must be a JSON object")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkLabelsAndMetadata", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name: "theIndexName",
+			Labels: &indexproto.PushRequest_Labels{
+				Values: map[string]string{"team": "search", "example.com/retention": "short"},
+			},
+			Metadata: proto.String(`{"owner": {"email": "search@example.com"}}`),
+		}))
+		require.NoError(t, err)
+
+		res, err := cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theIndexName"}))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"team": "search", "example.com/retention": "short"}, res.Msg.Labels)
+		assert.JSONEq(t, `{"owner": {"email": "search@example.com"}}`, res.Msg.Metadata)
+
+		// Labels and metadata omitted in a push are left unchanged
+		_, err = cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndexName"}))
+		require.NoError(t, err)
+
+		res, err = cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theIndexName"}))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"team": "search", "example.com/retention": "short"}, res.Msg.Labels)
+		assert.JSONEq(t, `{"owner": {"email": "search@example.com"}}`, res.Msg.Metadata)
+
+		// Empty labels and metadata clear them
+		_, err = cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name:     "theIndexName",
+			Labels:   &indexproto.PushRequest_Labels{},
+			Metadata: proto.String(""),
+		}))
+		require.NoError(t, err)
+
+		res, err = cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theIndexName"}))
+		require.NoError(t, err)
+		assert.Empty(t, res.Msg.Labels)
+		assert.JSONEq(t, `{}`, res.Msg.Metadata)
+
+		ta.AssertNoWarnsAndErrors()
+	})
 }
@@ -0,0 +1,134 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestIndex_SetSchema(main *testing.T) {
+	main.Parallel()
+
+	main.Run("InvalidAuthorization", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("anInvalidAuthToken")
+		_, err := cli.I.SetSchema(context.Background(), connect.NewRequest(&indexproto.SetSchemaRequest{}))
+
+		assert.EqualError(t, err, "unauthenticated: not authorized")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("IndexNotExists", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		cli := ta.Client("")
+		_, err := cli.I.SetSchema(context.Background(), connect.NewRequest(&indexproto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{}`,
+		}))
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("InvalidSchema", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		ta.DB().InsertIndex("theIndex", "")
+
+		cli := ta.Client("")
+		_, err := cli.I.SetSchema(context.Background(), connect.NewRequest(&indexproto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{"type":"foo"}`,
+		}))
+
+		assert.ErrorContains(t, err, "invalid_argument: invalid json schema: ")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		ta.DB().InsertIndex("theIndex", "")
+
+		cli := ta.Client("")
+
+		for i, sch := range []string{`{"required":["title"]}`, `{"required":["author"]}`} {
+			res, err := cli.I.SetSchema(context.Background(), connect.NewRequest(&indexproto.SetSchemaRequest{
+				Name:   "theIndex",
+				Schema: sch,
+			}))
+			require.NoError(t, err)
+			assert.Equal(t, uint32(i+1), res.Msg.Version)
+		}
+
+		getRes, err := cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+		assert.Equal(t, uint32(2), getRes.Msg.SchemaVersion)
+		assert.Equal(t, []string{
+			`{"$schema":"http://json-schema.org/draft-07/schema#","required": ["author"]}`,
+		}, getRes.Msg.Schemas)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{{Index: "theIndex", Id: "theRecordID", Data: `{"title":"foo"}`}},
+		}))
+		assert.EqualError(t, err, `invalid_argument: record 0, id=theRecordID: validation failed: invalid json: (root): missing property 'author'`)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{{Index: "theIndex", Id: "theRecordID", Data: `{"author":"foo"}`}},
+		}))
+		require.NoError(t, err)
+
+		histRes, err := cli.I.GetSchemaHistory(context.Background(), connect.NewRequest(&indexproto.GetSchemaHistoryRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+		require.Len(t, histRes.Msg.Schemas, 2)
+		assert.Equal(t, uint32(2), histRes.Msg.Schemas[0].Version)
+		assert.Equal(t, uint32(1), histRes.Msg.Schemas[1].Version)
+		assert.Zero(t, histRes.Msg.Cursor)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkDeactivate", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		ta.DB().InsertIndex("theIndex", "")
+
+		cli := ta.Client("")
+		_, err := cli.I.SetSchema(context.Background(), connect.NewRequest(&indexproto.SetSchemaRequest{
+			Name:   "theIndex",
+			Schema: `{"required":["author"]}`,
+		}))
+		require.NoError(t, err)
+
+		res, err := cli.I.SetSchema(context.Background(), connect.NewRequest(&indexproto.SetSchemaRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+		assert.Zero(t, res.Msg.Version)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{{Index: "theIndex", Id: "theRecordID", Data: `{}`}},
+		}))
+		require.NoError(t, err)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
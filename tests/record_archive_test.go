@@ -0,0 +1,153 @@
+//go:build functest
+
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordarchiver"
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestRecord_Archive(main *testing.T) {
+	main.Parallel()
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		ta := testapp.New(t, testapp.WithConfigOptionArchiver(time.Millisecond*100, dir,
+			map[string]recordarchiver.Policy{"^theIndex$": {OlderThan: "1s"}}))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		for i := range 4 {
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndex", Id: "foo", Data: fmt.Sprintf(`{"v":%d}`, i)},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		require.Eventually(t, func() bool {
+			return len(ta.DB().GetRecordLogs("theIndex")) == 1
+		}, time.Second*10, time.Millisecond*100)
+
+		segs, err := filepath.Glob(filepath.Join(dir, "*", "*.ndjson.gz"))
+		require.NoError(t, err)
+		assert.NotEmpty(t, segs)
+
+		res, err := cli.R.History(context.Background(), connect.NewRequest(&recordproto.HistoryRequest{
+			Index: "theIndex",
+			Id:    "foo",
+			Limit: 2,
+		}))
+		require.NoError(t, err)
+		require.Len(t, res.Msg.Records, 2)
+		assert.Equal(t, `{"v": 3}`, res.Msg.Records[0].Data)
+		assert.Equal(t, `{"v": 2}`, res.Msg.Records[1].Data)
+		assert.Equal(t, res.Msg.Records[1].Rev, res.Msg.Cursor)
+
+		res, err = cli.R.History(context.Background(), connect.NewRequest(&recordproto.HistoryRequest{
+			Index:  "theIndex",
+			Id:     "foo",
+			Cursor: res.Msg.Cursor,
+			Limit:  2,
+		}))
+		require.NoError(t, err)
+		require.Len(t, res.Msg.Records, 2)
+		assert.Equal(t, `{"v": 1}`, res.Msg.Records[0].Data)
+		assert.Equal(t, `{"v": 0}`, res.Msg.Records[1].Data)
+		assert.Zero(t, res.Msg.Cursor)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkCopyAndExportWithHistory", func(t *testing.T) {
+		t.Parallel()
+
+		ta := testapp.New(t, testapp.WithConfigOptionArchiver(time.Millisecond*100, t.TempDir(),
+			map[string]recordarchiver.Policy{"^theIndex$": {OlderThan: "1s"}}))
+		cli := ta.Client("")
+
+		for _, name := range []string{"theIndex", "theTarget"} {
+			_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: name}))
+			require.NoError(t, err)
+		}
+
+		for i := range 4 {
+			_, err := cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndex", Id: "foo", Data: fmt.Sprintf(`{"v":%d}`, i)},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		require.Eventually(t, func() bool {
+			return len(ta.DB().GetRecordLogs("theIndex")) == 1
+		}, time.Second*10, time.Millisecond*100)
+
+		res, err := cli.I.Copy(context.Background(), connect.NewRequest(&indexproto.CopyRequest{
+			Source:      "theIndex",
+			Target:      "theTarget",
+			WithHistory: true,
+		}))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			job, err := cli.I.GetJob(context.Background(), connect.NewRequest(&indexproto.GetJobRequest{Id: res.Msg.JobId}))
+			require.NoError(t, err)
+			return job.Msg.Status == "done"
+		}, time.Second*5, time.Millisecond*100)
+
+		// Archived revisions of the source are in the database history of the target
+		logs := ta.DB().GetRecordLogs("theTarget")
+		require.Len(t, logs, 4)
+
+		stream, err := cli.I.Export(context.Background(), connect.NewRequest(&indexproto.ExportRequest{
+			Name:        "theIndex",
+			WithHistory: true,
+		}))
+		require.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		for stream.Receive() {
+			buf.Write(stream.Msg().Data)
+		}
+		require.NoError(t, stream.Err())
+
+		ln := struct {
+			History []struct {
+				Data json.RawMessage `json:"data"`
+			} `json:"history"`
+		}{}
+
+		sc := bufio.NewScanner(buf)
+		require.True(t, sc.Scan())
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &ln))
+		require.Len(t, ln.History, 4)
+
+		for i, rev := range ln.History {
+			assert.JSONEq(t, fmt.Sprintf(`{"v":%d}`, i), string(rev.Data))
+		}
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
@@ -0,0 +1,202 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestRecord_Batch(main *testing.T) {
+	main.Parallel()
+
+	// setup creates two indices with a record in each one
+	setup := func(t *testing.T) *testapp.TestApp {
+		t.Helper()
+
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		for _, name := range []string{"theIndex1", "theIndex2"} {
+			_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: name}))
+			require.NoError(t, err)
+		}
+
+		_, err := cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex1", Id: "theRecord1", Data: `{"foo":"bar","baz":{"a":1}}`},
+				{Index: "theIndex2", Id: "theRecord2", Data: `{"foo":"bar"}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		return ta
+	}
+
+	main.Run("InvalidAuthorization", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("anInvalidAuthToken")
+
+		_, err := cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{}))
+
+		assert.EqualError(t, err, "unauthenticated: not authorized")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("EmptyOperations", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{}))
+
+		assert.EqualError(t, err, "invalid_argument: empty operations")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("ExpectedRevMismatchRollsBack", func(t *testing.T) {
+		t.Parallel()
+		ta := setup(t)
+		cli := ta.Client("")
+
+		_, err := cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{
+				{Op: "put", Index: "theIndex1", Id: "theRecord1", Data: `{"foo":"baz"}`},
+				{Op: "delete", Index: "theIndex2", Id: "theRecord2", ExpectedRev: 1},
+			},
+		}))
+
+		assert.EqualError(t, err, "failed_precondition: operation 1, id=theRecord2: precondition failed: "+
+			"expected rev 1, current rev 2")
+
+		// Nothing is changed
+		require.Len(t, ta.DB().GetRecordLogs("theIndex1"), 1)
+		require.Len(t, ta.DB().GetRecords("theIndex2"), 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("MustExist", func(t *testing.T) {
+		t.Parallel()
+		ta := setup(t)
+		cli := ta.Client("")
+
+		_, err := cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{
+				{Op: "put", Index: "theIndex1", Id: "theRecord3", Data: `{}`, MustExist: true},
+			},
+		}))
+
+		assert.EqualError(t, err, "failed_precondition: operation 0, id=theRecord3: precondition failed: "+
+			"record doesn't exist")
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex1"), 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("PatchNotFound", func(t *testing.T) {
+		t.Parallel()
+		ta := setup(t)
+		cli := ta.Client("")
+
+		_, err := cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{
+				{Op: "patch", Index: "theIndex1", Id: "theRecord3", Data: `{"foo":"baz"}`},
+			},
+		}))
+
+		assert.EqualError(t, err, "not_found: operation 0, id=theRecord3: record is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("PatchedDataValidationError", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionValidationIndex("theIndex", json.RawMessage(`{"required": ["foo"]}`)))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{{Index: "theIndex", Id: "theRecord", Data: `{"foo":"bar"}`}},
+		}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{
+				{Op: "patch", Index: "theIndex", Id: "theRecord", Data: `{"foo":null}`},
+			},
+		}))
+
+		assert.EqualError(t, err, `invalid_argument: operation 0, id=theRecord: validation failed: invalid json: `+
+			`(root): missing property 'foo'`)
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := setup(t)
+		cli := ta.Client("")
+
+		res, err := cli.R.Batch(context.Background(), connect.NewRequest(&recordproto.BatchRequest{
+			Operations: []*recordproto.BatchRequest_Operation{
+				{Op: "patch", Index: "theIndex1", Id: "theRecord1", ExpectedRev: 1, Data: `{"baz":{"a":null,"b":2}}`},
+				{Op: "delete", Index: "theIndex2", Id: "theRecord2", MustExist: true},
+				{Op: "put", Index: "theIndex2", Id: "theRecord3", Data: `{"foo":"bar"}`},
+				{Op: "put", Index: "theIndex2", Id: "theRecord3", Data: `{"foo": "bar"}`, ExpectedRev: 4},
+				{Op: "delete", Index: "theIndex2", Id: "theRecord4"},
+			},
+		}))
+		require.NoError(t, err)
+
+		results := res.Msg.GetResults()
+		require.Len(t, results, 5)
+
+		assert.Equal(t, "theRecord1", results[0].GetId())
+		assert.Equal(t, uint64(3), results[0].GetRev())
+		assert.Equal(t, "updated", results[0].GetStatus())
+
+		assert.Equal(t, "theRecord2", results[1].GetId())
+		assert.Zero(t, results[1].GetRev())
+		assert.Equal(t, "deleted", results[1].GetStatus())
+
+		assert.Equal(t, uint64(4), results[2].GetRev())
+		assert.Equal(t, "created", results[2].GetStatus())
+
+		assert.Equal(t, uint64(4), results[3].GetRev())
+		assert.Equal(t, "unchanged", results[3].GetStatus())
+
+		assert.Equal(t, "theRecord4", results[4].GetId())
+		assert.Equal(t, "not_found", results[4].GetStatus())
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex1",
+			Id:    "theRecord1",
+		}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"foo":"bar","baz":{"b":2}}`, rec.Msg.GetRecord().GetData())
+
+		_, err = cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex2",
+			Id:    "theRecord2",
+		}))
+		assert.EqualError(t, err, "not_found: record is not found")
+
+		// The deleted record's history is kept
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex2"), 2)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
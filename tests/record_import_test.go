@@ -0,0 +1,184 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestRecord_Import(main *testing.T) {
+	main.Parallel()
+
+	main.Run("IndexNotFound", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		stream := cli.R.Import(context.Background())
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Index:   "theIndex",
+			Records: []*recordproto.ImportRequest_Record{{Id: "theRecordID", Data: `{}`}},
+		}))
+		_, err := stream.CloseAndReceive()
+
+		assert.EqualError(t, err, "not_found: index is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("IndexChanged", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		stream := cli.R.Import(context.Background())
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Index:   "theIndex",
+			Records: []*recordproto.ImportRequest_Record{{Id: "theRecordID1", Data: `{}`}},
+		}))
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Index:   "theOtherIndex",
+			Records: []*recordproto.ImportRequest_Record{{Id: "theRecordID2", Data: `{}`}},
+		}))
+		_, err = stream.CloseAndReceive()
+
+		assert.EqualError(t, err, "invalid_argument: index must not change within the stream")
+		assert.Empty(t, ta.DB().GetRecords("theIndex"))
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"foo":"bar1"}`},
+				{Index: "theIndex", Id: "theRecordID2", Data: `{"foo":"bar2"}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		stream := cli.R.Import(context.Background())
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Index: "theIndex",
+			Records: []*recordproto.ImportRequest_Record{
+				{Id: "theRecordID1", Data: `{"foo":"bar1"}`},
+				{Id: "theRecordID2", Data: `{"foo":"baz2"}`},
+			},
+		}))
+		require.NoError(t, stream.Send(&recordproto.ImportRequest{
+			Records: []*recordproto.ImportRequest_Record{
+				{Id: "theRecordID3", Data: `{"foo":"bar3"}`},
+				{Id: "", Data: `{"foo":"bar4"}`},
+				{Id: "theRecordID5", Data: `{"foo":`},
+			},
+		}))
+		res, err := stream.CloseAndReceive()
+		require.NoError(t, err)
+
+		assert.Equal(t, uint64(1), res.Msg.Created)
+		assert.Equal(t, uint64(1), res.Msg.Updated)
+		assert.Equal(t, uint64(1), res.Msg.Unchanged)
+		assert.Equal(t, uint64(2), res.Msg.Failed)
+
+		require.Len(t, res.Msg.Errors, 2)
+		assert.Equal(t, uint64(4), res.Msg.Errors[0].Line)
+		assert.Equal(t, "record 4, id=: validation failed: invalid record id: must not be empty", res.Msg.Errors[0].Error)
+		assert.Equal(t, uint64(5), res.Msg.Errors[1].Line)
+		assert.Equal(t, "theRecordID5", res.Msg.Errors[1].RecordId)
+		assert.Equal(t, "validation failed: malformed json", res.Msg.Errors[1].Error)
+
+		recs := ta.DB().GetRecords("theIndex")
+		require.Len(t, recs, 3)
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 4)
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID2",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, `{"foo": "baz2"}`, rec.Msg.Record.Data)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("NDJSONUnauthorized", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+
+		res, err := http.Post(ta.URL()+"/import/theIndex", "application/x-ndjson", strings.NewReader(""))
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("NDJSONOk", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		body := strings.Join([]string{
+			`{"id":"theRecordID1","data":{"foo":"bar1"}}`,
+			`{"id":"theRecordID2","data":{"foo":"bar2"}}`,
+			`{"id":"theRecordID1","data":{"foo":"baz1"}}`,
+			`not a json`,
+		}, "\n")
+
+		req, err := http.NewRequest(http.MethodPost, ta.URL()+"/import/theIndex", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+ta.AuthToken())
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+
+		ir := &recordproto.ImportResponse{}
+		require.NoError(t, protojson.Unmarshal(b, ir))
+
+		assert.Equal(t, uint64(2), ir.Created)
+		assert.Equal(t, uint64(2), ir.Failed)
+		require.Len(t, ir.Errors, 2)
+		assert.Equal(t, uint64(1), ir.Errors[0].Line)
+		assert.Equal(t, "superseded by line 3", ir.Errors[0].Error)
+		assert.Equal(t, uint64(4), ir.Errors[1].Line)
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID1",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, `{"foo": "baz1"}`, rec.Msg.Record.Data)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
@@ -0,0 +1,113 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordrepo"
+	"github.com/ashep/ujds/internal/validation"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+const benchPushBatchSize = 1000
+
+// BenchmarkRecord_Push compares pushing a batch of records set-wise with pushing them one by one, the way records
+// were pushed before. Half of the records are changed by every push.
+func BenchmarkRecord_Push(b *testing.B) {
+	db := testapp.NewBenchDB(b)
+
+	recIDValidator, err := validation.NewRecordIDValidator(nil)
+	require.NoError(b, err)
+
+	repo := recordrepo.New(db, validation.NewIndexNameValidator(), recIDValidator, zerolog.Nop())
+
+	b.Run("serial", func(b *testing.B) {
+		benchPush(b, db, "theSerialIndex", func(updates []recordrepo.RecordUpdate) error {
+			return pushSerial(db, updates)
+		})
+	})
+
+	b.Run("setwise", func(b *testing.B) {
+		benchPush(b, db, "theSetwiseIndex", func(updates []recordrepo.RecordUpdate) error {
+			return repo.Push(context.Background(), updates)
+		})
+	})
+}
+
+// benchPush measures pushing the same workload to a new index with the push function.
+func benchPush(b *testing.B, db *sql.DB, index string, push func([]recordrepo.RecordUpdate) error) {
+	b.Helper()
+
+	indexID := uint64(0)
+	require.NoError(b, db.QueryRow(`INSERT INTO index (name) VALUES ($1) RETURNING id`, index).Scan(&indexID))
+
+	updates := make([]recordrepo.RecordUpdate, benchPushBatchSize)
+	run := 0
+
+	for b.Loop() {
+		run++
+
+		for i := range updates {
+			updates[i] = recordrepo.RecordUpdate{
+				IndexID: indexID,
+				ID:      fmt.Sprintf("theRecordID%d", i),
+				Data:    fmt.Sprintf(`{"run":%d}`, run*(i%2)),
+			}
+		}
+
+		if err := push(updates); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportMetric(float64(benchPushBatchSize), "records/op")
+}
+
+// pushSerial pushes records one by one, with a statement per record.
+func pushSerial(db *sql.DB, updates []recordrepo.RecordUpdate) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, upd := range updates {
+		res, err := tx.Exec(`UPDATE record SET touched_at=now(), expires_at=$3, stale_at=NULL
+WHERE index_id=$1 AND id=$2 AND checksum=sha256(convert_to($4::JSONB::TEXT, 'UTF8'))`,
+			upd.IndexID, upd.ID, upd.ExpiresAt, upd.Data)
+		if err != nil {
+			return err
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n != 0 {
+			continue
+		}
+
+		logID := uint64(0)
+		if err := tx.QueryRow(`INSERT INTO record_log (index_id, record_id, data) VALUES ($1, $2, $3) RETURNING id`,
+			upd.IndexID, upd.ID, upd.Data).Scan(&logID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`INSERT INTO record (id, index_id, log_id, checksum, data, expires_at)
+VALUES ($1, $2, $3, sha256(convert_to($4::JSONB::TEXT, 'UTF8')), $4, $5)
+ON CONFLICT (id, index_id) DO UPDATE SET log_id=$3, checksum=EXCLUDED.checksum, data=$4, expires_at=$5,
+stale_at=NULL, updated_at=now(), touched_at=now()`, upd.ID, upd.IndexID, logID, upd.Data, upd.ExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -5,12 +5,18 @@ package tests
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ashep/ujds/internal/validation"
 	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
 	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
 	"github.com/ashep/ujds/tests/testapp"
@@ -143,6 +149,65 @@ func TestRecord_Push(main *testing.T) {
 		ta.AssertNoWarnsAndErrors()
 	})
 
+	main.Run("RecordIDTooLong", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{
+					Index: "theIndex",
+					Id:    strings.Repeat("a", 65),
+					Data:  "{}",
+				},
+			},
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: record 0, id="+strings.Repeat("a", 65)+
+			": validation failed: invalid record id: must not be longer than 64 characters")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("RecordIDRules", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionValidationRecordID("^theIndex$", validation.RecordIDRule{
+			Format:   validation.RecordIDFormatUUID,
+			Generate: validation.RecordIDGenerateUUIDv7,
+		}))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID", Data: "{}"},
+			},
+		}))
+		assert.EqualError(t, err, "invalid_argument: record 0, id=theRecordID: validation failed: invalid record id: must be a UUID")
+
+		res, err := cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Data: `{"foo":"bar"}`},
+			},
+		}))
+		require.NoError(t, err)
+		require.Len(t, res.Msg.Ids, 1)
+
+		getRes, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    res.Msg.Ids[0],
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, `{"foo": "bar"}`, getRes.Msg.Record.Data)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
 	main.Run("SchemaValidationFailed", func(t *testing.T) {
 		t.Parallel()
 		ta := testapp.New(t, testapp.WithConfigOptionValidationIndex("theIndex", json.RawMessage(`{"required": ["foo"]}`)))
@@ -163,7 +228,120 @@ func TestRecord_Push(main *testing.T) {
 			},
 		}))
 
-		assert.EqualError(t, err, `invalid_argument: record 0, id=theRecordID: validation failed: invalid json: (root): foo is required`)
+		assert.EqualError(t, err, `invalid_argument: record 0, id=theRecordID: validation failed: invalid json: (root): missing property 'foo'`)
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("SchemasDirValidationFailed", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "defs"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "theIndex.json"),
+			[]byte(`{"properties": {"foo": {"$ref": "defs/foo.json"}}}`), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "defs", "foo.json"),
+			[]byte(`{"type": "string"}`), 0o600))
+
+		ta := testapp.New(t, testapp.WithConfigOptionValidationSchemasDir(dir))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{
+					Index: "theIndex",
+					Id:    "theRecordID",
+					Data:  `{"foo": 1}`,
+				},
+			},
+		}))
+
+		assert.EqualError(t, err, `invalid_argument: record 0, id=theRecordID: validation failed: invalid json: foo: got number, want string`)
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("SchemasDirReloaded", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "theIndex.json"), []byte(`{}`), 0o600))
+
+		ta := testapp.New(t, testapp.WithConfigOptionValidationSchemasDir(dir))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+
+		req := &recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{
+					Index: "theIndex",
+					Id:    "theRecordID",
+					Data:  `{}`,
+				},
+			},
+		}
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(req))
+		require.NoError(t, err)
+
+		// An invalid schema is not applied
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "theIndex.json"), []byte(`{]`), 0o600))
+		time.Sleep(time.Second * 2)
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(req))
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "theIndex.json"), []byte(`{"required": ["foo"]}`), 0o600))
+		require.Eventually(t, func() bool {
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(req))
+			return err != nil
+		}, time.Second*5, time.Millisecond*100)
+
+		assert.EqualError(t, err, `invalid_argument: record 0, id=theRecordID: validation failed: invalid json: (root): missing property 'foo'`)
+	})
+
+	main.Run("JSONValidationErrorDetails", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionValidationIndex("theIndex",
+			json.RawMessage(`{"required": ["foo"], "properties": {"bar": {"type": "string"}}}`)))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"foo": 1}`},
+				{Index: "theIndex", Id: "theRecordID2", Data: `{"bar": 1}`},
+			},
+		}))
+
+		cErr := &connect.Error{}
+		require.ErrorAs(t, err, &cErr)
+		assert.Equal(t, connect.CodeInvalidArgument, cErr.Code())
+		require.Len(t, cErr.Details(), 1)
+
+		d, err := cErr.Details()[0].Value()
+		require.NoError(t, err)
+
+		vErr, ok := d.(*recordproto.ValidationError)
+		require.True(t, ok)
+		assert.Equal(t, uint32(1), vErr.Record)
+		assert.Equal(t, "theRecordID2", vErr.RecordId)
+		require.Len(t, vErr.Violations, 2)
+		assert.Equal(t, "", vErr.Violations[0].Pointer)
+		assert.Equal(t, "required", vErr.Violations[0].Keyword)
+		assert.Equal(t, "/bar", vErr.Violations[1].Pointer)
+		assert.Equal(t, "type", vErr.Violations[1].Keyword)
+
 		ta.AssertNoWarnsAndErrors()
 	})
 
@@ -203,7 +381,7 @@ func TestRecord_Push(main *testing.T) {
 		assert.Equal(t, 1, rcs[0].IndexID)
 		assert.Equal(t, 1, rcs[0].LogID)
 		assert.Equal(t, `{"foo": "bar"}`, rcs[0].Data)
-		assert.Equal(t, []byte{0xd7, 0xae, 0xdc, 0xee, 0x96, 0x9c, 0xe8, 0x55, 0x47, 0x83, 0xff, 0x37, 0x31, 0x3a, 0xcd, 0x6b, 0x7e, 0xcc, 0xa, 0xcf, 0x68, 0xcd, 0xfc, 0xdb, 0x86, 0x70, 0xd7, 0x65, 0xa6, 0x2, 0x9c, 0x0}, rcs[0].Checksum)
+		assert.Equal(t, []byte{0x42, 0x6f, 0xc0, 0x4f, 0x4, 0xbf, 0x8f, 0xdb, 0x58, 0x31, 0xdc, 0x37, 0xbb, 0xb6, 0xdc, 0xf7, 0xf, 0x63, 0xa3, 0x7e, 0x5, 0xa6, 0x8c, 0x6e, 0xa5, 0xf6, 0x3e, 0x85, 0xae, 0x57, 0x93, 0x76}, rcs[0].Checksum)
 		assert.NotZero(t, rcs[0].CreatedAt)
 		assert.Equal(t, rcs[0].CreatedAt, rcs[0].UpdatedAt) // the record has no updates
 		assert.Equal(t, rcs[0].CreatedAt, rcs[0].TouchedAt) // the record has no touches
@@ -268,7 +446,7 @@ func TestRecord_Push(main *testing.T) {
 		assert.Equal(t, 1, rcs[0].IndexID)
 		assert.Equal(t, 2, rcs[0].LogID)
 		assert.Equal(t, `{"foo": "bar2"}`, rcs[0].Data)
-		assert.Equal(t, []byte{0x2e, 0x82, 0xd8, 0x32, 0xa1, 0x18, 0xff, 0x72, 0x77, 0x32, 0xf7, 0xb4, 0xec, 0x4f, 0x9c, 0xef, 0xf1, 0x16, 0x97, 0x5, 0xfc, 0xc7, 0xa0, 0xd1, 0xe9, 0x9f, 0xbb, 0x6a, 0x91, 0xca, 0x23, 0x72}, rcs[0].Checksum)
+		assert.Equal(t, []byte{0x24, 0xb7, 0xcc, 0x6d, 0xb0, 0x20, 0x4f, 0x6a, 0x2f, 0x32, 0xff, 0x98, 0x97, 0x9, 0xe7, 0x1f, 0x52, 0xdc, 0x19, 0x85, 0xab, 0x3c, 0x5c, 0xf2, 0xfd, 0xcb, 0x9e, 0x45, 0x58, 0x10, 0x26, 0xd5}, rcs[0].Checksum)
 		assert.Greater(t, rcs[0].UpdatedAt, rcs[0].CreatedAt) // the record was updated after creation
 		assert.Equal(t, rcs[0].UpdatedAt, rcs[0].TouchedAt)   // and was not touched after the update
 
@@ -322,7 +500,7 @@ func TestRecord_Push(main *testing.T) {
 		assert.Equal(t, "theRecordID", rcs[0].ID)
 		assert.Equal(t, 1, rcs[0].IndexID)
 		assert.Equal(t, 1, rcs[0].LogID)
-		assert.Equal(t, []byte{0xd7, 0xae, 0xdc, 0xee, 0x96, 0x9c, 0xe8, 0x55, 0x47, 0x83, 0xff, 0x37, 0x31, 0x3a, 0xcd, 0x6b, 0x7e, 0xcc, 0xa, 0xcf, 0x68, 0xcd, 0xfc, 0xdb, 0x86, 0x70, 0xd7, 0x65, 0xa6, 0x2, 0x9c, 0x0}, rcs[0].Checksum)
+		assert.Equal(t, []byte{0x42, 0x6f, 0xc0, 0x4f, 0x4, 0xbf, 0x8f, 0xdb, 0x58, 0x31, 0xdc, 0x37, 0xbb, 0xb6, 0xdc, 0xf7, 0xf, 0x63, 0xa3, 0x7e, 0x5, 0xa6, 0x8c, 0x6e, 0xa5, 0xf6, 0x3e, 0x85, 0xae, 0x57, 0x93, 0x76}, rcs[0].Checksum)
 		assert.NotZero(t, rcs[0].CreatedAt)
 		assert.Equal(t, rcs[0].CreatedAt, rcs[0].UpdatedAt)   // no data updated after second push
 		assert.Greater(t, rcs[0].TouchedAt, rcs[0].UpdatedAt) // but the record was touched
@@ -330,6 +508,78 @@ func TestRecord_Push(main *testing.T) {
 		ta.AssertNoWarnsAndErrors()
 	})
 
+	main.Run("OkDefaultsApplied", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionValidationIndex("theIndex", json.RawMessage(`{
+			"properties": {
+				"foo": {"type": "string"},
+				"lang": {"type": "string", "default": "en"},
+				"meta": {"type": "object", "properties": {"draft": {"default": true}}, "default": {}}
+			}
+		}`)))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"foo":"bar"}`},
+				{Index: "theIndex", Id: "theRecordID2", Data: `{"foo":"baz","lang":"uk","meta":{"draft":false}}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		res, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID1",
+		}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"foo": "bar", "lang": "en", "meta": {"draft": true}}`, res.Msg.Record.Data)
+
+		res, err = cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID2",
+		}))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"foo": "baz", "lang": "uk", "meta": {"draft": false}}`, res.Msg.Record.Data)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("OkUpdateWithSameReorderedData", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID", Data: `{"foo":"bar","baz":1}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		// The same data, with keys reordered and formatted differently
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID", Data: "{\n  \"baz\": 1,\n  \"foo\": \"bar\"\n}"},
+			},
+		}))
+		require.NoError(t, err)
+
+		rls := ta.DB().GetRecordLogs("theIndex")
+		require.Len(t, rls, 1) // only one log record despite two pushes
+
+		rcs := ta.DB().GetRecords("theIndex")
+		require.Len(t, rcs, 1)
+		assert.Equal(t, rcs[0].CreatedAt, rcs[0].UpdatedAt)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
 	// Check that records with same IDs but in different indices are not interfering
 	main.Run("OkDifferentIndicesWithSameData", func(t *testing.T) {
 		t.Parallel()
@@ -386,7 +636,7 @@ func TestRecord_Push(main *testing.T) {
 		assert.Equal(t, 1, rcs[0].IndexID)
 		assert.Equal(t, 1, rcs[0].LogID)
 		assert.Equal(t, `{"foo1": "bar1"}`, rcs[0].Data)
-		assert.Equal(t, []byte{0x0, 0xdb, 0x9a, 0xb1, 0xa1, 0xff, 0x7d, 0x9d, 0x7a, 0x93, 0xf1, 0xf7, 0x4f, 0xb9, 0x20, 0x7, 0x34, 0x5f, 0xa3, 0x85, 0x5c, 0xd6, 0x98, 0xcc, 0x9e, 0x35, 0x5d, 0x43, 0x93, 0x4e, 0x64, 0x90}, rcs[0].Checksum)
+		assert.Equal(t, []byte{0xf8, 0x29, 0xa8, 0x1, 0x47, 0x1a, 0x3e, 0x22, 0x78, 0x7a, 0x78, 0x85, 0xd2, 0x92, 0x9e, 0xd6, 0x45, 0x41, 0xf, 0x29, 0x7e, 0x8a, 0x60, 0xba, 0x1d, 0x6c, 0x4c, 0xcb, 0xce, 0xaf, 0x3e, 0x11}, rcs[0].Checksum)
 
 		rls = ta.DB().GetRecordLogs("theIndex2")
 		require.Len(t, rls, 1)
@@ -402,7 +652,119 @@ func TestRecord_Push(main *testing.T) {
 		assert.Equal(t, 2, rcs[0].IndexID)
 		assert.Equal(t, 2, rcs[0].LogID)
 		assert.Equal(t, `{"foo2": "bar2"}`, rcs[0].Data)
-		assert.Equal(t, []byte{0x3c, 0x79, 0x3c, 0xf4, 0xdd, 0x1c, 0x1f, 0x6d, 0x37, 0x11, 0xe3, 0x1c, 0xaf, 0xcf, 0x74, 0xe0, 0xcc, 0x9f, 0x7b, 0xcb, 0x1d, 0x1f, 0x3b, 0x58, 0xb2, 0x72, 0xe3, 0x60, 0x6e, 0x61, 0xbe, 0x8d}, rcs[0].Checksum)
+		assert.Equal(t, []byte{0x73, 0x92, 0x44, 0x80, 0x37, 0xc8, 0x24, 0x12, 0xaa, 0x1, 0x53, 0x87, 0xa1, 0xf5, 0xf6, 0x4c, 0x84, 0x97, 0xfa, 0xe6, 0x21, 0x69, 0xe1, 0x20, 0x49, 0x98, 0x72, 0xfc, 0xdb, 0x80, 0xf8, 0x57}, rcs[0].Checksum)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+	main.Run("OkRepeatedRecord", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+
+		// Each update of the same record makes a revision, unless it repeats the previous one
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID", Data: `{"foo":"bar1"}`},
+				{Index: "theIndex", Id: "theRecordID", Data: `{"foo":"bar1"}`},
+				{Index: "theIndex", Id: "theRecordID", Data: `{"foo":"bar2"}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		rls := ta.DB().GetRecordLogs("theIndex")
+		require.Len(t, rls, 2)
+		assert.Equal(t, `{"foo": "bar1"}`, rls[0].Data)
+		assert.Equal(t, `{"foo": "bar2"}`, rls[1].Data)
+
+		rcs := ta.DB().GetRecords("theIndex")
+		require.Len(t, rcs, 1)
+		assert.Equal(t, rls[1].ID, rcs[0].LogID)
+		assert.Equal(t, `{"foo": "bar2"}`, rcs[0].Data)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+	main.Run("OkSameDataInDifferentRecords", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+
+		// Identical documents in different records have the same checksum, but never touch each other
+		for range 2 {
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: "theIndex", Id: "theRecordID1", Data: `{"foo":"bar"}`},
+					{Index: "theIndex", Id: "theRecordID2", Data: `{ "foo" : "bar" }`},
+				},
+			}))
+			require.NoError(t, err)
+		}
+
+		require.Len(t, ta.DB().GetRecordLogs("theIndex"), 2)
+
+		rcs := ta.DB().GetRecords("theIndex")
+		require.Len(t, rcs, 2)
+		assert.Equal(t, rcs[0].Checksum, rcs[1].Checksum)
+		assert.NotEqual(t, rcs[0].LogID, rcs[1].LogID)
+
+		// Differently formatted same data doesn't make a new revision
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"foo":  "bar"}`},
+			},
+		}))
+		require.NoError(t, err)
+		require.Len(t, ta.DB().GetRecordLogs("theIndex"), 2)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+	main.Run("OkBatchLargerThanChunk", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{
+			Name: "theIndex",
+		}))
+		require.NoError(t, err)
+
+		// Records are written in chunks; the second push changes every other record only
+		const numRecords = 2500
+
+		for run := range 2 {
+			recs := make([]*recordproto.PushRequest_Record, numRecords)
+			for i := range recs {
+				recs[i] = &recordproto.PushRequest_Record{
+					Index: "theIndex",
+					Id:    fmt.Sprintf("theRecordID%d", i),
+					Data:  fmt.Sprintf(`{"run": %d}`, run*(i%2)),
+				}
+			}
+
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{Records: recs}))
+			require.NoError(t, err)
+		}
+
+		require.Len(t, ta.DB().GetRecordLogs("theIndex"), numRecords+numRecords/2)
+
+		rcs := ta.DB().GetRecords("theIndex")
+		require.Len(t, rcs, numRecords)
+
+		for _, rc := range rcs {
+			i := 0
+			_, err := fmt.Sscanf(rc.ID, "theRecordID%d", &i)
+			require.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf(`{"run": %d}`, i%2), rc.Data)
+		}
 
 		ta.AssertNoWarnsAndErrors()
 	})
@@ -0,0 +1,140 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ashep/ujds/internal/recordsweeper"
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestRecord_Sweep(main *testing.T) {
+	main.Parallel()
+
+	policies := map[string]recordsweeper.Policy{
+		"^theDeletedIndex$": {NotTouchedFor: "1s", Action: "delete"},
+		"^theFlaggedIndex$": {NotTouchedFor: "1s", Action: "flag"},
+	}
+
+	pushRecords := func(t *testing.T, ta *testapp.TestApp) {
+		t.Helper()
+		cli := ta.Client("")
+
+		for _, idx := range []string{"theDeletedIndex", "theFlaggedIndex", "theKeptIndex"} {
+			_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: idx}))
+			require.NoError(t, err)
+
+			_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+				Records: []*recordproto.PushRequest_Record{
+					{Index: idx, Id: "theRecordID", Data: `{"foo":"bar"}`},
+				},
+			}))
+			require.NoError(t, err)
+		}
+	}
+
+	main.Run("FindStalePolicyNotFound", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionSweeper(time.Hour, policies))
+		pushRecords(t, ta)
+
+		_, err := ta.Client("").R.FindStale(context.Background(), connect.NewRequest(&recordproto.FindStaleRequest{
+			Index: "theKeptIndex",
+		}))
+
+		assert.EqualError(t, err, "not_found: sweep policy is not found")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("FindStaleOk", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionSweeper(time.Hour, policies))
+		pushRecords(t, ta)
+		cli := ta.Client("")
+
+		res, err := cli.R.FindStale(context.Background(), connect.NewRequest(&recordproto.FindStaleRequest{
+			Index: "theDeletedIndex",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, "delete", res.Msg.Action)
+		assert.Empty(t, res.Msg.Records)
+
+		time.Sleep(time.Second * 2)
+
+		res, err = cli.R.FindStale(context.Background(), connect.NewRequest(&recordproto.FindStaleRequest{
+			Index: "theDeletedIndex",
+		}))
+		require.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(-time.Second).Unix(), res.Msg.NotTouchedSince, 2)
+		require.Len(t, res.Msg.Records, 1)
+		assert.Equal(t, "theRecordID", res.Msg.Records[0].Id)
+
+		// The dry run changes nothing
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theDeletedIndex",
+			Id:    "theRecordID",
+		}))
+		require.NoError(t, err)
+		assert.Zero(t, rec.Msg.Record.StaleAt)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("SweepOk", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionSweeper(time.Millisecond*100, policies))
+		pushRecords(t, ta)
+		cli := ta.Client("")
+
+		require.Eventually(t, func() bool {
+			_, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+				Index: "theDeletedIndex",
+				Id:    "theRecordID",
+			}))
+
+			if connect.CodeOf(err) != connect.CodeNotFound {
+				return false
+			}
+
+			rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+				Index: "theFlaggedIndex",
+				Id:    "theRecordID",
+			}))
+
+			return err == nil && rec.Msg.Record.StaleAt != 0
+		}, time.Second*10, time.Millisecond*100)
+
+		rec, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theKeptIndex",
+			Id:    "theRecordID",
+		}))
+		require.NoError(t, err)
+		assert.Zero(t, rec.Msg.Record.StaleAt)
+
+		// Touching a flagged record clears the flag
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theFlaggedIndex", Id: "theRecordID", Data: `{"foo":"bar"}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		rec, err = cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theFlaggedIndex",
+			Id:    "theRecordID",
+		}))
+		require.NoError(t, err)
+		assert.Zero(t, rec.Msg.Record.StaleAt)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
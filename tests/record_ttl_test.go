@@ -0,0 +1,142 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestRecord_TTL(main *testing.T) {
+	main.Parallel()
+
+	main.Run("ExpiresAtInPast", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID", Data: `{}`, ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+			},
+		}))
+
+		assert.EqualError(t, err, "invalid_argument: record 0, id=theRecordID: validation failed: invalid expires_at: must be in the future")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("IndexDefaultTTL", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex", Ttl: proto.Uint64(3600)}))
+		require.NoError(t, err)
+
+		idx, err := cli.I.Get(context.Background(), connect.NewRequest(&indexproto.GetRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+		assert.Equal(t, uint64(3600), idx.Msg.Ttl)
+
+		expiresAt := time.Now().Add(time.Minute * 5).Unix()
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{}`},
+				{Index: "theIndex", Id: "theRecordID2", Data: `{}`, ExpiresAt: expiresAt},
+			},
+		}))
+		require.NoError(t, err)
+
+		res, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID1",
+		}))
+		require.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(time.Hour).Unix(), res.Msg.Record.ExpiresAt, 5)
+
+		res, err = cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID2",
+		}))
+		require.NoError(t, err)
+		assert.Equal(t, expiresAt, res.Msg.Record.ExpiresAt)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("ExpiredRecordsHidden", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionReaperInterval(time.Hour))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"foo":1}`, ExpiresAt: time.Now().Add(time.Second * 2).Unix()},
+				{Index: "theIndex", Id: "theRecordID2", Data: `{"foo":2}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		fnd, err := cli.R.Find(context.Background(), connect.NewRequest(&recordproto.FindRequest{Index: "theIndex"}))
+		require.NoError(t, err)
+		assert.Len(t, fnd.Msg.Records, 2)
+
+		require.Eventually(t, func() bool {
+			_, err := cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+				Index: "theIndex",
+				Id:    "theRecordID1",
+			}))
+			return err != nil && err.Error() == "not_found: record is not found"
+		}, time.Second*5, time.Millisecond*100)
+
+		fnd, err = cli.R.Find(context.Background(), connect.NewRequest(&recordproto.FindRequest{Index: "theIndex"}))
+		require.NoError(t, err)
+		require.Len(t, fnd.Msg.Records, 1)
+		assert.Equal(t, "theRecordID2", fnd.Msg.Records[0].Id)
+
+		// Not reaped yet
+		assert.Len(t, ta.DB().GetRecords("theIndex"), 2)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("ExpiredRecordsReaped", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionReaperInterval(time.Millisecond*100))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex", Ttl: proto.Uint64(1)}))
+		require.NoError(t, err)
+
+		_, err = cli.R.Push(context.Background(), connect.NewRequest(&recordproto.PushRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID", Data: `{"foo":1}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			return len(ta.DB().GetRecords("theIndex")) == 0
+		}, time.Second*5, time.Millisecond*100)
+
+		// History is kept
+		assert.Len(t, ta.DB().GetRecordLogs("theIndex"), 1)
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
@@ -0,0 +1,87 @@
+//go:build functest
+
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	indexproto "github.com/ashep/ujds/sdk/proto/ujds/index/v1"
+	recordproto "github.com/ashep/ujds/sdk/proto/ujds/record/v1"
+	"github.com/ashep/ujds/tests/testapp"
+)
+
+func TestRecord_Validate(main *testing.T) {
+	main.Parallel()
+
+	main.Run("InvalidAuthorization", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("anInvalidAuthToken")
+
+		_, err := cli.R.Validate(context.Background(), connect.NewRequest(&recordproto.ValidateRequest{}))
+
+		assert.EqualError(t, err, "unauthenticated: not authorized")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("EmptyRecords", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t)
+		cli := ta.Client("")
+
+		_, err := cli.R.Validate(context.Background(), connect.NewRequest(&recordproto.ValidateRequest{}))
+
+		assert.EqualError(t, err, "invalid_argument: empty records")
+		ta.AssertNoWarnsAndErrors()
+	})
+
+	main.Run("Ok", func(t *testing.T) {
+		t.Parallel()
+		ta := testapp.New(t, testapp.WithConfigOptionValidationIndex("theIndex", json.RawMessage(`{"required": ["foo"]}`)))
+		cli := ta.Client("")
+
+		_, err := cli.I.Push(context.Background(), connect.NewRequest(&indexproto.PushRequest{Name: "theIndex"}))
+		require.NoError(t, err)
+
+		res, err := cli.R.Validate(context.Background(), connect.NewRequest(&recordproto.ValidateRequest{
+			Records: []*recordproto.PushRequest_Record{
+				{Index: "theIndex", Id: "theRecordID1", Data: `{"foo": "bar"}`},
+				{Index: "theIndex", Id: "theRecordID2", Data: `{}`},
+				{Index: "theIndex", Id: "", Data: `{"foo": "bar"}`},
+				{Index: "theMissingIndex", Id: "theRecordID3", Data: `{"foo": "bar"}`},
+			},
+		}))
+		require.NoError(t, err)
+
+		assert.False(t, res.Msg.Valid)
+		require.Len(t, res.Msg.Results, 4)
+
+		assert.True(t, res.Msg.Results[0].Valid)
+
+		assert.False(t, res.Msg.Results[1].Valid)
+		assert.Equal(t, "invalid json: (root): missing property 'foo'", res.Msg.Results[1].Error)
+		require.Len(t, res.Msg.Results[1].Violations, 1)
+		assert.Equal(t, "required", res.Msg.Results[1].Violations[0].Keyword)
+
+		assert.False(t, res.Msg.Results[2].Valid)
+		assert.Equal(t, "record 2, id=: validation failed: invalid record id: must not be empty", res.Msg.Results[2].Error)
+
+		assert.False(t, res.Msg.Results[3].Valid)
+		assert.Equal(t, "index is not found", res.Msg.Results[3].Error)
+
+		// Nothing is written
+		_, err = cli.R.Get(context.Background(), connect.NewRequest(&recordproto.GetRequest{
+			Index: "theIndex",
+			Id:    "theRecordID1",
+		}))
+		assert.EqualError(t, err, "not_found: record is not found")
+
+		ta.AssertNoWarnsAndErrors()
+	})
+}
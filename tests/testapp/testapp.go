@@ -9,6 +9,10 @@ import (
 	"github.com/ashep/go-app/testlogger"
 	"github.com/ashep/go-app/testrunner"
 	"github.com/ashep/ujds/internal/app"
+	"github.com/ashep/ujds/internal/recordarchiver"
+	"github.com/ashep/ujds/internal/recordcompactor"
+	"github.com/ashep/ujds/internal/recordsweeper"
+	"github.com/ashep/ujds/internal/validation"
 	"github.com/ashep/ujds/sdk/client"
 	_ "github.com/lib/pq" // it's ok in tests
 )
@@ -32,6 +36,53 @@ func WithConfigOptionValidationIndex(k string, v json.RawMessage) ConfigOption {
 	}
 }
 
+func WithConfigOptionValidationSchemasDir(dir string) ConfigOption {
+	return func(cfg *app.Config) {
+		cfg.Validation.SchemasDir = dir
+	}
+}
+
+func WithConfigOptionValidationRecordID(k string, v validation.RecordIDRule) ConfigOption {
+	return func(cfg *app.Config) {
+		if cfg.Validation.RecordIDStruct == nil {
+			cfg.Validation.RecordIDStruct = make(map[string]validation.RecordIDRule)
+		}
+		cfg.Validation.RecordIDStruct[k] = v
+	}
+}
+
+func WithConfigOptionReaperInterval(d time.Duration) ConfigOption {
+	return func(cfg *app.Config) {
+		cfg.Reaper.Interval = d
+	}
+}
+
+func WithConfigOptionSweeper(interval time.Duration, policies map[string]recordsweeper.Policy) ConfigOption {
+	return func(cfg *app.Config) {
+		cfg.Sweeper.Interval = interval
+		cfg.Sweeper.IndexStruct = policies
+	}
+}
+
+func WithConfigOptionCompactor(interval time.Duration, policies map[string]recordcompactor.Policy) ConfigOption {
+	return func(cfg *app.Config) {
+		cfg.Compactor.Interval = interval
+		cfg.Compactor.IndexStruct = policies
+	}
+}
+
+func WithConfigOptionArchiver(
+	interval time.Duration,
+	dir string,
+	policies map[string]recordarchiver.Policy,
+) ConfigOption {
+	return func(cfg *app.Config) {
+		cfg.Archiver.Interval = interval
+		cfg.Archiver.Dir = dir
+		cfg.Archiver.IndexStruct = policies
+	}
+}
+
 func New(t *testing.T, opts ...ConfigOption) *TestApp {
 	t.Helper()
 
@@ -71,7 +122,19 @@ func (ta *TestApp) Client(authToken string) *client.Client {
 	if authToken == "" {
 		authToken = ta.cfg.Server.AuthToken
 	}
-	return client.New("http://"+ta.cfg.Server.Addr, authToken, http.DefaultClient)
+	return client.New(ta.URL(), authToken, http.DefaultClient)
+}
+
+func (ta *TestApp) URL() string {
+	return "http://" + ta.cfg.Server.Addr
+}
+
+func (ta *TestApp) Config() app.Config {
+	return ta.cfg
+}
+
+func (ta *TestApp) AuthToken() string {
+	return ta.cfg.Server.AuthToken
 }
 
 func (ta *TestApp) AssertNoWarnsAndErrors() {
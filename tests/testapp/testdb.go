@@ -1,16 +1,38 @@
 package testapp
 
 import (
+	"context"
 	"database/sql"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ashep/go-app/dbmigrator"
 	"github.com/ashep/go-app/testpostgres"
+	migrations "github.com/ashep/ujds/sql"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/lib/pq" // ok in tests
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 )
 
+// dbServer is the PostgreSQL server the test and benchmark databases are created at.
+var dbServer = struct { //nolint:gochecknoglobals // ok
+	host     string
+	port     int
+	user     string
+	password string
+}{
+	host:     "postgres",
+	port:     5432,
+	user:     "postgres",
+	password: "postgres",
+}
+
 type Index struct {
 	ID        int
 	Name      string
@@ -48,7 +70,12 @@ type TestDB struct {
 func newDB(t *testing.T) *TestDB {
 	t.Helper()
 
-	tp := testpostgres.New(t)
+	tp := testpostgres.New(t,
+		testpostgres.WithHost(dbServer.host),
+		testpostgres.WithPort(dbServer.port),
+		testpostgres.WithUser(dbServer.user),
+		testpostgres.WithPassword(dbServer.password),
+	)
 
 	return &TestDB{
 		DSN: tp.DSN(),
@@ -58,6 +85,42 @@ func newDB(t *testing.T) *TestDB {
 	}
 }
 
+// NewBenchDB creates a migrated database for a benchmark, since the test app and its database can only be used within
+// tests. The database is dropped once the benchmark is done.
+func NewBenchDB(b *testing.B) *sql.DB {
+	b.Helper()
+
+	serverDSN := (&url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(dbServer.user, dbServer.password),
+		Host:   net.JoinHostPort(dbServer.host, strconv.Itoa(dbServer.port)),
+	}).String()
+
+	admin, err := sql.Open("postgres", serverDSN+"?sslmode=disable")
+	require.NoError(b, err)
+
+	dbName := "bench_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	_, err = admin.Exec("CREATE DATABASE " + dbName)
+	require.NoError(b, err)
+
+	dsn := serverDSN + "/" + dbName + "?sslmode=disable"
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(b, err)
+
+	b.Cleanup(func() {
+		_ = db.Close()
+		_, err := admin.ExecContext(context.Background(), "DROP DATABASE "+dbName)
+		require.NoError(b, err)
+		_ = admin.Close()
+	})
+
+	_, err = dbmigrator.RunPostgres(dsn, zerolog.Nop(), dbmigrator.Source{FS: migrations.FS, Path: "migrations"})
+	require.NoError(b, err)
+
+	return db
+}
+
 func (d *TestDB) GetIndex(name string) Index {
 	row := d.d.QueryRow(`SELECT id, name, title, created_at, updated_at FROM index WHERE name=$1`, name)
 	require.NoError(d.t, row.Err())